@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/pkg/client"
+)
+
+// env содержит окружение выполнения команды.
+type env struct {
+	ctx    context.Context
+	client *client.Client
+	out    *printer
+	stdin  io.Reader
+	stderr io.Writer
+}
+
+// commands — команды shortctl.
+var commands = map[string]func(e *env, args []string) error{
+	"shorten": runShorten,
+	"batch":   runBatch,
+	"resolve": runResolve,
+	"list":    runList,
+	"delete":  runDelete,
+	"stats":   runStats,
+	"qr":      runQR,
+}
+
+// usages — синтаксис команд для справки.
+var usages = map[string]string{
+	"shorten": "shorten [-title T] [-tag T]... [-folder F] [-workspace W] [url...]",
+	"batch":   "batch <file.csv|file.ndjson>",
+	"resolve": "resolve <id>",
+	"list":    "list [-tag T] [-folder F] [-q words] [-workspace W] [-limit N] [-all]",
+	"delete":  "delete [id...]",
+	"stats":   "stats <id>",
+	"qr":      "qr <id> -o file.png|file.svg [-size N]",
+}
+
+// tags собирает значения повторяющегося флага -tag.
+type tags []string
+
+func (t *tags) String() string     { return strings.Join(*t, ",") }
+func (t *tags) Set(v string) error { *t = append(*t, v); return nil }
+
+// runShorten сокращает ссылки из аргументов или, если их нет, из стандартного ввода по одной на строку.
+func runShorten(e *env, args []string) error {
+	fs := newFlagSet("shorten", e.stderr)
+	req := client.ShortenRequest{}
+	var tagValues tags
+	fs.StringVar(&req.Title, "title", "", "link title")
+	fs.Var(&tagValues, "tag", "link tag (repeatable)")
+	fs.StringVar(&req.Folder, "folder", "", "link folder")
+	fs.StringVar(&req.Workspace, "workspace", "", "workspace to create the link in")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	req.Tags = tagValues
+
+	urls := fs.Args()
+	if len(urls) == 0 {
+		var err error
+		if urls, err = readLines(e.stdin); err != nil {
+			return err
+		}
+	}
+	if len(urls) == 0 {
+		return errors.New("no URLs to shorten")
+	}
+
+	type result struct {
+		URL      string `json:"url"`
+		ShortURL string `json:"short_url"`
+		Conflict bool   `json:"conflict"`
+	}
+	results := make([]result, 0, len(urls))
+	rows := make([][]string, 0, len(urls))
+	for _, originalURL := range urls {
+		req.URL = originalURL
+		shortened, err := e.client.ShortenJSON(e.ctx, req)
+		if err != nil {
+			return fmt.Errorf("shorten %s: %w", originalURL, err)
+		}
+		results = append(results, result{URL: originalURL, ShortURL: shortened.ShortURL, Conflict: shortened.Conflict})
+		rows = append(rows, []string{originalURL, shortened.ShortURL, strconv.FormatBool(shortened.Conflict)})
+	}
+	return e.out.print([]string{"url", "short_url", "conflict"}, rows, results)
+}
+
+// runBatch сокращает ссылки из файла CSV (столбцы correlation_id и original_url или один столбец адресов)
+// или JSON Lines с объектами {"correlation_id", "original_url"}. Файл "-" читается из стандартного ввода как CSV.
+func runBatch(e *env, args []string) error {
+	fs := newFlagSet("batch", e.stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected one file")
+	}
+
+	path := fs.Arg(0)
+	var read func(io.Reader) ([]client.BatchItem, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		read = readNDJSONBatch
+	case ".csv", "":
+		read = readCSVBatch
+	default:
+		return fmt.Errorf("unsupported batch file %s, expected .csv or .ndjson", path)
+	}
+
+	in := e.stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	items, err := read(in)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return errors.New("empty batch")
+	}
+
+	results, err := e.client.ShortenBatch(e.ctx, items)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(results))
+	for _, result := range results {
+		rows = append(rows, []string{result.CorrelationID, result.ShortURL, result.Status})
+	}
+	return e.out.print([]string{"correlation_id", "short_url", "status"}, rows, results)
+}
+
+// runResolve выводит адрес назначения ссылки.
+func runResolve(e *env, args []string) error {
+	id, err := singleArg("resolve", args, e.stderr)
+	if err != nil {
+		return err
+	}
+	resolution, err := e.client.Resolve(e.ctx, id)
+	if err != nil {
+		return err
+	}
+	return e.out.print([]string{"location", "status"},
+		[][]string{{resolution.Location, strconv.Itoa(resolution.StatusCode)}},
+		map[string]any{"location": resolution.Location, "status": resolution.StatusCode})
+}
+
+// runList выводит ссылки пользователя, с флагом -all — все страницы списка.
+func runList(e *env, args []string) error {
+	fs := newFlagSet("list", e.stderr)
+	opts := client.ListOptions{}
+	fs.StringVar(&opts.Tag, "tag", "", "filter by tag")
+	fs.StringVar(&opts.Folder, "folder", "", "filter by folder")
+	fs.StringVar(&opts.Query, "q", "", "search words")
+	fs.StringVar(&opts.Workspace, "workspace", "", "list workspace links")
+	fs.IntVar(&opts.Limit, "limit", 0, "page size")
+	all := fs.Bool("all", false, "fetch all pages")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var links []client.Link
+	for {
+		page, err := e.client.UserURLs(e.ctx, opts)
+		if err != nil {
+			return err
+		}
+		links = append(links, page.Items...)
+		if !*all || page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+
+	rows := make([][]string, 0, len(links))
+	for _, link := range links {
+		rows = append(rows, []string{link.ID, link.ShortURL, link.URL, strconv.FormatInt(link.Clicks, 10),
+			link.Title, strings.Join(link.Tags, ","), link.CreatedAt.Format(time.RFC3339)})
+	}
+	if links == nil {
+		links = []client.Link{}
+	}
+	return e.out.print([]string{"id", "short_url", "url", "clicks", "title", "tags", "created_at"}, rows, links)
+}
+
+// runDelete удаляет ссылки из аргументов или, если их нет, из стандартного ввода по одной на строку.
+func runDelete(e *env, args []string) error {
+	fs := newFlagSet("delete", e.stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ids := fs.Args()
+	if len(ids) == 0 {
+		var err error
+		if ids, err = readLines(e.stdin); err != nil {
+			return err
+		}
+	}
+	if len(ids) == 0 {
+		return errors.New("no links to delete")
+	}
+	return e.client.DeleteURLs(e.ctx, ids...)
+}
+
+// runStats выводит статистику переходов по ссылке.
+func runStats(e *env, args []string) error {
+	id, err := singleArg("stats", args, e.stderr)
+	if err != nil {
+		return err
+	}
+	stats, err := e.client.Stats(e.ctx, id)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{{"", stats.URL, strconv.FormatInt(stats.Clicks, 10)}}
+	for _, variant := range stats.Variants {
+		rows = append(rows, []string{variant.Name, variant.URL, strconv.FormatInt(variant.Clicks, 10)})
+	}
+	return e.out.print([]string{"variant", "url", "clicks"}, rows, stats)
+}
+
+// runQR сохраняет QR-код ссылки в файл. Формат определяется расширением файла.
+func runQR(e *env, args []string) error {
+	fs := newFlagSet("qr", e.stderr)
+	output := fs.String("o", "", "output file (.png or .svg)")
+	size := fs.Int("size", 0, "image size in pixels")
+	// Флаги допускаются и после идентификатора ссылки: qr <id> -o file.png.
+	var id string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if id == "" && fs.NArg() == 1 {
+		id = fs.Arg(0)
+	}
+	if id == "" || *output == "" {
+		return errors.New("expected link id and -o file")
+	}
+
+	format := "png"
+	if strings.EqualFold(filepath.Ext(*output), ".svg") {
+		format = "svg"
+	}
+	image, err := e.client.QR(e.ctx, id, client.QROptions{Format: format, Size: *size})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*output, image, 0o644)
+}
+
+// newFlagSet создаёт набор флагов команды, выводящий ошибки в stderr.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: shortctl %s\n", usages[name])
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// singleArg разбирает аргументы команды с единственным идентификатором ссылки.
+func singleArg(name string, args []string, stderr io.Writer) (string, error) {
+	fs := newFlagSet(name, stderr)
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		return "", errors.New("expected one link id")
+	}
+	return fs.Arg(0), nil
+}
+
+// readLines читает непустые строки.
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// readCSVBatch читает пакет из CSV. Строка заголовка с correlation_id пропускается,
+// для файла из одного столбца адресов идентификатором служит номер строки.
+func readCSVBatch(r io.Reader) ([]client.BatchItem, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read CSV: %w", err)
+	}
+
+	var items []client.BatchItem
+	for i, record := range records {
+		if i == 0 && len(record) > 0 && strings.EqualFold(record[0], "correlation_id") {
+			continue
+		}
+		switch len(record) {
+		case 1:
+			items = append(items, client.BatchItem{CorrelationID: strconv.Itoa(i + 1), OriginalURL: record[0]})
+		case 2:
+			items = append(items, client.BatchItem{CorrelationID: record[0], OriginalURL: record[1]})
+		default:
+			return nil, fmt.Errorf("line %d: expected correlation_id,original_url", i+1)
+		}
+	}
+	return items, nil
+}
+
+// readNDJSONBatch читает пакет из JSON Lines.
+func readNDJSONBatch(r io.Reader) ([]client.BatchItem, error) {
+	var items []client.BatchItem
+	decoder := json.NewDecoder(r)
+	for {
+		var item client.BatchItem
+		err := decoder.Decode(&item)
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read NDJSON: %w", err)
+		}
+		items = append(items, item)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/alexuryumtsev/go-shortener/pkg/client"
+)
+
+// Config содержит адрес сервиса и учётные данные. Значения читаются из файла конфигурации,
+// переопределяются переменными окружения, а адрес сервиса — также флагом -server.
+type Config struct {
+	Server string `json:"server"`  // Адрес сервиса
+	APIKey string `json:"api_key"` // API-ключ
+	Token  string `json:"token"`   // JWT, например полученный в обмен на API-ключ
+	Cookie string `json:"cookie"`  // Значение cookie пользователя, если вход выполнен в браузере
+}
+
+// Значения по умолчанию.
+const (
+	defaultServer     = "http://localhost:8080"
+	defaultConfigName = "shortctl/config.json"
+	userCookieName    = "user_id"
+)
+
+// loadConfig читает конфигурацию из файла path или, если путь не задан, из файла
+// SHORTCTL_CONFIG либо shortctl/config.json в каталоге настроек пользователя.
+// Отсутствие файла по умолчанию не считается ошибкой.
+func loadConfig(path string) (Config, error) {
+	cfg := Config{}
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv("SHORTCTL_CONFIG")
+		explicit = path != ""
+	}
+	if !explicit {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, defaultConfigName)
+		}
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist) && !explicit:
+		case err != nil:
+			return cfg, fmt.Errorf("read config: %w", err)
+		default:
+			if err := json.Unmarshal(data, &cfg); err != nil {
+				return cfg, fmt.Errorf("parse config %s: %w", path, err)
+			}
+		}
+	}
+
+	for env, dst := range map[string]*string{
+		"SHORTENER_URL":     &cfg.Server,
+		"SHORTENER_API_KEY": &cfg.APIKey,
+		"SHORTENER_TOKEN":   &cfg.Token,
+		"SHORTENER_COOKIE":  &cfg.Cookie,
+	} {
+		if value := os.Getenv(env); value != "" {
+			*dst = value
+		}
+	}
+	if cfg.Server == "" {
+		cfg.Server = defaultServer
+	}
+	return cfg, nil
+}
+
+// newClient создаёт клиент API по конфигурации.
+func newClient(cfg Config) (*client.Client, error) {
+	var opts []client.Option
+	switch {
+	case cfg.APIKey != "":
+		opts = append(opts, client.WithAPIKey(cfg.APIKey))
+	case cfg.Token != "":
+		opts = append(opts, client.WithToken(cfg.Token))
+	}
+	if cfg.Cookie != "" {
+		opts = append(opts, client.WithCookie(&http.Cookie{Name: userCookieName, Value: cfg.Cookie}))
+	}
+	return client.New(cfg.Server, opts...)
+}
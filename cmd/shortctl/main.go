@@ -0,0 +1,92 @@
+// Команда shortctl — клиент командной строки сервиса сокращения ссылок.
+//
+//	shortctl [-config file] [-server url] [-format table|json|csv] <command> [args]
+//
+// Адрес сервиса и учётные данные читаются из файла конфигурации в формате JSON
+// ({"server", "api_key", "token", "cookie"}) и переменных окружения SHORTENER_URL,
+// SHORTENER_API_KEY, SHORTENER_TOKEN и SHORTENER_COOKIE.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run выполняет команду и возвращает код завершения.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("shortctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "", "path to config file")
+	server := fs.String("server", "", "shortener base URL")
+	format := fs.String("format", formatTable, "output format: table, json or csv")
+	fs.Usage = func() { usage(stderr, fs) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		usage(stderr, fs)
+		return 2
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "shortctl: unknown command %q\n", fs.Arg(0))
+		usage(stderr, fs)
+		return 2
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(stderr, "shortctl:", err)
+		return 1
+	}
+	if *server != "" {
+		cfg.Server = *server
+	}
+	c, err := newClient(cfg)
+	if err != nil {
+		fmt.Fprintln(stderr, "shortctl:", err)
+		return 1
+	}
+	out, err := newPrinter(stdout, *format)
+	if err != nil {
+		fmt.Fprintln(stderr, "shortctl:", err)
+		return 2
+	}
+
+	e := &env{ctx: ctx, client: c, out: out, stdin: stdin, stderr: stderr}
+	if err := cmd(e, fs.Args()[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "shortctl %s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "Usage: shortctl [flags] <command> [args]")
+	fmt.Fprintln(w, "\nCommands:")
+	names := make([]string, 0, len(usages))
+	for name := range usages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(w, "  "+usages[name])
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	fs.PrintDefaults()
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/config"
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/router"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/alexuryumtsev/go-shortener/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	logger.InitLogger()
+	repo := memory.NewInMemoryStorage()
+	srv := httptest.NewServer(nil)
+	t.Cleanup(srv.Close)
+	srv.Config.Handler = router.ShortenerRouter(&config.Config{BaseURL: srv.URL, SecretKey: "secret"}, repo, nil)
+
+	key, err := service.NewAPIKeyService(context.Background(), repo).Create("alice", "cli", "",
+		[]string{models.ScopeLinksRead, models.ScopeLinksWrite, models.ScopeStatsRead})
+	require.NoError(t, err)
+
+	// Адрес сервиса берётся из файла конфигурации, ключ — из окружения.
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"server": "`+srv.URL+`", "api_key": "sk_wrong"}`), 0o600))
+	t.Setenv("SHORTCTL_CONFIG", configPath)
+	t.Setenv("SHORTENER_API_KEY", key.Key)
+
+	shortctl := func(stdin string, args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+
+	code, out, errOut := shortctl("", "shorten", "-tag", "docs", "https://example.com/docs")
+	require.Equal(t, 0, code, errOut)
+	// Ссылка с тегом получает случайный идентификатор.
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	shortURL := strings.Fields(lines[1])[1]
+	require.True(t, strings.HasPrefix(shortURL, srv.URL+"/"), out)
+	id := strings.TrimPrefix(shortURL, srv.URL+"/")
+
+	code, out, errOut = shortctl("https://example.com/a\n\nhttps://example.com/b\n", "-format", "csv", "shorten")
+	require.Equal(t, 0, code, errOut)
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"url", "short_url", "conflict"},
+		{"https://example.com/a", srv.URL + "/" + service.GenerateID("https://example.com/a"), "false"},
+		{"https://example.com/b", srv.URL + "/" + service.GenerateID("https://example.com/b"), "false"},
+	}, records)
+
+	t.Run("batch", func(t *testing.T) {
+		csvFile := filepath.Join(dir, "links.csv")
+		require.NoError(t, os.WriteFile(csvFile, []byte("correlation_id,original_url\nc1,https://example.com/c1\n"), 0o600))
+		ndjsonFile := filepath.Join(dir, "links.ndjson")
+		require.NoError(t, os.WriteFile(ndjsonFile, []byte(`{"correlation_id":"n1","original_url":"https://example.com/n1"}`+"\n"), 0o600))
+
+		code, out, errOut := shortctl("", "-format", "json", "batch", csvFile)
+		require.Equal(t, 0, code, errOut)
+		var results []client.BatchResult
+		require.NoError(t, json.Unmarshal([]byte(out), &results))
+		assert.Equal(t, []client.BatchResult{{CorrelationID: "c1", ShortURL: srv.URL + "/" + service.GenerateID("https://example.com/c1"), Status: "created"}}, results)
+
+		code, out, errOut = shortctl("", "batch", ndjsonFile)
+		require.Equal(t, 0, code, errOut)
+		assert.Contains(t, out, "n1")
+
+		code, _, errOut = shortctl("", "batch", filepath.Join(dir, "links.xml"))
+		assert.Equal(t, 1, code)
+		assert.Contains(t, errOut, "unsupported batch file")
+	})
+
+	t.Run("list, resolve and stats", func(t *testing.T) {
+		code, out, errOut := shortctl("", "-format", "json", "list", "-all", "-limit", "2")
+		require.Equal(t, 0, code, errOut)
+		var links []client.Link
+		require.NoError(t, json.Unmarshal([]byte(out), &links))
+		assert.Len(t, links, 5)
+
+		code, out, errOut = shortctl("", "list", "-tag", "docs")
+		require.Equal(t, 0, code, errOut)
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		require.Len(t, lines, 2)
+		assert.True(t, strings.HasPrefix(lines[0], "ID"))
+		assert.True(t, strings.HasPrefix(lines[1], id))
+
+		code, out, errOut = shortctl("", "resolve", id)
+		require.Equal(t, 0, code, errOut)
+		assert.Contains(t, out, "https://example.com/docs")
+		assert.Contains(t, out, "307")
+
+		code, out, errOut = shortctl("", "-format", "json", "stats", id)
+		require.Equal(t, 0, code, errOut)
+		var stats client.Stats
+		require.NoError(t, json.Unmarshal([]byte(out), &stats))
+		assert.Equal(t, int64(1), stats.Clicks)
+	})
+
+	t.Run("qr", func(t *testing.T) {
+		file := filepath.Join(dir, "qr.png")
+		code, _, errOut := shortctl("", "qr", id, "-o", file)
+		require.Equal(t, 0, code, errOut)
+		image, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(image, []byte("\x89PNG")))
+
+		code, _, _ = shortctl("", "qr", id)
+		assert.Equal(t, 1, code)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		code, _, errOut := shortctl(id+"\n", "delete")
+		require.Equal(t, 0, code, errOut)
+		code, _, errOut = shortctl("", "resolve", id)
+		assert.Equal(t, 1, code)
+		assert.Contains(t, errOut, "410")
+	})
+
+	t.Run("usage errors", func(t *testing.T) {
+		code, _, errOut := shortctl("", "unknown")
+		assert.Equal(t, 2, code)
+		assert.Contains(t, errOut, "unknown command")
+
+		code, _, _ = shortctl("", "-format", "xml", "list")
+		assert.Equal(t, 2, code)
+
+		code, _, _ = shortctl("")
+		assert.Equal(t, 2, code)
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Форматы вывода.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// printer выводит результаты команд в выбранном формате: таблицу и CSV — по строкам,
+// JSON — исходное значение целиком.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case formatTable, formatJSON, formatCSV:
+		return &printer{w: w, format: format}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format %q, expected table, json or csv", format)
+	}
+}
+
+// print выводит строки rows с заголовком header или, для формата JSON, значение v.
+func (p *printer) print(header []string, rows [][]string, v any) error {
+	switch p.format {
+	case formatJSON:
+		encoder := json.NewEncoder(p.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case formatCSV:
+		writer := csv.NewWriter(p.w)
+		writer.Write(header)
+		writer.WriteAll(rows)
+		return writer.Error()
+	default:
+		writer := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.ToUpper(strings.Join(header, "\t")))
+		for _, row := range rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		return writer.Flush()
+	}
+}
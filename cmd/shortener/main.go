@@ -2,12 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/config"
 	"github.com/alexuryumtsev/go-shortener/internal/app/db"
+	"github.com/alexuryumtsev/go-shortener/internal/app/enrich"
+	"github.com/alexuryumtsev/go-shortener/internal/app/grpcserver"
+	"github.com/alexuryumtsev/go-shortener/internal/app/idempotency"
 	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
 	"github.com/alexuryumtsev/go-shortener/internal/app/router"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
@@ -16,6 +25,12 @@ import (
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage/pg"
 )
 
+// Таймаут загрузки страницы назначения для получения её метаданных.
+const enrichTimeout = 10 * time.Second
+
+// Время на завершение обработки текущих запросов при остановке сервера.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	// Инициализируем конфигурацию
 	cfg, err := config.InitConfig()
@@ -26,9 +41,11 @@ func main() {
 	// Инициализируем логгер
 	logger.InitLogger()
 
+	// Контекст отменяется при получении сигнала завершения и останавливает фоновые обработчики.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Подключаемся к базе данных
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 
 	var repo storage.URLStorage
 	if cfg.DatabaseDSN != "" {
@@ -44,10 +61,49 @@ func main() {
 		repo = memory.NewInMemoryStorage()
 	}
 
+	// Созданные и изменённые ссылки ставятся в очередь на получение метаданных страницы.
+	enricher := enrich.NewWorker(repo, enrich.NewHTTPFetcher(enrichTimeout), enrich.DefaultOptions())
+	enricher.Start(ctx)
+
+	// Маршруты создаются до запуска gRPC-сервера: при этом загружается файловое хранилище.
+	handler := router.ShortenerRouter(cfg, repo, enricher)
+
+	// Ответы на идемпотентные запросы с истёкшим сроком хранения периодически удаляются.
+	idempotency.StartPurge(ctx, repo, idempotency.PurgeInterval)
+
+	// Запуск gRPC-сервера на отдельном порту
+	if cfg.GRPCAddress != "" {
+		listener, err := net.Listen("tcp", cfg.GRPCAddress)
+		if err != nil {
+			log.Fatalf("Failed to listen gRPC address: %v", err)
+		}
+		grpcServer := grpcserver.NewServer(cfg, repo, enricher)
+		go func() {
+			<-ctx.Done()
+			grpcServer.GracefulStop()
+		}()
+		go func() {
+			fmt.Println("gRPC server started at", cfg.GRPCAddress)
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatalf("Failed to start gRPC server: %v", err)
+			}
+		}()
+	}
+
 	// Запуск сервера
+	server := &http.Server{Addr: cfg.ServerAddress, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Failed to shut down server: %v", err)
+		}
+	}()
+
 	fmt.Println("Server started at", cfg.ServerAddress)
-	err = http.ListenAndServe(cfg.ServerAddress, router.ShortenerRouter(cfg, repo))
-	if err != nil {
+	err = server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
@@ -1,18 +1,45 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/idempotency"
 	"github.com/alexuryumtsev/go-shortener/internal/app/validator"
 )
 
 type Config struct {
-	ServerAddress   string // Адрес запуска HTTP-сервера
-	BaseURL         string // Базовый адрес для сокращённых URL
-	FileStoragePath string // Путь к файлу хранилища
-	DatabaseDSN     string // подключения к PostgreSQL
+	ServerAddress   string   // Адрес запуска HTTP-сервера
+	GRPCAddress     string   // Адрес запуска gRPC-сервера; пустой отключает gRPC API
+	BaseURL         string   // Базовый адрес для сокращённых URL
+	FileStoragePath string   // Путь к файлу хранилища
+	DatabaseDSN     string   // подключения к PostgreSQL
+	FlaggedDomains  []string // Домены, для которых на странице предпросмотра выводится предупреждение
+	SecretKey       string   // Ключ для подписи cookie
+	GeoIPDBPath     string   // Путь к файлу базы GeoIP для правил по странам
+	JWTKeysFile     string   // Путь к файлу ключей JWT в формате JWKS
+	JWTIssuer       string   // Издатель (iss) выпускаемых и принимаемых JWT
+	JWTAudience     string   // Аудитория (aud) выпускаемых и принимаемых JWT
+
+	OIDCIssuerURL      string // Адрес издателя или документа обнаружения OpenID Connect; пустой отключает вход через провайдера
+	OIDCClientID       string // Идентификатор клиента у провайдера
+	OIDCClientSecret   string // Секрет клиента у провайдера
+	OIDCRedirectURL    string // Адрес возврата после входа, по умолчанию BaseURL + auth/callback
+	OIDCWorkspaceClaim string // Утверждение ID-токена со списком групп пользователя
+	OIDCWorkspaces     string // Правила членства в рабочих пространствах: group=workspace:role через запятую
+
+	TrustedSubnet string   // Доверенная подсеть (CIDR) для внутренних эндпоинтов; пустая запрещает доступ к ним
+	AdminUsers    []string // Идентификаторы пользователей-администраторов
+
+	APIV1Deprecation time.Time     // Дата объявления API v1 устаревшим для заголовка Deprecation; нулевая — дата выпуска API v2
+	APIV1Sunset      time.Time     // Дата отключения API v1 для заголовка Sunset; нулевая — через год после объявления устаревшим
+	IdempotencyTTL   time.Duration // Срок хранения ответов на запросы с заголовком Idempotency-Key
 }
 
 // Значения по умолчанию.
@@ -21,6 +48,8 @@ const (
 	defaultBaseURL       = "http://localhost:8080/"
 	defaultStoragePath   = "tmp/storage.json"
 	defaultDatabaseDSN   = ""
+	defaultOIDCClaim     = "groups"
+	defaultIdempotency   = idempotency.DefaultWindow
 )
 
 func InitConfig() (*Config, error) {
@@ -28,16 +57,52 @@ func InitConfig() (*Config, error) {
 
 	// Получаем значения из переменных окружения.
 	envServerAddress := os.Getenv("SERVER_ADDRESS")
+	envGRPCAddress := os.Getenv("GRPC_ADDRESS")
 	envBaseURL := os.Getenv("BASE_URL")
 	envPath := os.Getenv("FILE_STORAGE_PATH")
 	envFileStorageName := os.Getenv("FILE_STORAGE_NAME")
 	envDatabaseDSN := os.Getenv("DATABASE_DSN")
+	envFlaggedDomains := os.Getenv("FLAGGED_DOMAINS")
+	envSecretKey := os.Getenv("SECRET_KEY")
+	envGeoIPDBPath := os.Getenv("GEOIP_DB_PATH")
+	envJWTKeysFile := os.Getenv("JWT_KEYS_FILE")
+	envJWTIssuer := os.Getenv("JWT_ISSUER")
+	envJWTAudience := os.Getenv("JWT_AUDIENCE")
+	envOIDCIssuerURL := os.Getenv("OIDC_ISSUER_URL")
+	envOIDCClientID := os.Getenv("OIDC_CLIENT_ID")
+	envOIDCClientSecret := os.Getenv("OIDC_CLIENT_SECRET")
+	envOIDCRedirectURL := os.Getenv("OIDC_REDIRECT_URL")
+	envOIDCWorkspaceClaim := os.Getenv("OIDC_WORKSPACE_CLAIM")
+	envOIDCWorkspaces := os.Getenv("OIDC_WORKSPACES")
+	envTrustedSubnet := os.Getenv("TRUSTED_SUBNET")
+	envAdminUsers := os.Getenv("ADMIN_USERS")
+	envAPIV1Deprecation := os.Getenv("API_V1_DEPRECATION")
+	envAPIV1Sunset := os.Getenv("API_V1_SUNSET")
+	envIdempotencyTTL := os.Getenv("IDEMPOTENCY_TTL")
 
 	// Определяем флаги
 	flag.StringVar(&cfg.ServerAddress, "a", "", "HTTP server address, host:port")
+	flag.StringVar(&cfg.GRPCAddress, "g", envGRPCAddress, "gRPC server address, host:port")
 	flag.StringVar(&cfg.BaseURL, "b", "", "Base URL for shortened links")
 	flag.StringVar(&cfg.FileStoragePath, "f", "", "Path to file storage")
 	flag.StringVar(&cfg.DatabaseDSN, "d", envDatabaseDSN, "Строка подключения к базе данных (DSN)")
+	flaggedDomains := flag.String("flagged-domains", envFlaggedDomains, "Comma-separated list of flagged domains")
+	flag.StringVar(&cfg.SecretKey, "k", envSecretKey, "Secret key for signing cookies")
+	flag.StringVar(&cfg.GeoIPDBPath, "geoip-db", envGeoIPDBPath, "Path to GeoIP database file (network,country per line)")
+	flag.StringVar(&cfg.JWTKeysFile, "jwt-keys", envJWTKeysFile, "Path to JWKS file with JWT signing keys")
+	flag.StringVar(&cfg.JWTIssuer, "jwt-issuer", envJWTIssuer, "JWT issuer (iss)")
+	flag.StringVar(&cfg.JWTAudience, "jwt-audience", envJWTAudience, "JWT audience (aud)")
+	flag.StringVar(&cfg.OIDCIssuerURL, "oidc-issuer", envOIDCIssuerURL, "OpenID Connect issuer or discovery URL")
+	flag.StringVar(&cfg.OIDCClientID, "oidc-client-id", envOIDCClientID, "OpenID Connect client ID")
+	flag.StringVar(&cfg.OIDCClientSecret, "oidc-client-secret", envOIDCClientSecret, "OpenID Connect client secret")
+	flag.StringVar(&cfg.OIDCRedirectURL, "oidc-redirect-url", envOIDCRedirectURL, "OpenID Connect redirect URL")
+	flag.StringVar(&cfg.OIDCWorkspaceClaim, "oidc-workspace-claim", envOIDCWorkspaceClaim, "ID token claim with user groups")
+	flag.StringVar(&cfg.OIDCWorkspaces, "oidc-workspaces", envOIDCWorkspaces, "Comma-separated workspace rules: group=workspace:role")
+	flag.StringVar(&cfg.TrustedSubnet, "t", envTrustedSubnet, "Trusted subnet (CIDR) for internal endpoints")
+	adminUsers := flag.String("admin-users", envAdminUsers, "Comma-separated list of admin user IDs")
+	apiV1Deprecation := flag.String("api-v1-deprecation", envAPIV1Deprecation, "API v1 deprecation date, YYYY-MM-DD")
+	apiV1Sunset := flag.String("api-v1-sunset", envAPIV1Sunset, "API v1 sunset date, YYYY-MM-DD")
+	idempotencyTTL := flag.String("idempotency-ttl", envIdempotencyTTL, "How long responses to requests with Idempotency-Key are replayed, e.g. 24h")
 
 	// Обрабатываем флаги
 	flag.Parse()
@@ -57,6 +122,12 @@ func InitConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if cfg.GRPCAddress != "" {
+		if err := validator.ValidateServerAddress(cfg.GRPCAddress); err != nil {
+			return nil, err
+		}
+	}
+
 	if cfg.BaseURL == "" {
 		cfg.BaseURL = envBaseURL
 	}
@@ -81,11 +152,67 @@ func InitConfig() (*Config, error) {
 		cfg.DatabaseDSN = defaultDatabaseDSN
 	}
 
+	for _, domain := range strings.Split(*flaggedDomains, ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			cfg.FlaggedDomains = append(cfg.FlaggedDomains, domain)
+		}
+	}
+
+	for _, userID := range strings.Split(*adminUsers, ",") {
+		if userID = strings.TrimSpace(userID); userID != "" {
+			cfg.AdminUsers = append(cfg.AdminUsers, userID)
+		}
+	}
+
+	if cfg.TrustedSubnet != "" {
+		if err := validator.ValidateCIDR(cfg.TrustedSubnet); err != nil {
+			return nil, err
+		}
+	}
+
+	if *apiV1Deprecation != "" {
+		if cfg.APIV1Deprecation, err = time.Parse(time.DateOnly, *apiV1Deprecation); err != nil {
+			return nil, fmt.Errorf("invalid API v1 deprecation date: %w", err)
+		}
+	}
+	if *apiV1Sunset != "" {
+		if cfg.APIV1Sunset, err = time.Parse(time.DateOnly, *apiV1Sunset); err != nil {
+			return nil, fmt.Errorf("invalid API v1 sunset date: %w", err)
+		}
+	}
+	if !cfg.APIV1Deprecation.IsZero() && !cfg.APIV1Sunset.IsZero() && cfg.APIV1Sunset.Before(cfg.APIV1Deprecation) {
+		return nil, fmt.Errorf("API v1 sunset date %s is before its deprecation date %s",
+			*apiV1Sunset, *apiV1Deprecation)
+	}
+
+	cfg.IdempotencyTTL = defaultIdempotency
+	if *idempotencyTTL != "" {
+		if cfg.IdempotencyTTL, err = time.ParseDuration(*idempotencyTTL); err != nil || cfg.IdempotencyTTL <= 0 {
+			return nil, fmt.Errorf("invalid idempotency TTL %q", *idempotencyTTL)
+		}
+	}
+
+	// Без заданного ключа подписи cookie теряют силу после перезапуска сервера.
+	if cfg.SecretKey == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate secret key: %w", err)
+		}
+		cfg.SecretKey = hex.EncodeToString(secret)
+	}
+
 	// Проверка корректности URL
 	err = validator.ValidateBaseURL(cfg.BaseURL)
 	if err != nil {
 		return nil, err
 	}
 
+	if cfg.OIDCRedirectURL == "" {
+		cfg.OIDCRedirectURL = strings.TrimSuffix(cfg.BaseURL, "/") + "/auth/callback"
+	}
+	if cfg.OIDCWorkspaceClaim == "" {
+		cfg.OIDCWorkspaceClaim = defaultOIDCClaim
+	}
+
 	return cfg, nil
 }
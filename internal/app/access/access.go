@@ -0,0 +1,184 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// ErrForbidden возвращается, если у пользователя нет прав на действие.
+var ErrForbidden = apperr.New(apperr.Forbidden, "access denied")
+
+// Action описывает действие над ссылками или рабочим пространством.
+type Action string
+
+// Действия, права на которые определяются ролью участника рабочего пространства.
+const (
+	ActionList   Action = "list"   // Просмотр ссылок и участников
+	ActionCreate Action = "create" // Создание ссылок
+	ActionEdit   Action = "edit"   // Изменение и откат ссылок
+	ActionDelete Action = "delete" // Удаление ссылок
+	ActionStats  Action = "stats"  // Просмотр статистики и истории изменений
+	ActionManage Action = "manage" // Управление участниками и приглашениями
+)
+
+// permissions задаёт действия, разрешённые каждой роли.
+var permissions = map[string]map[Action]bool{
+	models.RoleOwner: {
+		ActionList: true, ActionCreate: true, ActionEdit: true, ActionDelete: true, ActionStats: true, ActionManage: true,
+	},
+	models.RoleEditor: {
+		ActionList: true, ActionCreate: true, ActionEdit: true, ActionDelete: true, ActionStats: true,
+	},
+	models.RoleViewer: {
+		ActionList: true, ActionStats: true,
+	},
+}
+
+// ValidRole проверяет, что роль существует.
+func ValidRole(role string) bool {
+	_, ok := permissions[role]
+	return ok
+}
+
+// Allowed проверяет, разрешено ли действие роли.
+func Allowed(role string, action Action) bool {
+	return permissions[role][action]
+}
+
+// Policy проверяет права пользователей на ссылки и рабочие пространства.
+type Policy struct {
+	repo storage.URLStorage
+}
+
+// NewPolicy создаёт политику доступа, читающую участников из repo.
+func NewPolicy(repo storage.URLStorage) *Policy {
+	return &Policy{repo: repo}
+}
+
+// AuthorizeLink проверяет право пользователя на действие со ссылкой.
+// Личной ссылкой полностью распоряжается её владелец, ссылкой рабочего пространства —
+// участники в соответствии с ролью. API-ключ рабочего пространства даёт доступ только к его ссылкам.
+func (p *Policy) AuthorizeLink(ctx context.Context, userID string, urlModel models.URLModel, action Action) error {
+	if keyWorkspace := auth.WorkspaceID(ctx); keyWorkspace != "" && keyWorkspace != urlModel.WorkspaceID {
+		return ErrForbidden
+	}
+	if urlModel.WorkspaceID != "" {
+		return p.authorizeMember(ctx, userID, urlModel.WorkspaceID, action)
+	}
+	if userID == "" || urlModel.UserID != userID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// AuthorizeWorkspace проверяет право пользователя на действие в рабочем пространстве.
+// Для несуществующего пространства возвращается storage.ErrNotFound.
+func (p *Policy) AuthorizeWorkspace(ctx context.Context, userID, workspaceID string, action Action) error {
+	if keyWorkspace := auth.WorkspaceID(ctx); keyWorkspace != "" && keyWorkspace != workspaceID {
+		return ErrForbidden
+	}
+	if _, err := p.repo.Workspace(ctx, workspaceID); err != nil {
+		return err
+	}
+	return p.authorizeMember(ctx, userID, workspaceID, action)
+}
+
+func (p *Policy) authorizeMember(ctx context.Context, userID, workspaceID string, action Action) error {
+	member, err := p.repo.Member(ctx, workspaceID, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	if !Allowed(member.Role, action) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Link возвращает middleware, проверяющее право на действие со ссылкой из параметра маршрута {id}.
+func (p *Policy) Link(action Action) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			urlModel, exists := p.repo.Get(ctx, chi.URLParam(r, "id"))
+			if !exists {
+				middleware.WriteError(w, r, apperr.New(apperr.NotFound, "URL not found"))
+				return
+			}
+			if err := p.AuthorizeLink(ctx, auth.UserID(ctx), urlModel, action); err != nil {
+				writeError(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Workspace возвращает middleware, проверяющее право на действие в рабочем пространстве
+// из параметра маршрута {workspace}.
+func (p *Policy) Workspace(action Action) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if err := p.AuthorizeWorkspace(ctx, auth.UserID(ctx), chi.URLParam(r, "workspace"), action); err != nil {
+				writeError(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// APIKeyWorkspace возвращает middleware для маршрутов без параметра {workspace}, которые при запросе
+// с API-ключом рабочего пространства работают с этим пространством: проверяется право на действие в нём.
+func (p *Policy) APIKeyWorkspace(action Action) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if workspaceID := auth.WorkspaceID(ctx); workspaceID != "" {
+				if err := p.AuthorizeWorkspace(ctx, auth.UserID(ctx), workspaceID, action); err != nil {
+					writeError(w, r, err)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Admin возвращает middleware, пропускающее только пользователей из списка администраторов admins.
+// Действия администратора недоступны по API-ключам.
+func Admin(admins []string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(admins))
+	for _, userID := range admins {
+		allowed[userID] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if _, ok := auth.APIKey(ctx); ok || !allowed[auth.UserID(ctx)] {
+				middleware.WriteError(w, r, apperr.New(apperr.Forbidden, "Access denied"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeError преобразует ошибку проверки прав в HTTP-ответ.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		err = apperr.Wrap(apperr.NotFound, "Workspace not found", err)
+	}
+	middleware.WriteError(w, r, err)
+}
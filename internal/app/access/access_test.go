@@ -0,0 +1,74 @@
+package access
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowed(t *testing.T) {
+	assert.True(t, Allowed(models.RoleOwner, ActionManage))
+	assert.True(t, Allowed(models.RoleEditor, ActionDelete))
+	assert.False(t, Allowed(models.RoleEditor, ActionManage))
+	assert.True(t, Allowed(models.RoleViewer, ActionStats))
+	assert.False(t, Allowed(models.RoleViewer, ActionEdit))
+	assert.False(t, Allowed("admin", ActionList))
+	assert.False(t, ValidRole("admin"))
+}
+
+func TestPolicy_Middleware(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	repo := memory.NewInMemoryStorage()
+	require.NoError(t, repo.CreateWorkspace(ctx,
+		models.Workspace{ID: "ws1", Name: "Team", CreatedBy: "alice", CreatedAt: now},
+		models.Member{WorkspaceID: "ws1", UserID: "alice", Role: models.RoleOwner, JoinedAt: now}))
+	require.NoError(t, repo.SaveMember(ctx, models.Member{WorkspaceID: "ws1", UserID: "bob", Role: models.RoleViewer, JoinedAt: now}))
+	require.NoError(t, repo.Save(ctx, models.URLModel{ID: "team", URL: "https://example.com/", UserID: "alice", WorkspaceID: "ws1"}))
+	require.NoError(t, repo.Save(ctx, models.URLModel{ID: "own", URL: "https://example.org/", UserID: "carol"}))
+
+	policy := NewPolicy(repo)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	r := chi.NewRouter()
+	r.With(policy.Link(ActionStats)).Get("/urls/{id}/stats", ok)
+	r.With(policy.Link(ActionEdit)).Patch("/urls/{id}", ok)
+	r.With(policy.Workspace(ActionManage)).Get("/workspaces/{workspace}/invitations", ok)
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		userID       string
+		expectedCode int
+	}{
+		{name: "viewer reads stats", method: http.MethodGet, target: "/urls/team/stats", userID: "bob", expectedCode: http.StatusOK},
+		{name: "viewer cannot edit", method: http.MethodPatch, target: "/urls/team", userID: "bob", expectedCode: http.StatusForbidden},
+		{name: "owner edits", method: http.MethodPatch, target: "/urls/team", userID: "alice", expectedCode: http.StatusOK},
+		{name: "non-member", method: http.MethodGet, target: "/urls/team/stats", userID: "carol", expectedCode: http.StatusForbidden},
+		{name: "personal link owner", method: http.MethodPatch, target: "/urls/own", userID: "carol", expectedCode: http.StatusOK},
+		{name: "personal link stranger", method: http.MethodGet, target: "/urls/own/stats", userID: "alice", expectedCode: http.StatusForbidden},
+		{name: "unknown link", method: http.MethodGet, target: "/urls/missing/stats", userID: "alice", expectedCode: http.StatusNotFound},
+		{name: "owner manages", method: http.MethodGet, target: "/workspaces/ws1/invitations", userID: "alice", expectedCode: http.StatusOK},
+		{name: "viewer cannot manage", method: http.MethodGet, target: "/workspaces/ws1/invitations", userID: "bob", expectedCode: http.StatusForbidden},
+		{name: "unknown workspace", method: http.MethodGet, target: "/workspaces/ws2/invitations", userID: "alice", expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req = req.WithContext(auth.WithUserID(req.Context(), tt.userID))
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Package apperr описывает ошибки приложения. Каждая ошибка относится к виду (Kind),
+// который определяет HTTP-статус ответа, заголовок и тип описания проблемы (RFC 7807),
+// а также код ответа gRPC. Сентинельные ошибки хранилища и сервисов создаются
+// этим пакетом, поэтому вид ошибки сохраняется при обёртывании через fmt.Errorf("%w").
+package apperr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Kind — вид ошибки приложения.
+type Kind int
+
+// Виды ошибок.
+const (
+	Internal             Kind = iota // Внутренняя ошибка; подробности не раскрываются клиенту
+	InvalidInput                     // Некорректный запрос
+	Unauthorized                     // Не удалось определить пользователя
+	Forbidden                        // Действие запрещено
+	NotFound                         // Объект не найден
+	Conflict                         // Объект уже существует
+	Gone                             // Объект удалён или больше недоступен
+	UnsupportedMediaType             // Формат тела запроса не поддерживается
+	Unprocessable                    // Запрос корректен, но не может быть выполнен
+	TooManyRequests                  // Превышен лимит запросов
+	BadGateway                       // Ошибка внешнего сервиса
+	Unavailable                      // Сервис временно недоступен
+)
+
+// kindInfo содержит HTTP-статус, заголовок и короткое имя вида ошибки.
+type kindInfo struct {
+	status int
+	title  string
+	slug   string
+}
+
+var kinds = map[Kind]kindInfo{
+	Internal:             {http.StatusInternalServerError, "Internal server error", "internal"},
+	InvalidInput:         {http.StatusBadRequest, "Invalid input", "invalid-input"},
+	Unauthorized:         {http.StatusUnauthorized, "Unauthorized", "unauthorized"},
+	Forbidden:            {http.StatusForbidden, "Forbidden", "forbidden"},
+	NotFound:             {http.StatusNotFound, "Not found", "not-found"},
+	Conflict:             {http.StatusConflict, "Conflict", "conflict"},
+	Gone:                 {http.StatusGone, "Gone", "gone"},
+	UnsupportedMediaType: {http.StatusUnsupportedMediaType, "Unsupported media type", "unsupported-media-type"},
+	Unprocessable:        {http.StatusUnprocessableEntity, "Unprocessable entity", "unprocessable"},
+	TooManyRequests:      {http.StatusTooManyRequests, "Too many requests", "too-many-requests"},
+	BadGateway:           {http.StatusBadGateway, "Bad gateway", "bad-gateway"},
+	Unavailable:          {http.StatusServiceUnavailable, "Service unavailable", "unavailable"},
+}
+
+// Kinds возвращает все виды ошибок в порядке объявления.
+func Kinds() []Kind {
+	return []Kind{Internal, InvalidInput, Unauthorized, Forbidden, NotFound, Conflict, Gone,
+		UnsupportedMediaType, Unprocessable, TooManyRequests, BadGateway, Unavailable}
+}
+
+// Status возвращает HTTP-статус ответа.
+func (k Kind) Status() int {
+	return k.info().status
+}
+
+// Title возвращает краткое описание вида ошибки, не зависящее от конкретного случая.
+func (k Kind) Title() string {
+	return k.info().title
+}
+
+// Slug возвращает короткое имя вида ошибки, например not-found.
+func (k Kind) Slug() string {
+	return k.info().slug
+}
+
+// Type возвращает URI типа проблемы: ссылку на описание вида ошибки на странице документации API.
+func (k Kind) Type() string {
+	return "/api/docs#problem-" + k.Slug()
+}
+
+// ServerError сообщает, что ошибка вызвана сбоем сервера или внешнего сервиса, а не запросом.
+func (k Kind) ServerError() bool {
+	return k.Status() >= http.StatusInternalServerError
+}
+
+func (k Kind) info() kindInfo {
+	if info, ok := kinds[k]; ok {
+		return info
+	}
+	return kinds[Internal]
+}
+
+// FieldError описывает ошибку в поле запроса.
+// Field — путь к полю, например [0].correlation_id; пустой для тела целиком.
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// Error — ошибка приложения определённого вида.
+type Error struct {
+	Kind    Kind
+	Message string       // Сообщение для клиента
+	Fields  []FieldError // Ошибки в полях запроса
+	Err     error        // Исходная ошибка
+}
+
+// New создаёт ошибку вида kind с сообщением message.
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Wrap создаёт ошибку вида kind с сообщением message, вызванную err.
+func Wrap(kind Kind, message string, err error) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+// Validation создаёт ошибку некорректного запроса с ошибками в полях.
+func Validation(message string, fields []FieldError) *Error {
+	return &Error{Kind: InvalidInput, Message: message, Fields: fields}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf возвращает вид ошибки. Ошибки, не созданные этим пакетом, считаются внутренними,
+// кроме истечения срока запроса, которое означает временную недоступность сервиса.
+func KindOf(err error) Kind {
+	var appErr *Error
+	switch {
+	case errors.As(err, &appErr):
+		return appErr.Kind
+	case errors.Is(err, context.DeadlineExceeded):
+		return Unavailable
+	default:
+		return Internal
+	}
+}
+
+// Message возвращает описание ошибки для клиента. Для ошибки приложения возвращается её сообщение
+// без исходной ошибки. У ошибок сервера (статус 5xx) исходная ошибка не раскрывается: возвращается
+// только сообщение ошибки приложения или заголовок вида.
+func Message(err error) string {
+	var appErr *Error
+	if errors.As(err, &appErr) && appErr == err && appErr.Message != "" {
+		return appErr.Message
+	}
+	kind := KindOf(err)
+	if !kind.ServerError() {
+		return err.Error()
+	}
+	if appErr != nil && appErr.Message != "" {
+		return appErr.Message
+	}
+	return kind.Title()
+}
+
+// Fields возвращает ошибки в полях запроса, если они есть.
+func Fields(err error) []FieldError {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Fields
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKindOf(t *testing.T) {
+	notFound := New(NotFound, "URL not found")
+
+	tests := []struct {
+		name        string
+		err         error
+		wantKind    Kind
+		wantStatus  int
+		wantMessage string
+	}{
+		{
+			name:        "application error",
+			err:         notFound,
+			wantKind:    NotFound,
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "URL not found",
+		},
+		{
+			name:        "wrapped application error",
+			err:         fmt.Errorf("get link: %w", notFound),
+			wantKind:    NotFound,
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "get link: URL not found",
+		},
+		{
+			name:        "cause of client error is not disclosed",
+			err:         Wrap(InvalidInput, "Failed to read compressed request", errors.New("gzip: invalid header")),
+			wantKind:    InvalidInput,
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "Failed to read compressed request",
+		},
+		{
+			name:        "server error keeps only application message",
+			err:         fmt.Errorf("ping: %w", Wrap(Internal, "Database connection error", errors.New("dial tcp: refused"))),
+			wantKind:    Internal,
+			wantStatus:  http.StatusInternalServerError,
+			wantMessage: "Database connection error",
+		},
+		{
+			name:        "unknown error is internal",
+			err:         errors.New("pq: connection reset"),
+			wantKind:    Internal,
+			wantStatus:  http.StatusInternalServerError,
+			wantMessage: "Internal server error",
+		},
+		{
+			name:        "deadline exceeded",
+			err:         fmt.Errorf("query: %w", context.DeadlineExceeded),
+			wantKind:    Unavailable,
+			wantStatus:  http.StatusServiceUnavailable,
+			wantMessage: "Service unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind := KindOf(tt.err)
+			assert.Equal(t, tt.wantKind, kind)
+			assert.Equal(t, tt.wantStatus, kind.Status())
+			assert.Equal(t, tt.wantMessage, Message(tt.err))
+		})
+	}
+}
+
+func TestKinds(t *testing.T) {
+	slugs := make(map[string]bool)
+	for _, kind := range Kinds() {
+		assert.NotEmpty(t, kind.Title())
+		assert.False(t, slugs[kind.Slug()], "duplicate slug %s", kind.Slug())
+		slugs[kind.Slug()] = true
+		assert.Equal(t, "/api/docs#problem-"+kind.Slug(), kind.Type())
+	}
+	assert.Len(t, slugs, len(kinds))
+}
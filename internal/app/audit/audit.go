@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// Действия, записываемые в журнал аудита.
+const (
+	ActionLinkCreate       = "link.create"
+	ActionLinkBatchCreate  = "link.batch_create"
+	ActionLinkUpdate       = "link.update"
+	ActionLinkDelete       = "link.delete"
+	ActionLinkDisable      = "link.disable"
+	ActionLinkEnable       = "link.enable"
+	ActionLinkReassign     = "link.reassign"
+	ActionLinkPurge        = "link.purge"
+	ActionWorkspaceCreate  = "workspace.create"
+	ActionMemberSave       = "member.save"
+	ActionMemberDelete     = "member.delete"
+	ActionInvitationCreate = "invitation.create"
+	ActionInvitationDelete = "invitation.delete"
+	ActionInvitationAccept = "invitation.accept"
+	ActionAPIKeyCreate     = "apikey.create"
+	ActionAPIKeyDelete     = "apikey.delete"
+	ActionLogin            = "auth.login"
+	ActionLoginFailed      = "auth.login_failed"
+	ActionLogout           = "auth.logout"
+	ActionToken            = "auth.token"
+	ActionAdminSearch      = "admin.search"
+	ActionAdminGet         = "admin.get"
+	ActionInternalStats    = "internal.stats"
+	ActionAuditExport      = "audit.export"
+)
+
+// Recorder записывает события в журнал аудита хранилища, дополняя их данными запроса:
+// пользователем, API-ключом, идентификатором запроса и IP-адресом клиента.
+type Recorder struct {
+	storage storage.AuditStorage
+	now     func() time.Time
+}
+
+// NewRecorder создаёт журнал аудита поверх хранилища.
+func NewRecorder(storage storage.AuditStorage) *Recorder {
+	return &Recorder{storage: storage, now: time.Now}
+}
+
+type contextKey struct{}
+
+// requestInfo связывает контекст запроса с журналом аудита.
+type requestInfo struct {
+	recorder *Recorder
+	clientIP string
+}
+
+// Middleware подключает журнал аудита к контексту запроса. IP-адрес клиента берётся из заголовка
+// X-Real-IP, выставляемого обратным прокси, а при его отсутствии — из адреса соединения.
+func (rec *Recorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithRecorder(r.Context(), rec, clientIP(r))))
+	})
+}
+
+// WithRecorder возвращает контекст, события в котором записываются в журнал rec от имени клиента clientIP.
+func WithRecorder(ctx context.Context, rec *Recorder, clientIP string) context.Context {
+	return context.WithValue(ctx, contextKey{}, requestInfo{recorder: rec, clientIP: clientIP})
+}
+
+// Record записывает событие в журнал аудита, подключённый к контексту. Без журнала событие
+// не записывается. Ошибка записи не отменяет уже выполненное действие и попадает в журнал приложения.
+func Record(ctx context.Context, event models.AuditEvent) {
+	info, ok := ctx.Value(contextKey{}).(requestInfo)
+	if !ok {
+		return
+	}
+	if event.ClientIP == "" {
+		event.ClientIP = info.clientIP
+	}
+	if err := info.recorder.Record(ctx, event); err != nil {
+		log.Printf("Error recording audit event %s: %v", event.Action, err)
+	}
+}
+
+// Record дополняет событие данными из контекста и сохраняет его в журнале.
+func (rec *Recorder) Record(ctx context.Context, event models.AuditEvent) error {
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.Time.IsZero() {
+		event.Time = rec.now().UTC()
+	}
+	if event.Actor == "" {
+		event.Actor = auth.UserID(ctx)
+	}
+	if key, ok := auth.APIKey(ctx); ok && event.APIKeyID == "" {
+		event.APIKeyID = key.ID
+	}
+	if event.RequestID == "" {
+		event.RequestID = middleware.GetRequestID(ctx)
+	}
+	return rec.storage.AppendAudit(ctx, event)
+}
+
+// Snapshot кодирует состояние объекта для полей Before и After события. Для nil возвращает nil.
+func Snapshot(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// clientIP возвращает IP-адрес клиента без порта.
+func clientIP(r *http.Request) string {
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
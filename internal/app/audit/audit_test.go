@@ -0,0 +1,148 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrap(t *testing.T) {
+	backend := memory.NewInMemoryStorage()
+	repo := Wrap(backend)
+	ctx := auth.WithUserID(context.Background(), "alice")
+	ctx = middleware.WithRequestID(ctx, "req-1")
+	ctx = WithRecorder(ctx, NewRecorder(backend), "203.0.113.7")
+
+	require.NoError(t, repo.Save(ctx, models.URLModel{ID: "abc", URL: "https://example.com", UserID: "alice", PasswordHash: "secret"}))
+	_, err := repo.Update(ctx, "abc", models.URLVersion{ChangedBy: "alice", After: models.URLSettings{URL: "https://evil.example"}})
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, "abc", "missing"))
+	require.NoError(t, repo.SaveAPIKey(ctx, models.APIKey{ID: "key1", UserID: "alice", Key: "sk_secret"}, "hash"))
+	require.NoError(t, repo.DeleteAPIKey(ctx, "alice", "key1"))
+
+	events, err := backend.AuditEvents(ctx, models.AuditFilter{})
+	require.NoError(t, err)
+	var actions []string
+	for _, event := range events {
+		actions = append(actions, event.Action)
+		assert.Equal(t, "alice", event.Actor)
+		assert.Equal(t, "req-1", event.RequestID)
+		assert.Equal(t, "203.0.113.7", event.ClientIP)
+		assert.NotEmpty(t, event.ID)
+		assert.NotContains(t, string(event.Before)+string(event.After), "secret")
+	}
+	assert.ElementsMatch(t, []string{ActionLinkCreate, ActionLinkUpdate, ActionLinkDelete, ActionAPIKeyCreate, ActionAPIKeyDelete}, actions)
+
+	updates, err := backend.AuditEvents(ctx, models.AuditFilter{Action: ActionLinkUpdate})
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	var before, after linkSnapshot
+	require.NoError(t, json.Unmarshal(updates[0].Before, &before))
+	require.NoError(t, json.Unmarshal(updates[0].After, &after))
+	assert.Equal(t, "https://example.com", before.URL)
+	assert.Equal(t, "https://evil.example", after.URL)
+	assert.True(t, after.Protected)
+	assert.Equal(t, "abc", updates[0].Target)
+
+	deletes, err := backend.AuditEvents(ctx, models.AuditFilter{Target: "abc", Action: "link.delete"})
+	require.NoError(t, err)
+	require.Len(t, deletes, 1)
+	require.NoError(t, json.Unmarshal(deletes[0].After, &after))
+	assert.True(t, after.Deleted)
+}
+
+// overwritingStorage перезаписывает существующую ссылку при Save вместо ошибки storage.ErrConflict.
+type overwritingStorage struct {
+	*memory.InMemoryStorage
+}
+
+func (s overwritingStorage) Save(ctx context.Context, urlModel models.URLModel) error {
+	if err := s.InMemoryStorage.Purge(ctx, urlModel.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	return s.InMemoryStorage.Save(ctx, urlModel)
+}
+
+func TestWrap_SaveExisting(t *testing.T) {
+	backend := memory.NewInMemoryStorage()
+	ctx := WithRecorder(auth.WithUserID(context.Background(), "alice"), NewRecorder(backend), "")
+	original := models.URLModel{ID: "abc", URL: "https://example.com", UserID: "alice"}
+	require.NoError(t, backend.Save(ctx, original))
+
+	// Отклонённое хранилищем сохранение не журналируется.
+	err := Wrap(backend).Save(ctx, models.URLModel{ID: "abc", URL: "https://other.example", UserID: "bob"})
+	require.ErrorIs(t, err, storage.ErrConflict)
+	events, err := backend.AuditEvents(ctx, models.AuditFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// Перезапись существующей ссылки записывается как обновление, а не создание.
+	require.NoError(t, Wrap(overwritingStorage{backend}).Save(ctx, models.URLModel{ID: "abc", URL: "https://other.example", UserID: "bob"}))
+	events, err = backend.AuditEvents(ctx, models.AuditFilter{Target: "abc"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, ActionLinkUpdate, events[0].Action)
+	var before, after linkSnapshot
+	require.NoError(t, json.Unmarshal(events[0].Before, &before))
+	require.NoError(t, json.Unmarshal(events[0].After, &after))
+	assert.Equal(t, "https://example.com", before.URL)
+	assert.Equal(t, "alice", before.UserID)
+	assert.Equal(t, "https://other.example", after.URL)
+}
+
+func TestWrap_WithoutRecorder(t *testing.T) {
+	backend := memory.NewInMemoryStorage()
+	require.NoError(t, Wrap(backend).Save(context.Background(), models.URLModel{ID: "abc", URL: "https://example.com"}))
+
+	events, err := backend.AuditEvents(context.Background(), models.AuditFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestMiddleware(t *testing.T) {
+	backend := memory.NewInMemoryStorage()
+	recorder := NewRecorder(backend)
+	recorder.now = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name       string
+		realIP     string
+		remoteAddr string
+		wantIP     string
+	}{
+		{name: "remote address", remoteAddr: "192.0.2.1:1234", wantIP: "192.0.2.1"},
+		{name: "real ip header", realIP: "198.51.100.2", remoteAddr: "10.0.0.1:1234", wantIP: "198.51.100.2"},
+		{name: "invalid real ip header", realIP: "unknown", remoteAddr: "10.0.0.1:1234", wantIP: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := recorder.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Record(r.Context(), models.AuditEvent{Action: ActionLogout, Target: tt.name})
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			events, err := backend.AuditEvents(context.Background(), models.AuditFilter{Target: tt.name})
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, tt.wantIP, events[0].ClientIP)
+			assert.Equal(t, recorder.now(), events[0].Time)
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// Wrap возвращает хранилище, записывающее в журнал аудита из контекста каждое изменение ссылок,
+// рабочих пространств и API-ключей со снимками состояния до и после изменения.
+// Учёт переходов, обогащение метаданными и использование API-ключей не журналируются.
+func Wrap(repo storage.URLStorage) storage.URLStorage {
+	return &auditingStorage{URLStorage: repo}
+}
+
+type auditingStorage struct {
+	storage.URLStorage
+}
+
+// linkSnapshot описывает состояние ссылки в журнале аудита. Хеш пароля в журнал не попадает.
+type linkSnapshot struct {
+	models.URLSettings
+	UserID      string `json:"user_id,omitempty"`
+	WorkspaceID string `json:"workspace_id,omitempty"`
+	Protected   bool   `json:"protected,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+	Deleted     bool   `json:"deleted,omitempty"`
+}
+
+func snapshotLink(urlModel models.URLModel, exists bool) json.RawMessage {
+	if !exists {
+		return nil
+	}
+	return Snapshot(linkSnapshot{
+		URLSettings: urlModel.Settings(),
+		UserID:      urlModel.UserID,
+		WorkspaceID: urlModel.WorkspaceID,
+		Protected:   urlModel.PasswordHash != "",
+		Disabled:    urlModel.Disabled,
+		Deleted:     urlModel.Deleted,
+	})
+}
+
+// Save журналирует создание ссылки, только если хранилище вставило её под новым идентификатором.
+// Если ссылка с таким идентификатором уже существовала и хранилище всё же сохранило модель,
+// изменение записывается как обновление со снимком до сохранения.
+func (s *auditingStorage) Save(ctx context.Context, urlModel models.URLModel) error {
+	before, existed := s.URLStorage.Get(ctx, urlModel.ID)
+	if err := s.URLStorage.Save(ctx, urlModel); err != nil {
+		return err
+	}
+	after, exists := s.URLStorage.Get(ctx, urlModel.ID)
+	switch {
+	case !exists:
+		return nil
+	case existed:
+		s.recordLink(ctx, ActionLinkUpdate, urlModel.ID, before, true, after, true)
+	default:
+		s.recordLink(ctx, ActionLinkCreate, urlModel.ID, models.URLModel{}, false, after, true)
+	}
+	return nil
+}
+
+// SaveBatch журналирует только вставленные ссылки; снимок строится по сохранённой модели без чтения из хранилища.
+func (s *auditingStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) ([]bool, error) {
+	existed, err := s.URLStorage.SaveBatch(ctx, urlModels)
+	if err != nil {
+		return nil, err
+	}
+	for i, urlModel := range urlModels {
+		if !existed[i] {
+			s.recordLink(ctx, ActionLinkBatchCreate, urlModel.ID, models.URLModel{}, false, urlModel, true)
+		}
+	}
+	return existed, nil
+}
+
+func (s *auditingStorage) Update(ctx context.Context, id string, version models.URLVersion) (models.URLVersion, error) {
+	err := s.changeLink(ctx, ActionLinkUpdate, id, func() error {
+		var err error
+		version, err = s.URLStorage.Update(ctx, id, version)
+		return err
+	})
+	return version, err
+}
+
+func (s *auditingStorage) Delete(ctx context.Context, ids ...string) error {
+	before := make(map[string]models.URLModel, len(ids))
+	for _, id := range ids {
+		if urlModel, exists := s.URLStorage.Get(ctx, id); exists && !urlModel.Deleted {
+			before[id] = urlModel
+		}
+	}
+	if err := s.URLStorage.Delete(ctx, ids...); err != nil {
+		return err
+	}
+	for id, urlModel := range before {
+		after, exists := s.URLStorage.Get(ctx, id)
+		s.recordLink(ctx, ActionLinkDelete, id, urlModel, true, after, exists)
+	}
+	return nil
+}
+
+func (s *auditingStorage) SetDisabled(ctx context.Context, id string, disabled bool) error {
+	action := ActionLinkEnable
+	if disabled {
+		action = ActionLinkDisable
+	}
+	return s.changeLink(ctx, action, id, func() error {
+		return s.URLStorage.SetDisabled(ctx, id, disabled)
+	})
+}
+
+func (s *auditingStorage) SetOwner(ctx context.Context, id, userID, workspaceID string) error {
+	return s.changeLink(ctx, ActionLinkReassign, id, func() error {
+		return s.URLStorage.SetOwner(ctx, id, userID, workspaceID)
+	})
+}
+
+func (s *auditingStorage) Purge(ctx context.Context, id string) error {
+	return s.changeLink(ctx, ActionLinkPurge, id, func() error {
+		return s.URLStorage.Purge(ctx, id)
+	})
+}
+
+// changeLink выполняет изменение ссылки и записывает его со снимками до и после.
+func (s *auditingStorage) changeLink(ctx context.Context, action, id string, change func() error) error {
+	before, existed := s.URLStorage.Get(ctx, id)
+	if err := change(); err != nil {
+		return err
+	}
+	after, exists := s.URLStorage.Get(ctx, id)
+	s.recordLink(ctx, action, id, before, existed, after, exists)
+	return nil
+}
+
+func (s *auditingStorage) recordLink(ctx context.Context, action, id string,
+	before models.URLModel, existed bool, after models.URLModel, exists bool) {
+	workspaceID := before.WorkspaceID
+	if exists {
+		workspaceID = after.WorkspaceID
+	}
+	Record(ctx, models.AuditEvent{
+		Action:      action,
+		WorkspaceID: workspaceID,
+		Target:      id,
+		Before:      snapshotLink(before, existed),
+		After:       snapshotLink(after, exists),
+	})
+}
+
+func (s *auditingStorage) CreateWorkspace(ctx context.Context, workspace models.Workspace, owner models.Member) error {
+	if err := s.URLStorage.CreateWorkspace(ctx, workspace, owner); err != nil {
+		return err
+	}
+	Record(ctx, models.AuditEvent{
+		Action:      ActionWorkspaceCreate,
+		WorkspaceID: workspace.ID,
+		Target:      workspace.ID,
+		After:       Snapshot(workspace),
+	})
+	return nil
+}
+
+func (s *auditingStorage) SaveMember(ctx context.Context, member models.Member) error {
+	before, err := s.URLStorage.Member(ctx, member.WorkspaceID, member.UserID)
+	if err := s.URLStorage.SaveMember(ctx, member); err != nil {
+		return err
+	}
+	event := models.AuditEvent{
+		Action:      ActionMemberSave,
+		WorkspaceID: member.WorkspaceID,
+		Target:      member.UserID,
+		After:       Snapshot(member),
+	}
+	if err == nil {
+		event.Before = Snapshot(before)
+	}
+	Record(ctx, event)
+	return nil
+}
+
+func (s *auditingStorage) DeleteMember(ctx context.Context, workspaceID, userID string) error {
+	before, err := s.URLStorage.Member(ctx, workspaceID, userID)
+	if err := s.URLStorage.DeleteMember(ctx, workspaceID, userID); err != nil {
+		return err
+	}
+	event := models.AuditEvent{Action: ActionMemberDelete, WorkspaceID: workspaceID, Target: userID}
+	if err == nil {
+		event.Before = Snapshot(before)
+	}
+	Record(ctx, event)
+	return nil
+}
+
+func (s *auditingStorage) SaveInvitation(ctx context.Context, invitation models.Invitation, tokenHash string) error {
+	if err := s.URLStorage.SaveInvitation(ctx, invitation, tokenHash); err != nil {
+		return err
+	}
+	invitation.Token = ""
+	Record(ctx, models.AuditEvent{
+		Action:      ActionInvitationCreate,
+		WorkspaceID: invitation.WorkspaceID,
+		Target:      invitation.ID,
+		After:       Snapshot(invitation),
+	})
+	return nil
+}
+
+func (s *auditingStorage) DeleteInvitation(ctx context.Context, workspaceID, id string) error {
+	var before json.RawMessage
+	if invitations, err := s.URLStorage.Invitations(ctx, workspaceID); err == nil {
+		for _, invitation := range invitations {
+			if invitation.ID == id {
+				invitation.Token = ""
+				before = Snapshot(invitation)
+			}
+		}
+	}
+	if err := s.URLStorage.DeleteInvitation(ctx, workspaceID, id); err != nil {
+		return err
+	}
+	Record(ctx, models.AuditEvent{Action: ActionInvitationDelete, WorkspaceID: workspaceID, Target: id, Before: before})
+	return nil
+}
+
+func (s *auditingStorage) AcceptInvitation(ctx context.Context, tokenHash, userID string, now time.Time) (models.Member, error) {
+	member, err := s.URLStorage.AcceptInvitation(ctx, tokenHash, userID, now)
+	if err != nil {
+		return member, err
+	}
+	Record(ctx, models.AuditEvent{
+		Action:      ActionInvitationAccept,
+		WorkspaceID: member.WorkspaceID,
+		Target:      member.UserID,
+		After:       Snapshot(member),
+	})
+	return member, nil
+}
+
+func (s *auditingStorage) SaveAPIKey(ctx context.Context, key models.APIKey, keyHash string) error {
+	if err := s.URLStorage.SaveAPIKey(ctx, key, keyHash); err != nil {
+		return err
+	}
+	key.Key = ""
+	Record(ctx, models.AuditEvent{
+		Action:      ActionAPIKeyCreate,
+		WorkspaceID: key.WorkspaceID,
+		Target:      key.ID,
+		After:       Snapshot(key),
+	})
+	return nil
+}
+
+func (s *auditingStorage) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	var before models.APIKey
+	var found bool
+	if keys, err := s.URLStorage.APIKeys(ctx, userID); err == nil {
+		for _, key := range keys {
+			if key.ID == id {
+				before, found = key, true
+			}
+		}
+	}
+	if err := s.URLStorage.DeleteAPIKey(ctx, userID, id); err != nil {
+		return err
+	}
+	event := models.AuditEvent{Action: ActionAPIKeyDelete, WorkspaceID: before.WorkspaceID, Target: id}
+	if found {
+		event.Before = Snapshot(before)
+	}
+	Record(ctx, event)
+	return nil
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// APIKeyPrefix — префикс, по которому API-ключ отличается от других токенов в заголовке Authorization.
+const APIKeyPrefix = "sk_"
+
+// KeyResolver находит API-ключ по его значению.
+type KeyResolver interface {
+	Resolve(ctx context.Context, key string) (models.APIKey, error)
+}
+
+type apiKeyContextKey struct{}
+
+// APIKeyMiddleware определяет пользователя по API-ключу из заголовка Authorization: Bearer.
+// Запросы без ключа передаются дальше без изменений, неизвестный или отозванный ключ даёт 401.
+func APIKeyMiddleware(resolver KeyResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := BearerToken(r)
+			if !ok || !strings.HasPrefix(token, APIKeyPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := resolver.Resolve(r.Context(), token)
+			if err != nil {
+				if !errors.Is(err, storage.ErrNotFound) {
+					log.Printf("Error resolving API key: %v", err)
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				middleware.WriteError(w, r, apperr.New(apperr.Unauthorized, "Invalid API key"))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithAPIKey(r.Context(), key)))
+		})
+	}
+}
+
+// BearerToken возвращает токен из заголовка Authorization со схемой Bearer.
+func BearerToken(r *http.Request) (string, bool) {
+	return ParseBearer(r.Header.Get("Authorization"))
+}
+
+// ParseBearer возвращает токен из значения заголовка Authorization со схемой Bearer.
+func ParseBearer(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// WithAPIKey возвращает контекст запроса, выполненного с API-ключом:
+// пользователем запроса становится владелец ключа.
+func WithAPIKey(ctx context.Context, key models.APIKey) context.Context {
+	return context.WithValue(WithUserID(ctx, key.UserID), apiKeyContextKey{}, key)
+}
+
+// APIKey возвращает API-ключ, с которым выполнен запрос.
+func APIKey(ctx context.Context) (models.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(models.APIKey)
+	return key, ok
+}
+
+// WorkspaceID возвращает рабочее пространство API-ключа запроса или пустую строку.
+func WorkspaceID(ctx context.Context) string {
+	key, _ := APIKey(ctx)
+	return key.WorkspaceID
+}
+
+// HasScope проверяет, разрешено ли запросу действие из области scope.
+// Запросы пользователя с cookie не ограничены областями действия.
+func HasScope(ctx context.Context, scope string) bool {
+	key, ok := APIKey(ctx)
+	return !ok || slices.Contains(key.Scopes, scope)
+}
+
+// RequireScope возвращает middleware, отклоняющее запросы с API-ключом без области scope.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasScope(r.Context(), scope) {
+				middleware.WriteError(w, r, apperr.New(apperr.Forbidden, "API key lacks scope "+scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession возвращает middleware, отклоняющее запросы с API-ключом.
+// Используется для управления ключами и участниками, которое доступно только пользователю.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := APIKey(r.Context()); ok {
+			middleware.WriteError(w, r, apperr.New(apperr.Forbidden, "Not allowed with API key"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
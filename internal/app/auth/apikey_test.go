@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/signer"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+type staticResolver map[string]models.APIKey
+
+func (r staticResolver) Resolve(ctx context.Context, key string) (models.APIKey, error) {
+	apiKey, ok := r[key]
+	if !ok {
+		return models.APIKey{}, storage.ErrNotFound
+	}
+	return apiKey, nil
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	resolver := staticResolver{
+		"sk_1234_read": {ID: "k1", UserID: "bot", Scopes: []string{models.ScopeLinksRead}},
+	}
+	var seen string
+	handler := APIKeyMiddleware(resolver)(Middleware(signer.NewSigner([]byte("secret")))(
+		RequireScope(models.ScopeLinksRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = UserID(r.Context())
+		}))))
+
+	tests := []struct {
+		name          string
+		authorization string
+		expectedCode  int
+		expectedUser  string
+		cookie        bool
+	}{
+		{name: "valid key", authorization: "Bearer sk_1234_read", expectedCode: http.StatusOK, expectedUser: "bot"},
+		{name: "scheme is case insensitive", authorization: "bearer sk_1234_read", expectedCode: http.StatusOK, expectedUser: "bot"},
+		{name: "unknown key", authorization: "Bearer sk_0000_none", expectedCode: http.StatusUnauthorized},
+		{name: "other bearer token", authorization: "Bearer eyJhbGciOi", expectedCode: http.StatusOK, cookie: true},
+		{name: "no header", expectedCode: http.StatusOK, cookie: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = ""
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedUser != "" {
+				assert.Equal(t, tt.expectedUser, seen)
+			}
+			assert.Equal(t, tt.cookie, len(rec.Result().Cookies()) > 0)
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	handler := RequireScope(models.ScopeLinksWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// Пользователь с cookie не ограничен областями действия.
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req = req.WithContext(WithAPIKey(req.Context(), models.APIKey{UserID: "bot", Scopes: []string{models.ScopeLinksRead}}))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	req = req.WithContext(WithAPIKey(req.Context(), models.APIKey{UserID: "bot", Scopes: []string{models.ScopeLinksWrite}}))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/signer"
+)
+
+// CookieName — имя cookie с подписанным идентификатором пользователя.
+const CookieName = "user_id"
+
+// cookieTTL определяет срок действия cookie пользователя.
+const cookieTTL = 365 * 24 * time.Hour
+
+type contextKey struct{}
+
+// issuedKey отмечает в контексте идентификатор, выданный текущим запросом.
+type issuedKey struct{}
+
+// Middleware определяет пользователя по подписанной cookie.
+// Если cookie отсутствует или подпись неверна, пользователю выдаётся новый идентификатор.
+// Запросы, пользователь которых уже определён по API-ключу, передаются дальше без cookie.
+func Middleware(cookieSigner *signer.Signer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if UserID(r.Context()) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if cookie, err := r.Cookie(CookieName); err == nil {
+				if userID, ok := cookieSigner.Verify(signer.PurposeUser, cookie.Value); ok && userID != "" {
+					next.ServeHTTP(w, r.WithContext(WithUserID(r.Context(), userID)))
+					return
+				}
+			}
+
+			userID := NewUserID()
+			SetCookie(w, cookieSigner, userID)
+			ctx := context.WithValue(WithUserID(r.Context(), userID), issuedKey{}, true)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// SetCookie выдаёт пользователю подписанную cookie с его идентификатором.
+func SetCookie(w http.ResponseWriter, cookieSigner *signer.Signer, userID string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    cookieSigner.Sign(signer.PurposeUser, userID, time.Now().Add(cookieTTL)),
+		Path:     "/",
+		MaxAge:   int(cookieTTL.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearCookie удаляет cookie пользователя.
+func ClearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// WithUserID возвращает контекст с идентификатором пользователя.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, userID)
+}
+
+// UserID возвращает идентификатор пользователя из контекста или пустую строку.
+func UserID(ctx context.Context) string {
+	userID, _ := ctx.Value(contextKey{}).(string)
+	return userID
+}
+
+// Anonymous сообщает, что клиент не предъявил ни cookie, ни API-ключа, ни JWT: пользователь
+// не определён или его идентификатор выдан текущим запросом.
+func Anonymous(ctx context.Context) bool {
+	issued, _ := ctx.Value(issuedKey{}).(bool)
+	return issued || UserID(ctx) == ""
+}
+
+// NewUserID генерирует новый идентификатор пользователя.
+func NewUserID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
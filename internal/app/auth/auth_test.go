@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/signer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	var seen string
+	var anonymous bool
+	handler := Middleware(signer.NewSigner([]byte("secret")))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = UserID(r.Context())
+		anonymous = Anonymous(r.Context())
+	}))
+
+	// Новый пользователь получает cookie.
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, CookieName, cookies[0].Name)
+	first := seen
+	assert.NotEmpty(t, first)
+	assert.True(t, anonymous)
+
+	// С выданной cookie пользователь определяется повторно.
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, first, seen)
+	assert.False(t, anonymous)
+	assert.Empty(t, rec.Result().Cookies())
+
+	// Поддельная cookie заменяется новой.
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: "forged"})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.NotEqual(t, first, seen)
+	assert.True(t, anonymous)
+	assert.Len(t, rec.Result().Cookies(), 1)
+}
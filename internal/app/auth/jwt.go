@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/jwt"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+)
+
+// JWTMiddleware определяет пользователя по JWT из заголовка Authorization: Bearer.
+// Пользователем становится subject токена. Токен, выданный в обмен на API-ключ, ограничен
+// областями действия и рабочим пространством ключа. API-ключи и запросы без токена
+// передаются дальше без изменений, недействительный токен даёт 401.
+func JWTMiddleware(authority *jwt.Authority) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := BearerToken(r)
+			if !ok || strings.HasPrefix(token, APIKeyPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := authority.Verify(token, time.Now())
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				middleware.WriteError(w, r, apperr.New(apperr.Unauthorized, "Invalid token"))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// WithClaims возвращает контекст запроса, выполненного с JWT: пользователем запроса становится
+// subject токена, а токен, выданный в обмен на API-ключ, действует как этот ключ.
+func WithClaims(ctx context.Context, claims jwt.Claims) context.Context {
+	ctx = WithUserID(ctx, claims.Subject)
+	if claims.KeyID != "" {
+		ctx = WithAPIKey(ctx, models.APIKey{
+			ID:          claims.KeyID,
+			UserID:      claims.Subject,
+			WorkspaceID: claims.WorkspaceID,
+			Scopes:      claims.Scopes(),
+		})
+	}
+	return ctx
+}
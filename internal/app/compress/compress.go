@@ -5,6 +5,9 @@ import (
 	"io"
 	"net/http"
 	"strings"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
 )
 
 func GzipMiddleware(next http.Handler) http.Handler {
@@ -13,7 +16,7 @@ func GzipMiddleware(next http.Handler) http.Handler {
 		if strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
 			cr, err := newCompressReader(r.Body)
 			if err != nil {
-				http.Error(w, "Failed to read compressed request", http.StatusBadRequest)
+				middleware.WriteError(w, r, apperr.Wrap(apperr.InvalidInput, "Failed to read compressed request", err))
 				return
 			}
 			r.Body = cr
@@ -58,6 +61,19 @@ func (cw *conditionalCompressWriter) Write(p []byte) (int, error) {
 	return cw.ResponseWriter.Write(p)
 }
 
+// Flush отправляет клиенту сжатые к этому моменту данные, чтобы потоковые ответы не задерживались в буфере gzip.
+func (cw *conditionalCompressWriter) Flush() {
+	if cw.Header().Get("Content-Encoding") == "gzip" {
+		cw.writer.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap позволяет http.ResponseController добраться до EnableFullDuplex исходного ответа.
+func (cw *conditionalCompressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
 func (cw *conditionalCompressWriter) Close() error {
 	if cw.Header().Get("Content-Encoding") == "gzip" {
 		return cw.writer.Close()
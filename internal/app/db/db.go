@@ -34,6 +34,9 @@ func NewDatabaseConnection(ctx context.Context, dsn string) (*Database, error) {
 	if err := db.createTables(ctx); err != nil {
 		return nil, fmt.Errorf("error creating tables: %w", err)
 	}
+	if err := db.createSearchIndex(ctx); err != nil {
+		return nil, fmt.Errorf("error creating search index: %w", err)
+	}
 
 	log.Println("Successfully connected to PostgreSQL and ensured tables exist")
 
@@ -48,11 +51,154 @@ func (db *Database) createTables(ctx context.Context) error {
         short_url VARCHAR(255) NOT NULL UNIQUE,
         original_url TEXT NOT NULL
     );
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS clicks BIGINT NOT NULL DEFAULT 0;
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS interstitial BOOLEAN NOT NULL DEFAULT false;
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '';
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS max_clicks BIGINT NOT NULL DEFAULT 0;
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS rules JSONB;
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS variants JSONB;
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS params JSONB;
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS user_id TEXT NOT NULL DEFAULT '';
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '';
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS redirect_code INTEGER NOT NULL DEFAULT 0;
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT '';
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS folder TEXT NOT NULL DEFAULT '';
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS meta JSONB;
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS workspace_id TEXT NOT NULL DEFAULT '';
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS is_deleted BOOLEAN NOT NULL DEFAULT false;
+    ALTER TABLE urls ADD COLUMN IF NOT EXISTS is_disabled BOOLEAN NOT NULL DEFAULT false;
+
+    CREATE INDEX IF NOT EXISTS urls_user_created_idx ON urls (user_id, created_at DESC, short_url DESC);
+    CREATE INDEX IF NOT EXISTS urls_workspace_created_idx ON urls (workspace_id, created_at DESC, short_url DESC);
+    CREATE INDEX IF NOT EXISTS urls_tags_idx ON urls USING GIN (tags);
+
+    CREATE TABLE IF NOT EXISTS click_events (
+        id BIGSERIAL PRIMARY KEY,
+        short_url VARCHAR(255) NOT NULL,
+        variant TEXT NOT NULL DEFAULT '',
+        clicked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+    );
+    CREATE INDEX IF NOT EXISTS click_events_short_url_idx ON click_events (short_url, variant);
+    ALTER TABLE click_events ADD COLUMN IF NOT EXISTS click_id TEXT NOT NULL DEFAULT '';
+
+    CREATE TABLE IF NOT EXISTS url_history (
+        id BIGSERIAL PRIMARY KEY,
+        short_url VARCHAR(255) NOT NULL,
+        version INTEGER NOT NULL,
+        changed_by TEXT NOT NULL,
+        changed_at TIMESTAMPTZ NOT NULL,
+        fields TEXT[] NOT NULL,
+        before JSONB NOT NULL,
+        after JSONB NOT NULL,
+        UNIQUE (short_url, version)
+    );
+
+    CREATE TABLE IF NOT EXISTS workspaces (
+        id TEXT PRIMARY KEY,
+        name TEXT NOT NULL,
+        created_by TEXT NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL
+    );
+    CREATE TABLE IF NOT EXISTS workspace_members (
+        workspace_id TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+        user_id TEXT NOT NULL,
+        role TEXT NOT NULL,
+        joined_at TIMESTAMPTZ NOT NULL,
+        PRIMARY KEY (workspace_id, user_id)
+    );
+    CREATE INDEX IF NOT EXISTS workspace_members_user_idx ON workspace_members (user_id);
+    CREATE TABLE IF NOT EXISTS workspace_invitations (
+        token_hash TEXT PRIMARY KEY,
+        id TEXT NOT NULL UNIQUE,
+        workspace_id TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+        role TEXT NOT NULL,
+        created_by TEXT NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL,
+        expires_at TIMESTAMPTZ NOT NULL
+    );
+    CREATE TABLE IF NOT EXISTS api_keys (
+        key_hash TEXT PRIMARY KEY,
+        id TEXT NOT NULL UNIQUE,
+        prefix TEXT NOT NULL,
+        name TEXT NOT NULL,
+        user_id TEXT NOT NULL,
+        workspace_id TEXT NOT NULL DEFAULT '',
+        scopes TEXT[] NOT NULL DEFAULT '{}',
+        created_at TIMESTAMPTZ NOT NULL,
+        last_used_at TIMESTAMPTZ
+    );
+    CREATE INDEX IF NOT EXISTS api_keys_user_idx ON api_keys (user_id);
+
+    CREATE TABLE IF NOT EXISTS idempotency_keys (
+        user_id TEXT NOT NULL,
+        key TEXT NOT NULL,
+        request_hash TEXT NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL,
+        expires_at TIMESTAMPTZ NOT NULL,
+        completed BOOLEAN NOT NULL DEFAULT false,
+        status INTEGER NOT NULL DEFAULT 0,
+        header JSONB,
+        body BYTEA,
+        PRIMARY KEY (user_id, key)
+    );
+    ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ NOT NULL DEFAULT 'epoch';
+    CREATE INDEX IF NOT EXISTS idempotency_keys_expires_idx ON idempotency_keys (expires_at);
+
+    CREATE TABLE IF NOT EXISTS audit_log (
+        id TEXT PRIMARY KEY,
+        time TIMESTAMPTZ NOT NULL,
+        action TEXT NOT NULL,
+        actor TEXT NOT NULL DEFAULT '',
+        api_key_id TEXT NOT NULL DEFAULT '',
+        workspace_id TEXT NOT NULL DEFAULT '',
+        target TEXT NOT NULL DEFAULT '',
+        request_id TEXT NOT NULL DEFAULT '',
+        client_ip TEXT NOT NULL DEFAULT '',
+        before JSONB,
+        after JSONB
+    );
+    CREATE INDEX IF NOT EXISTS audit_log_time_idx ON audit_log (time DESC, id DESC);
+    CREATE INDEX IF NOT EXISTS audit_log_target_idx ON audit_log (target, time DESC);
+    CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor, time DESC);
+    CREATE INDEX IF NOT EXISTS audit_log_workspace_idx ON audit_log (workspace_id, time DESC);
+
+    -- Журнал аудита только пополняется: изменение и удаление событий запрещены.
+    CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+    BEGIN
+        RAISE EXCEPTION 'audit_log is append-only';
+    END;
+    $$ LANGUAGE plpgsql;
+    DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+    CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+        FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
     `
 	_, err := db.Pool.Exec(ctx, query)
 	return err
 }
 
+// createSearchIndex создает триграммный индекс для поиска ссылок, если установлено расширение pg_trgm.
+// Сервис не устанавливает расширение сам: в управляемых базах на это обычно нет прав.
+// Без индекса поиск работает, но просматривает все ссылки пользователя; чтобы включить индекс,
+// администратор базы выполняет CREATE EXTENSION pg_trgm, и индекс создается при следующем запуске.
+func (db *Database) createSearchIndex(ctx context.Context) error {
+	var installed bool
+	err := db.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')`).Scan(&installed)
+	if err != nil {
+		return err
+	}
+	if !installed {
+		log.Println("pg_trgm extension is not installed, link search runs without the trigram index")
+		return nil
+	}
+	_, err = db.Pool.Exec(ctx, `
+    CREATE INDEX IF NOT EXISTS urls_search_idx ON urls USING GIN ((title || ' ' || notes || ' ' || original_url) gin_trgm_ops);
+    `)
+	return err
+}
+
 // Close закрывает соединение с базой данных
 func (db *Database) Close() {
 	db.Pool.Close()
@@ -0,0 +1,242 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+)
+
+// Ограничения загрузки страницы назначения.
+const (
+	maxBodySize    = 1 << 20 // Читается только начало страницы, метаданные находятся в <head>
+	maxRedirects   = 5
+	maxFieldLength = 512
+	userAgent      = "go-shortener-bot/1.0 (+link preview)"
+)
+
+// Fetcher загружает метаданные страницы по адресу.
+type Fetcher interface {
+	Fetch(ctx context.Context, pageURL string) (models.PageMeta, error)
+}
+
+// PermanentError сообщает, что повторная загрузка страницы не имеет смысла.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// ErrPrivateAddress возвращается при попытке загрузить страницу из внутренней сети.
+var ErrPrivateAddress = errors.New("private address is not allowed")
+
+// HTTPFetcher загружает страницы по HTTP с ограничением времени, размера ответа и числа редиректов.
+type HTTPFetcher struct {
+	client       *http.Client
+	allowPrivate bool // Разрешает загрузку из внутренней сети, используется в тестах
+}
+
+// NewHTTPFetcher создаёт загрузчик с таймаутом timeout на весь запрос.
+// Соединения с loopback- и приватными адресами запрещены.
+func NewHTTPFetcher(timeout time.Duration) *HTTPFetcher {
+	f := &HTTPFetcher{}
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return f.checkAddress(address)
+		},
+	}
+	f.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConnsPerHost:   2,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return &PermanentError{Err: errors.New("too many redirects")}
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+// checkAddress запрещает соединения с адресами внутренней сети.
+func (f *HTTPFetcher) checkAddress(address string) error {
+	if f.allowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return &PermanentError{Err: ErrPrivateAddress}
+	}
+	return nil
+}
+
+// Fetch загружает страницу и извлекает из неё название, описание, изображение и иконку.
+func (f *HTTPFetcher) Fetch(ctx context.Context, pageURL string) (models.PageMeta, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return models.PageMeta{}, &PermanentError{Err: err}
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		var permanent *PermanentError
+		if errors.As(err, &permanent) {
+			return models.PageMeta{}, permanent
+		}
+		return models.PageMeta{}, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests:
+		return models.PageMeta{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return models.PageMeta{}, &PermanentError{Err: fmt.Errorf("unexpected status %d", resp.StatusCode)}
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.Contains(contentType, "html") {
+		return models.PageMeta{}, &PermanentError{Err: fmt.Errorf("unsupported content type %q", contentType)}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return models.PageMeta{}, err
+	}
+	meta := Parse(string(body), resp.Request.URL)
+	meta.FetchedAt = time.Now().UTC()
+	return meta, nil
+}
+
+var (
+	titleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	tagRe   = regexp.MustCompile(`(?is)<(meta|link)\s[^>]*>`)
+	attrRe  = regexp.MustCompile(`(?is)([a-z][a-z0-9:_-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	spaceRe = regexp.MustCompile(`\s+`)
+)
+
+// Parse извлекает метаданные из HTML-страницы, загруженной по адресу base.
+// Значения OpenGraph имеют приоритет над <title> и <meta name="description">.
+func Parse(page string, base *url.URL) models.PageMeta {
+	if end := strings.Index(strings.ToLower(page), "</head>"); end >= 0 {
+		page = page[:end]
+	}
+
+	var meta models.PageMeta
+	var title, description string
+	if m := titleRe.FindStringSubmatch(page); m != nil {
+		title = m[1]
+	}
+
+	for _, tag := range tagRe.FindAllStringSubmatch(page, -1) {
+		attrs := parseAttrs(tag[0])
+		switch strings.ToLower(tag[1]) {
+		case "meta":
+			key := strings.ToLower(attrs["property"])
+			if key == "" {
+				key = strings.ToLower(attrs["name"])
+			}
+			switch key {
+			case "og:title":
+				meta.Title = attrs["content"]
+			case "og:description":
+				meta.Description = attrs["content"]
+			case "og:image":
+				meta.Image = attrs["content"]
+			case "description":
+				description = attrs["content"]
+			}
+		case "link":
+			for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+				if rel == "icon" && meta.Favicon == "" {
+					meta.Favicon = attrs["href"]
+				}
+			}
+		}
+	}
+
+	if meta.Title == "" {
+		meta.Title = title
+	}
+	if meta.Description == "" {
+		meta.Description = description
+	}
+	if meta.Favicon == "" {
+		// Браузеры запрашивают иконку по этому адресу, если она не указана явно.
+		meta.Favicon = "/favicon.ico"
+	}
+
+	meta.Title = cleanText(meta.Title)
+	meta.Description = cleanText(meta.Description)
+	meta.Image = resolve(base, meta.Image)
+	meta.Favicon = resolve(base, meta.Favicon)
+	return meta
+}
+
+// parseAttrs возвращает атрибуты тега с именами в нижнем регистре.
+func parseAttrs(tag string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range attrRe.FindAllStringSubmatch(tag, -1) {
+		attrs[strings.ToLower(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+	}
+	return attrs
+}
+
+// cleanText декодирует HTML-сущности, схлопывает пробелы и обрезает текст до maxFieldLength.
+func cleanText(s string) string {
+	s = strings.TrimSpace(spaceRe.ReplaceAllString(html.UnescapeString(s), " "))
+	if len(s) > maxFieldLength {
+		s = s[:maxFieldLength]
+		for !utf8.ValidString(s) {
+			s = s[:len(s)-1]
+		}
+	}
+	return s
+}
+
+// resolve преобразует ссылку на ресурс в абсолютный http(s)-адрес.
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
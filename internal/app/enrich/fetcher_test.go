@@ -0,0 +1,106 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://example.com/articles/go")
+
+	tests := []struct {
+		name        string
+		page        string
+		title       string
+		description string
+		image       string
+		favicon     string
+	}{
+		{
+			name: "opengraph takes precedence",
+			page: `<html><head><title>Plain title</title>
+				<meta name="description" content="Plain description">
+				<meta property="og:title" content="OG &amp; title">
+				<meta property='og:description' content='OG description'>
+				<meta property="og:image" content="/img/cover.png">
+				<link rel="shortcut icon" href="/static/icon.png">
+				</head><body><title>ignored</title></body></html>`,
+			title:       "OG & title",
+			description: "OG description",
+			image:       "https://example.com/img/cover.png",
+			favicon:     "https://example.com/static/icon.png",
+		},
+		{
+			name:        "fallback to title and description",
+			page:        "<HEAD><TITLE>\n  Go   курс \n</TITLE><META NAME=description CONTENT=Описание></HEAD>",
+			title:       "Go курс",
+			description: "Описание",
+			favicon:     "https://example.com/favicon.ico",
+		},
+		{
+			name:    "unsafe image scheme is dropped",
+			page:    `<head><meta property="og:image" content="javascript:alert(1)"></head>`,
+			favicon: "https://example.com/favicon.ico",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := Parse(tt.page, base)
+			assert.Equal(t, tt.title, meta.Title)
+			assert.Equal(t, tt.description, meta.Description)
+			assert.Equal(t, tt.image, meta.Image)
+			assert.Equal(t, tt.favicon, meta.Favicon)
+		})
+	}
+}
+
+func TestHTTPFetcher_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<head><title>Page</title></head>`))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(time.Second)
+	fetcher.allowPrivate = true
+	ctx := context.Background()
+
+	meta, err := fetcher.Fetch(ctx, server.URL+"/page")
+	require.NoError(t, err)
+	assert.Equal(t, "Page", meta.Title)
+	assert.Equal(t, server.URL+"/favicon.ico", meta.Favicon)
+	assert.False(t, meta.FetchedAt.IsZero())
+
+	var permanent *PermanentError
+	_, err = fetcher.Fetch(ctx, server.URL+"/image")
+	assert.ErrorAs(t, err, &permanent)
+
+	_, err = fetcher.Fetch(ctx, server.URL+"/missing")
+	assert.ErrorAs(t, err, &permanent)
+
+	_, err = fetcher.Fetch(ctx, server.URL+"/unavailable")
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &permanent), "server errors should be retried")
+
+	// По умолчанию загрузка из внутренней сети запрещена.
+	_, err = NewHTTPFetcher(time.Second).Fetch(ctx, server.URL+"/page")
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+}
@@ -0,0 +1,220 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// Options задаёт параметры фонового обогащения ссылок.
+type Options struct {
+	Workers   int           // Количество параллельных обработчиков
+	QueueSize int           // Размер очереди ссылок, ожидающих обработки
+	Retries   int           // Количество повторных попыток после временной ошибки
+	Backoff   time.Duration // Задержка перед первой повторной попыткой, удваивается с каждой попыткой
+	PerHost   int           // Максимум одновременных загрузок с одного хоста
+}
+
+// DefaultOptions возвращает параметры обогащения по умолчанию.
+func DefaultOptions() Options {
+	return Options{
+		Workers:   4,
+		QueueSize: 1024,
+		Retries:   3,
+		Backoff:   time.Second,
+		PerHost:   2,
+	}
+}
+
+// Worker в фоне загружает метаданные страниц назначения и сохраняет их в ссылках.
+type Worker struct {
+	repo    storage.URLStorage
+	fetcher Fetcher
+	opts    Options
+	queue   chan string
+	hosts   *hostLimiter
+}
+
+// NewWorker создаёт обработчик, сохраняющий метаданные в repo.
+func NewWorker(repo storage.URLStorage, fetcher Fetcher, opts Options) *Worker {
+	return &Worker{
+		repo:    repo,
+		fetcher: fetcher,
+		opts:    opts,
+		queue:   make(chan string, opts.QueueSize),
+		hosts:   newHostLimiter(opts.PerHost),
+	}
+}
+
+// Start запускает обработчики очереди. Они завершаются при отмене ctx.
+func (w *Worker) Start(ctx context.Context) {
+	for i := 0; i < w.opts.Workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-w.queue:
+					w.process(ctx, id)
+				}
+			}
+		}()
+	}
+}
+
+// Enqueue ставит ссылки в очередь на обогащение. Если очередь заполнена, ссылка пропускается.
+func (w *Worker) Enqueue(ids ...string) {
+	for _, id := range ids {
+		select {
+		case w.queue <- id:
+		default:
+			log.Printf("Enrichment queue is full, skipping %s", id)
+		}
+	}
+}
+
+// process загружает метаданные страницы назначения ссылки с повторными попытками.
+func (w *Worker) process(ctx context.Context, id string) {
+	urlModel, exists := w.repo.Get(ctx, id)
+	if !exists {
+		return
+	}
+	destination, err := url.Parse(urlModel.URL)
+	if err != nil {
+		return
+	}
+
+	var meta models.PageMeta
+	for attempt := 0; ; attempt++ {
+		meta, err = w.fetch(ctx, destination.Host, urlModel.URL)
+		if err == nil {
+			break
+		}
+		var permanent *PermanentError
+		if errors.As(err, &permanent) || attempt >= w.opts.Retries {
+			log.Printf("Error fetching metadata for %s: %v", id, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.opts.Backoff << attempt):
+		}
+	}
+
+	if err := w.repo.SetMeta(ctx, id, meta); err != nil {
+		log.Printf("Error saving metadata for %s: %v", id, err)
+	}
+}
+
+// fetch загружает страницу, соблюдая ограничение одновременных загрузок с хоста.
+func (w *Worker) fetch(ctx context.Context, host, pageURL string) (models.PageMeta, error) {
+	if err := w.hosts.acquire(ctx, host); err != nil {
+		return models.PageMeta{}, err
+	}
+	defer w.hosts.release(host)
+	return w.fetcher.Fetch(ctx, pageURL)
+}
+
+// Wrap возвращает хранилище, ставящее в очередь на обогащение созданные ссылки
+// и ссылки с изменённым адресом назначения.
+func (w *Worker) Wrap(repo storage.URLStorage) storage.URLStorage {
+	return &enrichingStorage{URLStorage: repo, worker: w}
+}
+
+type enrichingStorage struct {
+	storage.URLStorage
+	worker *Worker
+}
+
+func (s *enrichingStorage) Save(ctx context.Context, urlModel models.URLModel) error {
+	if err := s.URLStorage.Save(ctx, urlModel); err != nil {
+		return err
+	}
+	s.worker.Enqueue(urlModel.ID)
+	return nil
+}
+
+func (s *enrichingStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) ([]bool, error) {
+	existed, err := s.URLStorage.SaveBatch(ctx, urlModels)
+	if err != nil {
+		return nil, err
+	}
+	for i, urlModel := range urlModels {
+		if !existed[i] {
+			s.worker.Enqueue(urlModel.ID)
+		}
+	}
+	return existed, nil
+}
+
+func (s *enrichingStorage) Update(ctx context.Context, id string, version models.URLVersion) (models.URLVersion, error) {
+	version, err := s.URLStorage.Update(ctx, id, version)
+	if err != nil {
+		return version, err
+	}
+	if slices.Contains(version.Fields, "url") {
+		s.worker.Enqueue(id)
+	}
+	return version, nil
+}
+
+// hostLimiter ограничивает количество одновременных загрузок с одного хоста.
+type hostLimiter struct {
+	mu    sync.Mutex
+	limit int
+	hosts map[string]*hostSlots
+}
+
+type hostSlots struct {
+	sem   chan struct{}
+	users int // Ожидающие и выполняющиеся загрузки, при нуле запись удаляется
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, hosts: make(map[string]*hostSlots)}
+}
+
+func (l *hostLimiter) acquire(ctx context.Context, host string) error {
+	l.mu.Lock()
+	slots, ok := l.hosts[host]
+	if !ok {
+		slots = &hostSlots{sem: make(chan struct{}, l.limit)}
+		l.hosts[host] = slots
+	}
+	slots.users++
+	l.mu.Unlock()
+
+	select {
+	case slots.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		l.leave(host, slots)
+		return ctx.Err()
+	}
+}
+
+func (l *hostLimiter) release(host string) {
+	l.mu.Lock()
+	slots := l.hosts[host]
+	l.mu.Unlock()
+	<-slots.sem
+	l.leave(host, slots)
+}
+
+func (l *hostLimiter) leave(host string, slots *hostSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slots.users--
+	if slots.users == 0 {
+		delete(l.hosts, host)
+	}
+}
@@ -0,0 +1,129 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fetcherFunc позволяет использовать функцию в качестве Fetcher.
+type fetcherFunc func(ctx context.Context, pageURL string) (models.PageMeta, error)
+
+func (f fetcherFunc) Fetch(ctx context.Context, pageURL string) (models.PageMeta, error) {
+	return f(ctx, pageURL)
+}
+
+func testOptions() Options {
+	return Options{Workers: 4, QueueSize: 16, Retries: 2, Backoff: time.Millisecond, PerHost: 1}
+}
+
+func TestWorker_RetriesAndSavesMeta(t *testing.T) {
+	repo := memory.NewInMemoryStorage()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var attempts atomic.Int32
+	worker := NewWorker(repo, fetcherFunc(func(ctx context.Context, pageURL string) (models.PageMeta, error) {
+		if attempts.Add(1) < 3 {
+			return models.PageMeta{}, errors.New("temporary failure")
+		}
+		return models.PageMeta{Title: "Example"}, nil
+	}), testOptions())
+	worker.Start(ctx)
+
+	links := worker.Wrap(repo)
+	require.NoError(t, links.Save(ctx, models.URLModel{ID: "a", URL: "https://example.com/"}))
+
+	assert.Eventually(t, func() bool {
+		urlModel, _ := repo.Get(ctx, "a")
+		return urlModel.Meta != nil && urlModel.Meta.Title == "Example"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestWorker_PermanentErrorIsNotRetried(t *testing.T) {
+	repo := memory.NewInMemoryStorage()
+	ctx := context.Background()
+	require.NoError(t, repo.Save(ctx, models.URLModel{ID: "a", URL: "https://example.com/"}))
+
+	var attempts atomic.Int32
+	worker := NewWorker(repo, fetcherFunc(func(ctx context.Context, pageURL string) (models.PageMeta, error) {
+		attempts.Add(1)
+		return models.PageMeta{}, &PermanentError{Err: errors.New("not found")}
+	}), testOptions())
+	worker.process(ctx, "a")
+
+	assert.Equal(t, int32(1), attempts.Load())
+	urlModel, _ := repo.Get(ctx, "a")
+	assert.Nil(t, urlModel.Meta)
+}
+
+func TestWorker_PerHostLimit(t *testing.T) {
+	repo := memory.NewInMemoryStorage()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	active := map[string]int{}
+	maxActive := 0
+	worker := NewWorker(repo, fetcherFunc(func(ctx context.Context, pageURL string) (models.PageMeta, error) {
+		mu.Lock()
+		active[pageURL[:len("https://a.example")]]++
+		for _, n := range active {
+			maxActive = max(maxActive, n)
+		}
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		active[pageURL[:len("https://a.example")]]--
+		mu.Unlock()
+		return models.PageMeta{Title: pageURL}, nil
+	}), testOptions())
+	worker.Start(ctx)
+
+	links := worker.Wrap(repo)
+	var urlModels []models.URLModel
+	for _, u := range []string{"https://a.example/1", "https://a.example/2", "https://a.example/3", "https://b.example/1"} {
+		urlModels = append(urlModels, models.URLModel{ID: u, URL: u})
+	}
+	_, err := links.SaveBatch(ctx, urlModels)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		for _, urlModel := range urlModels {
+			if saved, _ := repo.Get(ctx, urlModel.ID); saved.Meta == nil {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 1, maxActive)
+	assert.Empty(t, worker.hosts.hosts)
+}
+
+func TestWorker_WrapUpdate(t *testing.T) {
+	repo := memory.NewInMemoryStorage()
+	ctx := context.Background()
+	require.NoError(t, repo.Save(ctx, models.URLModel{ID: "a", URL: "https://example.com/"}))
+
+	worker := NewWorker(repo, nil, testOptions())
+	links := worker.Wrap(repo)
+
+	_, err := links.Update(ctx, "a", models.URLVersion{After: models.URLSettings{URL: "https://example.com/", Title: "Title"}})
+	require.NoError(t, err)
+	assert.Empty(t, worker.queue, "only destination changes are enriched")
+
+	_, err = links.Update(ctx, "a", models.URLVersion{After: models.URLSettings{URL: "https://example.org/"}})
+	require.NoError(t, err)
+	assert.Equal(t, "a", <-worker.queue)
+}
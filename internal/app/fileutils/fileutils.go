@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
 )
@@ -32,6 +33,34 @@ func NewFileStorage(filePath string) *FileStorage {
 	return &FileStorage{filePath: filePath}
 }
 
+// record описывает формат строки в файле хранилища.
+// Поздние записи с тем же short_url перекрывают ранние.
+type record struct {
+	UUID          string                `json:"uuid"`
+	ShortURL      string                `json:"short_url"`
+	OriginalURL   string                `json:"original_url"`
+	CreatedAt     *time.Time            `json:"created_at,omitempty"`
+	Clicks        int64                 `json:"clicks,omitempty"`
+	Interstitial  bool                  `json:"interstitial,omitempty"`
+	PasswordHash  string                `json:"password_hash,omitempty"`
+	MaxClicks     int64                 `json:"max_clicks,omitempty"`
+	Rules         []models.Rule         `json:"rules,omitempty"`
+	Variants      []models.Variant      `json:"variants,omitempty"`
+	VariantClicks map[string]int64      `json:"variant_clicks,omitempty"`
+	Params        *models.ParamTemplate `json:"params,omitempty"`
+	UserID        string                `json:"user_id,omitempty"`
+	Title         string                `json:"title,omitempty"`
+	RedirectCode  int                   `json:"redirect_code,omitempty"`
+	ExpiresAt     *time.Time            `json:"expires_at,omitempty"`
+	Notes         string                `json:"notes,omitempty"`
+	Tags          []string              `json:"tags,omitempty"`
+	Folder        string                `json:"folder,omitempty"`
+	Meta          *models.PageMeta      `json:"meta,omitempty"`
+	WorkspaceID   string                `json:"workspace_id,omitempty"`
+	Deleted       bool                  `json:"is_deleted,omitempty"`
+	Disabled      bool                  `json:"is_disabled,omitempty"`
+}
+
 // SaveRecord сохраняет запись в файл.
 func (fs *FileStorage) SaveRecord(w io.WriteCloser, counter int, urlModel models.URLModel) error {
 	defer w.Close()
@@ -39,38 +68,93 @@ func (fs *FileStorage) SaveRecord(w io.WriteCloser, counter int, urlModel models
 	bufferedWriter := bufio.NewWriter(w)
 	defer bufferedWriter.Flush()
 
-	record := struct {
-		UUID        string `json:"uuid"`
-		ShortURL    string `json:"short_url"`
-		OriginalURL string `json:"original_url"`
-	}{
-		UUID:        strconv.Itoa(counter),
-		ShortURL:    urlModel.ID,
-		OriginalURL: urlModel.URL,
-	}
-
 	encoder := json.NewEncoder(bufferedWriter)
-	if err := encoder.Encode(record); err != nil {
+	if err := encoder.Encode(newRecord(counter, urlModel)); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// newRecord преобразует ссылку в строку файла хранилища.
+func newRecord(counter int, urlModel models.URLModel) record {
+	rec := record{
+		UUID:          strconv.Itoa(counter),
+		ShortURL:      urlModel.ID,
+		OriginalURL:   urlModel.URL,
+		Clicks:        urlModel.Clicks,
+		Interstitial:  urlModel.Interstitial,
+		PasswordHash:  urlModel.PasswordHash,
+		MaxClicks:     urlModel.MaxClicks,
+		Rules:         urlModel.Rules,
+		Variants:      urlModel.Variants,
+		VariantClicks: urlModel.VariantClicks,
+		Params:        urlModel.Params,
+		UserID:        urlModel.UserID,
+		Title:         urlModel.Title,
+		RedirectCode:  urlModel.RedirectCode,
+		ExpiresAt:     urlModel.ExpiresAt,
+		Notes:         urlModel.Notes,
+		Tags:          urlModel.Tags,
+		Folder:        urlModel.Folder,
+		Meta:          urlModel.Meta,
+		WorkspaceID:   urlModel.WorkspaceID,
+		Deleted:       urlModel.Deleted,
+		Disabled:      urlModel.Disabled,
+	}
+	if !urlModel.CreatedAt.IsZero() {
+		rec.CreatedAt = &urlModel.CreatedAt
+	}
+	return rec
+}
+
+// Rewrite заменяет содержимое файла хранилища записями urlModels, нумеруя их с единицы.
+// Файл заменяется атомарно, поэтому при сбое остаётся прежнее содержимое.
+func (fs *FileStorage) Rewrite(urlModels []models.URLModel) error {
+	records := make([]any, 0, len(urlModels))
+	for i, urlModel := range urlModels {
+		records = append(records, newRecord(i+1, urlModel))
+	}
+	return WriteJSONLines(fs.filePath, records)
+}
+
 // LoadRecords загружает записи из файла.
-func (fs *FileStorage) LoadRecords(r io.Reader) (map[string]string, error) {
-	data := make(map[string]string)
+func (fs *FileStorage) LoadRecords(r io.Reader) (map[string]models.URLModel, error) {
+	data := make(map[string]models.URLModel)
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
-		var record struct {
-			UUID        string `json:"uuid"`
-			ShortURL    string `json:"short_url"`
-			OriginalURL string `json:"original_url"`
-		}
-		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
 			return nil, err
 		}
-		data[record.ShortURL] = record.OriginalURL
+
+		urlModel := models.URLModel{
+			ID:            rec.ShortURL,
+			URL:           rec.OriginalURL,
+			Clicks:        rec.Clicks,
+			Interstitial:  rec.Interstitial,
+			PasswordHash:  rec.PasswordHash,
+			MaxClicks:     rec.MaxClicks,
+			Rules:         rec.Rules,
+			Variants:      rec.Variants,
+			VariantClicks: rec.VariantClicks,
+			Params:        rec.Params,
+			UserID:        rec.UserID,
+			Title:         rec.Title,
+			RedirectCode:  rec.RedirectCode,
+			ExpiresAt:     rec.ExpiresAt,
+			Notes:         rec.Notes,
+			Tags:          rec.Tags,
+			Folder:        rec.Folder,
+			Meta:          rec.Meta,
+			WorkspaceID:   rec.WorkspaceID,
+			Deleted:       rec.Deleted,
+			Disabled:      rec.Disabled,
+		}
+		if rec.CreatedAt != nil {
+			urlModel.CreatedAt = *rec.CreatedAt
+		}
+		data[rec.ShortURL] = urlModel
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -79,3 +163,64 @@ func (fs *FileStorage) LoadRecords(r io.Reader) (map[string]string, error) {
 
 	return data, nil
 }
+
+// AppendJSONLine дописывает значение в конец файла в формате JSON Lines.
+func AppendJSONLine(filePath string, v any) error {
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return json.NewEncoder(file).Encode(v)
+}
+
+// WriteJSONLines атомарно заменяет содержимое файла значениями values в формате JSON Lines.
+func WriteJSONLines(filePath string, values []any) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, v := range values {
+		if err := encoder.Encode(v); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// ReadJSONLines читает файл в формате JSON Lines, вызывая fn для каждой строки.
+// Отсутствие файла не считается ошибкой.
+func ReadJSONLines(filePath string, fn func(line []byte) error) error {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
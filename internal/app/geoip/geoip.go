@@ -0,0 +1,103 @@
+package geoip
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// DB определяет страну по IP-адресу на основе локального файла.
+// Файл содержит строки вида "сеть,код страны", например "203.0.113.0/24,AU".
+// Пустые строки и строки, начинающиеся с #, пропускаются.
+type DB struct {
+	// prefixes хранит сети, сгруппированные по длине префикса,
+	// чтобы поиск выполнялся от наиболее специфичной сети.
+	prefixes map[int]map[netip.Prefix]string
+	lengths  []int
+}
+
+// Open загружает базу из файла.
+func Open(path string) (*DB, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Load(file)
+}
+
+// Load загружает базу из потока.
+func Load(r io.Reader) (*DB, error) {
+	db := &DB{prefixes: make(map[int]map[netip.Prefix]string)}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		network, country, found := strings.Cut(text, ",")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected network,country", line)
+		}
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(network))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		db.add(prefix.Masked(), strings.ToUpper(strings.TrimSpace(country)))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Country возвращает код страны для IP-адреса или пустую строку, если адрес не найден.
+func (db *DB) Country(ip string) string {
+	if db == nil {
+		return ""
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	for _, bits := range db.lengths {
+		if bits > addr.BitLen() {
+			continue
+		}
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if country, ok := db.prefixes[bits][prefix]; ok {
+			return country
+		}
+	}
+	return ""
+}
+
+func (db *DB) add(prefix netip.Prefix, country string) {
+	bits := prefix.Bits()
+	if _, ok := db.prefixes[bits]; !ok {
+		db.prefixes[bits] = make(map[netip.Prefix]string)
+
+		// Поддерживаем длины префиксов в порядке убывания.
+		i := 0
+		for i < len(db.lengths) && db.lengths[i] > bits {
+			i++
+		}
+		db.lengths = append(db.lengths[:i], append([]int{bits}, db.lengths[i:]...)...)
+	}
+	db.prefixes[bits][prefix] = country
+}
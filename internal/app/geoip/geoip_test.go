@@ -0,0 +1,45 @@
+package geoip
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_Country(t *testing.T) {
+	data := `# network,country
+203.0.113.0/24,au
+203.0.113.128/25,NZ
+2001:db8::/32,DE
+`
+	db, err := Load(strings.NewReader(data))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		ip   string
+		want string
+	}{
+		{ip: "203.0.113.5", want: "AU"},
+		{ip: "203.0.113.200", want: "NZ"},
+		{ip: "::ffff:203.0.113.5", want: "AU"},
+		{ip: "2001:db8::1", want: "DE"},
+		{ip: "198.51.100.1", want: ""},
+		{ip: "not an ip", want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.ip, func(t *testing.T) {
+			assert.Equal(t, tc.want, db.Country(tc.ip))
+		})
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	_, err := Load(strings.NewReader("203.0.113.0/24"))
+	assert.Error(t, err)
+
+	_, err = Load(strings.NewReader("bad,AU"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,160 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/audit"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/jwt"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	shortenerv1 "github.com/alexuryumtsev/go-shortener/pkg/api/shortener/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Ключи метаданных запроса.
+const (
+	authorizationKey = "authorization"
+	requestIDKey     = "x-request-id"
+	realIPKey        = "x-real-ip"
+)
+
+// methodScopes — области действия API-ключа, необходимые для вызова методов.
+// Методы, которых нет в списке, доступны без учётных данных.
+var methodScopes = map[string]string{
+	shortenerv1.Shortener_Shorten_FullMethodName:      models.ScopeLinksWrite,
+	shortenerv1.Shortener_ShortenBatch_FullMethodName: models.ScopeLinksWrite,
+	shortenerv1.Shortener_ListUserURLs_FullMethodName: models.ScopeLinksRead,
+	shortenerv1.Shortener_DeleteURLs_FullMethodName:   models.ScopeLinksWrite,
+	shortenerv1.Shortener_Stats_FullMethodName:        models.ScopeStatsRead,
+}
+
+// requestIDUnary присваивает вызову идентификатор из метаданных x-request-id или генерирует новый
+// и возвращает его клиенту в заголовке ответа, как middleware.RequestID.
+func requestIDUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(withRequestID(ctx), req)
+}
+
+// requestIDStream присваивает идентификатор запроса потоковому вызову.
+func requestIDStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &serverStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := middleware.ResolveRequestID(first(md, requestIDKey))
+	if err := grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id)); err != nil {
+		log.Printf("Error setting request ID header: %v", err)
+	}
+	return middleware.WithRequestID(ctx, id)
+}
+
+// interceptor определяет пользователя вызова и подключает журнал аудита, как API-ключ, JWT
+// и журнал аудита в цепочке middleware HTTP-сервера. Методы, требующие учётных данных,
+// без них не выполняются.
+type interceptor struct {
+	keys     auth.KeyResolver
+	tokens   *jwt.Authority
+	recorder *audit.Recorder
+}
+
+func (i *interceptor) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := i.prepare(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (i *interceptor) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := i.prepare(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+func (i *interceptor) prepare(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	scope, protected := methodScopes[method]
+	if protected {
+		var err error
+		if ctx, err = i.authenticate(ctx, first(md, authorizationKey)); err != nil {
+			return nil, err
+		}
+		if !auth.HasScope(ctx, scope) {
+			return nil, status.Error(codes.PermissionDenied, "API key lacks scope "+scope)
+		}
+	}
+	return audit.WithRecorder(ctx, i.recorder, clientIP(ctx, md)), nil
+}
+
+// authenticate определяет пользователя по API-ключу или JWT из метаданных authorization: Bearer.
+func (i *interceptor) authenticate(ctx context.Context, header string) (context.Context, error) {
+	token, ok := auth.ParseBearer(header)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "Missing bearer token")
+	}
+
+	if strings.HasPrefix(token, auth.APIKeyPrefix) {
+		key, err := i.keys.Resolve(ctx, token)
+		if err != nil {
+			if !errors.Is(err, storage.ErrNotFound) {
+				log.Printf("Error resolving API key: %v", err)
+			}
+			return nil, status.Error(codes.Unauthenticated, "Invalid API key")
+		}
+		return auth.WithAPIKey(ctx, key), nil
+	}
+
+	claims, err := i.tokens.Verify(token, time.Now())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	return auth.WithClaims(ctx, claims), nil
+}
+
+// clientIP возвращает IP-адрес клиента из метаданных x-real-ip, выставляемых обратным прокси,
+// а при их отсутствии — из адреса соединения.
+func clientIP(ctx context.Context, md metadata.MD) string {
+	if ip := net.ParseIP(strings.TrimSpace(first(md, realIPKey))); ip != nil {
+		return ip.String()
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// first возвращает первое значение ключа метаданных.
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// serverStream подменяет контекст потокового вызова.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
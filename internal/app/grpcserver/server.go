@@ -0,0 +1,339 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/config"
+	"github.com/alexuryumtsev/go-shortener/internal/app/access"
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/audit"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/enrich"
+	"github.com/alexuryumtsev/go-shortener/internal/app/jwt"
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	shortenerv1 "github.com/alexuryumtsev/go-shortener/pkg/api/shortener/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// batchChunkSize — количество ссылок из потока ShortenBatch, сохраняемых одной пачкой.
+const batchChunkSize = 1000
+
+// NewServer создаёт gRPC-сервер API сокращения ссылок поверх хранилища repo. Как и HTTP-сервер,
+// он записывает изменения в журнал аудита, ставит созданные ссылки в очередь enricher
+// на получение метаданных страниц и принимает API-ключи и JWT, подписанные ключами из конфигурации.
+// Обработчик enricher общий с HTTP-сервером и запускается вызывающей стороной; если он равен nil,
+// метаданные не загружаются.
+func NewServer(cfg *config.Config, repo storage.URLStorage, enricher *enrich.Worker) *grpc.Server {
+	recorder := audit.NewRecorder(repo)
+	repo = audit.Wrap(repo)
+
+	jwtKeys, err := jwt.ConfiguredKeySet(cfg.JWTKeysFile, cfg.SecretKey)
+	if err != nil {
+		log.Printf("Error loading JWT keys: %v", err)
+	}
+
+	links := repo
+	if enricher != nil {
+		links = enricher.Wrap(repo)
+	}
+
+	i := &interceptor{
+		keys:     service.NewAPIKeyResolver(repo),
+		tokens:   jwt.NewAuthority(jwtKeys, cfg.JWTIssuer, cfg.JWTAudience),
+		recorder: recorder,
+	}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(requestIDUnary, logger.UnaryServerInterceptor, i.unary),
+		grpc.ChainStreamInterceptor(requestIDStream, logger.StreamServerInterceptor, i.stream),
+	)
+	shortenerv1.RegisterShortenerServer(server, &shortenerServer{
+		repo:    repo,
+		links:   links,
+		policy:  access.NewPolicy(repo),
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+	})
+	return server
+}
+
+// shortenerServer реализует gRPC API через те же сервисы, что и HTTP-обработчики.
+// Пользователь и права API-ключа определяются перехватчиком до вызова методов.
+type shortenerServer struct {
+	shortenerv1.UnimplementedShortenerServer
+	repo    storage.URLStorage
+	links   storage.URLStorage // Хранилище для создания ссылок с получением метаданных страниц
+	policy  *access.Policy
+	baseURL string
+}
+
+// Shorten сокращает ссылку, как POST /api/shorten.
+func (s *shortenerServer) Shorten(ctx context.Context, req *shortenerv1.ShortenRequest) (*shortenerv1.ShortenResponse, error) {
+	if err := s.authorizeKeyWorkspace(ctx, access.ActionCreate); err != nil {
+		return nil, err
+	}
+	if req.GetMaxClicks() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "max_clicks must not be negative")
+	}
+
+	urlModel := models.URLModel{
+		URL:          strings.TrimSpace(req.GetUrl()),
+		Interstitial: req.GetInterstitial(),
+		MaxClicks:    req.GetMaxClicks(),
+		Title:        strings.TrimSpace(req.GetTitle()),
+		Notes:        req.GetNotes(),
+		Tags:         service.NormalizeTags(req.GetTags()),
+		Folder:       strings.TrimSpace(req.GetFolder()),
+	}
+	if urlModel.URL == "" {
+		return nil, status.Error(codes.InvalidArgument, "url must not be empty")
+	}
+	if err := service.ValidateMetadata(urlModel.Settings()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.GetPassword() != "" {
+		hash, err := service.HashPassword(req.GetPassword())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		urlModel.PasswordHash = hash
+	}
+
+	resp, err := service.NewURLService(ctx, s.links, s.baseURL).ShortenerURLModel(urlModel)
+	conflict := errors.Is(err, storage.ErrConflict)
+	if err != nil && !conflict {
+		return nil, serviceError(err)
+	}
+	return &shortenerv1.ShortenResponse{
+		Id:       resp.ID,
+		ShortUrl: resp.ShortURL,
+		Conflict: conflict,
+	}, nil
+}
+
+// ShortenBatch сокращает ссылки из потока, как POST /api/shorten/batch. Ссылки сохраняются пачками
+// по мере поступления, поэтому при ошибке уже сохранённые пачки остаются в хранилище.
+func (s *shortenerServer) ShortenBatch(stream shortenerv1.Shortener_ShortenBatchServer) error {
+	ctx := stream.Context()
+	if err := s.authorizeKeyWorkspace(ctx, access.ActionCreate); err != nil {
+		return err
+	}
+
+	urlService := service.NewURLService(ctx, s.links, s.baseURL)
+	resp := &shortenerv1.ShortenBatchResponse{}
+	batch := make([]models.URLBatchModel, 0, batchChunkSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := urlService.SaveBatchShortenerURL(batch)
+		if err != nil {
+			return serviceError(err)
+		}
+		for _, result := range results {
+			resp.Results = append(resp.Results, &shortenerv1.ShortenBatchResult{
+				CorrelationId: result.CorrelationID,
+				ShortUrl:      result.ShortURL,
+				Status:        batchStatus(result.Status),
+			})
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		batch = append(batch, models.URLBatchModel{
+			CorrelationID: req.GetCorrelationId(),
+			OriginalURL:   req.GetOriginalUrl(),
+		})
+		if len(batch) == batchChunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if len(resp.Results) == 0 {
+		return status.Error(codes.InvalidArgument, "Empty batch")
+	}
+	return stream.SendAndClose(resp)
+}
+
+// batchStatus сопоставляет статус элемента пакета из сервиса значению перечисления API.
+func batchStatus(status string) shortenerv1.BatchStatus {
+	switch status {
+	case models.BulkCreated:
+		return shortenerv1.BatchStatus_BATCH_STATUS_CREATED
+	case models.BulkConflict:
+		return shortenerv1.BatchStatus_BATCH_STATUS_CONFLICT
+	default:
+		return shortenerv1.BatchStatus_BATCH_STATUS_UNSPECIFIED
+	}
+}
+
+// Resolve возвращает адрес назначения ссылки. Недоступные для перехода ссылки дают ошибку
+// с тем же смыслом, что и ответ GET /{id}.
+func (s *shortenerServer) Resolve(ctx context.Context, req *shortenerv1.ResolveRequest) (*shortenerv1.ResolveResponse, error) {
+	urlModel, exists := s.repo.Get(ctx, req.GetId())
+	switch {
+	case !exists:
+		return nil, status.Error(codes.NotFound, "URL not found")
+	case urlModel.Deleted:
+		return nil, status.Error(codes.FailedPrecondition, "URL deleted")
+	case urlModel.Disabled:
+		return nil, status.Error(codes.PermissionDenied, "URL disabled")
+	case urlModel.ClicksExhausted():
+		return nil, status.Error(codes.FailedPrecondition, "URL click limit exhausted")
+	case urlModel.Expired(time.Now()):
+		return nil, status.Error(codes.FailedPrecondition, "URL expired")
+	case urlModel.PasswordHash != "":
+		return nil, status.Error(codes.PermissionDenied, "URL is password protected")
+	}
+	return &shortenerv1.ResolveResponse{
+		Id:           urlModel.ID,
+		OriginalUrl:  urlModel.URL,
+		RedirectCode: int32(urlModel.StatusCode()),
+	}, nil
+}
+
+// ListUserURLs возвращает страницу ссылок, как GET /api/user/urls.
+func (s *shortenerServer) ListUserURLs(ctx context.Context, req *shortenerv1.ListUserURLsRequest) (*shortenerv1.ListUserURLsResponse, error) {
+	if err := s.authorizeKeyWorkspace(ctx, access.ActionList); err != nil {
+		return nil, err
+	}
+
+	filter := models.URLFilter{
+		UserID:      auth.UserID(ctx),
+		WorkspaceID: auth.WorkspaceID(ctx),
+		Tag:         req.GetTag(),
+		Folder:      req.GetFolder(),
+		Query:       req.GetQuery(),
+		Limit:       int(req.GetLimit()),
+	}
+	page, err := service.NewLinkService(ctx, s.repo, s.baseURL).List(filter, req.GetCursor())
+	if err != nil {
+		return nil, serviceError(err)
+	}
+
+	resp := &shortenerv1.ListUserURLsResponse{NextCursor: page.NextCursor}
+	for _, link := range page.Items {
+		resp.Links = append(resp.Links, &shortenerv1.Link{
+			Id:          link.ID,
+			ShortUrl:    link.ShortURL,
+			OriginalUrl: link.URL,
+			CreatedAt:   timestamppb.New(link.CreatedAt),
+			Clicks:      link.Clicks,
+			Title:       link.Title,
+			Notes:       link.Notes,
+			Tags:        link.Tags,
+			Folder:      link.Folder,
+			WorkspaceId: link.Workspace,
+		})
+	}
+	return resp, nil
+}
+
+// DeleteURLs удаляет ссылки, как DELETE /api/user/urls, и возвращает идентификаторы удалённых.
+func (s *shortenerServer) DeleteURLs(ctx context.Context, req *shortenerv1.DeleteURLsRequest) (*shortenerv1.DeleteURLsResponse, error) {
+	deleted, err := service.NewLinkService(ctx, s.repo, s.baseURL).DeleteAllowed(s.policy, auth.UserID(ctx), req.GetIds())
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return &shortenerv1.DeleteURLsResponse{DeletedIds: deleted}, nil
+}
+
+// Stats возвращает статистику переходов по ссылке, как GET /api/urls/{id}/stats.
+func (s *shortenerServer) Stats(ctx context.Context, req *shortenerv1.StatsRequest) (*shortenerv1.StatsResponse, error) {
+	urlModel, exists := s.repo.Get(ctx, req.GetId())
+	if !exists {
+		return nil, status.Error(codes.NotFound, "URL not found")
+	}
+	if err := s.policy.AuthorizeLink(ctx, auth.UserID(ctx), urlModel, access.ActionStats); err != nil {
+		return nil, serviceError(err)
+	}
+
+	stats, err := service.NewLinkService(ctx, s.repo, s.baseURL).Stats(urlModel.ID)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	resp := &shortenerv1.StatsResponse{
+		Id:        stats.ID,
+		Url:       stats.URL,
+		CreatedAt: timestamppb.New(stats.CreatedAt),
+		Clicks:    stats.Clicks,
+		MaxClicks: stats.MaxClicks,
+	}
+	for _, variant := range stats.Variants {
+		resp.Variants = append(resp.Variants, &shortenerv1.VariantStats{
+			Name:   variant.Name,
+			Url:    variant.URL,
+			Weight: int32(variant.Weight),
+			Clicks: variant.Clicks,
+		})
+	}
+	return resp, nil
+}
+
+// authorizeKeyWorkspace проверяет право на действие в рабочем пространстве API-ключа вызова,
+// как access.Policy.APIKeyWorkspace.
+func (s *shortenerServer) authorizeKeyWorkspace(ctx context.Context, action access.Action) error {
+	workspaceID := auth.WorkspaceID(ctx)
+	if workspaceID == "" {
+		return nil
+	}
+	if err := s.policy.AuthorizeWorkspace(ctx, auth.UserID(ctx), workspaceID, action); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return status.Error(codes.NotFound, "Workspace not found")
+		}
+		return serviceError(err)
+	}
+	return nil
+}
+
+// grpcCodes сопоставляет видам ошибок приложения коды ответа gRPC.
+var grpcCodes = map[apperr.Kind]codes.Code{
+	apperr.Internal:             codes.Internal,
+	apperr.InvalidInput:         codes.InvalidArgument,
+	apperr.Unauthorized:         codes.Unauthenticated,
+	apperr.Forbidden:            codes.PermissionDenied,
+	apperr.NotFound:             codes.NotFound,
+	apperr.Conflict:             codes.AlreadyExists,
+	apperr.Gone:                 codes.FailedPrecondition,
+	apperr.UnsupportedMediaType: codes.InvalidArgument,
+	apperr.Unprocessable:        codes.FailedPrecondition,
+	apperr.TooManyRequests:      codes.ResourceExhausted,
+	apperr.BadGateway:           codes.Unavailable,
+	apperr.Unavailable:          codes.Unavailable,
+}
+
+// serviceError преобразует ошибку сервиса в статус gRPC по виду ошибки, как это делается
+// для ответов HTTP. Ошибки сервера записываются в лог и не раскрываются клиенту.
+func serviceError(err error) error {
+	kind := apperr.KindOf(err)
+	if kind.ServerError() {
+		log.Printf("Error processing gRPC request: %v", err)
+	}
+	code, ok := grpcCodes[kind]
+	if !ok {
+		code = codes.Internal
+	}
+	return status.Error(code, apperr.Message(err))
+}
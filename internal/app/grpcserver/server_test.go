@@ -0,0 +1,165 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/config"
+	"github.com/alexuryumtsev/go-shortener/internal/app/jwt"
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	shortenerv1 "github.com/alexuryumtsev/go-shortener/pkg/api/shortener/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestServer(t *testing.T) {
+	logger.InitLogger()
+	repo := memory.NewInMemoryStorage()
+	cfg := &config.Config{BaseURL: "http://localhost:8080/", SecretKey: "secret"}
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(cfg, repo, nil)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	client := shortenerv1.NewShortenerClient(conn)
+
+	ctx := context.Background()
+	keys := service.NewAPIKeyService(ctx, repo)
+	writer, err := keys.Create("alice", "ci", "", []string{models.ScopeLinksRead, models.ScopeLinksWrite, models.ScopeStatsRead})
+	require.NoError(t, err)
+	reader, err := keys.Create("alice", "dashboard", "", []string{models.ScopeLinksRead})
+	require.NoError(t, err)
+	jwtKeys, err := jwt.ConfiguredKeySet("", cfg.SecretKey)
+	require.NoError(t, err)
+	token, err := jwt.NewAuthority(jwtKeys, "", "").Issue(jwt.Claims{Subject: "alice"}, time.Minute, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, repo.Save(ctx, models.URLModel{ID: "bob1", URL: "https://example.org/bob", UserID: "bob"}))
+
+	as := func(secret string) context.Context {
+		return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+secret)
+	}
+
+	t.Run("authentication", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			ctx      context.Context
+			wantCode codes.Code
+		}{
+			{name: "no credentials", ctx: ctx, wantCode: codes.Unauthenticated},
+			{name: "unknown API key", ctx: as("sk_unknown"), wantCode: codes.Unauthenticated},
+			{name: "invalid token", ctx: as("not.a.token"), wantCode: codes.Unauthenticated},
+			{name: "missing scope", ctx: as(reader.Key), wantCode: codes.PermissionDenied},
+			{name: "api key", ctx: as(writer.Key), wantCode: codes.OK},
+			{name: "jwt", ctx: as(token), wantCode: codes.OK},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := client.Shorten(tt.ctx, &shortenerv1.ShortenRequest{Url: "https://example.com/auth"})
+				assert.Equal(t, tt.wantCode, status.Code(err))
+			})
+		}
+	})
+
+	t.Run("shorten and resolve", func(t *testing.T) {
+		var header metadata.MD
+		resp, err := client.Shorten(metadata.AppendToOutgoingContext(as(writer.Key), "x-request-id", "req-42"),
+			&shortenerv1.ShortenRequest{Url: "https://example.com/docs", Title: " Docs ", Tags: []string{"Go"}},
+			grpc.Header(&header))
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080/"+resp.Id, resp.ShortUrl)
+		assert.Equal(t, []string{"req-42"}, header.Get("x-request-id"))
+
+		resolved, err := client.Resolve(ctx, &shortenerv1.ResolveRequest{Id: resp.Id})
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/docs", resolved.OriginalUrl)
+		assert.EqualValues(t, 307, resolved.RedirectCode)
+
+		_, err = client.Resolve(ctx, &shortenerv1.ResolveRequest{Id: "missing"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = client.Shorten(as(writer.Key), &shortenerv1.ShortenRequest{Url: "https://example.com/x", MaxClicks: -1})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		events, err := repo.AuditEvents(ctx, models.AuditFilter{Target: resp.Id, Action: "link.create"})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "alice", events[0].Actor)
+		assert.Equal(t, writer.ID, events[0].APIKeyID)
+		assert.Equal(t, "req-42", events[0].RequestID)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		stream, err := client.ShortenBatch(as(writer.Key))
+		require.NoError(t, err)
+		for _, id := range []string{"1", "2", "3"} {
+			require.NoError(t, stream.Send(&shortenerv1.ShortenBatchRequest{CorrelationId: id, OriginalUrl: "https://example.com/batch/" + id}))
+		}
+		// Повторно сокращённый адрес возвращается со статусом конфликта.
+		require.NoError(t, stream.Send(&shortenerv1.ShortenBatchRequest{CorrelationId: "4", OriginalUrl: "https://example.com/batch/1"}))
+		resp, err := stream.CloseAndRecv()
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 4)
+		for i, result := range resp.Results[:3] {
+			assert.Equal(t, []string{"1", "2", "3"}[i], result.CorrelationId)
+			assert.Equal(t, "http://localhost:8080/"+service.GenerateID("https://example.com/batch/"+result.CorrelationId), result.ShortUrl)
+			assert.Equal(t, shortenerv1.BatchStatus_BATCH_STATUS_CREATED, result.Status)
+		}
+		assert.Equal(t, "4", resp.Results[3].CorrelationId)
+		assert.Equal(t, resp.Results[0].ShortUrl, resp.Results[3].ShortUrl)
+		assert.Equal(t, shortenerv1.BatchStatus_BATCH_STATUS_CONFLICT, resp.Results[3].Status)
+
+		empty, err := client.ShortenBatch(as(writer.Key))
+		require.NoError(t, err)
+		_, err = empty.CloseAndRecv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("list, stats and delete", func(t *testing.T) {
+		page, err := client.ListUserURLs(as(reader.Key), &shortenerv1.ListUserURLsRequest{Tag: "go"})
+		require.NoError(t, err)
+		require.Len(t, page.Links, 1)
+		link := page.Links[0]
+		assert.Equal(t, "Docs", link.Title)
+		assert.Equal(t, "https://example.com/docs", link.OriginalUrl)
+
+		page, err = client.ListUserURLs(as(reader.Key), &shortenerv1.ListUserURLsRequest{Limit: 2})
+		require.NoError(t, err)
+		assert.Len(t, page.Links, 2)
+		assert.NotEmpty(t, page.NextCursor)
+
+		stats, err := client.Stats(as(writer.Key), &shortenerv1.StatsRequest{Id: link.Id})
+		require.NoError(t, err)
+		assert.Equal(t, link.Id, stats.Id)
+		assert.Zero(t, stats.Clicks)
+		_, err = client.Stats(as(reader.Key), &shortenerv1.StatsRequest{Id: link.Id})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		_, err = client.Stats(as(writer.Key), &shortenerv1.StatsRequest{Id: "bob1"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		deleted, err := client.DeleteURLs(as(writer.Key), &shortenerv1.DeleteURLsRequest{Ids: []string{link.Id, "bob1", "missing"}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{link.Id}, deleted.DeletedIds)
+
+		_, err = client.Resolve(ctx, &shortenerv1.ResolveRequest{Id: link.Id})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		_, err = client.Resolve(ctx, &shortenerv1.ResolveRequest{Id: "bob1"})
+		assert.NoError(t, err)
+	})
+}
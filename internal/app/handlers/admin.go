@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/audit"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// InternalStatsHandler возвращает общее количество ссылок и пользователей сервиса.
+func InternalStatsHandler(repo storage.URLStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := service.NewAdminService(r.Context(), repo, "").Stats()
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		audit.Record(r.Context(), models.AuditEvent{Action: audit.ActionInternalStats})
+		writeJSON(w, http.StatusOK, stats)
+	}
+}
+
+// AdminURLsHandler ищет ссылки всех пользователей с фильтрацией по владельцу (?user=, ?workspace=),
+// тегу (?tag=), папке (?folder=), поиском по словам (?q=) и постраничной навигацией (?limit=, ?cursor=).
+func AdminURLsHandler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := models.URLFilter{
+			UserID:      query.Get("user"),
+			WorkspaceID: query.Get("workspace"),
+			Tag:         query.Get("tag"),
+			Folder:      query.Get("folder"),
+			Query:       query.Get("q"),
+		}
+		if limit := query.Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil {
+				middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid limit"))
+				return
+			}
+			filter.Limit = n
+		}
+
+		page, err := service.NewAdminService(r.Context(), repo, baseURL).Search(filter, query.Get("cursor"))
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		audit.Record(r.Context(), models.AuditEvent{
+			Action:      audit.ActionAdminSearch,
+			WorkspaceID: filter.WorkspaceID,
+			After:       audit.Snapshot(map[string]string{"query": r.URL.RawQuery}),
+		})
+		writeJSON(w, http.StatusOK, page)
+	}
+}
+
+// AdminURLHandler возвращает ссылку любого пользователя, включая удалённые.
+func AdminURLHandler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		link, err := service.NewAdminService(r.Context(), repo, baseURL).Get(id)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		audit.Record(r.Context(), models.AuditEvent{Action: audit.ActionAdminGet, WorkspaceID: link.Workspace, Target: id})
+		writeJSON(w, http.StatusOK, link)
+	}
+}
+
+// SetURLDisabledHandler отключает ссылку (disabled) или снова включает её.
+func SetURLDisabledHandler(repo storage.URLStorage, baseURL string, disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		link, err := service.NewAdminService(r.Context(), repo, baseURL).SetDisabled(id, disabled)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, link)
+	}
+}
+
+// SetURLOwnerHandler передаёт ссылку другому пользователю или рабочему пространству.
+func SetURLOwnerHandler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			UserID      string `json:"user_id"`
+			WorkspaceID string `json:"workspace_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid request body"))
+			return
+		}
+
+		link, err := service.NewAdminService(r.Context(), repo, baseURL).SetOwner(chi.URLParam(r, "id"), req.UserID, req.WorkspaceID)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, link)
+	}
+}
+
+// PurgeURLHandler безвозвратно удаляет ссылку вместе с историей и статистикой.
+func PurgeURLHandler(repo storage.URLStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		if err := service.NewAdminService(r.Context(), repo, "").Purge(id); err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/access"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandlers(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewInMemoryStorage()
+	baseURL := "http://localhost:8080"
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.Save(ctx, models.URLModel{ID: "alice1", URL: "https://example.com/sale", Title: "Sale", UserID: "alice", CreatedAt: created}))
+	require.NoError(t, repo.Save(ctx, models.URLModel{ID: "alice2", URL: "https://example.com/blog", UserID: "alice", CreatedAt: created.Add(time.Hour)}))
+	require.NoError(t, repo.Save(ctx, models.URLModel{ID: "bob1", URL: "https://phishing.example/sale", UserID: "bob", CreatedAt: created.Add(2 * time.Hour)}))
+	workspace, err := service.NewWorkspaceService(ctx, repo).Create("alice", "Marketing")
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(access.Admin([]string{"admin"}))
+		r.Get("/urls", AdminURLsHandler(repo, baseURL))
+		r.Get("/urls/{id}", AdminURLHandler(repo, baseURL))
+		r.Post("/urls/{id}/disable", SetURLDisabledHandler(repo, baseURL, true))
+		r.Post("/urls/{id}/enable", SetURLDisabledHandler(repo, baseURL, false))
+		r.Put("/urls/{id}/owner", SetURLOwnerHandler(repo, baseURL))
+		r.Delete("/urls/{id}", PurgeURLHandler(repo))
+	})
+	r.Get("/api/internal/stats", InternalStatsHandler(repo))
+
+	do := func(method, target, body, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req = req.WithContext(auth.WithUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	decodeLink := func(rec *httptest.ResponseRecorder) models.AdminLink {
+		var link models.AdminLink
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &link))
+		return link
+	}
+
+	t.Run("not admin", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/admin/urls", "", "alice").Code)
+		assert.Equal(t, http.StatusForbidden, do(http.MethodDelete, "/api/admin/urls/bob1", "", "alice").Code)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/urls", nil)
+		ctx := auth.WithAPIKey(auth.WithUserID(req.Context(), "admin"), models.APIKey{ID: "key", UserID: "admin"})
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req.WithContext(ctx))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		tests := []struct {
+			query   string
+			wantIDs []string
+		}{
+			{query: "", wantIDs: []string{"bob1", "alice2", "alice1"}},
+			{query: "?q=sale", wantIDs: []string{"bob1", "alice1"}},
+			{query: "?user=alice", wantIDs: []string{"alice2", "alice1"}},
+			{query: "?user=bob&q=sale", wantIDs: []string{"bob1"}},
+		}
+		for _, tt := range tests {
+			rec := do(http.MethodGet, "/api/admin/urls"+tt.query, "", "admin")
+			require.Equal(t, http.StatusOK, rec.Code, tt.query)
+			var page models.AdminLinkPage
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+			var ids []string
+			for _, item := range page.Items {
+				ids = append(ids, item.ID)
+			}
+			assert.Equal(t, tt.wantIDs, ids, tt.query)
+		}
+
+		rec := do(http.MethodGet, "/api/admin/urls?limit=2", "", "admin")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var page models.AdminLinkPage
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+		assert.Len(t, page.Items, 2)
+		assert.Equal(t, "bob", page.Items[0].UserID)
+		assert.NotEmpty(t, page.NextCursor)
+	})
+
+	t.Run("disable and enable", func(t *testing.T) {
+		rec := do(http.MethodPost, "/api/admin/urls/bob1/disable", "", "admin")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, decodeLink(rec).Disabled)
+		urlModel, _ := repo.Get(ctx, "bob1")
+		assert.True(t, urlModel.Disabled)
+
+		rec = do(http.MethodPost, "/api/admin/urls/bob1/enable", "", "admin")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.False(t, decodeLink(rec).Disabled)
+
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/api/admin/urls/missing/disable", "", "admin").Code)
+	})
+
+	t.Run("reassign owner", func(t *testing.T) {
+		rec := do(http.MethodPut, "/api/admin/urls/alice2/owner", `{"user_id":"carol"}`, "admin")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "carol", decodeLink(rec).UserID)
+
+		rec = do(http.MethodPut, "/api/admin/urls/alice2/owner", `{"user_id":"carol","workspace_id":"`+workspace.ID+`"}`, "admin")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, workspace.ID, decodeLink(rec).Workspace)
+		links, err := repo.Search(ctx, models.URLFilter{WorkspaceID: workspace.ID})
+		require.NoError(t, err)
+		assert.Len(t, links, 1)
+
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/api/admin/urls/alice2/owner", `{"user_id":""}`, "admin").Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/api/admin/urls/alice2/owner", `{"user_id":"carol","workspace_id":"missing"}`, "admin").Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPut, "/api/admin/urls/missing/owner", `{"user_id":"carol"}`, "admin").Code)
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		require.NoError(t, repo.Delete(ctx, "bob1"))
+		rec := do(http.MethodGet, "/api/admin/urls/bob1", "", "admin")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, decodeLink(rec).Deleted)
+
+		assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api/admin/urls/bob1", "", "admin").Code)
+		_, exists := repo.Get(ctx, "bob1")
+		assert.False(t, exists)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/api/admin/urls/bob1", "", "admin").Code)
+	})
+
+	t.Run("internal stats", func(t *testing.T) {
+		rec := do(http.MethodGet, "/api/internal/stats", "", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"urls":2,"users":2}`, rec.Body.String())
+	})
+}
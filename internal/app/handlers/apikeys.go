@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/access"
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateAPIKeyHandler выпускает API-ключ текущего пользователя. Ключ рабочего пространства
+// может выпустить только его участник. Значение ключа возвращается только в этом ответе.
+func CreateAPIKeyHandler(repo storage.URLStorage, policy *access.Policy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Name        string   `json:"name"`
+			Scopes      []string `json:"scopes"`
+			WorkspaceID string   `json:"workspace_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid request body"))
+			return
+		}
+
+		ctx := r.Context()
+		userID := auth.UserID(ctx)
+		if req.WorkspaceID != "" {
+			if err := policy.AuthorizeWorkspace(ctx, userID, req.WorkspaceID, access.ActionList); err != nil {
+				middleware.WriteError(w, r, err)
+				return
+			}
+		}
+
+		key, err := service.NewAPIKeyService(ctx, repo).Create(userID, req.Name, req.WorkspaceID, req.Scopes)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, key)
+	}
+}
+
+// APIKeysHandler возвращает API-ключи текущего пользователя без их значений.
+func APIKeysHandler(repo storage.APIKeyStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		keys, err := service.NewAPIKeyService(ctx, repo).List(auth.UserID(ctx))
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, keys)
+	}
+}
+
+// RevokeAPIKeyHandler отзывает API-ключ текущего пользователя.
+func RevokeAPIKeyHandler(repo storage.APIKeyStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if err := service.NewAPIKeyService(ctx, repo).Revoke(auth.UserID(ctx), chi.URLParam(r, "id")); err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/access"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyHandlers(t *testing.T) {
+	repo := memory.NewInMemoryStorage()
+	policy := access.NewPolicy(repo)
+	baseURL := "http://localhost:8080"
+	now := time.Now().UTC()
+	require.NoError(t, repo.CreateWorkspace(context.Background(),
+		models.Workspace{ID: "ws1", Name: "CI", CreatedBy: "alice", CreatedAt: now},
+		models.Member{WorkspaceID: "ws1", UserID: "alice", Role: models.RoleOwner, JoinedAt: now}))
+
+	r := chi.NewRouter()
+	r.Use(auth.APIKeyMiddleware(service.NewAPIKeyResolver(repo)))
+	r.With(auth.RequireScope(models.ScopeLinksWrite), policy.APIKeyWorkspace(access.ActionCreate)).
+		Post("/api/shorten/batch", PostBatchHandler(repo, baseURL))
+	r.With(auth.RequireScope(models.ScopeLinksRead), policy.APIKeyWorkspace(access.ActionList)).
+		Get("/api/user/urls", UserURLsHandler(repo, baseURL))
+	r.Group(func(r chi.Router) {
+		r.Use(auth.RequireSession)
+		r.Post("/api/keys", CreateAPIKeyHandler(repo, policy))
+		r.Get("/api/keys", APIKeysHandler(repo))
+		r.Delete("/api/keys/{id}", RevokeAPIKeyHandler(repo))
+	})
+
+	do := func(method, target, body, userID, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		} else {
+			req = req.WithContext(auth.WithUserID(req.Context(), userID))
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	create := func(body, userID string) models.APIKey {
+		rec := do(http.MethodPost, "/api/keys", body, userID, "")
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var key models.APIKey
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &key))
+		return key
+	}
+
+	rec := do(http.MethodPost, "/api/keys", `{"name":"ci","scopes":["links:admin"]}`, "alice", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = do(http.MethodPost, "/api/keys", `{"name":"ci","scopes":["links:read"],"workspace_id":"ws1"}`, "bob", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	writer := create(`{"name":"ci","scopes":["links:write","links:write"],"workspace_id":"ws1"}`, "alice")
+	assert.True(t, strings.HasPrefix(writer.Key, writer.Prefix+"_"))
+	assert.Equal(t, []string{models.ScopeLinksWrite}, writer.Scopes)
+	reader := create(`{"name":"bot","scopes":["links:read"]}`, "alice")
+
+	// Ключ пространства создаёт ссылки в нём, без области links:read список недоступен.
+	rec = do(http.MethodPost, "/api/shorten/batch", `[{"correlation_id":"1","original_url":"https://example.com/ci"}]`, "", writer.Key)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var batch []models.BatchResponseModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &batch))
+	require.Len(t, batch, 1)
+	id := path.Base(batch[0].ShortURL)
+	assert.NotEqual(t, service.GenerateID("https://example.com/ci"), id)
+	rec = do(http.MethodGet, "/api/user/urls", "", "", writer.Key)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = do(http.MethodPost, "/api/shorten/batch", `[{"correlation_id":"1","original_url":"https://example.com/x"}]`, "", reader.Key)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	urlModel, exists := repo.Get(context.Background(), id)
+	require.True(t, exists)
+	assert.Equal(t, "ws1", urlModel.WorkspaceID)
+	assert.Equal(t, "alice", urlModel.UserID)
+
+	// Личный ключ не видит ссылки пространства.
+	rec = do(http.MethodGet, "/api/user/urls", "", "", reader.Key)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var page models.LinkPage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	assert.Empty(t, page.Items)
+
+	// Управлять ключами с помощью ключа нельзя.
+	rec = do(http.MethodGet, "/api/keys", "", "", reader.Key)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = do(http.MethodGet, "/api/keys", "", "alice", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var keys []models.APIKey
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &keys))
+	require.Len(t, keys, 2)
+	for _, key := range keys {
+		assert.Empty(t, key.Key)
+		assert.NotNil(t, key.LastUsedAt)
+	}
+
+	rec = do(http.MethodDelete, "/api/keys/"+writer.ID, "", "bob", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = do(http.MethodDelete, "/api/keys/"+writer.ID, "", "alice", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = do(http.MethodPost, "/api/shorten/batch", `[{"correlation_id":"1","original_url":"https://example.com/y"}]`, "", writer.Key)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/audit"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// auditCSVHeader — заголовок выгрузки журнала аудита в формате CSV.
+var auditCSVHeader = []string{"id", "time", "action", "actor", "api_key_id", "workspace_id", "target",
+	"request_id", "client_ip", "before", "after"}
+
+// AuditHandler возвращает страницу журнала аудита с фильтрацией по исполнителю (?actor=), действию
+// или группе действий (?action=), объекту (?target=), рабочему пространству (?workspace=),
+// интервалу времени в RFC 3339 (?since=, ?until=) и постраничной навигацией (?limit=, ?cursor=).
+// На маршруте рабочего пространства выборка ограничена его событиями.
+func AuditHandler(repo storage.AuditStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, ok := parseAuditFilter(w, r)
+		if !ok {
+			return
+		}
+		page, err := service.NewAuditService(r.Context(), repo).List(filter, r.URL.Query().Get("cursor"))
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	}
+}
+
+// AuditExportHandler выгружает все события журнала аудита, подходящие под фильтр AuditHandler,
+// в формате JSON Lines (?format=ndjson, по умолчанию) или CSV (?format=csv). Выгрузка сама
+// записывается в журнал аудита.
+func AuditExportHandler(repo storage.AuditStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "ndjson"
+		}
+		if format != "ndjson" && format != "csv" {
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Unsupported format, expected ndjson or csv"))
+			return
+		}
+		filter, ok := parseAuditFilter(w, r)
+		if !ok {
+			return
+		}
+		events, err := service.NewAuditService(r.Context(), repo).Export(filter)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		audit.Record(r.Context(), models.AuditEvent{
+			Action:      audit.ActionAuditExport,
+			WorkspaceID: filter.WorkspaceID,
+			After:       audit.Snapshot(map[string]any{"query": r.URL.RawQuery, "events": len(events)}),
+		})
+
+		w.Header().Set("Content-Disposition", `attachment; filename="audit.`+format+`"`)
+		if format == "csv" {
+			writeAuditCSV(w, events)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(w)
+		for _, event := range events {
+			if err := encoder.Encode(event); err != nil {
+				log.Printf("Error encoding audit export: %v", err)
+				return
+			}
+		}
+	}
+}
+
+// parseAuditFilter читает условия выборки журнала аудита из параметров запроса.
+// При ошибке отвечает 400 и возвращает false.
+func parseAuditFilter(w http.ResponseWriter, r *http.Request) (models.AuditFilter, bool) {
+	query := r.URL.Query()
+	filter := models.AuditFilter{
+		Actor:       query.Get("actor"),
+		Action:      query.Get("action"),
+		Target:      query.Get("target"),
+		WorkspaceID: query.Get("workspace"),
+	}
+	if workspaceID := chi.URLParam(r, "workspace"); workspaceID != "" {
+		filter.WorkspaceID = workspaceID
+	}
+	for name, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid "+name+", expected RFC 3339 time"))
+				return filter, false
+			}
+			*dst = t
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid limit"))
+			return filter, false
+		}
+		filter.Limit = n
+	}
+	return filter, true
+}
+
+// writeAuditCSV записывает события в ответ в формате CSV. Снимки состояния выводятся как JSON.
+func writeAuditCSV(w http.ResponseWriter, events []models.AuditEvent) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	writer := csv.NewWriter(w)
+	writer.Write(auditCSVHeader)
+	for _, event := range events {
+		writer.Write([]string{
+			event.ID, event.Time.Format(time.RFC3339Nano), event.Action, event.Actor, event.APIKeyID,
+			event.WorkspaceID, event.Target, event.RequestID, event.ClientIP, string(event.Before), string(event.After),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("Error encoding audit export: %v", err)
+	}
+}
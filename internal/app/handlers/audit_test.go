@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/access"
+	"github.com/alexuryumtsev/go-shortener/internal/app/audit"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditHandlers(t *testing.T) {
+	backend := memory.NewInMemoryStorage()
+	recorder := audit.NewRecorder(backend)
+	repo := audit.Wrap(backend)
+	baseURL := "http://localhost:8080"
+
+	// Наполняем журнал изменениями от имени разных пользователей.
+	as := func(userID string) context.Context {
+		return audit.WithRecorder(auth.WithUserID(context.Background(), userID), recorder, "192.0.2.1")
+	}
+	require.NoError(t, repo.Save(as("alice"), models.URLModel{ID: "alice1", URL: "https://example.com", UserID: "alice"}))
+	require.NoError(t, repo.Save(as("bob"), models.URLModel{ID: "bob1", URL: "https://example.org", UserID: "bob", WorkspaceID: "team"}))
+	_, err := repo.Update(as("bob"), "bob1", models.URLVersion{ChangedBy: "bob", After: models.URLSettings{URL: "https://example.net"}})
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(as("alice"), "alice1"))
+
+	r := chi.NewRouter()
+	r.Use(recorder.Middleware)
+	r.Route("/api/audit", func(r chi.Router) {
+		r.Use(access.Admin([]string{"admin"}))
+		r.Get("/", AuditHandler(repo))
+		r.Get("/export", AuditExportHandler(repo))
+	})
+	r.Get("/api/workspaces/{workspace}/audit", AuditHandler(repo))
+	r.Post("/api/urls/{id}/disable", SetURLDisabledHandler(repo, baseURL, true))
+
+	do := func(target, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req = req.WithContext(auth.WithUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	list := func(target, userID string) models.AuditPage {
+		rec := do(target, userID)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var page models.AuditPage
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+		return page
+	}
+	actions := func(page models.AuditPage) []string {
+		var result []string
+		for _, event := range page.Items {
+			result = append(result, event.Action)
+		}
+		return result
+	}
+
+	t.Run("not admin", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, do("/api/audit/", "alice").Code)
+		assert.Equal(t, http.StatusForbidden, do("/api/audit/export", "alice").Code)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		tests := []struct {
+			query       string
+			wantActions []string
+		}{
+			{query: "", wantActions: []string{"link.delete", "link.update", "link.create", "link.create"}},
+			{query: "?actor=bob", wantActions: []string{"link.update", "link.create"}},
+			{query: "?action=link.create", wantActions: []string{"link.create", "link.create"}},
+			{query: "?action=link&target=alice1", wantActions: []string{"link.delete", "link.create"}},
+			{query: "?workspace=team", wantActions: []string{"link.update", "link.create"}},
+			{query: "?since=2999-01-01T00:00:00Z", wantActions: nil},
+		}
+		for _, tt := range tests {
+			assert.Equal(t, tt.wantActions, actions(list("/api/audit/"+tt.query, "admin")), tt.query)
+		}
+
+		assert.Equal(t, http.StatusBadRequest, do("/api/audit/?since=yesterday", "admin").Code)
+		assert.Equal(t, http.StatusBadRequest, do("/api/audit/?limit=1000", "admin").Code)
+		assert.Equal(t, http.StatusBadRequest, do("/api/audit/?since=2024-02-01T00:00:00Z&until=2024-01-01T00:00:00Z", "admin").Code)
+	})
+
+	t.Run("snapshots", func(t *testing.T) {
+		page := list("/api/audit/?action=link.update", "admin")
+		require.Len(t, page.Items, 1)
+		event := page.Items[0]
+		assert.Equal(t, "bob", event.Actor)
+		assert.Equal(t, "bob1", event.Target)
+		assert.Equal(t, "192.0.2.1", event.ClientIP)
+		assert.Contains(t, string(event.Before), "https://example.org")
+		assert.Contains(t, string(event.After), "https://example.net")
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		page := list("/api/audit/?action=link&limit=3", "admin")
+		assert.Len(t, page.Items, 3)
+		require.NotEmpty(t, page.NextCursor)
+		next := list("/api/audit/?action=link&limit=3&cursor="+page.NextCursor, "admin")
+		assert.Len(t, next.Items, 1)
+		assert.Empty(t, next.NextCursor)
+	})
+
+	t.Run("workspace route", func(t *testing.T) {
+		page := list("/api/workspaces/team/audit?workspace=other", "bob")
+		assert.Equal(t, []string{"link.update", "link.create"}, actions(page))
+	})
+
+	t.Run("admin action", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/urls/bob1/disable", nil)
+		req.Header.Set("X-Real-IP", "198.51.100.9")
+		req = req.WithContext(auth.WithUserID(req.Context(), "admin"))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		page := list("/api/audit/?action=link.disable", "admin")
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, "admin", page.Items[0].Actor)
+		assert.Equal(t, "team", page.Items[0].WorkspaceID)
+		assert.Equal(t, "198.51.100.9", page.Items[0].ClientIP)
+	})
+
+	t.Run("export", func(t *testing.T) {
+		rec := do("/api/audit/export?actor=bob", "admin")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+		var lines int
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			var event models.AuditEvent
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+			assert.Equal(t, "bob", event.Actor)
+			lines++
+		}
+		assert.Equal(t, 2, lines)
+
+		rec = do("/api/audit/export?format=csv&target=bob1&action=link.update", "admin")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv"))
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, auditCSVHeader, records[0])
+		assert.Equal(t, "link.update", records[1][2])
+
+		assert.Equal(t, http.StatusBadRequest, do("/api/audit/export?format=xml", "admin").Code)
+
+		// Выгрузка журнала сама записывается в журнал.
+		page := list("/api/audit/?action=audit.export", "admin")
+		assert.Len(t, page.Items, 2)
+	})
+}
@@ -1,25 +1,144 @@
 package handlers
 
 import (
+	"embed"
+	"errors"
+	"html/template"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/redirect"
+	"github.com/alexuryumtsev/go-shortener/internal/app/safety"
+	"github.com/alexuryumtsev/go-shortener/internal/app/signer"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
 	"github.com/go-chi/chi/v5"
 )
 
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var previewTemplate = template.Must(template.ParseFS(templatesFS, "templates/preview.html"))
+
+// previewData содержит данные для страницы предпросмотра ссылки.
+type previewData struct {
+	URL         string
+	CreatedAt   time.Time
+	Clicks      int64
+	Flagged     bool
+	ContinueURL string
+	Title       string // Название ссылки или страницы назначения
+	Description string
+	Image       string
+}
+
 // GetHandler обрабатывает GET-запросы с динамическими id.
-func GetHandler(storage storage.URLReader) http.HandlerFunc {
+// Суффикс "+" или параметр preview=1 показывают страницу предпросмотра вместо редиректа.
+// Для защищённых паролем ссылок вместо редиректа отображается форма ввода пароля,
+// а после исчерпания лимита переходов возвращается 410 Gone. Отключённые администратором ссылки
+// не открываются (403 Forbidden).
+func GetHandler(repo storage.URLStorage, flagged *safety.DomainList, cookieSigner *signer.Signer, resolver *redirect.Resolver) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		id := chi.URLParam(r, "id")
+		id, preview := strings.CutSuffix(chi.URLParam(r, "id"), "+")
+		query := r.URL.Query()
+		if query.Get("preview") == "1" {
+			preview = true
+		}
+
 		ctx := r.Context()
-		urlModel, exists := storage.Get(ctx, id)
+		urlModel, exists := repo.Get(ctx, id)
 		if !exists {
-			http.Error(w, "URL not found", http.StatusNotFound)
+			middleware.WriteError(w, r, apperr.New(apperr.NotFound, "URL not found"))
+			return
+		}
+
+		if urlModel.Deleted {
+			middleware.WriteError(w, r, apperr.New(apperr.Gone, "URL deleted"))
+			return
+		}
+		if urlModel.Disabled {
+			middleware.WriteError(w, r, apperr.New(apperr.Forbidden, "URL disabled"))
+			return
+		}
+		if urlModel.ClicksExhausted() {
+			middleware.WriteError(w, r, apperr.New(apperr.Gone, "URL click limit exhausted"))
+			return
+		}
+		if urlModel.Expired(time.Now()) {
+			middleware.WriteError(w, r, apperr.New(apperr.Gone, "URL expired"))
+			return
+		}
+
+		if urlModel.PasswordHash != "" && !isUnlocked(r, urlModel, cookieSigner) {
+			renderPasswordForm(w, id, "", http.StatusOK)
 			return
 		}
 
+		destination := resolver.Resolve(w, r, urlModel)
+
+		// Ссылки с флагом interstitial открываются только после подтверждения.
+		if preview || (urlModel.Interstitial && query.Get("confirm") != "1") {
+			renderPreview(w, r, id, destination.URL, urlModel, flagged)
+			return
+		}
+
+		// Лимит переходов проверяется атомарно при регистрации перехода.
+		err := repo.RegisterClick(ctx, models.Click{
+			ID:      id,
+			ClickID: destination.ClickID,
+			Variant: destination.Variant,
+			Time:    time.Now().UTC(),
+		})
+		if errors.Is(err, storage.ErrClickLimitExceeded) {
+			middleware.WriteError(w, r, apperr.New(apperr.Gone, "URL click limit exhausted"))
+			return
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			// Ссылка удалена между чтением и регистрацией перехода.
+			middleware.WriteError(w, r, apperr.New(apperr.NotFound, "URL not found"))
+			return
+		}
+		if err != nil {
+			log.Printf("Error registering click for %s: %v", id, err)
+		}
+
 		// Ответ с редиректом на оригинальный URL.
-		w.Header().Set("Location", urlModel.URL)
-		w.WriteHeader(http.StatusTemporaryRedirect)
+		w.Header().Set("Location", destination.URL)
+		w.WriteHeader(urlModel.StatusCode())
+	}
+}
+
+// renderPreview отображает HTML-страницу с информацией о ссылке.
+func renderPreview(w http.ResponseWriter, r *http.Request, id, destination string, urlModel models.URLModel, flagged *safety.DomainList) {
+	// Параметры запроса сохраняются, чтобы их можно было передать на адрес назначения.
+	query := r.URL.Query()
+	query.Del("preview")
+	query.Set("confirm", "1")
+
+	data := previewData{
+		URL:         destination,
+		CreatedAt:   urlModel.CreatedAt,
+		Clicks:      urlModel.Clicks,
+		Flagged:     flagged.IsFlagged(destination),
+		ContinueURL: "/" + id + "?" + query.Encode(),
+		Title:       urlModel.Title,
+	}
+	if urlModel.Meta != nil {
+		if data.Title == "" {
+			data.Title = urlModel.Meta.Title
+		}
+		data.Description = urlModel.Meta.Description
+		data.Image = urlModel.Meta.Image
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := previewTemplate.Execute(w, data); err != nil {
+		log.Printf("Error rendering preview for %s: %v", id, err)
 	}
 }
@@ -1,15 +1,21 @@
 package handlers
 
 import (
+	"compress/gzip"
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/compress"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/safety"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetHandler(t *testing.T) {
@@ -17,10 +23,11 @@ func TestGetHandler(t *testing.T) {
 	id := "0dd11111"
 	repo := storage.NewMockStorage()
 	repo.Save(context.Background(), models.URLModel{ID: id, URL: "https://practicum.yandex.ru/"})
+	repo.Save(context.Background(), models.URLModel{ID: "0dd33333", URL: "https://phishing.example/", Disabled: true})
 
 	// Инициализация маршрутизатора.
 	r := chi.NewRouter()
-	r.Get("/{id}", GetHandler(repo))
+	r.Get("/{id}", GetHandler(repo, nil, nil, nil))
 
 	type want struct {
 		code        int
@@ -48,7 +55,16 @@ func TestGetHandler(t *testing.T) {
 			want: want{
 				code:        http.StatusNotFound,
 				header:      "",
-				contentType: "text/plain; charset=utf-8",
+				contentType: middleware.ProblemContentType,
+			},
+		},
+		{
+			name:        "Disabled ID",
+			requestPath: "/0dd33333",
+			want: want{
+				code:        http.StatusForbidden,
+				header:      "",
+				contentType: middleware.ProblemContentType,
 			},
 		},
 	}
@@ -76,3 +92,97 @@ func TestGetHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestGetHandlerPreview(t *testing.T) {
+	repo := storage.NewMockStorage()
+	repo.Save(context.Background(), models.URLModel{ID: "0dd11111", URL: "https://practicum.yandex.ru/"})
+	repo.Save(context.Background(), models.URLModel{ID: "0dd22222", URL: "https://evil.com/login", Interstitial: true})
+
+	r := chi.NewRouter()
+	r.Use(compress.GzipMiddleware)
+	r.Get("/{id}", GetHandler(repo, safety.NewDomainList([]string{"evil.com"}), nil, nil))
+
+	testCases := []struct {
+		name        string
+		requestPath string
+		code        int
+		location    string
+		contains    []string
+	}{
+		{
+			name:        "Plus suffix",
+			requestPath: "/0dd11111+",
+			code:        http.StatusOK,
+			contains:    []string{"https://practicum.yandex.ru/", "/0dd11111?confirm=1"},
+		},
+		{
+			name:        "Preview query",
+			requestPath: "/0dd11111?preview=1",
+			code:        http.StatusOK,
+			contains:    []string{"https://practicum.yandex.ru/"},
+		},
+		{
+			name:        "Always interstitial with flagged domain",
+			requestPath: "/0dd22222",
+			code:        http.StatusOK,
+			contains:    []string{"https://evil.com/login", "небезопасный"},
+		},
+		{
+			name:        "Confirmed interstitial",
+			requestPath: "/0dd22222?confirm=1",
+			code:        http.StatusTemporaryRedirect,
+			location:    "https://evil.com/login",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.requestPath, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.code, res.StatusCode)
+			assert.Equal(t, tc.location, res.Header.Get("Location"))
+			if len(tc.contains) == 0 {
+				return
+			}
+
+			// HTML-страница должна отдаваться в сжатом виде.
+			assert.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+			gz, err := gzip.NewReader(res.Body)
+			require.NoError(t, err)
+			defer gz.Close()
+
+			body, err := io.ReadAll(gz)
+			require.NoError(t, err)
+			for _, s := range tc.contains {
+				assert.Contains(t, string(body), s)
+			}
+		})
+	}
+
+	// Просмотр страницы предпросмотра не считается переходом.
+	urlModel, _ := repo.Get(context.Background(), "0dd22222")
+	assert.Equal(t, int64(1), urlModel.Clicks)
+}
+
+func TestGetHandlerOneTimeLink(t *testing.T) {
+	repo := storage.NewMockStorage()
+	repo.Save(context.Background(), models.URLModel{ID: "0dd11111", URL: "https://practicum.yandex.ru/", MaxClicks: 1})
+
+	r := chi.NewRouter()
+	r.Get("/{id}", GetHandler(repo, nil, nil, nil))
+
+	wantCodes := []int{http.StatusTemporaryRedirect, http.StatusGone, http.StatusGone}
+	for _, want := range wantCodes {
+		req := httptest.NewRequest(http.MethodGet, "/0dd11111", nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, want, rec.Code)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/access"
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// UpdateHandler обрабатывает PATCH-запросы на изменение ссылки.
+func UpdateHandler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var patch map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid request body"))
+			return
+		}
+
+		ctx := r.Context()
+		link, err := service.NewLinkService(ctx, repo, baseURL).Update(chi.URLParam(r, "id"), auth.UserID(ctx), patch)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, link)
+	}
+}
+
+// HistoryHandler возвращает историю изменений ссылки.
+func HistoryHandler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		history, err := service.NewLinkService(ctx, repo, baseURL).History(chi.URLParam(r, "id"))
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, history)
+	}
+}
+
+// RollbackHandler возвращает ссылку к указанной версии.
+func RollbackHandler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Version *int `json:"version"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Version == nil {
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid request body"))
+			return
+		}
+
+		ctx := r.Context()
+		link, err := service.NewLinkService(ctx, repo, baseURL).Rollback(chi.URLParam(r, "id"), auth.UserID(ctx), *req.Version)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, link)
+	}
+}
+
+// DeleteURLHandler удаляет ссылку. Удалённая ссылка перестаёт открываться и пропадает из списков.
+func DeleteURLHandler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := service.NewLinkService(r.Context(), repo, baseURL).Delete(chi.URLParam(r, "id")); err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DeleteUserURLsHandler удаляет ссылки по списку идентификаторов в теле запроса.
+// Ссылки, которые пользователь не вправе удалять, и несуществующие ссылки пропускаются.
+func DeleteUserURLsHandler(repo storage.URLStorage, policy *access.Policy, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var ids []string
+		if err := json.NewDecoder(r.Body).Decode(&ids); err != nil {
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid request body"))
+			return
+		}
+
+		ctx := r.Context()
+		if _, err := service.NewLinkService(ctx, repo, baseURL).DeleteAllowed(policy, auth.UserID(ctx), ids); err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// UserURLsHandler возвращает личные ссылки текущего пользователя или ссылки рабочего пространства
+// из параметра маршрута {workspace} с фильтрацией по тегу (?tag=), папке (?folder=),
+// поиском по словам (?q=) и постраничной навигацией (?limit=, ?cursor=).
+func UserURLsHandler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := urlFilter(r)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+
+		page, err := service.NewLinkService(r.Context(), repo, baseURL).List(filter, r.URL.Query().Get("cursor"))
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	}
+}
+
+// urlFilter возвращает фильтр списка ссылок из параметров запроса.
+func urlFilter(r *http.Request) (models.URLFilter, error) {
+	query := r.URL.Query()
+	filter := models.URLFilter{
+		UserID:      auth.UserID(r.Context()),
+		WorkspaceID: workspaceID(r),
+		Tag:         query.Get("tag"),
+		Folder:      query.Get("folder"),
+		Query:       query.Get("q"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return models.URLFilter{}, apperr.New(apperr.InvalidInput, "Invalid limit")
+		}
+		filter.Limit = n
+	}
+	return filter, nil
+}
+
+// workspaceID возвращает рабочее пространство из параметра маршрута {workspace},
+// а для маршрутов без него — пространство API-ключа запроса.
+func workspaceID(r *http.Request) string {
+	if id := chi.URLParam(r, "workspace"); id != "" {
+		return id
+	}
+	return auth.WorkspaceID(r.Context())
+}
+
+// writeJSON записывает значение в ответ в формате JSON.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
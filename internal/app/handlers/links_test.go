@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/access"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkHandlers(t *testing.T) {
+	repo := storage.NewMockStorage()
+	repo.Save(context.Background(), models.URLModel{
+		ID:     "0dd11111",
+		URL:    "https://practicum.yandex.ru/",
+		UserID: "owner",
+	})
+	repo.Save(context.Background(), models.URLModel{
+		ID:     service.GenerateID("https://example.com/"),
+		URL:    "https://example.com/",
+		UserID: "owner",
+	})
+
+	policy := access.NewPolicy(repo)
+	r := chi.NewRouter()
+	r.Get("/{id}", GetHandler(repo, nil, nil, nil))
+	r.With(policy.Link(access.ActionEdit)).Patch("/api/urls/{id}", UpdateHandler(repo, "http://localhost:8080"))
+	r.With(policy.Link(access.ActionStats)).Get("/api/urls/{id}/history", HistoryHandler(repo, "http://localhost:8080"))
+	r.With(policy.Link(access.ActionEdit)).Post("/api/urls/{id}/rollback", RollbackHandler(repo, "http://localhost:8080"))
+	r.With(policy.Link(access.ActionDelete)).Delete("/api/urls/{id}", DeleteURLHandler(repo, "http://localhost:8080"))
+
+	do := func(method, target, body, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req = req.WithContext(auth.WithUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		body         string
+		userID       string
+		expectedCode int
+	}{
+		{
+			name:         "foreign user",
+			method:       http.MethodPatch,
+			target:       "/api/urls/0dd11111",
+			body:         `{"title":"hack"}`,
+			userID:       "stranger",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "unknown link",
+			method:       http.MethodPatch,
+			target:       "/api/urls/unknown",
+			body:         `{"title":"x"}`,
+			userID:       "owner",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid redirect code",
+			method:       http.MethodPatch,
+			target:       "/api/urls/0dd11111",
+			body:         `{"redirect_code":200}`,
+			userID:       "owner",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "shared link destination",
+			method:       http.MethodPatch,
+			target:       "/api/urls/" + service.GenerateID("https://example.com/"),
+			body:         `{"url":"https://evil.example/"}`,
+			userID:       "owner",
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "update destination",
+			method:       http.MethodPatch,
+			target:       "/api/urls/0dd11111",
+			body:         `{"url":"https://yandex.ru/","redirect_code":301,"title":"Yandex"}`,
+			userID:       "owner",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(tt.method, tt.target, tt.body, tt.userID)
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+
+	// Редирект ведёт на новый адрес с новым кодом.
+	rec := do(http.MethodGet, "/0dd11111", "", "")
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "https://yandex.ru/", rec.Header().Get("Location"))
+
+	rec = do(http.MethodGet, "/api/urls/0dd11111/history", "", "owner")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var history []models.URLVersion
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &history))
+	require.Len(t, history, 1)
+	assert.Equal(t, 1, history[0].Version)
+	assert.Equal(t, "owner", history[0].ChangedBy)
+	assert.Equal(t, "https://practicum.yandex.ru/", history[0].Before.URL)
+	assert.ElementsMatch(t, []string{"url", "redirect_code", "title"}, history[0].Fields)
+
+	// Откат к исходному состоянию записывается как новая версия.
+	rec = do(http.MethodPost, "/api/urls/0dd11111/rollback", `{"version":0}`, "owner")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var link models.LinkResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &link))
+	assert.Equal(t, "https://practicum.yandex.ru/", link.URL)
+	assert.Equal(t, "http://localhost:8080/0dd11111", link.ShortURL)
+
+	rec = do(http.MethodGet, "/0dd11111", "", "")
+	assert.Equal(t, http.StatusTemporaryRedirect, rec.Code)
+
+	rec = do(http.MethodGet, "/api/urls/0dd11111/history", "", "stranger")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Удалённая ссылка перестаёт открываться.
+	rec = do(http.MethodDelete, "/api/urls/0dd11111", "", "stranger")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = do(http.MethodDelete, "/api/urls/0dd11111", "", "owner")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = do(http.MethodGet, "/0dd11111", "", "")
+	assert.Equal(t, http.StatusGone, rec.Code)
+}
+
+func TestUserURLsHandler(t *testing.T) {
+	repo := storage.NewMockStorage()
+	now := time.Now().UTC()
+	for i, title := range []string{"Go course", "Go blog", "Recipes", "Go tour"} {
+		tag := "go"
+		if title == "Recipes" {
+			tag = "food"
+		}
+		repo.Save(context.Background(), models.URLModel{
+			ID:        fmt.Sprintf("id%d", i),
+			URL:       fmt.Sprintf("https://example.com/%d", i),
+			Title:     title,
+			Tags:      []string{tag},
+			UserID:    "owner",
+			CreatedAt: now.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	repo.Save(context.Background(), models.URLModel{ID: "foreign", URL: "https://go.dev/", Title: "Go", UserID: "stranger"})
+
+	handler := UserURLsHandler(repo, "http://localhost:8080")
+	list := func(query string) (*httptest.ResponseRecorder, models.LinkPage) {
+		req := httptest.NewRequest(http.MethodGet, "/api/user/urls?"+query, nil)
+		req = req.WithContext(auth.WithUserID(req.Context(), "owner"))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		var page models.LinkPage
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+		}
+		return rec, page
+	}
+
+	ids := func(page models.LinkPage) []string {
+		result := []string{}
+		for _, item := range page.Items {
+			result = append(result, item.ID)
+		}
+		return result
+	}
+
+	// Постраничный обход результатов поиска.
+	rec, page := list("q=go&limit=2")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"id3", "id1"}, ids(page))
+	require.NotEmpty(t, page.NextCursor)
+
+	_, page = list("q=go&limit=2&cursor=" + page.NextCursor)
+	assert.Equal(t, []string{"id0"}, ids(page))
+	assert.Empty(t, page.NextCursor)
+
+	_, page = list("tag=FOOD&q=recipes")
+	assert.Equal(t, []string{"id2"}, ids(page))
+
+	rec, _ = list("cursor=broken")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec, _ = list("limit=1000")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateLinkV2Handler создаёт ссылку в API v2 и возвращает её ресурс с заголовком Location.
+// Если адрес уже сокращён, отвечает 409 Conflict с заголовком Location существующей ссылки.
+func CreateLinkV2Handler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		urlModel, err := decodeURLModel(r)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+		links := service.NewLinkService(ctx, repo, baseURL)
+		resp, err := service.NewURLService(ctx, repo, baseURL).ShortenerURLModel(urlModel)
+		if errors.Is(err, storage.ErrConflict) {
+			if existing, err := links.Get(resp.ID); err == nil {
+				w.Header().Set("Location", existing.Links.Self)
+			}
+			middleware.WriteError(w, r, apperr.Wrap(apperr.Conflict, "URL already shortened", err))
+			return
+		}
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+
+		link, err := links.Get(resp.ID)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Location", link.Links.Self)
+		writeJSON(w, http.StatusCreated, link)
+	}
+}
+
+// LinksV2Handler возвращает страницу ссылок в API v2 с теми же фильтрами, что и UserURLsHandler.
+// Адрес следующей страницы передаётся в поле pagination.next.
+func LinksV2Handler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := urlFilter(r)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+
+		list, err := service.NewLinkService(r.Context(), repo, baseURL).Resources(filter, r.URL.Query().Get("cursor"))
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		if list.Pagination.NextCursor != "" {
+			query := r.URL.Query()
+			query.Set("cursor", list.Pagination.NextCursor)
+			list.Pagination.Next = strings.TrimSuffix(baseURL, "/") + r.URL.Path + "?" + query.Encode()
+		}
+		writeJSON(w, http.StatusOK, list)
+	}
+}
+
+// LinkV2Handler возвращает ресурс ссылки в API v2.
+func LinkV2Handler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link, err := service.NewLinkService(r.Context(), repo, baseURL).Get(chi.URLParam(r, "id"))
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, link)
+	}
+}
+
+// UpdateLinkV2Handler изменяет ссылку, как UpdateHandler, и возвращает её ресурс в API v2.
+func UpdateLinkV2Handler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var patch map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid request body"))
+			return
+		}
+
+		ctx := r.Context()
+		links := service.NewLinkService(ctx, repo, baseURL)
+		id := chi.URLParam(r, "id")
+		if _, err := links.Update(id, auth.UserID(ctx), patch); err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		link, err := links.Get(id)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, link)
+	}
+}
+
+// LinkHistoryV2Handler возвращает историю изменений ссылки в API v2.
+func LinkHistoryV2Handler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		history, err := service.NewLinkService(r.Context(), repo, baseURL).History(chi.URLParam(r, "id"))
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		if history == nil {
+			history = []models.URLVersion{}
+		}
+		writeJSON(w, http.StatusOK, models.VersionList{Data: history})
+	}
+}
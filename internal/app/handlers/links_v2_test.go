@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/access"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// conflictStorage сообщает, что любой адрес уже сокращён, как хранилище PostgreSQL.
+type conflictStorage struct {
+	*storage.MockStorage
+}
+
+func (s conflictStorage) Save(ctx context.Context, urlModel models.URLModel) error {
+	return storage.ErrConflict
+}
+
+func TestLinkV2Handlers(t *testing.T) {
+	const baseURL = "http://localhost:8080/"
+	repo := storage.NewMockStorage()
+	policy := access.NewPolicy(repo)
+	r := chi.NewRouter()
+	r.Route("/api/v2/links", func(r chi.Router) {
+		r.Get("/", LinksV2Handler(repo, baseURL))
+		r.Post("/", CreateLinkV2Handler(repo, baseURL))
+		r.With(policy.Link(access.ActionStats)).Get("/{id}", LinkV2Handler(repo, baseURL))
+		r.With(policy.Link(access.ActionEdit)).Patch("/{id}", UpdateLinkV2Handler(repo, baseURL))
+		r.With(policy.Link(access.ActionStats)).Get("/{id}/history", LinkHistoryV2Handler(repo, baseURL))
+	})
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req = req.WithContext(auth.WithUserID(req.Context(), "owner"))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/v2/links", `{"url": "https://example.com/a", "title": "A", "password": "secret", "max_clicks": 5}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var link models.Link
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&link))
+	// Ссылка с параметрами получает случайный идентификатор.
+	id := link.ID
+	assert.NotEqual(t, service.GenerateID("https://example.com/a"), id)
+	self := "http://localhost:8080/api/v2/links/" + id
+	assert.Equal(t, self, rec.Header().Get("Location"))
+	assert.Equal(t, "http://localhost:8080/"+id, link.ShortURL)
+	assert.Equal(t, "A", link.Title)
+	assert.Equal(t, []string{}, link.Tags)
+	assert.Equal(t, models.LinkRedirect{Code: http.StatusTemporaryRedirect, MaxClicks: 5, PasswordProtected: true}, link.Redirect)
+	assert.Equal(t, models.LinkURLs{Self: self, Stats: self + "/stats", History: self + "/history", QR: self + "/qr"}, link.Links)
+
+	rec = do(http.MethodPatch, "/api/v2/links/"+id, `{"redirect_code": 301, "tags": ["Docs"]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&link))
+	assert.Equal(t, http.StatusMovedPermanently, link.Redirect.Code)
+	assert.Equal(t, []string{"docs"}, link.Tags)
+
+	rec = do(http.MethodGet, "/api/v2/links/"+id+"/history", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var history models.VersionList
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&history))
+	assert.Len(t, history.Data, 1)
+
+	rec = do(http.MethodGet, "/api/v2/links/unknown", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, middleware.ProblemContentType, rec.Header().Get("Content-Type"))
+
+	do(http.MethodPost, "/api/v2/links", `{"url": "https://example.com/b"}`)
+	rec = do(http.MethodGet, "/api/v2/links?limit=1&tag=", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list models.LinkList
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, 1, list.Pagination.Limit)
+	require.NotEmpty(t, list.Pagination.NextCursor)
+	assert.Equal(t, "http://localhost:8080/api/v2/links?cursor="+list.Pagination.NextCursor+"&limit=1&tag=", list.Pagination.Next)
+
+	rec = do(http.MethodGet, "/api/v2/links?limit=1&cursor="+list.Pagination.NextCursor, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	list = models.LinkList{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	assert.Len(t, list.Data, 1)
+	assert.Empty(t, list.Pagination.Next)
+}
+
+func TestCreateLinkV2Handler_Conflict(t *testing.T) {
+	repo := storage.NewMockStorage()
+	id := service.GenerateID("https://example.com/")
+	repo.Save(context.Background(), models.URLModel{ID: id, URL: "https://example.com/", UserID: "owner"})
+	handler := CreateLinkV2Handler(conflictStorage{repo}, "http://localhost:8080")
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/v2/links", strings.NewReader(`{"url": "https://example.com/"}`)))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, middleware.ProblemContentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "http://localhost:8080/api/v2/links/"+id, rec.Header().Get("Location"))
+	var problem middleware.Problem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+	assert.Equal(t, "URL already shortened", problem.Detail)
+}
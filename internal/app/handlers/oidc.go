@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/audit"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/oidc"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/signer"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// Параметры cookie с состоянием входа через OpenID Connect.
+const (
+	loginCookieName = "oidc_login"
+	loginCookiePath = "/auth"
+	loginCookieTTL  = 10 * time.Minute
+)
+
+// loginState хранит параметры незавершённого входа между /auth/login и /auth/callback.
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to"`
+}
+
+// SSOOptions задаёт сопоставление утверждений ID-токена с рабочими пространствами.
+type SSOOptions struct {
+	WorkspaceClaim string                  // Утверждение со списком групп пользователя
+	WorkspaceRules []service.WorkspaceRule // Правила членства в рабочих пространствах
+}
+
+// LoginHandler перенаправляет пользователя на страницу входа провайдера OpenID Connect.
+// Параметр ?return_to= задаёт локальный адрес, на который пользователь вернётся после входа.
+func LoginHandler(provider *oidc.Provider, cookieSigner *signer.Signer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state := loginState{
+			State:    oidc.RandomString(),
+			Nonce:    oidc.RandomString(),
+			Verifier: oidc.RandomString(),
+			ReturnTo: localPath(r.URL.Query().Get("return_to")),
+		}
+		authURL, err := provider.AuthCodeURL(r.Context(), state.State, state.Nonce, state.Verifier)
+		if err != nil {
+			middleware.WriteError(w, r, apperr.Wrap(apperr.BadGateway, "Identity provider is unavailable", err))
+			return
+		}
+
+		value, err := json.Marshal(state)
+		if err != nil {
+			middleware.WriteError(w, r, fmt.Errorf("encode login state: %w", err))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     loginCookieName,
+			Value:    cookieSigner.Sign(signer.PurposeOIDCLogin, string(value), time.Now().Add(loginCookieTTL)),
+			Path:     loginCookiePath,
+			MaxAge:   int(loginCookieTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			// Lax нужен, чтобы cookie пришла при возврате от провайдера.
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, authURL, http.StatusFound)
+	}
+}
+
+// CallbackHandler завершает вход: проверяет state, обменивает код на ID-токен, сопоставляет
+// пользователя провайдера с внутренним пользователем и выдаёт ему cookie.
+func CallbackHandler(provider *oidc.Provider, cookieSigner *signer.Signer, repo storage.WorkspaceStorage, opts SSOOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var state loginState
+		cookie, err := r.Cookie(loginCookieName)
+		if err == nil {
+			value, ok := cookieSigner.Verify(signer.PurposeOIDCLogin, cookie.Value)
+			if !ok || json.Unmarshal([]byte(value), &state) != nil {
+				state = loginState{}
+			}
+		}
+		http.SetCookie(w, &http.Cookie{Name: loginCookieName, Path: loginCookiePath, MaxAge: -1, HttpOnly: true})
+
+		query := r.URL.Query()
+		if state.State == "" || query.Get("state") != state.State {
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid login state"))
+			return
+		}
+		ctx := r.Context()
+		if providerError := query.Get("error"); providerError != "" {
+			auditLoginFailed(ctx, "provider error: "+providerError)
+			middleware.WriteError(w, r, apperr.New(apperr.Unauthorized, "Login failed: "+providerError))
+			return
+		}
+
+		rawIDToken, err := provider.Exchange(ctx, query.Get("code"), state.Verifier)
+		if err != nil {
+			log.Printf("Error exchanging OIDC code: %v", err)
+			auditLoginFailed(ctx, "code exchange failed")
+			middleware.WriteError(w, r, apperr.New(apperr.Unauthorized, "Login failed"))
+			return
+		}
+		identity, err := provider.VerifyIDToken(ctx, rawIDToken, state.Nonce)
+		if err != nil {
+			log.Printf("Error verifying OIDC id token: %v", err)
+			auditLoginFailed(ctx, "invalid id token")
+			middleware.WriteError(w, r, apperr.New(apperr.Unauthorized, "Login failed"))
+			return
+		}
+
+		// Изменения рабочих пространств при входе записываются в журнал аудита от имени входящего пользователя.
+		ctx = auth.WithUserID(ctx, service.SSOUserID(identity.Issuer, identity.Subject))
+		userID, err := service.NewSSOService(ctx, repo).SignIn(identity, opts.WorkspaceClaim, opts.WorkspaceRules)
+		if err != nil {
+			middleware.WriteError(w, r, fmt.Errorf("sign in OIDC user: %w", err))
+			return
+		}
+		audit.Record(ctx, models.AuditEvent{
+			Action: audit.ActionLogin,
+			Actor:  userID,
+			Target: userID,
+			After: audit.Snapshot(map[string]string{
+				"issuer":  identity.Issuer,
+				"subject": identity.Subject,
+				"email":   identity.Email,
+			}),
+		})
+		auth.SetCookie(w, cookieSigner, userID)
+		http.Redirect(w, r, state.ReturnTo, http.StatusFound)
+	}
+}
+
+// LogoutHandler удаляет cookie пользователя и, если провайдер это поддерживает,
+// перенаправляет на его страницу выхода с возвратом на baseURL. Выход принимается только
+// POST-запросом и отклоняется, если браузер сообщает о запросе с другого сайта:
+// иначе чужая страница могла бы разлогинить пользователя.
+func LogoutHandler(provider *oidc.Provider, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Sec-Fetch-Site") == "cross-site" {
+			middleware.WriteError(w, r, apperr.New(apperr.Forbidden, "Cross-site logout is not allowed"))
+			return
+		}
+		userID := auth.UserID(r.Context())
+		audit.Record(r.Context(), models.AuditEvent{Action: audit.ActionLogout, Target: userID})
+		auth.ClearCookie(w)
+		if endSessionURL := provider.EndSessionURL(r.Context(), baseURL); endSessionURL != "" {
+			http.Redirect(w, r, endSessionURL, http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, "/", http.StatusFound)
+	}
+}
+
+// auditLoginFailed записывает в журнал аудита неудачную попытку входа с причиной reason.
+// Пользователь провайдера на этом этапе неизвестен, исполнителем считается владелец cookie.
+func auditLoginFailed(ctx context.Context, reason string) {
+	audit.Record(ctx, models.AuditEvent{
+		Action: audit.ActionLoginFailed,
+		After:  audit.Snapshot(map[string]string{"reason": reason}),
+	})
+}
+
+// localPath возвращает путь для перенаправления после входа. Адреса других сайтов
+// заменяются на корень, чтобы вход нельзя было использовать как открытый редирект.
+func localPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
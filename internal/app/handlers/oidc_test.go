@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/oidc"
+	"github.com/alexuryumtsev/go-shortener/internal/app/oidc/oidctest"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/signer"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCHandlers(t *testing.T) {
+	server := oidctest.NewServer("shortener")
+	defer server.Close()
+	server.Subject = "alice"
+	server.Claims["groups"] = []any{"marketing", "marketing-admins", "sales"}
+
+	ctx := context.Background()
+	repo := memory.NewInMemoryStorage()
+	marketing, err := service.NewWorkspaceService(ctx, repo).Create("founder", "Marketing")
+	require.NoError(t, err)
+	rules, err := service.ParseWorkspaceRules("marketing=" + marketing.ID + ":viewer, marketing-admins=" + marketing.ID + ":editor, sales=missing:editor")
+	require.NoError(t, err)
+
+	cookieSigner := signer.NewSigner([]byte("secret"))
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL:   server.Issuer(),
+		ClientID:    "shortener",
+		RedirectURL: "http://localhost:8080/auth/callback",
+	}, nil)
+
+	r := chi.NewRouter()
+	r.Get("/auth/login", LoginHandler(provider, cookieSigner))
+	r.Get("/auth/callback", CallbackHandler(provider, cookieSigner, repo, SSOOptions{WorkspaceClaim: "groups", WorkspaceRules: rules}))
+	r.Post("/auth/logout", LogoutHandler(provider, "http://localhost:8080/"))
+
+	// login начинает вход и возвращает cookie состояния и адрес страницы провайдера.
+	login := func(target string) (*http.Cookie, string) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusFound, rec.Code)
+		cookies := rec.Result().Cookies()
+		require.Len(t, cookies, 1)
+		return cookies[0], rec.Header().Get("Location")
+	}
+	callback := func(query string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/auth/callback?"+query, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	// authorize проходит страницу входа провайдера и возвращает запрос к /auth/callback.
+	authorize := func(authURL string) string {
+		resp, err := noRedirect.Get(authURL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		location, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "/auth/callback", location.Path)
+		return location.RawQuery
+	}
+
+	t.Run("successful login", func(t *testing.T) {
+		stateCookie, authURL := login("/auth/login?return_to=/dashboard")
+		assert.Equal(t, "oidc_login", stateCookie.Name)
+		assert.True(t, stateCookie.HttpOnly)
+
+		rec := callback(authorize(authURL), stateCookie)
+		require.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "/dashboard", rec.Header().Get("Location"))
+
+		var userID string
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == auth.CookieName {
+				userID, _ = cookieSigner.Verify(signer.PurposeUser, c.Value)
+			}
+		}
+		assert.Equal(t, service.SSOUserID(server.Issuer(), "alice"), userID)
+
+		// Из двух правил для одного пространства выбирается роль с большими правами.
+		member, err := repo.Member(ctx, marketing.ID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, models.RoleEditor, member.Role)
+
+		// Повторный вход того же пользователя даёт тот же идентификатор.
+		stateCookie, authURL = login("/auth/login")
+		rec = callback(authorize(authURL), stateCookie)
+		require.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "/", rec.Header().Get("Location"))
+	})
+
+	t.Run("membership sync", func(t *testing.T) {
+		userID := service.SSOUserID(server.Issuer(), "alice")
+		sales, err := service.NewWorkspaceService(ctx, repo).Create("founder", "Sales")
+		require.NoError(t, err)
+		require.NoError(t, repo.SaveMember(ctx, models.Member{WorkspaceID: sales.ID, UserID: userID, Role: models.RoleEditor}))
+		defer func() { server.Claims["groups"] = []any{"marketing", "marketing-admins", "sales"} }()
+		signIn := func(groups ...any) {
+			server.Claims["groups"] = groups
+			stateCookie, authURL := login("/auth/login")
+			require.Equal(t, http.StatusFound, callback(authorize(authURL), stateCookie).Code)
+		}
+
+		// Роль, которая больше не выдаётся правилами, понижается при следующем входе.
+		signIn("marketing")
+		member, err := repo.Member(ctx, marketing.ID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, models.RoleViewer, member.Role)
+
+		// Без подходящих правил пользователь удаляется из пространства.
+		signIn()
+		_, err = repo.Member(ctx, marketing.ID, userID)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+
+		// Членство в пространствах вне правил не изменяется.
+		member, err = repo.Member(ctx, sales.ID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, models.RoleEditor, member.Role)
+
+		signIn("marketing-admins")
+		member, err = repo.Member(ctx, marketing.ID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, models.RoleEditor, member.Role)
+	})
+
+	t.Run("open redirect", func(t *testing.T) {
+		for _, target := range []string{"https://evil.example", "//evil.example", "/\\evil.example"} {
+			stateCookie, authURL := login("/auth/login?return_to=" + url.QueryEscape(target))
+			rec := callback(authorize(authURL), stateCookie)
+			require.Equal(t, http.StatusFound, rec.Code)
+			assert.Equal(t, "/", rec.Header().Get("Location"), target)
+		}
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		stateCookie, authURL := login("/auth/login")
+		query := authorize(authURL)
+
+		assert.Equal(t, http.StatusBadRequest, callback(query, nil).Code)
+
+		_, otherAuthURL := login("/auth/login")
+		assert.Equal(t, http.StatusBadRequest, callback(authorize(otherAuthURL), stateCookie).Code)
+
+		tampered := *stateCookie
+		tampered.Value += "x"
+		assert.Equal(t, http.StatusBadRequest, callback(query, &tampered).Code)
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		stateCookie, authURL := login("/auth/login")
+		u, err := url.Parse(authURL)
+		require.NoError(t, err)
+		rec := callback("error=access_denied&state="+u.Query().Get("state"), stateCookie)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("reused code", func(t *testing.T) {
+		stateCookie, authURL := login("/auth/login")
+		query := authorize(authURL)
+		require.Equal(t, http.StatusFound, callback(query, stateCookie).Code)
+		assert.Equal(t, http.StatusUnauthorized, callback(query, stateCookie).Code)
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		// Чужая страница не может разлогинить пользователя.
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+		req.Header.Set("Sec-Fetch-Site", "cross-site")
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, rec.Result().Cookies())
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusFound, rec.Code)
+		location, err := url.Parse(rec.Header().Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, server.Issuer()+"/logout", location.Scheme+"://"+location.Host+location.Path)
+
+		cookies := rec.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, auth.CookieName, cookies[0].Name)
+		assert.Negative(t, cookies[0].MaxAge)
+	})
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/ratelimit"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/signer"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// unlockTTL определяет, сколько действует cookie после ввода верного пароля.
+const unlockTTL = time.Hour
+
+var passwordTemplate = template.Must(template.ParseFS(templatesFS, "templates/password.html"))
+
+// PasswordHandler проверяет пароль защищённой ссылки и выдаёт подписанную cookie.
+func PasswordHandler(storage storage.URLReader, cookieSigner *signer.Signer, limiter *ratelimit.Limiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		urlModel, exists := storage.Get(r.Context(), id)
+		if !exists || urlModel.PasswordHash == "" {
+			middleware.WriteError(w, r, apperr.New(apperr.NotFound, "URL not found"))
+			return
+		}
+
+		// Попытка засчитывается до проверки пароля, чтобы параллельные запросы не обходили лимит.
+		key := clientIP(r) + "|" + id
+		if allowed, retryAfter := limiter.Reserve(key); !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			renderPasswordForm(w, id, "Слишком много неудачных попыток. Попробуйте позже.", http.StatusTooManyRequests)
+			return
+		}
+
+		if !service.CheckPassword(urlModel.PasswordHash, r.PostFormValue("password")) {
+			renderPasswordForm(w, id, "Неверный пароль.", http.StatusUnauthorized)
+			return
+		}
+		limiter.Reset(key)
+
+		// Cookie действует и для редиректа /{id}, и для предпросмотра /{id}+.
+		http.SetCookie(w, &http.Cookie{
+			Name:     unlockCookieName(id),
+			Value:    cookieSigner.Sign(signer.PurposeLinkUnlock, unlockValue(id, urlModel.PasswordHash), time.Now().Add(unlockTTL)),
+			Path:     "/",
+			MaxAge:   int(unlockTTL.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, "/"+id, http.StatusSeeOther)
+	}
+}
+
+// isUnlocked проверяет, вводил ли посетитель текущий пароль к ссылке.
+func isUnlocked(r *http.Request, urlModel models.URLModel, cookieSigner *signer.Signer) bool {
+	cookie, err := r.Cookie(unlockCookieName(urlModel.ID))
+	if err != nil {
+		return false
+	}
+	value, ok := cookieSigner.Verify(signer.PurposeLinkUnlock, cookie.Value)
+	return ok && value == unlockValue(urlModel.ID, urlModel.PasswordHash)
+}
+
+// unlockValue возвращает подписываемое значение cookie доступа. Оно включает отпечаток хеша пароля,
+// поэтому после смены пароля ранее выданные cookie перестают действовать.
+func unlockValue(id, passwordHash string) string {
+	sum := sha256.Sum256([]byte(passwordHash))
+	return id + ":" + hex.EncodeToString(sum[:8])
+}
+
+// renderPasswordForm отображает форму ввода пароля.
+func renderPasswordForm(w http.ResponseWriter, id, errorMessage string, status int) {
+	data := struct {
+		Action string
+		Error  string
+	}{
+		Action: "/" + id,
+		Error:  errorMessage,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := passwordTemplate.Execute(w, data); err != nil {
+		log.Printf("Error rendering password form for %s: %v", id, err)
+	}
+}
+
+func unlockCookieName(id string) string {
+	return "link_" + id
+}
+
+// clientIP возвращает IP-адрес клиента без порта.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
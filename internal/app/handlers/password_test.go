@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/ratelimit"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/signer"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordProtectedLink(t *testing.T) {
+	hash, err := service.HashPassword("s3cret")
+	require.NoError(t, err)
+
+	id := "0dd11111"
+	repo := storage.NewMockStorage()
+	repo.Save(context.Background(), models.URLModel{ID: id, URL: "https://practicum.yandex.ru/", PasswordHash: hash})
+
+	cookieSigner := signer.NewSigner([]byte("secret"))
+	r := chi.NewRouter()
+	r.Get("/{id}", GetHandler(repo, nil, cookieSigner, nil))
+	r.Post("/{id}", PasswordHandler(repo, cookieSigner, ratelimit.NewLimiter(2, time.Minute)))
+
+	submit := func(password string) *http.Response {
+		form := url.Values{"password": {password}}
+		req := httptest.NewRequest(http.MethodPost, "/"+id, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+
+	// Без cookie отображается форма ввода пароля.
+	req := httptest.NewRequest(http.MethodGet, "/"+id, nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Location"))
+	assert.Contains(t, rec.Body.String(), `name="password"`)
+
+	res := submit("wrong")
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res = submit("s3cret")
+	res.Body.Close()
+	assert.Equal(t, http.StatusSeeOther, res.StatusCode)
+	require.Len(t, res.Cookies(), 1)
+	cookie := res.Cookies()[0]
+
+	// Cookie пользователя с тем же значением не открывает ссылку: подпись привязана к назначению.
+	req = httptest.NewRequest(http.MethodGet, "/"+id, nil)
+	req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookieSigner.Sign(signer.PurposeUser, id, time.Now().Add(time.Hour))})
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `name="password"`)
+
+	// С подписанной cookie выполняется редирект.
+	req = httptest.NewRequest(http.MethodGet, "/"+id, nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTemporaryRedirect, rec.Code)
+	assert.Equal(t, "https://practicum.yandex.ru/", rec.Header().Get("Location"))
+
+	// Cookie действует и для страницы предпросмотра.
+	assert.Equal(t, "/", cookie.Path)
+	req = httptest.NewRequest(http.MethodGet, "/"+id+"+", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), `name="password"`)
+
+	// После смены пароля выданная cookie больше не открывает ссылку.
+	newHash, err := service.HashPassword("n3w")
+	require.NoError(t, err)
+	require.NoError(t, repo.Purge(context.Background(), id))
+	require.NoError(t, repo.Save(context.Background(), models.URLModel{ID: id, URL: "https://practicum.yandex.ru/", PasswordHash: newHash}))
+	req = httptest.NewRequest(http.MethodGet, "/"+id, nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `name="password"`)
+
+	// После исчерпания попыток даже верный пароль отклоняется.
+	for i := 0; i < 2; i++ {
+		res = submit("wrong")
+		res.Body.Close()
+	}
+	res = submit("n3w")
+	res.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.NotEmpty(t, res.Header.Get("Retry-After"))
+}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage/pg"
 )
@@ -13,7 +15,7 @@ func PingHandler(repo storage.URLStorage) http.HandlerFunc {
 		// Проверяем, является ли хранилище экземпляром DatabaseStorage
 		if dbRepo, ok := repo.(*pg.DatabaseStorage); ok {
 			if err := dbRepo.Ping(r.Context()); err != nil {
-				http.Error(w, "Database connection error", http.StatusInternalServerError)
+				middleware.WriteError(w, r, apperr.Wrap(apperr.Internal, "Database connection error", err))
 				return
 			}
 			w.WriteHeader(http.StatusOK)
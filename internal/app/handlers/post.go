@@ -2,66 +2,125 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
+	"log"
 	"net/http"
 	"strings"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
 	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/redirect"
 	"github.com/alexuryumtsev/go-shortener/internal/app/service"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
 )
 
 // PostHandler обрабатывает POST-запросы для создания короткого URL.
-func PostHandler(storage storage.URLWriter, baseURL string) http.HandlerFunc {
+// Эндпоинт принимает и возвращает простой текст, в том числе в ответах с ошибкой.
+func PostHandler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
-			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			middleware.WriteTextError(w, r, apperr.New(apperr.InvalidInput, "Failed to read request body"))
 			return
 		}
 		defer r.Body.Close()
 
 		ctx := r.Context()
 		originalURL := strings.TrimSpace(string(body))
-		shortenedURL, err := service.NewURLService(ctx, storage, baseURL).ShortenerURL(originalURL)
-
-		if err != nil {
-			middleware.ProcessError(w, err, shortenedURL, true)
+		shortenedURL, err := service.NewURLService(ctx, repo, baseURL).ShortenerURL(originalURL)
+
+		status := http.StatusCreated
+		switch {
+		case errors.Is(err, storage.ErrConflict):
+			// Адрес уже сокращён: возвращаем существующую короткую ссылку.
+			status = http.StatusConflict
+		case err != nil:
+			middleware.WriteTextError(w, r, err)
 			return
 		}
 
-		w.WriteHeader(http.StatusCreated)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
 		w.Write([]byte(shortenedURL))
 	}
 }
 
 // PostJSONHandler обрабатывает POST-запросы для создания короткого URL в формате JSON.
-func PostJSONHandler(storage storage.URLWriter, baseURL string) http.HandlerFunc {
+func PostJSONHandler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var req models.RequestBody
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "invalid request body", http.StatusBadRequest)
+		urlModel, err := decodeURLModel(r)
+		if err != nil {
+			middleware.WriteError(w, r, err)
 			return
 		}
-		defer r.Body.Close()
 
 		ctx := r.Context()
-		shortenedURL, err := service.NewURLService(ctx, storage, baseURL).ShortenerURL(req.URL)
-
-		if err != nil {
-			middleware.ProcessError(w, err, shortenedURL, false)
+		resp, err := service.NewURLService(ctx, repo, baseURL).ShortenerURLModel(urlModel)
+
+		status := http.StatusCreated
+		switch {
+		case errors.Is(err, storage.ErrConflict):
+			// Адрес уже сокращён: возвращаем существующую короткую ссылку.
+			status = http.StatusConflict
+		case err != nil:
+			middleware.WriteError(w, r, err)
 			return
 		}
 
-		resp := models.ResponseBody{
-			ShortURL: shortenedURL,
-		}
+		writeJSON(w, status, resp)
+	}
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(resp)
+// decodeURLModel разбирает и проверяет тело запроса на создание ссылки models.RequestBody.
+// Ссылка создаётся в рабочем пространстве из параметра маршрута {workspace}, если он есть.
+func decodeURLModel(r *http.Request) (models.URLModel, error) {
+	var req models.RequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return models.URLModel{}, apperr.New(apperr.InvalidInput, "invalid request body")
 	}
+	defer r.Body.Close()
+
+	if req.MaxClicks < 0 {
+		return models.URLModel{}, apperr.New(apperr.InvalidInput, "max_clicks must not be negative")
+	}
+	if err := redirect.ValidateRules(req.Rules); err != nil {
+		return models.URLModel{}, apperr.New(apperr.InvalidInput, err.Error())
+	}
+	if err := redirect.ValidateParams(req.Params); err != nil {
+		return models.URLModel{}, apperr.New(apperr.InvalidInput, err.Error())
+	}
+	variants := redirect.NormalizeVariants(req.Variants)
+	if err := redirect.ValidateVariants(variants); err != nil {
+		return models.URLModel{}, apperr.New(apperr.InvalidInput, err.Error())
+	}
+
+	urlModel := models.URLModel{
+		URL:          req.URL,
+		Interstitial: req.Interstitial,
+		MaxClicks:    req.MaxClicks,
+		Rules:        req.Rules,
+		Variants:     variants,
+		Params:       req.Params,
+		Title:        strings.TrimSpace(req.Title),
+		Notes:        req.Notes,
+		Tags:         service.NormalizeTags(req.Tags),
+		Folder:       strings.TrimSpace(req.Folder),
+		WorkspaceID:  chi.URLParam(r, "workspace"),
+	}
+	if err := service.ValidateMetadata(urlModel.Settings()); err != nil {
+		return models.URLModel{}, apperr.New(apperr.InvalidInput, err.Error())
+	}
+	if req.Password != "" {
+		hash, err := service.HashPassword(req.Password)
+		if err != nil {
+			return models.URLModel{}, apperr.New(apperr.InvalidInput, err.Error())
+		}
+		urlModel.PasswordHash = hash
+	}
+	return urlModel, nil
 }
 
 // PostBatchHandler обрабатывает POST-запросы для создания множества коротких URL.
@@ -71,36 +130,28 @@ func PostBatchHandler(repo storage.URLStorage, baseURL string) http.HandlerFunc
 
 		var batchModels []models.URLBatchModel
 		if err := json.NewDecoder(r.Body).Decode(&batchModels); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid request body"))
 			return
 		}
 
 		if len(batchModels) == 0 {
-			http.Error(w, "Empty batch", http.StatusBadRequest)
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Empty batch"))
 			return
 		}
 
 		ctx := r.Context()
 		urlService := service.NewURLService(ctx, repo, baseURL)
 
-		shortenedURLs, err := urlService.SaveBatchShortenerURL(batchModels)
+		batchResponseModels, err := urlService.SaveBatchShortenerURL(batchModels)
 		if err != nil {
-			middleware.ProcessError(w, err, "", false)
+			middleware.WriteError(w, r, err)
 			return
 		}
 
-		var batchResponseModels []models.BatchResponseModel
-		for i, shortenedURL := range shortenedURLs {
-			batchResponseModels = append(batchResponseModels, models.BatchResponseModel{
-				CorrelationID: batchModels[i].CorrelationID,
-				ShortURL:      shortenedURL,
-			})
-		}
-
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		if err := json.NewEncoder(w).Encode(batchResponseModels); err != nil {
-			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			log.Printf("Error encoding response: %v", err)
 		}
 	}
 }
@@ -61,6 +61,8 @@ func TestPostHandler(t *testing.T) {
 			res := rec.Result()
 			defer res.Body.Close()
 			assert.Equal(t, tc.want.code, res.StatusCode)
+			// Легаси-эндпоинт отвечает простым текстом, в том числе при ошибке.
+			assert.Equal(t, tc.want.contentType, res.Header.Get("Content-Type"))
 
 			resBody, err := io.ReadAll(res.Body)
 			require.NoError(t, err)
@@ -135,3 +137,34 @@ func TestPostJsonHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestPostBatchHandler(t *testing.T) {
+	repo := storage.NewMockStorage()
+	handler := PostBatchHandler(repo, "http://localhost:8080/")
+	shorten := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	rec := shorten(`[{"correlation_id": "1", "original_url": "https://example.com/a"}]`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	// Уже сокращённые адреса и повторы внутри пакета не мешают сохранить остальные.
+	rec = shorten(`[
+		{"correlation_id": "1", "original_url": "https://example.com/a"},
+		{"correlation_id": "2", "original_url": "https://example.com/b"},
+		{"correlation_id": "3", "original_url": "https://example.com/b"}
+	]`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var results []models.BatchResponseModel
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	require.Len(t, results, 3)
+	assert.Equal(t, []string{models.BulkConflict, models.BulkCreated, models.BulkConflict},
+		[]string{results[0].Status, results[1].Status, results[2].Status})
+	assert.Equal(t, results[1].ShortURL, results[2].ShortURL)
+
+	rec = shorten(`[]`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/qr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// QRHandler возвращает QR-код короткой ссылки в формате PNG или SVG.
+func QRHandler(storage storage.URLReader, baseURL string) http.HandlerFunc {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		if _, exists := storage.Get(r.Context(), id); !exists {
+			middleware.WriteError(w, r, apperr.New(apperr.NotFound, "URL not found"))
+			return
+		}
+
+		opts, err := qr.ParseOptions(r.URL.Query())
+		if err != nil {
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, err.Error()))
+			return
+		}
+
+		shortenedURL := baseURL + "/" + id
+		etag := opts.ETag(shortenedURL)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		image, err := qr.Render(shortenedURL, opts)
+		if err != nil {
+			middleware.WriteError(w, r, apperr.Wrap(apperr.Internal, "Failed to render QR code", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", opts.ContentType())
+		w.WriteHeader(http.StatusOK)
+		w.Write(image)
+	}
+}
+
+// etagMatches проверяет, содержит ли заголовок If-None-Match указанный тег.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQRHandler(t *testing.T) {
+	repo := storage.NewMockStorage()
+	repo.Save(context.Background(), models.URLModel{ID: "0dd11111", URL: "https://practicum.yandex.ru/"})
+
+	r := chi.NewRouter()
+	r.Get("/api/urls/{id}/qr", QRHandler(repo, "http://localhost:8080/"))
+
+	testCases := []struct {
+		name        string
+		requestPath string
+		code        int
+		contentType string
+	}{
+		{
+			name:        "PNG by default",
+			requestPath: "/api/urls/0dd11111/qr",
+			code:        http.StatusOK,
+			contentType: "image/png",
+		},
+		{
+			name:        "SVG",
+			requestPath: "/api/urls/0dd11111/qr?format=svg&fg=ff0000",
+			code:        http.StatusOK,
+			contentType: "image/svg+xml",
+		},
+		{
+			name:        "Invalid parameters",
+			requestPath: "/api/urls/0dd11111/qr?size=1",
+			code:        http.StatusBadRequest,
+			contentType: middleware.ProblemContentType,
+		},
+		{
+			name:        "Unknown ID",
+			requestPath: "/api/urls/1111/qr",
+			code:        http.StatusNotFound,
+			contentType: middleware.ProblemContentType,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.requestPath, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.code, res.StatusCode)
+			assert.Equal(t, tc.contentType, res.Header.Get("Content-Type"))
+		})
+	}
+}
+
+func TestQRHandlerETag(t *testing.T) {
+	repo := storage.NewMockStorage()
+	repo.Save(context.Background(), models.URLModel{ID: "0dd11111", URL: "https://practicum.yandex.ru/"})
+
+	r := chi.NewRouter()
+	r.Get("/api/urls/{id}/qr", QRHandler(repo, "http://localhost:8080/"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/urls/0dd11111/qr", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// Повторный запрос с тем же тегом не передаёт изображение заново.
+	req = httptest.NewRequest(http.MethodGet, "/api/urls/0dd11111/qr", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.Bytes())
+}
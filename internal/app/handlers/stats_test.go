@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsHandler(t *testing.T) {
+	repo := storage.NewMockStorage()
+	repo.Save(context.Background(), models.URLModel{
+		ID:  "0dd11111",
+		URL: "https://practicum.yandex.ru/",
+		Variants: []models.Variant{
+			{Name: "A", URL: "https://practicum.yandex.ru/a", Weight: 50},
+			{Name: "B", URL: "https://practicum.yandex.ru/b", Weight: 50},
+		},
+	})
+
+	r := chi.NewRouter()
+	r.Get("/{id}", GetHandler(repo, nil, nil, nil))
+	r.Get("/api/urls/{id}/stats", StatsHandler(repo))
+
+	// Переходы с cookie закреплённого варианта.
+	clicks := []struct {
+		variant  string
+		location string
+	}{
+		{variant: "A", location: "https://practicum.yandex.ru/a"},
+		{variant: "A", location: "https://practicum.yandex.ru/a"},
+		{variant: "B", location: "https://practicum.yandex.ru/b"},
+	}
+	for _, click := range clicks {
+		req := httptest.NewRequest(http.MethodGet, "/0dd11111", nil)
+		req.AddCookie(&http.Cookie{Name: "ab_0dd11111", Value: click.variant})
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, click.location, rec.Header().Get("Location"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/urls/0dd11111/stats", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var stats models.URLStats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Equal(t, int64(3), stats.Clicks)
+	require.Len(t, stats.Variants, 2)
+	assert.Equal(t, int64(2), stats.Variants[0].Clicks)
+	assert.Equal(t, int64(1), stats.Variants[1].Clicks)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/urls/1111/stats", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+const (
+	// NDJSONContentType — тип содержимого потока JSON-объектов, по одному на строку.
+	NDJSONContentType = "application/x-ndjson"
+	// CSVContentType — тип содержимого CSV.
+	CSVContentType = "text/csv"
+)
+
+// streamChunkSize — количество строк потока, сохраняемых одним обращением к хранилищу.
+const streamChunkSize = 1000
+
+// maxStreamLineSize ограничивает длину строки NDJSON.
+const maxStreamLineSize = 1 << 20
+
+// PostStreamHandler сокращает ссылки из тела запроса неограниченного размера в формате
+// application/x-ndjson (объекты models.URLBatchModel по одному на строку) или text/csv
+// (столбцы correlation_id и original_url, заголовок необязателен). Строки сохраняются пачками
+// по streamChunkSize, а результаты models.BulkResult отправляются в формате NDJSON
+// по мере чтения запроса, не дожидаясь его окончания.
+func PostStreamHandler(repo storage.URLStorage, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var items bulkReader
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case NDJSONContentType:
+			items = newNDJSONReader(r.Body)
+		case CSVContentType:
+			items = newCSVReader(r.Body)
+		default:
+			middleware.WriteError(w, r, apperr.New(apperr.UnsupportedMediaType,
+				"Content-Type must be "+NDJSONContentType+" or "+CSVContentType))
+			return
+		}
+
+		// Результаты отправляются, пока клиент ещё передаёт тело запроса. В HTTP/2 чтение
+		// и запись и так идут одновременно, поэтому ошибка переключения режима не важна.
+		rc := http.NewResponseController(w)
+		rc.EnableFullDuplex()
+
+		w.Header().Set("Content-Type", NDJSONContentType)
+		w.WriteHeader(http.StatusOK)
+
+		urlService := service.NewURLService(r.Context(), repo, baseURL)
+		enc := json.NewEncoder(w)
+		chunk := make([]models.BulkItem, 0, streamChunkSize)
+
+		// flush сохраняет накопленные строки и отправляет клиенту их результаты.
+		// Если хранилище не сохранило пачку, её строки отмечаются ошибкой и обработка прекращается.
+		flush := func() bool {
+			results, saveErr := urlService.ShortenBulk(chunk)
+			if saveErr != nil {
+				log.Printf("Error saving stream chunk: %v", saveErr)
+				results = make([]models.BulkResult, len(chunk))
+				for i, item := range chunk {
+					results[i] = models.BulkResult{
+						Line:          item.Line,
+						CorrelationID: item.CorrelationID,
+						Status:        models.BulkError,
+						Error:         apperr.Message(saveErr),
+					}
+				}
+			}
+			for _, result := range results {
+				if err := enc.Encode(result); err != nil {
+					log.Printf("Error encoding response: %v", err)
+					return false
+				}
+			}
+			rc.Flush()
+			chunk = chunk[:0]
+			return saveErr == nil
+		}
+
+		for {
+			item, err := items.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				// Тело запроса дальше не читается: ошибка становится последней строкой ответа.
+				chunk = append(chunk, models.BulkItem{Line: item.Line, Err: err})
+				break
+			}
+			chunk = append(chunk, item)
+			if len(chunk) == streamChunkSize && !flush() {
+				return
+			}
+		}
+		if len(chunk) > 0 {
+			flush()
+		}
+	}
+}
+
+// bulkReader читает строки потока на сокращение ссылок. Ошибки отдельных строк
+// возвращаются в BulkItem.Err, а ошибка Next, кроме io.EOF, прерывает чтение.
+type bulkReader interface {
+	Next() (models.BulkItem, error)
+}
+
+// ndjsonReader читает объекты models.URLBatchModel по одному на строку, пропуская пустые строки.
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+	return &ndjsonReader{scanner: scanner}
+}
+
+func (r *ndjsonReader) Next() (models.BulkItem, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		item := models.BulkItem{Line: r.line}
+		var req models.URLBatchModel
+		if err := json.Unmarshal(line, &req); err != nil {
+			item.Err = apperr.New(apperr.InvalidInput, "Invalid JSON")
+			return item, nil
+		}
+		item.CorrelationID = req.CorrelationID
+		item.URL = strings.TrimSpace(req.OriginalURL)
+		return item, nil
+	}
+
+	item := models.BulkItem{Line: r.line + 1}
+	switch err := r.scanner.Err(); {
+	case errors.Is(err, bufio.ErrTooLong):
+		return item, apperr.New(apperr.InvalidInput, "Line is too long")
+	case err != nil:
+		return item, apperr.Wrap(apperr.InvalidInput, "Failed to read request body", err)
+	}
+	return models.BulkItem{}, io.EOF
+}
+
+// csvReader читает строки CSV. Если первая строка содержит столбец original_url (или url),
+// она считается заголовком и задаёт положение столбцов; иначе единственный столбец —
+// адрес назначения, а при двух столбцах первый — correlation_id, второй — адрес.
+// Количество столбцов во всех строках должно совпадать с первой строкой.
+type csvReader struct {
+	reader    *csv.Reader
+	urlColumn int
+	idColumn  int // -1, если столбца correlation_id нет
+	started   bool
+	line      int // Номер последней прочитанной строки
+}
+
+func newCSVReader(r io.Reader) *csvReader {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	reader.TrimLeadingSpace = true
+	return &csvReader{reader: reader, idColumn: -1}
+}
+
+func (r *csvReader) Next() (models.BulkItem, error) {
+	for {
+		record, err := r.reader.Read()
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return models.BulkItem{
+				Line: parseErr.StartLine,
+				Err:  apperr.New(apperr.InvalidInput, "Invalid CSV: "+parseErr.Err.Error()),
+			}, nil
+		}
+		if errors.Is(err, io.EOF) {
+			return models.BulkItem{}, io.EOF
+		}
+		if err != nil {
+			return models.BulkItem{Line: r.line + 1}, apperr.Wrap(apperr.InvalidInput, "Failed to read request body", err)
+		}
+		r.line, _ = r.reader.FieldPos(0)
+
+		if !r.started {
+			r.started = true
+			if r.readHeader(record) {
+				continue
+			}
+		}
+
+		item := models.BulkItem{Line: r.line, URL: strings.TrimSpace(record[r.urlColumn])}
+		if r.idColumn >= 0 {
+			item.CorrelationID = record[r.idColumn]
+		}
+		return item, nil
+	}
+}
+
+// readHeader определяет положение столбцов по первой строке и сообщает, является ли она заголовком.
+func (r *csvReader) readHeader(record []string) bool {
+	r.urlColumn = -1
+	for i, name := range record {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "original_url", "url":
+			r.urlColumn = i
+		case "correlation_id":
+			r.idColumn = i
+		}
+	}
+	if r.urlColumn >= 0 {
+		return true
+	}
+
+	r.idColumn = -1
+	r.urlColumn = 0
+	if len(record) > 1 {
+		r.idColumn, r.urlColumn = 0, 1
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingBulkStorage не может сохранить ни одной пачки.
+type failingBulkStorage struct {
+	*storage.MockStorage
+}
+
+func (s failingBulkStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) ([]bool, error) {
+	return nil, assert.AnError
+}
+
+func TestPostStreamHandler(t *testing.T) {
+	const baseURL = "http://localhost:8080/"
+	short := func(url string) string {
+		return "http://localhost:8080/" + service.GenerateID(url)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []models.BulkResult
+	}{
+		{
+			name:        "ndjson",
+			contentType: "application/x-ndjson",
+			body: `{"correlation_id": "1", "original_url": "https://example.com/new"}
+{"correlation_id": "2", "original_url": "https://example.com/existing"}
+
+{"correlation_id": "4", "original_url": "https://example.com/new"}
+not json
+{"correlation_id": "6", "original_url": "/relative"}
+`,
+			want: []models.BulkResult{
+				{Line: 1, CorrelationID: "1", ShortURL: short("https://example.com/new"), Status: models.BulkCreated},
+				{Line: 2, CorrelationID: "2", ShortURL: short("https://example.com/existing"), Status: models.BulkConflict},
+				{Line: 4, CorrelationID: "4", ShortURL: short("https://example.com/new"), Status: models.BulkConflict},
+				{Line: 5, Status: models.BulkError, Error: "Invalid JSON"},
+				{Line: 6, CorrelationID: "6", Status: models.BulkError, Error: "URL must be absolute"},
+			},
+		},
+		{
+			name:        "csv with header",
+			contentType: "text/csv; charset=utf-8",
+			body:        "original_url,correlation_id\nhttps://example.com/new,1\n\"https://example.com/\nbroken\",2,extra\nhttps://example.com/existing,3\n",
+			want: []models.BulkResult{
+				{Line: 2, CorrelationID: "1", ShortURL: short("https://example.com/new"), Status: models.BulkCreated},
+				{Line: 3, Status: models.BulkError, Error: "Invalid CSV: wrong number of fields"},
+				{Line: 5, CorrelationID: "3", ShortURL: short("https://example.com/existing"), Status: models.BulkConflict},
+			},
+		},
+		{
+			name:        "csv without header",
+			contentType: "text/csv",
+			body:        "https://example.com/new\nhttps://example.com/other\n",
+			want: []models.BulkResult{
+				{Line: 1, ShortURL: short("https://example.com/new"), Status: models.BulkCreated},
+				{Line: 2, ShortURL: short("https://example.com/other"), Status: models.BulkCreated},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := storage.NewMockStorage()
+			require.NoError(t, repo.Save(context.Background(), models.URLModel{
+				ID:  service.GenerateID("https://example.com/existing"),
+				URL: "https://example.com/existing",
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten/stream", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			PostStreamHandler(repo, baseURL)(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, NDJSONContentType, rec.Header().Get("Content-Type"))
+			assert.True(t, rec.Flushed)
+			assert.Equal(t, tt.want, decodeResults(t, rec))
+
+			for _, result := range tt.want {
+				if result.Status == models.BulkCreated {
+					_, exists := repo.Get(context.Background(), strings.TrimPrefix(result.ShortURL, baseURL))
+					assert.True(t, exists, result.ShortURL)
+				}
+			}
+		})
+	}
+
+	t.Run("unsupported media type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten/stream", strings.NewReader(`[]`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		PostStreamHandler(storage.NewMockStorage(), baseURL)(rec, req)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+		assert.Equal(t, middleware.ProblemContentType, rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		body := strings.Repeat(`{"original_url": "https://example.com/"}`+"\n", streamChunkSize+1)
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten/stream", strings.NewReader(body))
+		req.Header.Set("Content-Type", NDJSONContentType)
+		rec := httptest.NewRecorder()
+		PostStreamHandler(failingBulkStorage{storage.NewMockStorage()}, baseURL)(rec, req)
+
+		// Обработка прекращается после первой пачки, которую не удалось сохранить.
+		results := decodeResults(t, rec)
+		require.Len(t, results, streamChunkSize)
+		assert.Equal(t, models.BulkError, results[0].Status)
+		assert.Equal(t, "Internal server error", results[0].Error)
+	})
+}
+
+func decodeResults(t *testing.T, rec *httptest.ResponseRecorder) []models.BulkResult {
+	t.Helper()
+	var results []models.BulkResult
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var result models.BulkResult
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/audit"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/jwt"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+)
+
+// TokenResponse — ответ с JWT, выданным в обмен на API-ключ.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// TokenHandler обменивает API-ключ из заголовка Authorization на JWT со сроком действия ttl.
+// Токен наследует области действия и рабочее пространство ключа. Отзыв ключа не отзывает
+// уже выданные токены, поэтому срок их действия должен быть коротким.
+func TokenHandler(authority *jwt.Authority, ttl time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, ok := auth.APIKey(r.Context())
+		if token, _ := auth.BearerToken(r); !ok || !strings.HasPrefix(token, auth.APIKeyPrefix) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			middleware.WriteError(w, r, apperr.New(apperr.Unauthorized, "API key required"))
+			return
+		}
+
+		token, err := authority.Issue(jwt.Claims{
+			Subject:     key.UserID,
+			Scope:       strings.Join(key.Scopes, " "),
+			KeyID:       key.ID,
+			WorkspaceID: key.WorkspaceID,
+		}, ttl, time.Now())
+		if err != nil {
+			middleware.WriteError(w, r, fmt.Errorf("issue token: %w", err))
+			return
+		}
+
+		audit.Record(r.Context(), models.AuditEvent{
+			Action:      audit.ActionToken,
+			Actor:       key.UserID,
+			WorkspaceID: key.WorkspaceID,
+			Target:      key.ID,
+			After:       audit.Snapshot(map[string]any{"scopes": key.Scopes, "expires_in": int(ttl.Seconds())}),
+		})
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, TokenResponse{AccessToken: token, TokenType: "Bearer", ExpiresIn: int(ttl.Seconds())})
+	}
+}
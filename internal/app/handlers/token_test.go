@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/jwt"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenHandler(t *testing.T) {
+	repo := memory.NewInMemoryStorage()
+	authority := jwt.NewAuthority(jwt.NewHMACKeySet("test", []byte(strings.Repeat("k", 32))), "shortener", "api")
+	key, err := service.NewAPIKeyService(context.Background(), repo).
+		Create("alice", "ci", "", []string{models.ScopeLinksRead})
+	require.NoError(t, err)
+
+	var seen string
+	r := chi.NewRouter()
+	r.Use(auth.APIKeyMiddleware(service.NewAPIKeyResolver(repo)))
+	r.Use(auth.JWTMiddleware(authority))
+	r.Post("/api/auth/token", TokenHandler(authority, 15*time.Minute))
+	r.With(auth.RequireScope(models.ScopeLinksRead)).Get("/read", func(w http.ResponseWriter, r *http.Request) {
+		seen = auth.UserID(r.Context())
+	})
+	r.With(auth.RequireScope(models.ScopeLinksWrite)).Post("/write", func(w http.ResponseWriter, r *http.Request) {})
+
+	do := func(method, target, bearer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/auth/token", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = do(http.MethodPost, "/api/auth/token", key.Key)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	var resp TokenResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Bearer", resp.TokenType)
+	assert.Equal(t, 900, resp.ExpiresIn)
+
+	// Токен наследует области действия ключа.
+	rec = do(http.MethodGet, "/read", resp.AccessToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "alice", seen)
+	rec = do(http.MethodPost, "/write", resp.AccessToken)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Токен нельзя обменять на новый токен.
+	rec = do(http.MethodPost, "/api/auth/token", resp.AccessToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Токен шлюза без областей действия не ограничен.
+	gatewayToken, err := authority.Issue(jwt.Claims{Subject: "bob"}, time.Minute, time.Now())
+	require.NoError(t, err)
+	rec = do(http.MethodPost, "/write", gatewayToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = do(http.MethodGet, "/read", resp.AccessToken+"x")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
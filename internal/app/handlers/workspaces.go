@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateWorkspaceHandler создаёт рабочее пространство, владельцем которого становится текущий пользователь.
+func CreateWorkspaceHandler(repo storage.WorkspaceStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid request body"))
+			return
+		}
+
+		ctx := r.Context()
+		workspace, err := service.NewWorkspaceService(ctx, repo).Create(auth.UserID(ctx), req.Name)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, workspace)
+	}
+}
+
+// WorkspacesHandler возвращает рабочие пространства текущего пользователя с его ролями.
+func WorkspacesHandler(repo storage.WorkspaceStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		memberships, err := service.NewWorkspaceService(ctx, repo).List(auth.UserID(ctx))
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, memberships)
+	}
+}
+
+// MembersHandler возвращает участников рабочего пространства.
+func MembersHandler(repo storage.WorkspaceStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		members, err := service.NewWorkspaceService(r.Context(), repo).Members(chi.URLParam(r, "workspace"))
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, members)
+	}
+}
+
+// SetMemberRoleHandler меняет роль участника рабочего пространства.
+func SetMemberRoleHandler(repo storage.WorkspaceStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid request body"))
+			return
+		}
+
+		member, err := service.NewWorkspaceService(r.Context(), repo).
+			SetRole(chi.URLParam(r, "workspace"), chi.URLParam(r, "user"), req.Role)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, member)
+	}
+}
+
+// RemoveMemberHandler исключает участника из рабочего пространства.
+func RemoveMemberHandler(repo storage.WorkspaceStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := service.NewWorkspaceService(r.Context(), repo).
+			RemoveMember(chi.URLParam(r, "workspace"), chi.URLParam(r, "user"))
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// InviteHandler создаёт приглашение в рабочее пространство. Токен приглашения
+// возвращается только в этом ответе.
+func InviteHandler(repo storage.WorkspaceStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid request body"))
+			return
+		}
+
+		ctx := r.Context()
+		invitation, err := service.NewWorkspaceService(ctx, repo).
+			Invite(chi.URLParam(r, "workspace"), auth.UserID(ctx), req.Role)
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, invitation)
+	}
+}
+
+// InvitationsHandler возвращает приглашения в рабочее пространство.
+func InvitationsHandler(repo storage.WorkspaceStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		invitations, err := service.NewWorkspaceService(r.Context(), repo).Invitations(chi.URLParam(r, "workspace"))
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, invitations)
+	}
+}
+
+// RevokeInvitationHandler отзывает приглашение.
+func RevokeInvitationHandler(repo storage.WorkspaceStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := service.NewWorkspaceService(r.Context(), repo).
+			RevokeInvitation(chi.URLParam(r, "workspace"), chi.URLParam(r, "invitation"))
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AcceptInvitationHandler добавляет текущего пользователя в рабочее пространство по токену приглашения.
+func AcceptInvitationHandler(repo storage.WorkspaceStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		member, err := service.NewWorkspaceService(ctx, repo).Accept(chi.URLParam(r, "token"), auth.UserID(ctx))
+		if err != nil {
+			middleware.WriteError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, member)
+	}
+}
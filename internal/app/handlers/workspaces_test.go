@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/access"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaceHandlers(t *testing.T) {
+	repo := memory.NewInMemoryStorage()
+	policy := access.NewPolicy(repo)
+	baseURL := "http://localhost:8080"
+
+	r := chi.NewRouter()
+	r.Post("/api/workspaces", CreateWorkspaceHandler(repo))
+	r.Get("/api/workspaces", WorkspacesHandler(repo))
+	r.Post("/api/invitations/{token}/accept", AcceptInvitationHandler(repo))
+	r.Delete("/api/user/urls", DeleteUserURLsHandler(repo, policy, baseURL))
+	r.Route("/api/workspaces/{workspace}", func(r chi.Router) {
+		r.With(policy.Workspace(access.ActionList)).Get("/urls", UserURLsHandler(repo, baseURL))
+		r.With(policy.Workspace(access.ActionCreate)).Post("/urls", PostJSONHandler(repo, baseURL))
+		r.With(policy.Workspace(access.ActionList)).Get("/members", MembersHandler(repo))
+		r.Group(func(r chi.Router) {
+			r.Use(policy.Workspace(access.ActionManage))
+			r.Put("/members/{user}", SetMemberRoleHandler(repo))
+			r.Delete("/members/{user}", RemoveMemberHandler(repo))
+			r.Post("/invitations", InviteHandler(repo))
+			r.Get("/invitations", InvitationsHandler(repo))
+			r.Delete("/invitations/{invitation}", RevokeInvitationHandler(repo))
+		})
+	})
+
+	do := func(method, target, body, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req = req.WithContext(auth.WithUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/workspaces", `{"name":"  "}`, "alice")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = do(http.MethodPost, "/api/workspaces", `{"name":"Marketing"}`, "alice")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var workspace models.Workspace
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &workspace))
+	prefix := "/api/workspaces/" + workspace.ID
+
+	// Приглашать участников может только владелец.
+	rec = do(http.MethodPost, prefix+"/invitations", `{"role":"editor"}`, "bob")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = do(http.MethodPost, prefix+"/invitations", `{"role":"admin"}`, "alice")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = do(http.MethodPost, prefix+"/invitations", `{"role":"editor"}`, "alice")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var invitation models.Invitation
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &invitation))
+	require.NotEmpty(t, invitation.Token)
+
+	rec = do(http.MethodPost, "/api/invitations/"+invitation.Token+"/accept", "", "bob")
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = do(http.MethodPost, "/api/invitations/"+invitation.Token+"/accept", "", "carol")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = do(http.MethodGet, "/api/workspaces", "", "bob")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var memberships []models.Membership
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &memberships))
+	require.Len(t, memberships, 1)
+	assert.Equal(t, models.RoleEditor, memberships[0].Role)
+
+	// Редактор создаёт ссылку, она видна всем участникам пространства.
+	rec = do(http.MethodPost, prefix+"/urls", `{"url":"https://practicum.yandex.ru/","title":"Курс"}`, "bob")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	rec = do(http.MethodGet, prefix+"/urls", "", "alice")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var page models.LinkPage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, workspace.ID, page.Items[0].Workspace)
+	rec = do(http.MethodGet, prefix+"/urls", "", "carol")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Пространство не может остаться без владельца.
+	rec = do(http.MethodPut, prefix+"/members/alice", `{"role":"viewer"}`, "alice")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Наблюдатель не может удалять ссылки, такие ссылки пропускаются.
+	rec = do(http.MethodPut, prefix+"/members/bob", `{"role":"viewer"}`, "alice")
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = do(http.MethodDelete, "/api/user/urls", `["`+page.Items[0].ID+`"]`, "bob")
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	urlModel, _ := repo.Get(context.Background(), page.Items[0].ID)
+	assert.False(t, urlModel.Deleted)
+
+	rec = do(http.MethodDelete, "/api/user/urls", `["`+page.Items[0].ID+`"]`, "alice")
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	urlModel, _ = repo.Get(context.Background(), page.Items[0].ID)
+	assert.True(t, urlModel.Deleted)
+
+	rec = do(http.MethodDelete, prefix+"/members/bob", "", "alice")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = do(http.MethodGet, prefix+"/members", "", "bob")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
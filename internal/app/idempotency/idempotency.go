@@ -0,0 +1,249 @@
+// Package idempotency делает повторы запросов на создание и удаление ссылок безопасными.
+// Клиент передаёт в заголовке Idempotency-Key уникальный ключ операции; первый ответ
+// сохраняется в хранилище для ключа и пользователя и возвращается на повторные запросы
+// без повторного выполнения. Поэтому запрос с ключом должен предъявить пользователя:
+// анонимный клиент получал бы новый идентификатор при каждой попытке.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+const (
+	// Header — заголовок запроса с ключом идемпотентности.
+	Header = "Idempotency-Key"
+	// ReplayedHeader отмечает ответ, возвращённый из сохранённого.
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+// DefaultWindow — срок хранения ответов по умолчанию.
+const DefaultWindow = 24 * time.Hour
+
+// PurgeInterval — период удаления записей с истёкшим сроком хранения.
+const PurgeInterval = time.Hour
+
+// Lease — срок, в течение которого выполняющийся запрос удерживает ключ. Если процесс
+// завершился, не сохранив ответ и не освободив ключ, повтор запроса с тем же ключом
+// по истечении срока выполняется заново, а не получает 409 до конца срока хранения.
+const Lease = 5 * time.Minute
+
+// maxKeyLength ограничивает длину ключа идемпотентности.
+const maxKeyLength = 255
+
+// Ошибки запросов с ключом идемпотентности.
+var (
+	ErrInProgress = apperr.New(apperr.Conflict, "A request with this Idempotency-Key is in progress")
+	ErrKeyReused  = apperr.New(apperr.Unprocessable, "Idempotency-Key has already been used for a different request")
+	errInvalidKey = apperr.New(apperr.InvalidInput, "Idempotency-Key must not be longer than 255 characters")
+	errAnonymous  = apperr.New(apperr.Unauthorized, "Idempotency-Key requires the user_id cookie, an API key or a JWT")
+)
+
+// skippedHeaders не сохраняются вместе с ответом: тело сохраняется несжатым,
+// а его длину сервер вычисляет заново.
+var skippedHeaders = []string{"Content-Encoding", "Content-Length"}
+
+// Guard сохраняет ответы на запросы с заголовком Idempotency-Key на время window.
+type Guard struct {
+	repo   storage.IdempotencyStorage
+	window time.Duration
+}
+
+// New создаёт Guard, хранящий ответы в repo. Нулевой срок хранения заменяется на DefaultWindow.
+func New(repo storage.IdempotencyStorage, window time.Duration) *Guard {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Guard{repo: repo, window: window}
+}
+
+// StartPurge каждые interval удаляет из repo записи с истёкшим сроком хранения.
+// Удаление прекращается при отмене ctx.
+func StartPurge(ctx context.Context, repo storage.IdempotencyStorage, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := repo.PurgeIdempotencyKeys(ctx, time.Now().UTC()); err != nil {
+					log.Printf("Error purging idempotency keys: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Middleware возвращает на повтор запроса с тем же ключом сохранённый ответ с заголовком
+// Idempotent-Replayed. Пока первый запрос выполняется, но не дольше Lease, повторы получают 409, а запрос
+// с тем же ключом, но другим методом, путём или телом — 422, а запрос с ключом без cookie
+// пользователя, API-ключа или JWT — 401. Ответы с ошибкой сервера
+// не сохраняются, чтобы запрос можно было повторить. Ошибки описываются в формате RFC 7807.
+func (g *Guard) Middleware(next http.Handler) http.Handler {
+	return g.handler(next, middleware.WriteError)
+}
+
+// TextMiddleware работает как Middleware, но отвечает на ошибки простым текстом,
+// как легаси-эндпоинт POST /.
+func (g *Guard) TextMiddleware(next http.Handler) http.Handler {
+	return g.handler(next, middleware.WriteTextError)
+}
+
+func (g *Guard) handler(next http.Handler, writeError func(http.ResponseWriter, *http.Request, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			writeError(w, r, errInvalidKey)
+			return
+		}
+		if auth.Anonymous(r.Context()) {
+			writeError(w, r, errAnonymous)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, r, apperr.New(apperr.InvalidInput, "Failed to read request body"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		// Время закрепления отличает запрос от повтора, перехватившего ключ после Lease,
+		// и округляется до точности хранения времени в базе данных.
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		hash := requestHash(r, body)
+		record, reserved, err := g.repo.ReserveIdempotencyKey(ctx, models.IdempotencyRecord{
+			UserID:      auth.UserID(ctx),
+			Key:         key,
+			RequestHash: hash,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(g.window),
+			LockedUntil: now.Add(Lease),
+		})
+		switch {
+		case err != nil:
+			writeError(w, r, err)
+			return
+		case reserved:
+		case record.RequestHash != hash:
+			writeError(w, r, ErrKeyReused)
+			return
+		case !record.Completed:
+			writeError(w, r, ErrInProgress)
+			return
+		default:
+			replay(w, record)
+			return
+		}
+
+		// Ответ сохраняется и после отмены запроса клиентом, иначе ключ освобождается,
+		// чтобы клиент мог повторить запрос.
+		ctx = context.WithoutCancel(ctx)
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := g.repo.ReleaseIdempotencyKey(ctx, record); err != nil {
+				log.Printf("Error releasing idempotency key: %v", err)
+			}
+		}()
+
+		rec := &recorder{ResponseWriter: w, before: w.Header().Clone()}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if rec.status >= http.StatusInternalServerError {
+			return
+		}
+
+		record.Status = rec.status
+		record.Header = rec.header()
+		record.Body = rec.body.Bytes()
+		if err := g.repo.CompleteIdempotencyKey(ctx, record); err != nil {
+			log.Printf("Error saving idempotent response: %v", err)
+			return
+		}
+		completed = true
+	})
+}
+
+// requestHash возвращает отпечаток метода, пути и тела запроса.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay повторяет сохранённый ответ.
+func replay(w http.ResponseWriter, record models.IdempotencyRecord) {
+	for name, values := range record.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+// recorder запоминает статус, заголовки и тело ответа, передавая их клиенту.
+type recorder struct {
+	http.ResponseWriter
+	before http.Header // Заголовки, установленные до обработчика
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного ответа.
+func (rec *recorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// header возвращает заголовки, установленные обработчиком. Заголовки внешних обработчиков,
+// например идентификатор запроса и cookie пользователя, при повторе выставляются заново.
+func (rec *recorder) header() map[string][]string {
+	header := make(map[string][]string)
+	for name, values := range rec.ResponseWriter.Header() {
+		if slices.Contains(skippedHeaders, name) || slices.Equal(rec.before[name], values) {
+			continue
+		}
+		header[name] = slices.Clone(values)
+	}
+	return header
+}
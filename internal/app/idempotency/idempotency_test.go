@@ -0,0 +1,170 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	status := http.StatusCreated
+	handler := New(memory.NewInMemoryStorage(), time.Hour).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/api/v2/links/abc")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"call":%d}`, n)
+	}))
+
+	do := func(userID, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body))
+		req = req.WithContext(auth.WithUserID(req.Context(), userID))
+		if key != "" {
+			req.Header.Set(Header, key)
+		}
+		rec := httptest.NewRecorder()
+		// Заголовки внешних обработчиков не сохраняются и не повторяются.
+		rec.Header().Set("X-Request-ID", key+userID+body)
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := do("alice", "k1", `[1]`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, `{"call":1}`, first.Body.String())
+	assert.Empty(t, first.Header().Get(ReplayedHeader))
+
+	replayed := do("alice", "k1", `[1]`)
+	assert.Equal(t, http.StatusCreated, replayed.Code)
+	assert.Equal(t, `{"call":1}`, replayed.Body.String())
+	assert.Equal(t, "true", replayed.Header().Get(ReplayedHeader))
+	assert.Equal(t, "/api/v2/links/abc", replayed.Header().Get("Location"))
+	assert.Equal(t, "k1alice[1]", replayed.Header().Get("X-Request-ID"))
+	assert.Equal(t, int32(1), calls.Load())
+
+	rec := do("alice", "k1", `[2]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, middleware.ProblemContentType, rec.Header().Get("Content-Type"))
+
+	// Ключи разных пользователей не пересекаются, запросы без ключа не сохраняются.
+	assert.Equal(t, `{"call":2}`, do("bob", "k1", `[1]`).Body.String())
+	assert.Equal(t, `{"call":3}`, do("alice", "", `[1]`).Body.String())
+	assert.Equal(t, `{"call":4}`, do("alice", "", `[1]`).Body.String())
+
+	// Ответ с ошибкой сервера не сохраняется, и запрос можно повторить.
+	status = http.StatusServiceUnavailable
+	assert.Equal(t, http.StatusServiceUnavailable, do("alice", "k2", `[1]`).Code)
+	status = http.StatusCreated
+	rec = do("alice", "k2", `[1]`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Empty(t, rec.Header().Get(ReplayedHeader))
+
+	rec = do("alice", strings.Repeat("k", maxKeyLength+1), `[1]`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Без пользователя ключ отклоняется, запрос не выполняется.
+	calls.Store(0)
+	assert.Equal(t, http.StatusUnauthorized, do("", "k3", `[1]`).Code)
+	assert.Zero(t, calls.Load())
+}
+
+func TestMiddleware_InProgress(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	guard := New(memory.NewInMemoryStorage(), time.Hour)
+	slow := guard.TextMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com"))
+		req.Header.Set(Header, "k1")
+		return req.WithContext(auth.WithUserID(req.Context(), "alice"))
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		slow.ServeHTTP(rec, newRequest())
+		done <- rec
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	slow.ServeHTTP(rec, newRequest())
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	close(release)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+
+	rec = httptest.NewRecorder()
+	slow.ServeHTTP(rec, newRequest())
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(ReplayedHeader))
+}
+
+func TestMiddleware_StaleReservation(t *testing.T) {
+	repo := memory.NewInMemoryStorage()
+	handler := New(repo, time.Hour).TextMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	// Запрос закрепил ключ и не завершился: процесс остановился до сохранения ответа.
+	now := time.Now().UTC()
+	_, reserved, err := repo.ReserveIdempotencyKey(context.Background(), models.IdempotencyRecord{
+		UserID:      "alice",
+		Key:         "k1",
+		RequestHash: "crashed",
+		CreatedAt:   now.Add(-2 * Lease),
+		ExpiresAt:   now.Add(time.Hour),
+		LockedUntil: now.Add(-Lease),
+	})
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com"))
+	req.Header.Set(Header, "k1")
+	req = req.WithContext(auth.WithUserID(req.Context(), "alice"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Empty(t, rec.Header().Get(ReplayedHeader))
+}
+
+func TestStartPurge(t *testing.T) {
+	repo := memory.NewInMemoryStorage()
+	expiresAt := time.Now().UTC().Add(-time.Minute)
+	record := models.IdempotencyRecord{UserID: "alice", Key: "k1", CreatedAt: expiresAt.Add(-time.Hour), ExpiresAt: expiresAt,
+		LockedUntil: expiresAt}
+	_, reserved, err := repo.ReserveIdempotencyKey(context.Background(), record)
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	StartPurge(ctx, repo, time.Millisecond)
+
+	// Удалённая запись закрепляется заново даже на момент до истечения её срока.
+	assert.Eventually(t, func() bool {
+		_, reserved, err := repo.ReserveIdempotencyKey(context.Background(), models.IdempotencyRecord{
+			UserID: "alice", Key: "k1", CreatedAt: record.CreatedAt, ExpiresAt: record.CreatedAt,
+		})
+		return err == nil && reserved
+	}, time.Second, 5*time.Millisecond)
+}
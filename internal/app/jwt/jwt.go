@@ -0,0 +1,245 @@
+// Package jwt выпускает и проверяет JSON Web Token (RFC 7519), подписанные HS256, RS256 или EdDSA.
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+)
+
+// DefaultLeeway — допустимое расхождение часов при проверке exp и nbf.
+const DefaultLeeway = time.Minute
+
+// Ошибки проверки токена.
+var (
+	ErrInvalidToken = apperr.New(apperr.Unauthorized, "invalid token")
+	ErrExpired      = fmt.Errorf("%w: token is expired", ErrInvalidToken)
+	ErrNotYetValid  = fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+)
+
+// Audience — значение claim aud, которое может быть строкой или массивом строк.
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Claims содержит утверждения токена. Scope и WorkspaceID задаются для токенов,
+// выданных в обмен на API-ключ, и ограничивают их так же, как сам ключ.
+type Claims struct {
+	Issuer      string   `json:"iss,omitempty"`
+	Subject     string   `json:"sub"`
+	Audience    Audience `json:"aud,omitempty"`
+	ExpiresAt   int64    `json:"exp,omitempty"`
+	NotBefore   int64    `json:"nbf,omitempty"`
+	IssuedAt    int64    `json:"iat,omitempty"`
+	ID          string   `json:"jti,omitempty"`
+	Scope       string   `json:"scope,omitempty"` // Области действия через пробел
+	KeyID       string   `json:"key_id,omitempty"`
+	WorkspaceID string   `json:"workspace_id,omitempty"`
+}
+
+// Scopes возвращает области действия токена.
+func (c Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Authority выпускает и проверяет токены с заданными издателем и аудиторией.
+type Authority struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+	Leeway   time.Duration // Допустимое расхождение часов
+}
+
+// NewAuthority создаёт выпускающий и проверяющий токены центр. Пустые issuer и audience
+// не записываются в выпускаемые токены и не проверяются.
+func NewAuthority(keys *KeySet, issuer, audience string) *Authority {
+	return &Authority{keys: keys, issuer: issuer, audience: audience, Leeway: DefaultLeeway}
+}
+
+// Issue выпускает токен со сроком действия ttl, заполняя iss, aud, iat, nbf, exp и jti.
+func (a *Authority) Issue(claims Claims, ttl time.Duration, now time.Time) (string, error) {
+	claims.Issuer = a.issuer
+	if a.audience != "" {
+		claims.Audience = Audience{a.audience}
+	}
+	claims.IssuedAt = now.Unix()
+	claims.NotBefore = now.Unix()
+	claims.ExpiresAt = now.Add(ttl).Unix()
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	claims.ID = base64.RawURLEncoding.EncodeToString(jti)
+	return a.Sign(claims)
+}
+
+// Sign подписывает произвольный набор утверждений без изменений.
+func (a *Authority) Sign(claims any) (string, error) {
+	key, ok := a.keys.signingKey()
+	if !ok {
+		return "", errors.New("key set has no private key")
+	}
+
+	headerJSON, err := json.Marshal(header{Alg: key.Algorithm, Typ: "JWT", Kid: key.ID})
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encode(headerJSON) + "." + encode(claimsJSON)
+	signature, err := sign(key, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + encode(signature), nil
+}
+
+// Verify проверяет подпись, издателя, аудиторию и сроки действия токена на момент now.
+func (a *Authority) Verify(token string, now time.Time) (Claims, error) {
+	return a.VerifyInto(token, now, nil)
+}
+
+// VerifyInto проверяет токен так же, как Verify, и дополнительно разбирает утверждения в extra,
+// если он не nil. Используется для утверждений, не входящих в Claims.
+func (a *Authority) VerifyInto(token string, now time.Time, extra any) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var h header
+	if err := decodeJSON(parts[0], &h); err != nil {
+		return Claims{}, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	// Алгоритм ключа должен совпадать с заголовком, поэтому открытый ключ RSA
+	// не может быть использован как секрет HS256.
+	signingInput := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range a.keys.verificationKeys(h.Kid, h.Alg) {
+		if verify(key, signingInput, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return Claims{}, fmt.Errorf("%w: signature verification failed", ErrInvalidToken)
+	}
+
+	var claims Claims
+	if err := decodeJSON(parts[1], &claims); err != nil {
+		return Claims{}, err
+	}
+	if extra != nil {
+		if err := decodeJSON(parts[1], extra); err != nil {
+			return Claims{}, err
+		}
+	}
+	if claims.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: subject is required", ErrInvalidToken)
+	}
+	if a.issuer != "" && claims.Issuer != a.issuer {
+		return Claims{}, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if a.audience != "" && !slices.Contains(claims.Audience, a.audience) {
+		return Claims{}, fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+	if claims.ExpiresAt == 0 {
+		return Claims{}, fmt.Errorf("%w: expiration is required", ErrInvalidToken)
+	}
+	if !now.Before(time.Unix(claims.ExpiresAt, 0).Add(a.Leeway)) {
+		return Claims{}, ErrExpired
+	}
+	if claims.NotBefore != 0 && now.Add(a.Leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return Claims{}, ErrNotYetValid
+	}
+	return claims, nil
+}
+
+func sign(key *Key, input []byte) ([]byte, error) {
+	switch key.Algorithm {
+	case HS256:
+		mac := hmac.New(sha256.New, key.secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case RS256:
+		digest := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(rand.Reader, key.private.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+	case EdDSA:
+		return ed25519.Sign(key.private.(ed25519.PrivateKey), input), nil
+	}
+	return nil, fmt.Errorf("unsupported algorithm %q", key.Algorithm)
+}
+
+func verify(key *Key, input, signature []byte) bool {
+	switch key.Algorithm {
+	case HS256:
+		mac := hmac.New(sha256.New, key.secret)
+		mac.Write(input)
+		return hmac.Equal(signature, mac.Sum(nil))
+	case RS256:
+		digest := sha256.Sum256(input)
+		return rsa.VerifyPKCS1v15(key.public.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	case EdDSA:
+		return ed25519.Verify(key.public.(ed25519.PublicKey), input, signature)
+	}
+	return false
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeJSON(part string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(t *testing.T, kid string, private bool) map[string]string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwk := map[string]string{"kty": "RSA", "kid": kid, "n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())}
+	if private {
+		jwk["d"], jwk["p"], jwk["q"] = b64(key.D.Bytes()), b64(key.Primes[0].Bytes()), b64(key.Primes[1].Bytes())
+	}
+	return jwk
+}
+
+func ed25519JWK(t *testing.T, kid string) map[string]string {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return map[string]string{"kty": "OKP", "crv": "Ed25519", "kid": kid, "x": b64(public), "d": b64(private.Seed())}
+}
+
+func keySet(t *testing.T, keys ...map[string]string) *KeySet {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	require.NoError(t, err)
+	set, err := ParseKeySet(data)
+	require.NoError(t, err)
+	return set
+}
+
+func TestAuthority_IssueAndVerify(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	hmacKey := map[string]string{"kty": "oct", "kid": "h1", "k": b64([]byte(strings.Repeat("s", 32)))}
+
+	tests := []struct {
+		name string
+		keys *KeySet
+		alg  string
+	}{
+		{name: "HS256", keys: keySet(t, hmacKey), alg: HS256},
+		{name: "RS256", keys: keySet(t, rsaJWK(t, "r1", true)), alg: RS256},
+		{name: "EdDSA", keys: keySet(t, ed25519JWK(t, "e1")), alg: EdDSA},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authority := NewAuthority(tt.keys, "shortener", "api")
+			token, err := authority.Issue(Claims{Subject: "alice", Scope: "links:read links:write"}, 15*time.Minute, now)
+			require.NoError(t, err)
+
+			var h header
+			require.NoError(t, decodeJSON(strings.Split(token, ".")[0], &h))
+			assert.Equal(t, tt.alg, h.Alg)
+
+			claims, err := authority.Verify(token, now.Add(time.Minute))
+			require.NoError(t, err)
+			assert.Equal(t, "alice", claims.Subject)
+			assert.Equal(t, []string{"links:read", "links:write"}, claims.Scopes())
+			assert.Equal(t, Audience{"api"}, claims.Audience)
+
+			// Подпись перестаёт сходиться при изменении утверждений.
+			parts := strings.Split(token, ".")
+			forged, _ := json.Marshal(Claims{Subject: "mallory", ExpiresAt: now.Add(time.Hour).Unix()})
+			_, err = authority.Verify(parts[0]+"."+b64(forged)+"."+parts[2], now)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestAuthority_Validation(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	keys := keySet(t, map[string]string{"kty": "oct", "kid": "h1", "k": b64([]byte(strings.Repeat("s", 32)))})
+	authority := NewAuthority(keys, "shortener", "api")
+	token, err := authority.Issue(Claims{Subject: "alice"}, time.Minute, now)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		authority *Authority
+		at        time.Time
+		err       error
+	}{
+		{name: "within clock skew after expiry", authority: authority, at: now.Add(time.Minute + 30*time.Second)},
+		{name: "expired", authority: authority, at: now.Add(2 * time.Minute), err: ErrExpired},
+		{name: "within clock skew before nbf", authority: authority, at: now.Add(-30 * time.Second)},
+		{name: "not valid yet", authority: authority, at: now.Add(-2 * time.Minute), err: ErrNotYetValid},
+		{name: "wrong issuer", authority: NewAuthority(keys, "gateway", "api"), at: now, err: ErrInvalidToken},
+		{name: "wrong audience", authority: NewAuthority(keys, "shortener", "admin"), at: now, err: ErrInvalidToken},
+		{name: "audience not checked", authority: NewAuthority(keys, "shortener", ""), at: now},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.authority.Verify(token, tt.at)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAuthority_Rotation(t *testing.T) {
+	now := time.Now()
+	oldKey := rsaJWK(t, "old", true)
+	newKey := ed25519JWK(t, "new")
+
+	oldToken, err := NewAuthority(keySet(t, oldKey), "", "").Issue(Claims{Subject: "alice"}, time.Hour, now)
+	require.NoError(t, err)
+
+	// После ротации новые токены подписываются новым ключом, а старые остаются действительными.
+	rotated := NewAuthority(keySet(t, newKey, oldKey), "", "")
+	newToken, err := rotated.Issue(Claims{Subject: "bob"}, time.Hour, now)
+	require.NoError(t, err)
+	var h header
+	require.NoError(t, decodeJSON(strings.Split(newToken, ".")[0], &h))
+	assert.Equal(t, "new", h.Kid)
+
+	_, err = rotated.Verify(oldToken, now)
+	assert.NoError(t, err)
+	_, err = rotated.Verify(newToken, now)
+	assert.NoError(t, err)
+
+	// Ключ, удалённый из набора, больше не принимается.
+	_, err = NewAuthority(keySet(t, newKey), "", "").Verify(oldToken, now)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Набор только из открытых ключей проверяет токены, но не выпускает их.
+	public := rsaJWK(t, "public", false)
+	_, err = NewAuthority(keySet(t, public), "", "").Issue(Claims{Subject: "alice"}, time.Hour, now)
+	assert.Error(t, err)
+}
+
+func TestAuthority_AlgorithmConfusion(t *testing.T) {
+	now := time.Now()
+	rsaKey := rsaJWK(t, "r1", false)
+	authority := NewAuthority(keySet(t, rsaKey), "", "")
+
+	// Токен HS256, подписанный открытым ключом RSA как секретом, отклоняется.
+	n, _ := base64.RawURLEncoding.DecodeString(rsaKey["n"])
+	forger := NewAuthority(NewHMACKeySet("r1", n), "", "")
+	token, err := forger.Issue(Claims{Subject: "mallory"}, time.Hour, now)
+	require.NoError(t, err)
+	_, err = authority.Verify(token, now)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	unsigned := b64([]byte(`{"alg":"none"}`)) + "." + b64([]byte(fmt.Sprintf(`{"sub":"mallory","exp":%d}`, now.Add(time.Hour).Unix()))) + "."
+	_, err = authority.Verify(unsigned, now)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestParseKeySet_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "invalid json", data: `{`},
+		{name: "empty", data: `{"keys":[]}`},
+		{name: "missing kid", data: `{"keys":[{"kty":"oct","k":"` + b64([]byte(strings.Repeat("s", 32))) + `"}]}`},
+		{name: "short secret", data: `{"keys":[{"kty":"oct","kid":"h","k":"c2hvcnQ"}]}`},
+		{name: "unsupported type", data: `{"keys":[{"kty":"EC","kid":"e"}]}`},
+		{name: "algorithm mismatch", data: `{"keys":[{"kty":"oct","kid":"h","alg":"RS256","k":"` + b64([]byte(strings.Repeat("s", 32))) + `"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseKeySet([]byte(tt.data))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// Поддерживаемые алгоритмы подписи.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// Key — ключ подписи или проверки токенов.
+type Key struct {
+	ID        string
+	Algorithm string
+	secret    []byte            // Общий секрет HS256
+	public    crypto.PublicKey  // Открытый ключ RS256 и EdDSA
+	private   crypto.PrivateKey // Закрытый ключ RS256 и EdDSA, если им можно подписывать
+}
+
+// CanSign сообщает, можно ли подписывать токены этим ключом.
+func (k *Key) CanSign() bool {
+	return k.secret != nil || k.private != nil
+}
+
+// KeySet хранит набор действующих ключей. Токены подписываются первым ключом, у которого
+// есть закрытая часть, а проверяются любым ключом набора. Для ротации новый ключ добавляется
+// в начало набора, а старый остаётся в нём до истечения выданных им токенов.
+type KeySet struct {
+	keys []*Key
+}
+
+// NewHMACKeySet создаёт набор из одного ключа HS256.
+func NewHMACKeySet(id string, secret []byte) *KeySet {
+	return &KeySet{keys: []*Key{{ID: id, Algorithm: HS256, secret: secret}}}
+}
+
+// ConfiguredKeySet возвращает набор ключей из файла JWKS path, а без файла — ключ HS256,
+// производный от секрета secret. При ошибке чтения файла вместе с ней возвращается производный ключ.
+func ConfiguredKeySet(path, secret string) (*KeySet, error) {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("jwt"))
+	derived := NewHMACKeySet("default", mac.Sum(nil))
+	if path == "" {
+		return derived, nil
+	}
+	keys, err := LoadKeySet(path)
+	if err != nil {
+		return derived, err
+	}
+	return keys, nil
+}
+
+// jwk описывает ключ в формате JSON Web Key (RFC 7517). Поддерживаются ключи
+// oct (HS256), RSA (RS256) и OKP с кривой Ed25519 (EdDSA).
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	D   string `json:"d"`
+	P   string `json:"p"`
+	Q   string `json:"q"`
+	X   string `json:"x"`
+}
+
+// LoadKeySet читает набор ключей из файла в формате JWKS: {"keys": [...]}.
+func LoadKeySet(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeySet(data)
+}
+
+// ParseKeySet разбирает набор ключей в формате JWKS. Каждый ключ должен иметь kid.
+func ParseKeySet(data []byte) (*KeySet, error) {
+	return parseKeySet(data, false)
+}
+
+// ParseProviderKeySet разбирает открытые ключи внешнего провайдера, пропуская ключи
+// неподдерживаемых типов. Ключи без kid допускаются.
+func ParseProviderKeySet(data []byte) (*KeySet, error) {
+	return parseKeySet(data, true)
+}
+
+func parseKeySet(data []byte, lenient bool) (*KeySet, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid key set: %w", err)
+	}
+
+	set := &KeySet{}
+	seen := make(map[string]bool)
+	for i, raw := range doc.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+		if lenient && raw.Kty == "oct" {
+			// Общий секрет не может быть опубликован провайдером.
+			continue
+		}
+		if lenient && raw.Kid == "" {
+			raw.Kid = fmt.Sprintf("#%d", i)
+		}
+		key, err := parseKey(raw)
+		if err != nil {
+			if lenient {
+				continue
+			}
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+		if lenient {
+			// Закрытые части ключей провайдера не используются.
+			key.private = nil
+		}
+		if seen[key.ID] {
+			return nil, fmt.Errorf("key %d: duplicate kid %q", i, key.ID)
+		}
+		seen[key.ID] = true
+		set.keys = append(set.keys, key)
+	}
+	if len(set.keys) == 0 {
+		return nil, errors.New("key set has no supported keys")
+	}
+	return set, nil
+}
+
+func parseKey(raw jwk) (*Key, error) {
+	if raw.Kid == "" {
+		return nil, errors.New("kid is required")
+	}
+	key := &Key{ID: raw.Kid}
+
+	switch raw.Kty {
+	case "oct":
+		secret, err := decodeField("k", raw.K)
+		if err != nil {
+			return nil, err
+		}
+		if len(secret) < 32 {
+			return nil, errors.New("HS256 secret must be at least 32 bytes")
+		}
+		key.Algorithm, key.secret = HS256, secret
+	case "RSA":
+		n, err := decodeInt("n", raw.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt("e", raw.E)
+		if err != nil {
+			return nil, err
+		}
+		public := &rsa.PublicKey{N: n, E: int(e.Int64())}
+		if public.N.BitLen() < 2048 {
+			return nil, errors.New("RSA key must be at least 2048 bits")
+		}
+		key.Algorithm, key.public = RS256, public
+		if raw.D != "" {
+			private, err := rsaPrivateKey(public, raw)
+			if err != nil {
+				return nil, err
+			}
+			key.private = private
+		}
+	case "OKP":
+		if raw.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", raw.Crv)
+		}
+		x, err := decodeField("x", raw.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+		key.Algorithm, key.public = EdDSA, ed25519.PublicKey(x)
+		if raw.D != "" {
+			seed, err := decodeField("d", raw.D)
+			if err != nil {
+				return nil, err
+			}
+			if len(seed) != ed25519.SeedSize {
+				return nil, errors.New("invalid Ed25519 private key")
+			}
+			private := ed25519.NewKeyFromSeed(seed)
+			if !private.Public().(ed25519.PublicKey).Equal(key.public) {
+				return nil, errors.New("Ed25519 private key does not match public key")
+			}
+			key.private = private
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", raw.Kty)
+	}
+
+	if raw.Alg != "" && raw.Alg != key.Algorithm {
+		return nil, fmt.Errorf("algorithm %q does not match key type %q", raw.Alg, raw.Kty)
+	}
+	return key, nil
+}
+
+func rsaPrivateKey(public *rsa.PublicKey, raw jwk) (*rsa.PrivateKey, error) {
+	d, err := decodeInt("d", raw.D)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decodeInt("p", raw.P)
+	if err != nil {
+		return nil, err
+	}
+	q, err := decodeInt("q", raw.Q)
+	if err != nil {
+		return nil, err
+	}
+	private := &rsa.PrivateKey{PublicKey: *public, D: d, Primes: []*big.Int{p, q}}
+	if err := private.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid RSA private key: %w", err)
+	}
+	private.Precompute()
+	return private, nil
+}
+
+func decodeField(name, value string) ([]byte, error) {
+	if value == "" {
+		return nil, fmt.Errorf("%s is required", name)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return b, nil
+}
+
+func decodeInt(name, value string) (*big.Int, error) {
+	b, err := decodeField(name, value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// signingKey возвращает ключ для подписи токенов.
+func (s *KeySet) signingKey() (*Key, bool) {
+	for _, key := range s.keys {
+		if key.CanSign() {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// verificationKeys возвращает ключи, которыми можно проверить токен с заголовком kid и alg.
+func (s *KeySet) verificationKeys(kid, alg string) []*Key {
+	var keys []*Key
+	for _, key := range s.keys {
+		if key.Algorithm == alg && (kid == "" || key.ID == kid) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
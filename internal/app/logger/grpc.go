@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"context"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor записывает в журнал сведения о вызовах gRPC: метод, код ответа
+// и длительность, так же как Middleware для HTTP-запросов.
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, err, time.Since(start), 1)
+	return resp, err
+}
+
+// StreamServerInterceptor записывает в журнал сведения о потоковых вызовах gRPC,
+// дополнительно указывая количество принятых сообщений.
+func StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	stream := &serverStream{ServerStream: ss}
+	err := handler(srv, stream)
+	logCall(ss.Context(), info.FullMethod, err, time.Since(start), stream.received)
+	return err
+}
+
+func logCall(ctx context.Context, method string, err error, duration time.Duration, received int) {
+	sugarLogger.Infow("gRPC request",
+		"method", method,
+		"code", status.Code(err).String(),
+		"messages", received,
+		"duration", duration,
+		"request_id", middleware.GetRequestID(ctx),
+	)
+}
+
+// serverStream подсчитывает сообщения, принятые от клиента.
+type serverStream struct {
+	grpc.ServerStream
+	received int
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+	}
+	return err
+}
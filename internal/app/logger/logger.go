@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
 	"go.uber.org/zap"
 )
 
@@ -29,6 +30,7 @@ func Middleware(next http.Handler) http.Handler {
 			"status", ww.status,
 			"size", ww.size,
 			"duration", duration,
+			"request_id", middleware.GetRequestID(r.Context()),
 		)
 	})
 }
@@ -49,3 +51,8 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	rw.size += size
 	return size, err
 }
+
+// Unwrap позволяет http.ResponseController добраться до Flush и EnableFullDuplex исходного ответа.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
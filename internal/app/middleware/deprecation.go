@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Deprecation помечает ответы устаревшей версии API заголовками Deprecation (RFC 9745)
+// с датой, с которой версия считается устаревшей, и Sunset (RFC 8594) с датой её отключения.
+// Заголовок Link ведёт на описание перехода на новую версию link.
+func Deprecation(deprecatedAt, sunset time.Time, link string) func(http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+	linkHeader := "<" + link + `>; rel="deprecation"; type="text/html"`
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			w.Header().Set("Sunset", sunsetDate)
+			w.Header().Add("Link", linkHeader)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
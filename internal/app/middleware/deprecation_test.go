@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecation(t *testing.T) {
+	deprecatedAt := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, time.October, 19, 0, 0, 0, 0, time.UTC)
+	handler := Deprecation(deprecatedAt, sunset, "/api/docs#versioning")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/shorten", nil))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "@1792368000", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Tue, 19 Oct 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, `</api/docs#versioning>; rel="deprecation"; type="text/html"`, rec.Header().Get("Link"))
+}
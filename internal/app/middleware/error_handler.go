@@ -2,21 +2,34 @@ package middleware
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
+	"log"
 	"net/http"
 
-	"github.com/alexuryumtsev/go-shortener/internal/app/models"
-	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
 )
 
+// ProblemContentType — тип содержимого описания проблемы (RFC 7807).
+const ProblemContentType = "application/problem+json"
+
+// Problem описывает ошибку в ответе API в формате RFC 7807.
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+	Errors    []apperr.FieldError `json:"errors,omitempty"`
+}
+
 // ErrorMiddleware — middleware для обработки ошибок.
 func ErrorMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			// Поймаем панику, если она возникла
 			if rec := recover(); rec != nil {
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				WriteError(w, r, fmt.Errorf("panic: %v", rec))
 			}
 		}()
 
@@ -25,25 +38,43 @@ func ErrorMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// ProcessError — функция для обработки ошибок в контексте работы с БД.
-func ProcessError(w http.ResponseWriter, err error, shortenedURL string, responseString bool) {
-	var pgErr *pgconn.PgError
-	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusConflict)
+// NewProblem описывает ошибку err, возникшую при обработке запроса r.
+func NewProblem(r *http.Request, err error) Problem {
+	kind := apperr.KindOf(err)
+	return Problem{
+		Type:      kind.Type(),
+		Title:     kind.Title(),
+		Status:    kind.Status(),
+		Detail:    apperr.Message(err),
+		Instance:  r.URL.Path,
+		RequestID: GetRequestID(r.Context()),
+		Errors:    apperr.Fields(err),
+	}
+}
 
-		if responseString {
-			w.Write([]byte(shortenedURL))
-			return
-		}
+// WriteError записывает ошибку в ответ в формате application/problem+json.
+// HTTP-статус определяется видом ошибки, ошибки сервера записываются в лог
+// и не раскрываются клиенту.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	logError(r, err)
+	problem := NewProblem(r, err)
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		log.Printf("Error encoding problem: %v", err)
+	}
+}
 
-		json.NewEncoder(w).Encode(models.ResponseBody{
-			ShortURL: shortenedURL,
-		})
+// WriteTextError записывает ошибку в ответ простым текстом для эндпоинтов,
+// клиенты которых не разбирают JSON. Статус определяется так же, как в WriteError.
+func WriteTextError(w http.ResponseWriter, r *http.Request, err error) {
+	logError(r, err)
+	http.Error(w, apperr.Message(err), apperr.KindOf(err).Status())
+}
 
-		return
+func logError(r *http.Request, err error) {
+	if apperr.KindOf(err).ServerError() {
+		log.Printf("Error processing request %s %s (request_id=%s): %v", r.Method, r.URL.Path, GetRequestID(r.Context()), err)
 	}
-
-	// Обработка других ошибок
-	http.Error(w, err.Error(), http.StatusBadRequest)
 }
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Problem
+	}{
+		{
+			name: "not found",
+			err:  apperr.New(apperr.NotFound, "URL not found"),
+			want: Problem{
+				Type:      "/api/docs#problem-not-found",
+				Title:     "Not found",
+				Status:    http.StatusNotFound,
+				Detail:    "URL not found",
+				Instance:  "/api/urls/abc",
+				RequestID: "req-1",
+			},
+		},
+		{
+			name: "validation",
+			err:  apperr.Validation("Request body does not match the schema", []apperr.FieldError{{Field: "url", Message: "is required"}}),
+			want: Problem{
+				Type:      "/api/docs#problem-invalid-input",
+				Title:     "Invalid input",
+				Status:    http.StatusBadRequest,
+				Detail:    "Request body does not match the schema",
+				Instance:  "/api/urls/abc",
+				RequestID: "req-1",
+				Errors:    []apperr.FieldError{{Field: "url", Message: "is required"}},
+			},
+		},
+		{
+			name: "internal error is not disclosed",
+			err:  errors.New("connection refused"),
+			want: Problem{
+				Type:      "/api/docs#problem-internal",
+				Title:     "Internal server error",
+				Status:    http.StatusInternalServerError,
+				Detail:    "Internal server error",
+				Instance:  "/api/urls/abc",
+				RequestID: "req-1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/urls/abc", nil)
+			req = req.WithContext(WithRequestID(req.Context(), "req-1"))
+			rec := httptest.NewRecorder()
+			WriteError(rec, req, tt.err)
+
+			assert.Equal(t, tt.want.Status, rec.Code)
+			assert.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+			var got Problem
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWriteTextError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteTextError(rec, httptest.NewRequest(http.MethodPost, "/", nil), apperr.New(apperr.InvalidInput, "empty URL"))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "empty URL\n", rec.Body.String())
+}
+
+func TestErrorMiddleware(t *testing.T) {
+	handler := ErrorMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/user/urls", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+	assert.NotContains(t, rec.Body.String(), "boom")
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+)
+
+// RequestIDHeader — заголовок с идентификатором запроса.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern ограничивает идентификаторы, принимаемые от клиента или балансировщика.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type requestIDKey struct{}
+
+// RequestID присваивает запросу идентификатор из заголовка X-Request-ID или генерирует новый,
+// сохраняет его в контексте и возвращает клиенту в том же заголовке.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := ResolveRequestID(r.Header.Get(RequestIDHeader))
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+	})
+}
+
+// ResolveRequestID возвращает переданный клиентом идентификатор запроса, если он допустим,
+// а иначе — новый идентификатор.
+func ResolveRequestID(id string) string {
+	if requestIDPattern.MatchString(id) {
+		return id
+	}
+	return newRequestID()
+}
+
+// WithRequestID возвращает контекст с идентификатором запроса.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// GetRequestID возвращает идентификатор запроса из контекста или пустую строку.
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+)
+
+// TrustedSubnet пропускает только запросы, реальный IP-адрес которых из заголовка X-Real-IP
+// входит в доверенную подсеть subnet. Если подсеть не задана, доступ запрещён всем.
+func TrustedSubnet(subnet *net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP")))
+			if subnet == nil || ip == nil || !subnet.Contains(ip) {
+				WriteError(w, r, apperr.New(apperr.Forbidden, "Forbidden"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
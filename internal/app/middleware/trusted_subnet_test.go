@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedSubnet(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		subnet   *net.IPNet
+		realIP   string
+		wantCode int
+	}{
+		{name: "inside subnet", subnet: subnet, realIP: "10.1.2.3", wantCode: http.StatusOK},
+		{name: "outside subnet", subnet: subnet, realIP: "192.168.1.1", wantCode: http.StatusForbidden},
+		{name: "no header", subnet: subnet, wantCode: http.StatusForbidden},
+		{name: "invalid header", subnet: subnet, realIP: "10.1.2.3, 1.2.3.4", wantCode: http.StatusForbidden},
+		{name: "subnet not configured", realIP: "10.1.2.3", wantCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := TrustedSubnet(tt.subnet)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil)
+			// Адрес соединения не учитывается, только заголовок X-Real-IP.
+			req.RemoteAddr = "10.0.0.1:1234"
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+}
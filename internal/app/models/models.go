@@ -1,26 +1,575 @@
 package models
 
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
 // URLMapping структура для хранения URL и его сокращённого идентификатора.
 type URLModel struct {
-	ID  string
-	URL string
+	ID            string
+	URL           string
+	CreatedAt     time.Time        // Дата создания ссылки
+	Clicks        int64            // Количество переходов по ссылке
+	Interstitial  bool             // Всегда показывать страницу предпросмотра вместо редиректа
+	PasswordHash  string           // Хеш пароля для защищённых ссылок
+	MaxClicks     int64            // Допустимое количество переходов, 0 — без ограничений
+	Rules         []Rule           // Правила выбора адреса назначения, URL используется по умолчанию
+	Variants      []Variant        // Варианты A/B-теста, заменяющие URL, если ни одно правило не подошло
+	VariantClicks map[string]int64 // Переходы по вариантам (для хранилищ без журнала переходов)
+	Params        *ParamTemplate   // Шаблон параметров, добавляемых к адресу назначения
+	UserID        string           // Владелец ссылки
+	Title         string           // Название ссылки
+	RedirectCode  int              // HTTP-код редиректа, 0 — 307 Temporary Redirect
+	ExpiresAt     *time.Time       // Срок действия ссылки
+	Notes         string           // Произвольные заметки владельца
+	Tags          []string         // Теги в нижнем регистре, отсортированные по алфавиту
+	Folder        string           // Папка (коллекция) ссылки
+	Meta          *PageMeta        // Метаданные страницы назначения
+	WorkspaceID   string           // Рабочее пространство, владеющее ссылкой; пусто для личных ссылок
+	Deleted       bool             // Ссылка удалена
+	Disabled      bool             // Ссылка отключена администратором и не открывается
+}
+
+// Роли участников рабочего пространства.
+const (
+	RoleOwner  = "owner"  // Управляет участниками и всеми ссылками пространства
+	RoleEditor = "editor" // Создаёт, изменяет и удаляет ссылки
+	RoleViewer = "viewer" // Просматривает ссылки и их статистику
+)
+
+// Workspace описывает рабочее пространство команды, которому принадлежат ссылки.
+type Workspace struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Member описывает участника рабочего пространства.
+type Member struct {
+	WorkspaceID string    `json:"workspace_id"`
+	UserID      string    `json:"user_id"`
+	Role        string    `json:"role"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+// Membership описывает рабочее пространство с ролью пользователя в нём.
+type Membership struct {
+	Workspace
+	Role string `json:"role"`
+}
+
+// Invitation описывает приглашение в рабочее пространство.
+// Токен приглашения хранится только в виде хеша и возвращается один раз при создании.
+type Invitation struct {
+	ID          string    `json:"id"`
+	WorkspaceID string    `json:"workspace_id"`
+	Role        string    `json:"role"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Token       string    `json:"token,omitempty"`
+}
+
+// Области действия API-ключей.
+const (
+	ScopeLinksRead  = "links:read"  // Просмотр списка ссылок и истории изменений
+	ScopeLinksWrite = "links:write" // Создание, изменение и удаление ссылок
+	ScopeStatsRead  = "stats:read"  // Просмотр статистики переходов
+)
+
+// APIKey описывает ключ доступа к API для машинных клиентов.
+// Ключ хранится только в виде хеша и возвращается один раз при создании,
+// по префиксу ключ можно опознать в списке.
+type APIKey struct {
+	ID          string     `json:"id"`
+	Prefix      string     `json:"prefix"`
+	Name        string     `json:"name"`
+	UserID      string     `json:"user_id"`
+	WorkspaceID string     `json:"workspace_id,omitempty"` // Пространство, в котором действует ключ; пусто для личных ссылок
+	Scopes      []string   `json:"scopes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	Key         string     `json:"key,omitempty"`
+}
+
+// PageMeta содержит метаданные страницы назначения, полученные фоновым обработчиком.
+type PageMeta struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Image       string    `json:"image,omitempty"`
+	Favicon     string    `json:"favicon,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// URLSettings содержит поля ссылки, которые владелец может изменять после создания.
+type URLSettings struct {
+	URL          string         `json:"url"`
+	ExpiresAt    *time.Time     `json:"expires_at,omitempty"`
+	RedirectCode int            `json:"redirect_code,omitempty"`
+	Title        string         `json:"title,omitempty"`
+	Rules        []Rule         `json:"rules,omitempty"`
+	Variants     []Variant      `json:"variants,omitempty"`
+	Params       *ParamTemplate `json:"params,omitempty"`
+	Notes        string         `json:"notes,omitempty"`
+	Tags         []string       `json:"tags,omitempty"`
+	Folder       string         `json:"folder,omitempty"`
+}
+
+// URLVersion описывает изменение ссылки в истории версий.
+type URLVersion struct {
+	Version   int         `json:"version"`
+	ChangedBy string      `json:"changed_by"`
+	ChangedAt time.Time   `json:"changed_at"`
+	Fields    []string    `json:"fields"` // Изменённые поля в терминах JSON
+	Before    URLSettings `json:"before"`
+	After     URLSettings `json:"after"`
+}
+
+// LinkResponse описывает ссылку в ответах API управления ссылками.
+type LinkResponse struct {
+	ID        string    `json:"id"`
+	ShortURL  string    `json:"short_url"`
+	CreatedAt time.Time `json:"created_at"`
+	Clicks    int64     `json:"clicks"`
+	Meta      *PageMeta `json:"meta,omitempty"`
+	Workspace string    `json:"workspace_id,omitempty"`
+	URLSettings
+}
+
+// LinkPage описывает страницу списка ссылок пользователя.
+type LinkPage struct {
+	Items      []LinkResponse `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"` // Пустой, если страница последняя
+}
+
+// Link описывает ссылку как ресурс API v2: адрес назначения, настройки редиректа,
+// метаданные и адреса связанных ресурсов.
+type Link struct {
+	ID        string       `json:"id"`
+	ShortURL  string       `json:"short_url"`
+	URL       string       `json:"url"`
+	Title     string       `json:"title,omitempty"`
+	Notes     string       `json:"notes,omitempty"`
+	Tags      []string     `json:"tags"`
+	Folder    string       `json:"folder,omitempty"`
+	Workspace string       `json:"workspace_id,omitempty"`
+	Redirect  LinkRedirect `json:"redirect"`
+	Metadata  LinkMetadata `json:"metadata"`
+	Links     LinkURLs     `json:"links"`
+}
+
+// LinkRedirect описывает настройки редиректа ссылки в API v2.
+type LinkRedirect struct {
+	Code              int            `json:"code"` // HTTP-код редиректа с учётом значения по умолчанию
+	ExpiresAt         *time.Time     `json:"expires_at,omitempty"`
+	MaxClicks         int64          `json:"max_clicks,omitempty"`
+	PasswordProtected bool           `json:"password_protected"`
+	Interstitial      bool           `json:"interstitial"`
+	Rules             []Rule         `json:"rules,omitempty"`
+	Variants          []Variant      `json:"variants,omitempty"`
+	Params            *ParamTemplate `json:"params,omitempty"`
+}
+
+// LinkMetadata содержит сведения о ссылке, которые не задаются пользователем.
+type LinkMetadata struct {
+	CreatedAt time.Time `json:"created_at"`
+	Clicks    int64     `json:"clicks"`
+	Page      *PageMeta `json:"page,omitempty"` // Метаданные страницы назначения
+}
+
+// LinkURLs содержит адреса ресурсов API v2, связанных со ссылкой.
+type LinkURLs struct {
+	Self    string `json:"self"`
+	Stats   string `json:"stats"`
+	History string `json:"history"`
+	QR      string `json:"qr"`
+}
+
+// Pagination описывает постраничную навигацию в ответах API v2.
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"` // Пустой, если страница последняя
+	Next       string `json:"next,omitempty"`        // Адрес следующей страницы
+}
+
+// LinkList — страница ссылок в API v2.
+type LinkList struct {
+	Data       []Link     `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// VersionList — история изменений ссылки в API v2.
+type VersionList struct {
+	Data []URLVersion `json:"data"`
+}
+
+// ServiceStats содержит общую статистику сервиса.
+type ServiceStats struct {
+	URLs  int `json:"urls"`  // Количество неудалённых ссылок
+	Users int `json:"users"` // Количество пользователей, владеющих неудалёнными ссылками
+}
+
+// AdminLink описывает ссылку в ответах API администратора.
+type AdminLink struct {
+	LinkResponse
+	UserID   string `json:"user_id"`
+	Disabled bool   `json:"disabled"`
+	Deleted  bool   `json:"deleted"`
+}
+
+// AdminLinkPage описывает страницу результатов поиска ссылок администратором.
+type AdminLinkPage struct {
+	Items      []AdminLink `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// URLFilter описывает условия поиска ссылок пользователя.
+// Пустые условия не ограничивают выборку.
+type URLFilter struct {
+	UserID      string // Владелец личных ссылок, учитывается, если WorkspaceID пуст
+	WorkspaceID string
+	All         bool // Искать среди ссылок всех владельцев, если UserID и WorkspaceID пусты; только для администраторов
+	Tag         string
+	Folder      string
+	Query       string  // Слова, которые должны встречаться в названии, заметках, адресе или тегах
+	After       *Cursor // Позиция, после которой начинается страница
+	Limit       int
+}
+
+// Cursor задаёт позицию в списке ссылок, отсортированном от новых к старым.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// Before проверяет, что курсор предшествует ссылке m, то есть m старше позиции курсора.
+func (c Cursor) Before(m URLModel) bool {
+	return c.precedes(m.CreatedAt, m.ID)
+}
+
+// BeforeEvent проверяет, что курсор предшествует событию аудита e, то есть e старше позиции курсора.
+func (c Cursor) BeforeEvent(e AuditEvent) bool {
+	return c.precedes(e.Time, e.ID)
 }
+
+func (c Cursor) precedes(t time.Time, id string) bool {
+	if t.Equal(c.CreatedAt) {
+		return id < c.ID
+	}
+	return t.Before(c.CreatedAt)
+}
+
+// AuditEvent описывает запись журнала аудита об изменении данных, действии администратора или событии входа.
+// Before и After содержат состояние объекта до и после изменения, а для событий без объекта
+// After содержит параметры события.
+type AuditEvent struct {
+	ID          string          `json:"id"`
+	Time        time.Time       `json:"time"`
+	Action      string          `json:"action"` // Действие, например link.create или auth.login
+	Actor       string          `json:"actor"`  // Пользователь, выполнивший действие
+	APIKeyID    string          `json:"api_key_id,omitempty"`
+	WorkspaceID string          `json:"workspace_id,omitempty"`
+	Target      string          `json:"target,omitempty"` // Идентификатор изменённого объекта
+	RequestID   string          `json:"request_id,omitempty"`
+	ClientIP    string          `json:"client_ip,omitempty"`
+	Before      json.RawMessage `json:"before,omitempty"`
+	After       json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditFilter описывает условия выборки событий журнала аудита.
+// Пустые условия не ограничивают выборку.
+type AuditFilter struct {
+	Actor       string
+	Action      string // Действие или группа действий: link выбирает link.create, link.update и т. д.
+	Target      string
+	WorkspaceID string
+	Since       time.Time // Начало интервала, включительно
+	Until       time.Time // Конец интервала, не включительно
+	After       *Cursor   // Позиция, после которой начинается страница
+	Limit       int       // 0 — без ограничений
+}
+
+// Match проверяет, что событие e удовлетворяет условиям фильтра, кроме позиции и количества.
+func (f AuditFilter) Match(e AuditEvent) bool {
+	switch {
+	case f.Actor != "" && e.Actor != f.Actor:
+		return false
+	case f.Action != "" && e.Action != f.Action && !strings.HasPrefix(e.Action, f.Action+"."):
+		return false
+	case f.Target != "" && e.Target != f.Target:
+		return false
+	case f.WorkspaceID != "" && e.WorkspaceID != f.WorkspaceID:
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// AuditPage описывает страницу журнала аудита.
+type AuditPage struct {
+	Items      []AuditEvent `json:"items"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// Режимы разрешения конфликтов параметров запроса.
+const (
+	ParamsMerge    = "merge"    // Добавлять значения к уже существующим
+	ParamsOverride = "override" // Заменять существующие значения
+)
+
+// ParamTemplate описывает параметры, добавляемые к адресу назначения при редиректе.
+// Значения могут содержать плейсхолдеры {id}, {click_id}, {referrer_host} и {variant}.
+type ParamTemplate struct {
+	Params      map[string]string `json:"params,omitempty"`
+	PassThrough bool              `json:"pass_through,omitempty"` // Передавать параметры запроса короткой ссылки
+	Mode        string            `json:"mode,omitempty"`         // merge (по умолчанию) или override
+}
+
+// Variant описывает вариант адреса назначения с весом в процентах.
+type Variant struct {
+	Name   string `json:"name,omitempty"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// Click описывает событие перехода по короткой ссылке.
+type Click struct {
+	ID      string    // Идентификатор ссылки
+	ClickID string    // Уникальный идентификатор перехода
+	Variant string    // Выбранный вариант A/B-теста, если есть
+	Time    time.Time // Время перехода
+}
+
+// URLStats содержит статистику переходов по ссылке.
+type URLStats struct {
+	ID        string         `json:"id"`
+	URL       string         `json:"url"`
+	CreatedAt time.Time      `json:"created_at"`
+	Clicks    int64          `json:"clicks"`
+	MaxClicks int64          `json:"max_clicks,omitempty"`
+	Variants  []VariantStats `json:"variants,omitempty"`
+}
+
+// VariantStats содержит статистику переходов по варианту A/B-теста.
+type VariantStats struct {
+	Variant
+	Clicks int64 `json:"clicks"`
+}
+
+// Rule описывает условие, при выполнении которого посетитель направляется на URL.
+// Пустые условия не ограничивают выбор, правила проверяются по порядку.
+type Rule struct {
+	Devices   []string    `json:"devices,omitempty"`   // ios, android, desktop
+	Languages []string    `json:"languages,omitempty"` // Языковые теги, например en или pt-BR
+	Countries []string    `json:"countries,omitempty"` // Коды стран ISO 3166-1 alpha-2
+	Time      *TimeWindow `json:"time,omitempty"`
+	URL       string      `json:"url"`
+}
+
+// TimeWindow описывает интервал времени суток и/или диапазон дат.
+type TimeWindow struct {
+	From     string     `json:"from,omitempty"`     // Начало интервала времени суток, HH:MM
+	To       string     `json:"to,omitempty"`       // Конец интервала времени суток, HH:MM
+	Start    *time.Time `json:"start,omitempty"`    // Начало диапазона дат
+	End      *time.Time `json:"end,omitempty"`      // Конец диапазона дат
+	Timezone string     `json:"timezone,omitempty"` // Часовой пояс IANA, по умолчанию UTC
+}
+
+// ClicksExhausted проверяет, исчерпан ли лимит переходов по ссылке.
+func (m URLModel) ClicksExhausted() bool {
+	return m.MaxClicks > 0 && m.Clicks >= m.MaxClicks
+}
+
+// Custom проверяет, заданы ли у ссылки собственные параметры помимо адреса назначения.
+func (m URLModel) Custom() bool {
+	return m.Interstitial || m.PasswordHash != "" || m.MaxClicks > 0 ||
+		len(m.Rules) > 0 || len(m.Variants) > 0 || m.Params != nil ||
+		m.ExpiresAt != nil || m.RedirectCode != 0 ||
+		m.Title != "" || m.Notes != "" || len(m.Tags) > 0 || m.Folder != ""
+}
+
+// WithClick возвращает копию ссылки с учтённым переходом по варианту variant.
+// Карта VariantClicks копируется, чтобы не изменять данные, доступные другим читателям.
+func (m URLModel) WithClick(variant string) URLModel {
+	m.Clicks++
+	if variant != "" {
+		variantClicks := make(map[string]int64, len(m.VariantClicks)+1)
+		for name, clicks := range m.VariantClicks {
+			variantClicks[name] = clicks
+		}
+		variantClicks[variant]++
+		m.VariantClicks = variantClicks
+	}
+	return m
+}
+
+// Expired проверяет, истёк ли срок действия ссылки.
+func (m URLModel) Expired(now time.Time) bool {
+	return m.ExpiresAt != nil && !now.Before(*m.ExpiresAt)
+}
+
+// StatusCode возвращает HTTP-код редиректа ссылки.
+func (m URLModel) StatusCode() int {
+	if m.RedirectCode == 0 {
+		return http.StatusTemporaryRedirect
+	}
+	return m.RedirectCode
+}
+
+// Settings возвращает изменяемые поля ссылки.
+func (m URLModel) Settings() URLSettings {
+	return URLSettings{
+		URL:          m.URL,
+		ExpiresAt:    m.ExpiresAt,
+		RedirectCode: m.RedirectCode,
+		Title:        m.Title,
+		Rules:        m.Rules,
+		Variants:     m.Variants,
+		Params:       m.Params,
+		Notes:        m.Notes,
+		Tags:         m.Tags,
+		Folder:       m.Folder,
+	}
+}
+
+// WithSettings возвращает копию ссылки с применёнными изменяемыми полями.
+func (m URLModel) WithSettings(s URLSettings) URLModel {
+	m.URL = s.URL
+	m.ExpiresAt = s.ExpiresAt
+	m.RedirectCode = s.RedirectCode
+	m.Title = s.Title
+	m.Rules = s.Rules
+	m.Variants = s.Variants
+	m.Params = s.Params
+	m.Notes = s.Notes
+	m.Tags = s.Tags
+	m.Folder = s.Folder
+	return m
+}
+
+// Diff возвращает JSON-имена полей, отличающихся в other.
+func (s URLSettings) Diff(other URLSettings) []string {
+	fields := []string{}
+	if s.URL != other.URL {
+		fields = append(fields, "url")
+	}
+	if !equalTime(s.ExpiresAt, other.ExpiresAt) {
+		fields = append(fields, "expires_at")
+	}
+	if s.RedirectCode != other.RedirectCode {
+		fields = append(fields, "redirect_code")
+	}
+	if s.Title != other.Title {
+		fields = append(fields, "title")
+	}
+	if !reflect.DeepEqual(s.Rules, other.Rules) {
+		fields = append(fields, "rules")
+	}
+	if !reflect.DeepEqual(s.Variants, other.Variants) {
+		fields = append(fields, "variants")
+	}
+	if !reflect.DeepEqual(s.Params, other.Params) {
+		fields = append(fields, "params")
+	}
+	if s.Notes != other.Notes {
+		fields = append(fields, "notes")
+	}
+	if !reflect.DeepEqual(s.Tags, other.Tags) {
+		fields = append(fields, "tags")
+	}
+	if s.Folder != other.Folder {
+		fields = append(fields, "folder")
+	}
+	return fields
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
 type URLBatchModel struct {
 	CorrelationID string `json:"correlation_id"`
 	OriginalURL   string `json:"original_url"`
 }
 
+// BatchResponseModel — результат сокращения элемента пакета.
+// Status — BulkCreated для созданной ссылки или BulkConflict, если адрес уже был сокращён.
 type BatchResponseModel struct {
 	CorrelationID string `json:"correlation_id"`
 	ShortURL      string `json:"short_url"`
+	Status        string `json:"status"`
+}
+
+// IdempotencyRecord описывает запрос с заголовком Idempotency-Key и сохранённый ответ на него.
+// Пока первый запрос выполняется, Completed равен false и ответ не заполнен.
+// RequestHash — отпечаток метода, пути и тела запроса.
+type IdempotencyRecord struct {
+	UserID      string              `json:"user_id"`
+	Key         string              `json:"key"`
+	RequestHash string              `json:"request_hash"`
+	CreatedAt   time.Time           `json:"created_at"`
+	ExpiresAt   time.Time           `json:"expires_at"`
+	LockedUntil time.Time           `json:"locked_until"` // Срок, до которого незавершённый запрос удерживает ключ
+	Completed   bool                `json:"completed,omitempty"`
+	Status      int                 `json:"status,omitempty"`
+	Header      map[string][]string `json:"header,omitempty"`
+	Body        []byte              `json:"body,omitempty"`
+}
+
+// Статусы строк потока на сокращение ссылок.
+const (
+	BulkCreated  = "created"  // Ссылка создана
+	BulkConflict = "conflict" // Адрес уже сокращён
+	BulkError    = "error"    // Строку не удалось обработать
+)
+
+// BulkItem — строка потока на сокращение ссылок. Err содержит ошибку разбора строки.
+type BulkItem struct {
+	Line          int
+	CorrelationID string
+	URL           string
+	Err           error
+}
+
+// BulkResult — результат обработки строки потока на сокращение ссылок.
+// Line — номер строки тела запроса, начиная с 1.
+type BulkResult struct {
+	Line          int    `json:"line"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	ShortURL      string `json:"short_url,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
 }
 
 // RequestBody определяет структуру входных данных.
 type RequestBody struct {
-	URL string `json:"url"`
+	URL          string         `json:"url"`
+	Interstitial bool           `json:"interstitial,omitempty"`
+	Password     string         `json:"password,omitempty"`
+	MaxClicks    int64          `json:"max_clicks,omitempty"`
+	Rules        []Rule         `json:"rules,omitempty"`
+	Variants     []Variant      `json:"variants,omitempty"`
+	Params       *ParamTemplate `json:"params,omitempty"`
+	Title        string         `json:"title,omitempty"`
+	Notes        string         `json:"notes,omitempty"`
+	Tags         []string       `json:"tags,omitempty"`
+	Folder       string         `json:"folder,omitempty"`
 }
 
 // ResponseBody определяет структуру ответа.
+// Поле result читают клиенты API v1, поэтому новые поля добавляются только необязательными.
 type ResponseBody struct {
 	ShortURL string `json:"result"`
+	ID       string `json:"id,omitempty"` // Идентификатор ссылки в API v2
 }
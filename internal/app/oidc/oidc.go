@@ -0,0 +1,312 @@
+// Package oidc реализует вход через OpenID Connect по схеме authorization code с PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/jwt"
+)
+
+// Ограничения обмена с провайдером.
+const (
+	discoveryPath   = "/.well-known/openid-configuration"
+	maxResponseSize = 1 << 20
+	// keysRefreshInterval ограничивает частоту повторной загрузки ключей провайдера
+	// при встрече токена с неизвестной подписью.
+	keysRefreshInterval = time.Minute
+)
+
+// ErrInvalidIDToken возвращается, если ID-токен не прошёл проверку.
+var ErrInvalidIDToken = apperr.New(apperr.Unauthorized, "invalid id token")
+
+// Config задаёт параметры клиента OpenID Connect.
+type Config struct {
+	IssuerURL    string   // Адрес издателя или документа обнаружения .well-known/openid-configuration
+	ClientID     string   // Идентификатор клиента
+	ClientSecret string   // Секрет клиента, пустой для публичных клиентов
+	RedirectURL  string   // Адрес /auth/callback, зарегистрированный у провайдера
+	Scopes       []string // Запрашиваемые области, по умолчанию openid profile email
+}
+
+// metadata содержит используемые поля документа обнаружения провайдера.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// Identity описывает пользователя по проверенному ID-токену.
+type Identity struct {
+	Issuer  string
+	Subject string
+	Email   string
+	Name    string
+	Claims  map[string]any // Все утверждения ID-токена
+}
+
+// Values возвращает значения утверждения claim, которое может быть строкой или массивом строк.
+func (i Identity) Values(claim string) []string {
+	switch v := i.Claims[claim].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Provider — клиент провайдера OpenID Connect. Документ обнаружения загружается при первом
+// обращении, ключи подписи — при первом обращении и при появлении токена с неизвестной подписью.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+
+	mu            sync.Mutex
+	meta          *metadata
+	keys          *jwt.KeySet
+	keysFetchedAt time.Time
+}
+
+// NewProvider создаёт клиент провайдера. Если client равен nil, используется http.DefaultClient.
+func NewProvider(cfg Config, client *http.Client) *Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	return &Provider{cfg: cfg, client: client, now: time.Now}
+}
+
+// discover возвращает документ обнаружения провайдера.
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	issuer := strings.TrimSuffix(p.cfg.IssuerURL, "/")
+	discoveryURL := issuer + discoveryPath
+	if strings.HasSuffix(issuer, discoveryPath) {
+		discoveryURL, issuer = issuer, ""
+	}
+
+	var meta metadata
+	if err := p.getJSON(ctx, discoveryURL, &meta); err != nil {
+		return nil, fmt.Errorf("failed to load provider metadata: %w", err)
+	}
+	if meta.Issuer == "" || meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("provider metadata is incomplete")
+	}
+	// Издатель в документе должен совпадать с адресом, по которому документ получен (OpenID Discovery, 4.3).
+	if issuer != "" && meta.Issuer != issuer {
+		return nil, fmt.Errorf("provider issuer %q does not match %q", meta.Issuer, issuer)
+	}
+	p.meta = &meta
+	return p.meta, nil
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", p.cfg.RedirectURL)
+	query.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", Challenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Exchange обменивает код авторизации на ID-токен.
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&token); err != nil {
+		return "", fmt.Errorf("invalid token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return "", fmt.Errorf("token request failed: status %d: %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return token.IDToken, nil
+}
+
+// VerifyIDToken проверяет подпись, издателя, аудиторию, сроки действия и nonce ID-токена.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (Identity, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	var extra map[string]any
+	claims, err := p.verify(ctx, meta, rawIDToken, &extra, false)
+	if errors.Is(err, jwt.ErrInvalidToken) && p.keysStale() {
+		// Провайдер мог сменить ключи подписи.
+		extra = nil
+		claims, err = p.verify(ctx, meta, rawIDToken, &extra, true)
+	}
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+	if tokenNonce, _ := extra["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return Identity{}, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	identity := Identity{Issuer: claims.Issuer, Subject: claims.Subject, Claims: extra}
+	identity.Email, _ = extra["email"].(string)
+	identity.Name, _ = extra["name"].(string)
+	return identity, nil
+}
+
+// EndSessionURL возвращает адрес выхода у провайдера или пустую строку, если провайдер его не поддерживает.
+func (p *Provider) EndSessionURL(ctx context.Context, postLogoutRedirect string) string {
+	meta, err := p.discover(ctx)
+	if err != nil || meta.EndSessionEndpoint == "" {
+		return ""
+	}
+	u, err := url.Parse(meta.EndSessionEndpoint)
+	if err != nil {
+		return ""
+	}
+	query := u.Query()
+	query.Set("client_id", p.cfg.ClientID)
+	if postLogoutRedirect != "" {
+		query.Set("post_logout_redirect_uri", postLogoutRedirect)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func (p *Provider) verify(ctx context.Context, meta *metadata, rawIDToken string, extra *map[string]any, refresh bool) (jwt.Claims, error) {
+	keys, err := p.keySet(ctx, meta, refresh)
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+	return jwt.NewAuthority(keys, meta.Issuer, p.cfg.ClientID).VerifyInto(rawIDToken, p.now(), extra)
+}
+
+// keySet возвращает ключи подписи провайдера, загружая их при первом обращении или по требованию.
+func (p *Provider) keySet(ctx context.Context, meta *metadata, refresh bool) (*jwt.KeySet, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.keys != nil && !refresh {
+		return p.keys, nil
+	}
+
+	var raw json.RawMessage
+	if err := p.getJSON(ctx, meta.JWKSURI, &raw); err != nil {
+		return nil, fmt.Errorf("failed to load provider keys: %w", err)
+	}
+	keys, err := jwt.ParseProviderKeySet(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid provider keys: %w", err)
+	}
+	p.keys, p.keysFetchedAt = keys, p.now()
+	return keys, nil
+}
+
+func (p *Provider) keysStale() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.now().Sub(p.keysFetchedAt) >= keysRefreshInterval
+}
+
+func (p *Provider) getJSON(ctx context.Context, target string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v)
+}
+
+// RandomString возвращает случайную строку для state, nonce и верификатора PKCE.
+func RandomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Challenge возвращает code_challenge PKCE для верификатора по методу S256.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,143 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/oidc"
+	"github.com/alexuryumtsev/go-shortener/internal/app/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authorize проходит страницу входа тестового провайдера и возвращает параметры возврата на redirect_uri.
+func authorize(t *testing.T, authURL string) url.Values {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query()
+}
+
+func TestProvider_Flow(t *testing.T) {
+	server := oidctest.NewServer("shortener")
+	defer server.Close()
+	server.Claims["email"] = "alice@example.com"
+	server.Claims["groups"] = []string{"marketing", "sales"}
+
+	tests := []struct {
+		name          string
+		issuerURL     string
+		verifier      string // Верификатор при обмене кода; пустой — тот же, что при входе
+		nonce         string // Ожидаемый nonce; пустой — тот же, что при входе
+		wantExchange  bool
+		wantVerifyErr bool
+	}{
+		{name: "issuer URL", issuerURL: server.Issuer(), wantExchange: true},
+		{name: "discovery URL", issuerURL: server.Issuer() + "/.well-known/openid-configuration", wantExchange: true},
+		{name: "wrong verifier", issuerURL: server.Issuer(), verifier: "other"},
+		{name: "wrong nonce", issuerURL: server.Issuer(), nonce: "other", wantExchange: true, wantVerifyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			provider := oidc.NewProvider(oidc.Config{
+				IssuerURL:    tt.issuerURL,
+				ClientID:     "shortener",
+				ClientSecret: "secret",
+				RedirectURL:  "http://localhost:8080/auth/callback",
+			}, nil)
+
+			state, nonce, verifier := oidc.RandomString(), oidc.RandomString(), oidc.RandomString()
+			authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+			require.NoError(t, err)
+			callback := authorize(t, authURL)
+			assert.Equal(t, state, callback.Get("state"))
+
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+			rawIDToken, err := provider.Exchange(ctx, callback.Get("code"), verifier)
+			if !tt.wantExchange {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			identity, err := provider.VerifyIDToken(ctx, rawIDToken, nonce)
+			if tt.wantVerifyErr {
+				assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, server.Issuer(), identity.Issuer)
+			assert.Equal(t, "user-1", identity.Subject)
+			assert.Equal(t, "alice@example.com", identity.Email)
+			assert.Equal(t, []string{"marketing", "sales"}, identity.Values("groups"))
+			assert.Equal(t, []string{"alice@example.com"}, identity.Values("email"))
+			assert.Nil(t, identity.Values("missing"))
+		})
+	}
+}
+
+func TestProvider_VerifyIDToken(t *testing.T) {
+	server := oidctest.NewServer("shortener")
+	defer server.Close()
+	other := oidctest.NewServer("shortener")
+	defer other.Close()
+
+	provider := oidc.NewProvider(oidc.Config{IssuerURL: server.Issuer(), ClientID: "shortener"}, nil)
+	ctx := context.Background()
+
+	valid, err := server.IDToken("n")
+	require.NoError(t, err)
+	_, err = provider.VerifyIDToken(ctx, valid, "n")
+	assert.NoError(t, err)
+
+	// Токен, подписанный чужим ключом.
+	forged, err := other.IDToken("n")
+	require.NoError(t, err)
+	_, err = provider.VerifyIDToken(ctx, forged, "n")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+
+	// Токен для другого клиента.
+	server.ClientID = "other-client"
+	foreign, err := server.IDToken("n")
+	server.ClientID = "shortener"
+	require.NoError(t, err)
+	_, err = provider.VerifyIDToken(ctx, foreign, "n")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+
+	// Истёкший токен.
+	server.TTL = -time.Hour
+	expired, err := server.IDToken("n")
+	require.NoError(t, err)
+	_, err = provider.VerifyIDToken(ctx, expired, "n")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
+
+func TestProvider_EndSessionURL(t *testing.T) {
+	server := oidctest.NewServer("shortener")
+	defer server.Close()
+
+	provider := oidc.NewProvider(oidc.Config{IssuerURL: server.Issuer(), ClientID: "shortener"}, nil)
+	endSession, err := url.Parse(provider.EndSessionURL(context.Background(), "http://localhost:8080/"))
+	require.NoError(t, err)
+	assert.Equal(t, "/logout", endSession.Path)
+	assert.Equal(t, "http://localhost:8080/", endSession.Query().Get("post_logout_redirect_uri"))
+
+	unavailable := oidc.NewProvider(oidc.Config{IssuerURL: "http://127.0.0.1:0"}, nil)
+	assert.Empty(t, unavailable.EndSessionURL(context.Background(), ""))
+}
@@ -0,0 +1,202 @@
+// Package oidctest реализует тестовый провайдер OpenID Connect на базе httptest.
+package oidctest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/jwt"
+	"github.com/alexuryumtsev/go-shortener/internal/app/oidc"
+)
+
+// Server — тестовый провайдер. Страница входа сразу возвращает пользователя на redirect_uri
+// с кодом авторизации, как если бы он вошёл под Subject и согласился на доступ.
+type Server struct {
+	*httptest.Server
+
+	ClientID string
+	Subject  string         // Идентификатор пользователя (sub) в выдаваемых ID-токенах
+	Claims   map[string]any // Дополнительные утверждения ID-токенов
+	TTL      time.Duration  // Срок действия ID-токенов
+
+	authority *jwt.Authority
+	jwks      []byte
+
+	mu    sync.Mutex
+	codes map[string]authRequest
+}
+
+// authRequest — параметры запроса входа, сохранённые до обмена кода.
+type authRequest struct {
+	redirectURI string
+	nonce       string
+	challenge   string
+}
+
+// NewServer запускает тестовый провайдер с новым ключом подписи Ed25519.
+func NewServer(clientID string) *Server {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	key := map[string]string{
+		"kty": "OKP",
+		"crv": "Ed25519",
+		"kid": "test",
+		"alg": jwt.EdDSA,
+		"use": "sig",
+		"x":   base64.RawURLEncoding.EncodeToString(public),
+	}
+	jwks, _ := json.Marshal(map[string]any{"keys": []any{key}})
+	key["d"] = base64.RawURLEncoding.EncodeToString(private.Seed())
+	signing, _ := json.Marshal(map[string]any{"keys": []any{key}})
+	keys, err := jwt.ParseKeySet(signing)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Server{
+		ClientID: clientID,
+		Subject:  "user-1",
+		Claims:   map[string]any{},
+		TTL:      5 * time.Minute,
+		jwks:     jwks,
+		codes:    make(map[string]authRequest),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/jwks", s.keys)
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {})
+	s.Server = httptest.NewServer(mux)
+	s.authority = jwt.NewAuthority(keys, s.URL, clientID)
+	return s
+}
+
+// Issuer возвращает адрес издателя провайдера.
+func (s *Server) Issuer() string {
+	return s.URL
+}
+
+// IDToken подписывает ID-токен с заданным nonce для текущих Subject и Claims.
+func (s *Server) IDToken(nonce string) (string, error) {
+	claims := map[string]any{}
+	for k, v := range s.Claims {
+		claims[k] = v
+	}
+	now := time.Now()
+	claims["iss"] = s.URL
+	claims["sub"] = s.Subject
+	claims["aud"] = s.ClientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(s.TTL).Unix()
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	return s.authority.Sign(claims)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+		"end_session_endpoint":   s.URL + "/logout",
+	})
+}
+
+func (s *Server) keys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(s.jwks)
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("client_id") != s.ClientID || query.Get("response_type") != "code" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "PKCE is required", http.StatusBadRequest)
+		return
+	}
+
+	code := oidc.RandomString()
+	s.mu.Lock()
+	s.codes[code] = authRequest{
+		redirectURI: redirectURI.String(),
+		nonce:       query.Get("nonce"),
+		challenge:   query.Get("code_challenge"),
+	}
+	s.mu.Unlock()
+
+	callback := redirectURI.Query()
+	callback.Set("code", code)
+	callback.Set("state", query.Get("state"))
+	redirectURI.RawQuery = callback.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	req, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	clientID := r.PostForm.Get("client_id")
+	if user, _, found := r.BasicAuth(); found {
+		clientID, _ = url.QueryUnescape(user)
+	}
+	switch {
+	case !ok, r.PostForm.Get("redirect_uri") != req.redirectURI:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case clientID != s.ClientID:
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	case challenge(r.PostForm.Get("code_verifier")) != req.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	idToken, err := s.IDToken(req.nonce)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": oidc.RandomString(),
+		"token_type":   "Bearer",
+		"expires_in":   int(s.TTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+// challenge вычисляет code_challenge по методу S256 (RFC 7636, 4.2).
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
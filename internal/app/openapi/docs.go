@@ -0,0 +1,301 @@
+package openapi
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+)
+
+//go:embed templates/docs.html
+var templatesFS embed.FS
+
+var docsTemplate = template.Must(template.ParseFS(templatesFS, "templates/docs.html"))
+
+// SpecHandler отдаёт спецификацию OpenAPI в формате JSON.
+func SpecHandler(spec *Spec) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec.raw)
+	}
+}
+
+// DocsHandler отдаёт страницу документации API в стиле Swagger UI, построенную по спецификации.
+// Страница не загружает внешние скрипты и стили и не требует доступа в интернет.
+func DocsHandler(spec *Spec) http.HandlerFunc {
+	var page bytes.Buffer
+	if err := docsTemplate.Execute(&page, newDocsPage(spec)); err != nil {
+		panic(fmt.Sprintf("render API docs: %v", err))
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if _, err := w.Write(page.Bytes()); err != nil {
+			log.Printf("Error writing API docs: %v", err)
+		}
+	}
+}
+
+// docsPage содержит данные страницы документации.
+type docsPage struct {
+	Info     Info
+	Tags     []docsTag
+	Schemas  []docsSchema
+	Problems []docsProblem
+}
+
+type docsTag struct {
+	Tag
+	Operations []docsOperation
+}
+
+type docsOperation struct {
+	Method     string
+	Class      string // CSS-класс метода
+	Path       string
+	Summary    string
+	Deprecated bool
+	Parameters []docsField
+	Body       []docsContent
+	Responses  []docsResponse
+}
+
+type docsContent struct {
+	ContentType string
+	Type        docsType
+}
+
+type docsResponse struct {
+	Status      string
+	Description string
+	Content     []docsContent
+}
+
+// docsProblem описывает вид ошибки; на него ссылается поле type описания проблемы.
+type docsProblem struct {
+	Slug   string
+	Type   string
+	Title  string
+	Status int
+}
+
+type docsSchema struct {
+	Name        string
+	Description string
+	Type        docsType
+	Fields      []docsField
+}
+
+// docsField описывает параметр операции или поле схемы.
+type docsField struct {
+	Name        string
+	In          string
+	Type        docsType
+	Required    bool
+	Description string
+}
+
+// docsType описывает тип значения: ссылку на именованную схему с префиксом ([] для массивов)
+// или название встроенного типа.
+type docsType struct {
+	Prefix string
+	Ref    string
+	Text   string
+}
+
+func newDocsPage(spec *Spec) docsPage {
+	page := docsPage{Info: spec.Info}
+
+	byTag := make(map[string][]docsOperation)
+	for _, route := range spec.routes {
+		for _, method := range spec.Methods(route.template) {
+			operation := spec.Paths[route.template][strings.ToLower(method)]
+			tag := ""
+			if len(operation.Tags) > 0 {
+				tag = operation.Tags[0]
+			}
+			byTag[tag] = append(byTag[tag], newDocsOperation(method, route.template, operation))
+		}
+	}
+	for _, tag := range spec.Tags {
+		page.Tags = append(page.Tags, docsTag{Tag: tag, Operations: byTag[tag.Name]})
+		delete(byTag, tag.Name)
+	}
+	for name, operations := range byTag {
+		page.Tags = append(page.Tags, docsTag{Tag: Tag{Name: name}, Operations: operations})
+	}
+
+	names := make([]string, 0, len(spec.Components.Schemas))
+	for name := range spec.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		schema := spec.Components.Schemas[name]
+		page.Schemas = append(page.Schemas, docsSchema{
+			Name:        name,
+			Description: schema.Description,
+			Type:        newDocsType(schema),
+			Fields:      schemaFields(schema),
+		})
+	}
+
+	for _, kind := range apperr.Kinds() {
+		page.Problems = append(page.Problems, docsProblem{
+			Slug:   kind.Slug(),
+			Type:   kind.Type(),
+			Title:  kind.Title(),
+			Status: kind.Status(),
+		})
+	}
+	return page
+}
+
+func newDocsOperation(method, path string, operation *Operation) docsOperation {
+	op := docsOperation{
+		Method:     method,
+		Class:      strings.ToLower(method),
+		Path:       path,
+		Summary:    operation.Summary,
+		Deprecated: operation.Deprecated,
+	}
+	for _, param := range operation.Parameters {
+		op.Parameters = append(op.Parameters, docsField{
+			Name:        param.Name,
+			In:          param.In,
+			Type:        newDocsType(param.Schema),
+			Required:    param.Required,
+			Description: describeField(param.Description, param.Schema),
+		})
+	}
+	if operation.RequestBody != nil {
+		op.Body = contents(operation.RequestBody.Content)
+	}
+
+	statuses := make([]string, 0, len(operation.Responses))
+	for status := range operation.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		response := operation.Responses[status]
+		op.Responses = append(op.Responses, docsResponse{
+			Status:      status,
+			Description: response.Description,
+			Content:     contents(response.Content),
+		})
+	}
+	return op
+}
+
+func contents(content map[string]MediaType) []docsContent {
+	types := make([]string, 0, len(content))
+	for contentType := range content {
+		types = append(types, contentType)
+	}
+	sort.Strings(types)
+	result := make([]docsContent, 0, len(types))
+	for _, contentType := range types {
+		result = append(result, docsContent{ContentType: contentType, Type: newDocsType(content[contentType].Schema)})
+	}
+	return result
+}
+
+// schemaFields возвращает поля объекта: сначала обязательные, затем остальные по алфавиту.
+func schemaFields(schema *Schema) []docsField {
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if required[names[i]] != required[names[j]] {
+			return required[names[i]]
+		}
+		return names[i] < names[j]
+	})
+
+	fields := make([]docsField, 0, len(names))
+	for _, name := range names {
+		property := schema.Properties[name]
+		fields = append(fields, docsField{
+			Name:        name,
+			Type:        newDocsType(property),
+			Required:    required[name],
+			Description: describeField(property.Description, property),
+		})
+	}
+	return fields
+}
+
+func newDocsType(schema *Schema) docsType {
+	switch {
+	case schema == nil:
+		return docsType{Text: "any"}
+	case schema.Ref != "":
+		return docsType{Ref: strings.TrimPrefix(schema.Ref, "#/components/schemas/")}
+	case len(schema.AllOf) == 1:
+		return newDocsType(schema.AllOf[0])
+	case schema.Type == "array":
+		t := newDocsType(schema.Items)
+		t.Prefix = "[]" + t.Prefix
+		return t
+	case schema.Type == "object" && schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil:
+		t := newDocsType(schema.AdditionalProperties.Schema)
+		t.Prefix = "map[string]" + t.Prefix
+		return t
+	case schema.Type == "":
+		return docsType{Text: "any"}
+	case schema.Format != "":
+		return docsType{Text: schema.Type + " (" + schema.Format + ")"}
+	default:
+		return docsType{Text: schema.Type}
+	}
+}
+
+// describeField дополняет описание поля ограничениями схемы.
+func describeField(description string, schema *Schema) string {
+	if schema == nil {
+		return description
+	}
+	var constraints []string
+	if len(schema.Enum) > 0 {
+		constraints = append(constraints, "одно из: "+formatEnum(schema.Enum))
+	}
+	if schema.Items != nil && len(schema.Items.Enum) > 0 {
+		constraints = append(constraints, "элементы: "+formatEnum(schema.Items.Enum))
+	}
+	if schema.Minimum != nil {
+		constraints = append(constraints, "минимум "+formatFloat(*schema.Minimum))
+	}
+	if schema.Maximum != nil {
+		constraints = append(constraints, "максимум "+formatFloat(*schema.Maximum))
+	}
+	if schema.MaxLength != nil {
+		constraints = append(constraints, fmt.Sprintf("до %d символов", *schema.MaxLength))
+	}
+	if schema.MaxItems != nil {
+		constraints = append(constraints, fmt.Sprintf("до %d элементов", *schema.MaxItems))
+	}
+	if schema.Pattern != "" {
+		constraints = append(constraints, "шаблон "+schema.Pattern)
+	}
+	if schema.Nullable {
+		constraints = append(constraints, "null сбрасывает значение")
+	}
+	if len(constraints) == 0 {
+		return description
+	}
+	if description == "" {
+		return strings.Join(constraints, "; ")
+	}
+	return description + " (" + strings.Join(constraints, "; ") + ")"
+}
@@ -0,0 +1,71 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+)
+
+// Validator проверяет тела JSON-запросов по схеме операции спецификации и отвечает
+// 400 Bad Request с описанием проблемы и списком ошибок по полям, если тело ей не соответствует.
+// Тело проверяется независимо от заголовка Content-Type, как и разбирается обработчиками;
+// запросы к операциям без тела JSON и к путям вне спецификации передаются дальше без проверки.
+func Validator(spec *Spec) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			operation, _, ok := spec.Operation(r.Method, r.URL.Path)
+			if !ok || operation.JSONSchema() == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				middleware.WriteError(w, r, apperr.Wrap(apperr.InvalidInput, "Failed to read request body", err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			if len(bytes.TrimSpace(body)) == 0 {
+				if operation.RequestBody.Required {
+					middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Request body is required"))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.UseNumber()
+			var value any
+			if err := decoder.Decode(&value); err != nil {
+				middleware.WriteError(w, r, apperr.New(apperr.InvalidInput, "Invalid JSON: "+jsonErrorMessage(err)))
+				return
+			}
+			if errs := spec.Validate(operation.JSONSchema(), value); len(errs) > 0 {
+				middleware.WriteError(w, r, apperr.Validation("Request body does not match the schema", errs))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// jsonErrorMessage описывает ошибку разбора JSON с позицией, если она известна.
+func jsonErrorMessage(err error) string {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return err.Error() + " at offset " + strconv.FormatInt(syntaxErr.Offset, 10)
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return "unexpected end of input"
+	}
+	return err.Error()
+}
@@ -0,0 +1,234 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidator(t *testing.T) {
+	spec, err := Load()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantCode   int
+		wantErrors []apperr.FieldError
+	}{
+		{
+			name:     "valid shorten request",
+			method:   http.MethodPost,
+			path:     "/api/shorten",
+			body:     `{"url": "https://example.com", "tags": ["docs"], "max_clicks": 10}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:       "missing url",
+			method:     http.MethodPost,
+			path:       "/api/shorten",
+			body:       `{"title": "Example"}`,
+			wantCode:   http.StatusBadRequest,
+			wantErrors: []apperr.FieldError{{Field: "url", Message: "is required"}},
+		},
+		{
+			name:     "wrong types and unknown field",
+			method:   http.MethodPost,
+			path:     "/api/shorten",
+			body:     `{"url": "example.com", "max_clicks": "10", "tag": ["docs"]}`,
+			wantCode: http.StatusBadRequest,
+			wantErrors: []apperr.FieldError{
+				{Field: "max_clicks", Message: "must be an integer, got string"},
+				{Field: "tag", Message: `unknown field, did you mean "tags"?`},
+				{Field: "url", Message: `must be an absolute URL, got "example.com"`},
+			},
+		},
+		{
+			name:     "nested rule and variant errors",
+			method:   http.MethodPost,
+			path:     "/api/shorten",
+			body:     `{"url": "https://example.com", "rules": [{"url": "https://m.example.com", "devices": ["tv"]}], "variants": [{"url": "https://a.example.com", "weight": 0.5}]}`,
+			wantCode: http.StatusBadRequest,
+			wantErrors: []apperr.FieldError{
+				{Field: "rules[0].devices[0]", Message: `must be one of "ios", "android", "desktop"`},
+				{Field: "variants[0].weight", Message: "must be an integer, got number"},
+			},
+		},
+		{
+			name:     "valid batch",
+			method:   http.MethodPost,
+			path:     "/api/shorten/batch",
+			body:     `[{"correlation_id": "1", "original_url": "https://example.com"}]`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "batch with guessed field names",
+			method:   http.MethodPost,
+			path:     "/api/shorten/batch",
+			body:     `[{"correlationId": "1", "url": "https://example.com"}]`,
+			wantCode: http.StatusBadRequest,
+			wantErrors: []apperr.FieldError{
+				{Field: "[0].correlation_id", Message: "is required"},
+				{Field: "[0].original_url", Message: "is required"},
+				{Field: "[0].correlationId", Message: `unknown field, did you mean "correlation_id"?`},
+				{Field: "[0].url", Message: "unknown field"},
+			},
+		},
+		{
+			name:       "empty batch",
+			method:     http.MethodPost,
+			path:       "/api/shorten/batch",
+			body:       `[]`,
+			wantCode:   http.StatusBadRequest,
+			wantErrors: []apperr.FieldError{{Message: "must not be empty"}},
+		},
+		{
+			name:       "batch is not an array",
+			method:     http.MethodPost,
+			path:       "/api/shorten/batch",
+			body:       `{"correlation_id": "1"}`,
+			wantCode:   http.StatusBadRequest,
+			wantErrors: []apperr.FieldError{{Message: "must be an array, got object"}},
+		},
+		{
+			name:     "invalid JSON",
+			method:   http.MethodPost,
+			path:     "/api/shorten",
+			body:     `{"url": `,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "missing body",
+			method:   http.MethodPost,
+			path:     "/api/shorten",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "workspace route with path parameter",
+			method:   http.MethodPost,
+			path:     "/api/workspaces/w1/urls",
+			body:     `{}`,
+			wantCode: http.StatusBadRequest,
+			wantErrors: []apperr.FieldError{
+				{Field: "url", Message: "is required"},
+			},
+		},
+		{
+			name:     "patch accepts null to reset field",
+			method:   http.MethodPatch,
+			path:     "/api/urls/abc",
+			body:     `{"expires_at": null, "params": null, "redirect_code": 308}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:       "patch rejects unsupported redirect code",
+			method:     http.MethodPatch,
+			path:       "/api/urls/abc",
+			body:       `{"redirect_code": 200}`,
+			wantCode:   http.StatusBadRequest,
+			wantErrors: []apperr.FieldError{{Field: "redirect_code", Message: "must be one of 301, 302, 303, 307, 308"}},
+		},
+		{
+			name:     "text body of legacy endpoint is not validated",
+			method:   http.MethodPost,
+			path:     "/",
+			body:     "not json",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "unknown path is not validated",
+			method:   http.MethodPost,
+			path:     "/api/unknown",
+			body:     "not json",
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received string
+			handler := Validator(spec)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Обработчик получает тело запроса целиком.
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				received = string(body)
+				w.WriteHeader(http.StatusOK)
+			}))
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantCode, rec.Code, rec.Body.String())
+			if tt.wantCode == http.StatusOK {
+				assert.Equal(t, tt.body, received)
+				return
+			}
+			assert.Equal(t, middleware.ProblemContentType, rec.Header().Get("Content-Type"))
+			var resp middleware.Problem
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, http.StatusBadRequest, resp.Status)
+			assert.Equal(t, tt.path, resp.Instance)
+			assert.NotEmpty(t, resp.Detail)
+			if tt.wantErrors != nil {
+				assert.Equal(t, tt.wantErrors, resp.Errors)
+			}
+		})
+	}
+}
+
+func TestSpecOperation(t *testing.T) {
+	spec, err := Load()
+	require.NoError(t, err)
+
+	tests := []struct {
+		method       string
+		path         string
+		wantTemplate string
+	}{
+		{method: http.MethodGet, path: "/ping", wantTemplate: "/ping"},
+		{method: http.MethodGet, path: "/abc123", wantTemplate: "/{id}"},
+		{method: http.MethodGet, path: "/api/audit/", wantTemplate: "/api/audit"},
+		{method: http.MethodPost, path: "/api/urls/abc/rollback", wantTemplate: "/api/urls/{id}/rollback"},
+		{method: http.MethodDelete, path: "/api/workspaces/w1/members/u1", wantTemplate: "/api/workspaces/{workspace}/members/{user}"},
+		{method: http.MethodPut, path: "/ping"},
+		{method: http.MethodGet, path: "/api/urls//stats"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			_, template, ok := spec.Operation(tt.method, tt.path)
+			assert.Equal(t, tt.wantTemplate != "", ok)
+			assert.Equal(t, tt.wantTemplate, template)
+		})
+	}
+}
+
+func TestDocsHandler(t *testing.T) {
+	spec, err := Load()
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	DocsHandler(spec)(rec, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, rec.Body.String(), "/api/shorten/batch")
+	assert.Contains(t, rec.Body.String(), `id="schema-URLBatchModel"`)
+	assert.Contains(t, rec.Body.String(), `id="problem-invalid-input"`)
+
+	rec = httptest.NewRecorder()
+	SpecHandler(spec)(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var document map[string]any
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&document))
+	assert.Equal(t, "3.0.3", document["openapi"])
+}
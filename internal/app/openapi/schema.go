@@ -0,0 +1,342 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+)
+
+// Schema описывает подмножество JSON Schema, используемое в спецификации OpenAPI 3.0.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	AllOf                []*Schema          `json:"allOf"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *Additional        `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Pattern              string             `json:"pattern"`
+
+	pattern *regexp.Regexp
+}
+
+// Additional описывает additionalProperties: запрет лишних полей или схему их значений.
+type Additional struct {
+	Forbidden bool
+	Schema    *Schema
+}
+
+// UnmarshalJSON разбирает additionalProperties, заданный логическим значением или схемой.
+func (a *Additional) UnmarshalJSON(data []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(data, &allowed); err == nil {
+		a.Forbidden = !allowed
+		return nil
+	}
+	return json.Unmarshal(data, &a.Schema)
+}
+
+// Validate проверяет значение, разобранное из JSON с json.Decoder.UseNumber, по схеме
+// и возвращает ошибки всех несоответствующих полей.
+func (s *Spec) Validate(schema *Schema, value any) []apperr.FieldError {
+	v := validation{spec: s}
+	v.validate(schema, value, "")
+	return v.errors
+}
+
+type validation struct {
+	spec   *Spec
+	errors []apperr.FieldError
+}
+
+func (v *validation) fail(path, format string, args ...any) {
+	v.errors = append(v.errors, apperr.FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validation) validate(schema *Schema, value any, path string) {
+	if schema == nil {
+		return
+	}
+	if schema.Ref != "" {
+		// Ссылки проверены при загрузке спецификации.
+		resolved, _ := v.spec.resolve(schema.Ref)
+		v.validate(resolved, value, path)
+		return
+	}
+	if value == nil {
+		if !schema.Nullable && (schema.Type != "" || len(schema.AllOf) > 0) {
+			v.fail(path, "must not be null")
+		}
+		return
+	}
+	for _, sub := range schema.AllOf {
+		v.validate(sub, value, path)
+	}
+	if schema.Type != "" && !v.checkType(schema.Type, value, path) {
+		return
+	}
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		v.fail(path, "must be one of %s", formatEnum(schema.Enum))
+		return
+	}
+
+	switch value := value.(type) {
+	case string:
+		v.validateString(schema, value, path)
+	case json.Number:
+		v.validateNumber(schema, value, path)
+	case []any:
+		v.validateArray(schema, value, path)
+	case map[string]any:
+		v.validateObject(schema, value, path)
+	}
+}
+
+// checkType проверяет тип значения и сообщает о несоответствии.
+func (v *validation) checkType(typ string, value any, path string) bool {
+	ok := false
+	switch typ {
+	case "string":
+		_, ok = value.(string)
+	case "boolean":
+		_, ok = value.(bool)
+	case "array":
+		_, ok = value.([]any)
+	case "object":
+		_, ok = value.(map[string]any)
+	case "number":
+		_, ok = value.(json.Number)
+	case "integer":
+		var n json.Number
+		if n, ok = value.(json.Number); ok {
+			_, err := strconv.ParseInt(n.String(), 10, 64)
+			ok = err == nil
+		}
+	default:
+		ok = true
+	}
+	if !ok {
+		v.fail(path, "must be %s, got %s", article(typ), describe(value))
+	}
+	return ok
+}
+
+func (v *validation) validateString(schema *Schema, value, path string) {
+	length := utf8.RuneCountInString(value)
+	if schema.MinLength != nil && length < *schema.MinLength {
+		if *schema.MinLength == 1 {
+			v.fail(path, "must not be empty")
+		} else {
+			v.fail(path, "must be at least %d characters long", *schema.MinLength)
+		}
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		v.fail(path, "must be at most %d characters long", *schema.MaxLength)
+	}
+	if schema.pattern != nil && !schema.pattern.MatchString(value) {
+		v.fail(path, "must match pattern %s", schema.Pattern)
+	}
+	switch schema.Format {
+	case "uri":
+		if u, err := url.Parse(value); err != nil || !u.IsAbs() || u.Host == "" {
+			v.fail(path, "must be an absolute URL, got %q", value)
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			v.fail(path, "must be a date-time in RFC 3339 format, got %q", value)
+		}
+	}
+}
+
+func (v *validation) validateNumber(schema *Schema, value json.Number, path string) {
+	n, err := value.Float64()
+	if err != nil {
+		v.fail(path, "must be a number")
+		return
+	}
+	if schema.Minimum != nil && n < *schema.Minimum {
+		v.fail(path, "must be at least %s", formatFloat(*schema.Minimum))
+	}
+	if schema.Maximum != nil && n > *schema.Maximum {
+		v.fail(path, "must be at most %s", formatFloat(*schema.Maximum))
+	}
+}
+
+func (v *validation) validateArray(schema *Schema, value []any, path string) {
+	if schema.MinItems != nil && len(value) < *schema.MinItems {
+		if *schema.MinItems == 1 {
+			v.fail(path, "must not be empty")
+		} else {
+			v.fail(path, "must contain at least %d items", *schema.MinItems)
+		}
+	}
+	if schema.MaxItems != nil && len(value) > *schema.MaxItems {
+		v.fail(path, "must contain at most %d items", *schema.MaxItems)
+	}
+	for i, item := range value {
+		v.validate(schema.Items, item, path+"["+strconv.Itoa(i)+"]")
+	}
+}
+
+func (v *validation) validateObject(schema *Schema, value map[string]any, path string) {
+	for _, name := range schema.Required {
+		if _, ok := value[name]; !ok {
+			v.fail(joinPath(path, name), "is required")
+		}
+	}
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := schema.Properties[name]
+		switch {
+		case ok:
+			v.validate(property, value[name], joinPath(path, name))
+		case schema.AdditionalProperties == nil:
+		case schema.AdditionalProperties.Forbidden:
+			if suggestion := closest(name, schema.Properties); suggestion != "" {
+				v.fail(joinPath(path, name), "unknown field, did you mean %q?", suggestion)
+			} else {
+				v.fail(joinPath(path, name), "unknown field")
+			}
+		default:
+			v.validate(schema.AdditionalProperties.Schema, value[name], joinPath(path, name))
+		}
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// closest возвращает известное поле, на которое похоже name: отличается регистром,
+// разделителями слов или не более чем двумя символами.
+func closest(name string, properties map[string]*Schema) string {
+	best, bestDistance := "", 3
+	normalized := normalizeName(name)
+	for property := range properties {
+		if normalizeName(property) == normalized {
+			return property
+		}
+		distance := levenshtein(name, property)
+		if distance < bestDistance || (distance == bestDistance && property < best) {
+			best, bestDistance = property, distance
+		}
+	}
+	return best
+}
+
+func normalizeName(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+}
+
+// levenshtein возвращает расстояние редактирования между строками.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+func inEnum(enum []any, value any) bool {
+	for _, allowed := range enum {
+		switch allowed := allowed.(type) {
+		case float64:
+			if n, ok := value.(json.Number); ok {
+				if f, err := n.Float64(); err == nil && f == allowed {
+					return true
+				}
+			}
+		default:
+			if allowed == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func formatEnum(enum []any) string {
+	values := make([]string, len(enum))
+	for i, value := range enum {
+		if s, ok := value.(string); ok {
+			values[i] = strconv.Quote(s)
+		} else {
+			values[i] = fmt.Sprint(value)
+		}
+	}
+	return strings.Join(values, ", ")
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// article возвращает название типа JSON для сообщения об ошибке.
+func article(typ string) string {
+	switch typ {
+	case "array", "object", "integer":
+		return "an " + typ
+	default:
+		return "a " + typ
+	}
+}
+
+// describe возвращает название типа значения JSON.
+func describe(value any) string {
+	switch value := value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case json.Number:
+		if _, err := strconv.ParseInt(value.String(), 10, 64); err == nil {
+			return "integer"
+		}
+		return "number"
+	default:
+		return "null"
+	}
+}
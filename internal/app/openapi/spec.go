@@ -0,0 +1,289 @@
+// Package openapi содержит спецификацию OpenAPI 3 HTTP API сервиса, страницу документации
+// по ней и middleware, проверяющее тела JSON-запросов по схемам спецификации.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//go:embed openapi.json
+var document []byte
+
+// mediaTypeJSON — тип содержимого, тела которого проверяются по схеме.
+const mediaTypeJSON = "application/json"
+
+// Spec описывает загруженную спецификацию OpenAPI.
+type Spec struct {
+	Info       Info                             `json:"info"`
+	Tags       []Tag                            `json:"tags"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+
+	raw    []byte
+	routes []route
+}
+
+// Info содержит название, версию и описание API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+// Tag описывает группу операций.
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Components содержит именованные схемы, на которые ссылаются операции.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation описывает операцию — метод HTTP на пути спецификации.
+type Operation struct {
+	Summary     string              `json:"summary"`
+	Tags        []string            `json:"tags"`
+	Parameters  []Parameter         `json:"parameters"`
+	RequestBody *RequestBody        `json:"requestBody"`
+	Responses   map[string]Response `json:"responses"`
+	Deprecated  bool                `json:"deprecated"`
+}
+
+// Parameter описывает параметр пути или строки запроса.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody описывает тело запроса по типам содержимого.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response описывает ответ операции.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// MediaType содержит схему содержимого определённого типа.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// route — шаблон пути спецификации, разобранный на сегменты.
+type route struct {
+	template string
+	segments []string
+	literals int // Количество сегментов без параметров; при совпадении нескольких шаблонов выбирается самый конкретный
+}
+
+// Load разбирает встроенную спецификацию и проверяет, что все ссылки на схемы разрешаются,
+// а шаблоны строк корректны.
+func Load() (*Spec, error) {
+	spec := &Spec{raw: document}
+	if err := json.Unmarshal(document, spec); err != nil {
+		return nil, fmt.Errorf("parse OpenAPI document: %w", err)
+	}
+
+	for name, schema := range spec.Components.Schemas {
+		if err := spec.prepare(schema); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+	for template, operations := range spec.Paths {
+		for method, operation := range operations {
+			if err := spec.prepareOperation(operation); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), template, err)
+			}
+		}
+		spec.routes = append(spec.routes, newRoute(template))
+	}
+	sort.Slice(spec.routes, func(i, j int) bool { return spec.routes[i].template < spec.routes[j].template })
+	return spec, nil
+}
+
+// MustLoad вызывает Load и паникует при ошибке. Спецификация встроена в программу,
+// поэтому ошибка означает дефект сборки.
+func MustLoad() *Spec {
+	spec, err := Load()
+	if err != nil {
+		panic(err)
+	}
+	return spec
+}
+
+func (s *Spec) prepareOperation(operation *Operation) error {
+	for _, param := range operation.Parameters {
+		if err := s.prepare(param.Schema); err != nil {
+			return fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
+	}
+	if operation.RequestBody != nil {
+		for contentType, media := range operation.RequestBody.Content {
+			if err := s.prepare(media.Schema); err != nil {
+				return fmt.Errorf("request body %s: %w", contentType, err)
+			}
+		}
+	}
+	for status, response := range operation.Responses {
+		for contentType, media := range response.Content {
+			if err := s.prepare(media.Schema); err != nil {
+				return fmt.Errorf("response %s %s: %w", status, contentType, err)
+			}
+		}
+	}
+	return nil
+}
+
+// prepare проверяет ссылки схемы и компилирует её шаблоны строк.
+func (s *Spec) prepare(schema *Schema) error {
+	if schema == nil {
+		return nil
+	}
+	if schema.Ref != "" {
+		if _, err := s.resolve(schema.Ref); err != nil {
+			return err
+		}
+	}
+	if schema.Pattern != "" && schema.pattern == nil {
+		pattern, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %q: %w", schema.Pattern, err)
+		}
+		schema.pattern = pattern
+	}
+	for name, property := range schema.Properties {
+		if err := s.prepare(property); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	for _, sub := range schema.AllOf {
+		if err := s.prepare(sub); err != nil {
+			return err
+		}
+	}
+	if schema.AdditionalProperties != nil {
+		if err := s.prepare(schema.AdditionalProperties.Schema); err != nil {
+			return err
+		}
+	}
+	return s.prepare(schema.Items)
+}
+
+// resolve возвращает схему, на которую указывает ссылка вида #/components/schemas/Name.
+func (s *Spec) resolve(ref string) (*Schema, error) {
+	name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+	if !ok {
+		return nil, fmt.Errorf("unsupported reference %q", ref)
+	}
+	schema, ok := s.Components.Schemas[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema %q", name)
+	}
+	return schema, nil
+}
+
+// Operation возвращает операцию method на пути path и шаблон пути, под который он подходит.
+// Путь сопоставляется так же, как в маршрутизаторе: сегменты без параметров имеют приоритет.
+func (s *Spec) Operation(method, path string) (*Operation, string, bool) {
+	segments := splitPath(path)
+	var best *route
+	for i := range s.routes {
+		candidate := &s.routes[i]
+		if !candidate.match(segments) {
+			continue
+		}
+		if _, ok := s.Paths[candidate.template][strings.ToLower(method)]; !ok {
+			continue
+		}
+		if best == nil || candidate.literals > best.literals {
+			best = candidate
+		}
+	}
+	if best == nil {
+		return nil, "", false
+	}
+	return s.Paths[best.template][strings.ToLower(method)], best.template, true
+}
+
+// JSONSchema возвращает схему тела JSON-запроса операции или nil, если тело не в формате JSON.
+func (o *Operation) JSONSchema() *Schema {
+	if o.RequestBody == nil {
+		return nil
+	}
+	return o.RequestBody.Content[mediaTypeJSON].Schema
+}
+
+func newRoute(template string) route {
+	r := route{template: template, segments: splitPath(template)}
+	for _, segment := range r.segments {
+		if !isParam(segment) {
+			r.literals++
+		}
+	}
+	return r
+}
+
+func (r route) match(segments []string) bool {
+	if len(segments) != len(r.segments) {
+		return false
+	}
+	for i, segment := range r.segments {
+		if isParam(segment) {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitPath разбивает путь на сегменты без учёта завершающей косой черты.
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// Methods возвращает методы HTTP, описанные для шаблона пути, в верхнем регистре.
+func (s *Spec) Methods(template string) []string {
+	methods := make([]string, 0, len(s.Paths[template]))
+	for method := range s.Paths[template] {
+		methods = append(methods, strings.ToUpper(method))
+	}
+	sort.Slice(methods, func(i, j int) bool { return methodOrder(methods[i]) < methodOrder(methods[j]) })
+	return methods
+}
+
+// methodOrder задаёт порядок методов в документации.
+func methodOrder(method string) int {
+	for i, m := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		if m == method {
+			return i
+		}
+	}
+	return len(method) + 100
+}
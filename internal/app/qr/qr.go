@@ -0,0 +1,220 @@
+package qr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Поддерживаемые форматы изображения.
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// Ограничения и значения по умолчанию для параметров QR-кода.
+const (
+	defaultSize   = 256
+	minSize       = 64
+	maxSize       = 2048
+	defaultMargin = 4
+	maxMargin     = 16
+)
+
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Options определяет параметры генерации QR-кода.
+type Options struct {
+	Format     string
+	Size       int    // Ширина и высота изображения в пикселях
+	Level      string // Уровень коррекции ошибок: L, M, Q или H
+	Margin     int    // Ширина свободной зоны вокруг кода в модулях
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+// DefaultOptions возвращает параметры QR-кода по умолчанию.
+func DefaultOptions() Options {
+	return Options{
+		Format:     FormatPNG,
+		Size:       defaultSize,
+		Level:      "M",
+		Margin:     defaultMargin,
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// ParseOptions разбирает параметры запроса format, size, level, margin, fg и bg.
+func ParseOptions(values url.Values) (Options, error) {
+	opts := DefaultOptions()
+
+	if v := values.Get("format"); v != "" {
+		v = strings.ToLower(v)
+		if v != FormatPNG && v != FormatSVG {
+			return opts, fmt.Errorf("unsupported format %q", v)
+		}
+		opts.Format = v
+	}
+
+	if v := values.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < minSize || size > maxSize {
+			return opts, fmt.Errorf("size must be between %d and %d", minSize, maxSize)
+		}
+		opts.Size = size
+	}
+
+	if v := values.Get("level"); v != "" {
+		v = strings.ToUpper(v)
+		if _, ok := levels[v]; !ok {
+			return opts, fmt.Errorf("level must be one of L, M, Q, H")
+		}
+		opts.Level = v
+	}
+
+	if v := values.Get("margin"); v != "" {
+		margin, err := strconv.Atoi(v)
+		if err != nil || margin < 0 || margin > maxMargin {
+			return opts, fmt.Errorf("margin must be between 0 and %d", maxMargin)
+		}
+		opts.Margin = margin
+	}
+
+	var err error
+	if v := values.Get("fg"); v != "" {
+		if opts.Foreground, err = parseColor(v); err != nil {
+			return opts, fmt.Errorf("invalid fg: %w", err)
+		}
+	}
+	if v := values.Get("bg"); v != "" {
+		if opts.Background, err = parseColor(v); err != nil {
+			return opts, fmt.Errorf("invalid bg: %w", err)
+		}
+	}
+
+	return opts, nil
+}
+
+// ContentType возвращает MIME-тип изображения.
+func (o Options) ContentType() string {
+	if o.Format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// ETag вычисляет тег для кэширования изображения с заданным содержимым.
+func (o Options) ETag(content string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%d|%s|%d|%s|%s", content, o.Format, o.Size, o.Level, o.Margin,
+		hexColor(o.Foreground), hexColor(o.Background))
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// Render генерирует QR-код для content в формате PNG или SVG.
+func Render(content string, opts Options) ([]byte, error) {
+	code, err := qrcode.New(content, levels[opts.Level])
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	code.DisableBorder = true
+	bitmap := code.Bitmap()
+
+	if opts.Format == FormatSVG {
+		return renderSVG(bitmap, opts), nil
+	}
+	return renderPNG(bitmap, opts)
+}
+
+// renderPNG рисует растровое изображение, масштабируя модули до целого числа пикселей.
+func renderPNG(bitmap [][]bool, opts Options) ([]byte, error) {
+	modules := len(bitmap) + 2*opts.Margin
+	size := max(opts.Size, modules)
+	scale := size / modules
+	offset := (size - scale*modules) / 2
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{opts.Background, opts.Foreground})
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			x0 := offset + (x+opts.Margin)*scale
+			y0 := offset + (y+opts.Margin)*scale
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(x0+dx, y0+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderSVG строит векторное изображение, объединяя соседние модули строки в один отрезок.
+func renderSVG(bitmap [][]bool, opts Options) []byte {
+	modules := len(bitmap) + 2*opts.Margin
+
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", start+opts.Margin, y+opts.Margin, x-start, x-start)
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`, hexColor(opts.Background))
+	fmt.Fprintf(&buf, `<path fill="%s" d="%s"/>`, hexColor(opts.Foreground), path.String())
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
+
+// parseColor разбирает цвет в формате RRGGBB или RGB с необязательным префиксом #.
+func parseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("expected hex color RRGGBB, got %q", s)
+	}
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("expected hex color RRGGBB, got %q", s)
+	}
+	return color.RGBA{R: b[0], G: b[1], B: b[2], A: 0xff}, nil
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,104 @@
+package qr
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOptions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		query   string
+		wantErr bool
+		check   func(t *testing.T, opts Options)
+	}{
+		{
+			name:  "Defaults",
+			query: "",
+			check: func(t *testing.T, opts Options) {
+				assert.Equal(t, DefaultOptions(), opts)
+			},
+		},
+		{
+			name:  "All parameters",
+			query: "format=SVG&size=512&level=h&margin=0&fg=%23f00&bg=00ff00",
+			check: func(t *testing.T, opts Options) {
+				assert.Equal(t, FormatSVG, opts.Format)
+				assert.Equal(t, 512, opts.Size)
+				assert.Equal(t, "H", opts.Level)
+				assert.Equal(t, 0, opts.Margin)
+				assert.Equal(t, color.RGBA{R: 0xff, A: 0xff}, opts.Foreground)
+				assert.Equal(t, color.RGBA{G: 0xff, A: 0xff}, opts.Background)
+			},
+		},
+		{name: "Unknown format", query: "format=gif", wantErr: true},
+		{name: "Too large", query: "size=100000", wantErr: true},
+		{name: "Unknown level", query: "level=X", wantErr: true},
+		{name: "Negative margin", query: "margin=-1", wantErr: true},
+		{name: "Invalid color", query: "fg=zzzzzz", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tc.query)
+			require.NoError(t, err)
+
+			opts, err := ParseOptions(values)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			tc.check(t, opts)
+		})
+	}
+}
+
+func TestRenderPNG(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Size = 300
+
+	data, err := Render("http://localhost:8080/0dd11111", opts)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 300, img.Bounds().Dx())
+	assert.Equal(t, 300, img.Bounds().Dy())
+
+	// Угол изображения попадает в свободную зону и окрашен цветом фона.
+	r, g, b, _ := img.At(0, 0).RGBA()
+	assert.Equal(t, [3]uint32{0xffff, 0xffff, 0xffff}, [3]uint32{r, g, b})
+}
+
+func TestRenderSVG(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Format = FormatSVG
+	opts.Foreground = color.RGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}
+
+	data, err := Render("http://localhost:8080/0dd11111", opts)
+	require.NoError(t, err)
+
+	svg := string(data)
+	assert.True(t, strings.HasPrefix(svg, "<svg"))
+	assert.Contains(t, svg, `fill="#112233"`)
+	assert.Contains(t, svg, `width="256"`)
+}
+
+func TestOptionsETag(t *testing.T) {
+	opts := DefaultOptions()
+	etag := opts.ETag("http://localhost:8080/0dd11111")
+
+	assert.Equal(t, etag, opts.ETag("http://localhost:8080/0dd11111"))
+	assert.NotEqual(t, etag, opts.ETag("http://localhost:8080/0dd22222"))
+
+	opts.Format = FormatSVG
+	assert.NotEqual(t, etag, opts.ETag("http://localhost:8080/0dd11111"))
+}
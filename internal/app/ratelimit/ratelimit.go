@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter ограничивает количество неудачных попыток по ключу в пределах окна времени.
+type Limiter struct {
+	mu       sync.Mutex
+	limit    int
+	window   time.Duration
+	failures map[string]*entry
+	now      func() time.Time
+}
+
+type entry struct {
+	count int
+	reset time.Time
+}
+
+// NewLimiter создаёт ограничитель, допускающий limit неудачных попыток за window.
+func NewLimiter(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:    limit,
+		window:   window,
+		failures: make(map[string]*entry),
+		now:      time.Now,
+	}
+}
+
+// Reserve засчитывает попытку как неудачную, если лимит не исчерпан, и сообщает, разрешена ли она
+// и через сколько можно повторить. Проверка и учёт выполняются атомарно, поэтому параллельные
+// попытки не превышают лимит. После успешной попытки счётчик сбрасывается вызовом Reset.
+func (l *Limiter) Reserve(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.cleanup(now)
+
+	e, ok := l.failures[key]
+	if !ok {
+		e = &entry{reset: now.Add(l.window)}
+		l.failures[key] = e
+	}
+	if e.count >= l.limit {
+		return false, e.reset.Sub(now)
+	}
+	e.count++
+	return true, 0
+}
+
+// Reset сбрасывает счётчик неудачных попыток, например после успешной проверки.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, key)
+}
+
+// cleanup удаляет устаревшие записи. Вызывается под блокировкой.
+func (l *Limiter) cleanup(now time.Time) {
+	for key, e := range l.failures {
+		if !now.Before(e.reset) {
+			delete(l.failures, key)
+		}
+	}
+}
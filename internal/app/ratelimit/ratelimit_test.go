@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	allowed, _ := l.Reserve("key")
+	assert.True(t, allowed)
+	allowed, _ = l.Reserve("key")
+	assert.True(t, allowed)
+
+	allowed, retryAfter := l.Reserve("key")
+	assert.False(t, allowed)
+	assert.Equal(t, time.Minute, retryAfter)
+
+	// Другие ключи не затрагиваются.
+	allowed, _ = l.Reserve("other")
+	assert.True(t, allowed)
+
+	// По истечении окна попытки снова разрешены.
+	now = now.Add(time.Minute)
+	allowed, _ = l.Reserve("key")
+	assert.True(t, allowed)
+
+	l.Reset("key")
+	allowed, _ = l.Reserve("key")
+	assert.True(t, allowed)
+	allowed, _ = l.Reserve("key")
+	assert.True(t, allowed)
+}
+
+func TestLimiter_Parallel(t *testing.T) {
+	l := NewLimiter(5, time.Minute)
+
+	// Параллельные попытки не превышают лимит.
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := l.Reserve("key"); ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(5), allowed.Load())
+}
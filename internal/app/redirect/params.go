@@ -0,0 +1,125 @@
+package redirect
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+)
+
+// controlParams — параметры короткой ссылки, управляющие её поведением,
+// которые не передаются на адрес назначения.
+var controlParams = map[string]struct{}{
+	"preview": {},
+	"confirm": {},
+}
+
+var placeholderPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+var knownPlaceholders = map[string]struct{}{
+	"id":            {},
+	"click_id":      {},
+	"referrer_host": {},
+	"variant":       {},
+}
+
+// ValidateParams проверяет шаблон параметров перед сохранением.
+func ValidateParams(tmpl *models.ParamTemplate) error {
+	if tmpl == nil {
+		return nil
+	}
+	if tmpl.Mode != "" && tmpl.Mode != models.ParamsMerge && tmpl.Mode != models.ParamsOverride {
+		return fmt.Errorf("params mode must be %q or %q", models.ParamsMerge, models.ParamsOverride)
+	}
+	for key, value := range tmpl.Params {
+		if key == "" {
+			return fmt.Errorf("params: empty parameter name")
+		}
+		for _, match := range placeholderPattern.FindAllStringSubmatch(value, -1) {
+			if _, ok := knownPlaceholders[match[1]]; !ok {
+				return fmt.Errorf("params: unknown placeholder %q in %q", match[0], key)
+			}
+		}
+	}
+	return nil
+}
+
+// NewClickID генерирует уникальный идентификатор перехода.
+func NewClickID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// applyParams добавляет к адресу назначения параметры шаблона и, если включено,
+// параметры запроса короткой ссылки. Более поздние источники имеют приоритет:
+// адрес назначения, затем шаблон, затем входящий запрос.
+func applyParams(destination string, r *http.Request, tmpl *models.ParamTemplate, vars map[string]string) string {
+	if tmpl == nil {
+		return destination
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+
+	query := u.Query()
+	set := func(key string, values ...string) {
+		if tmpl.Mode == models.ParamsOverride {
+			query.Del(key)
+		}
+		for _, value := range values {
+			query.Add(key, value)
+		}
+	}
+
+	for _, key := range sortedKeys(tmpl.Params) {
+		set(key, expandPlaceholders(tmpl.Params[key], vars))
+	}
+
+	if tmpl.PassThrough {
+		incoming := r.URL.Query()
+		for _, key := range sortedKeys(incoming) {
+			if _, ok := controlParams[key]; ok {
+				continue
+			}
+			set(key, incoming[key]...)
+		}
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// expandPlaceholders подставляет значения плейсхолдеров вида {name}.
+func expandPlaceholders(value string, vars map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(value, func(match string) string {
+		return vars[match[1:len(match)-1]]
+	})
+}
+
+// referrerHost возвращает хост из заголовка Referer.
+func referrerHost(r *http.Request) string {
+	u, err := url.Parse(r.Referer())
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
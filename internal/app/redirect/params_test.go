@@ -0,0 +1,90 @@
+package redirect
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyParams(t *testing.T) {
+	vars := map[string]string{"id": "0dd11111", "click_id": "c1", "referrer_host": "news.example", "variant": ""}
+
+	testCases := []struct {
+		name        string
+		destination string
+		requestURL  string
+		tmpl        *models.ParamTemplate
+		want        url.Values
+	}{
+		{
+			name:        "No template",
+			destination: "https://example.com/?a=1",
+			requestURL:  "/0dd11111?ref=newsletter",
+			want:        url.Values{"a": {"1"}},
+		},
+		{
+			name:        "Template with placeholders",
+			destination: "https://example.com/",
+			requestURL:  "/0dd11111",
+			tmpl: &models.ParamTemplate{Params: map[string]string{
+				"utm_source": "{referrer_host}",
+				"cid":        "{id}-{click_id}",
+			}},
+			want: url.Values{"utm_source": {"news.example"}, "cid": {"0dd11111-c1"}},
+		},
+		{
+			name:        "Pass through merges values",
+			destination: "https://example.com/?ref=site",
+			requestURL:  "/0dd11111?ref=newsletter&confirm=1",
+			tmpl:        &models.ParamTemplate{PassThrough: true},
+			want:        url.Values{"ref": {"site", "newsletter"}},
+		},
+		{
+			name:        "Override replaces values",
+			destination: "https://example.com/?utm_source=old&ref=site",
+			requestURL:  "/0dd11111?ref=newsletter",
+			tmpl: &models.ParamTemplate{
+				Params:      map[string]string{"utm_source": "short"},
+				PassThrough: true,
+				Mode:        models.ParamsOverride,
+			},
+			want: url.Values{"utm_source": {"short"}, "ref": {"newsletter"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.requestURL, nil)
+			got, err := url.Parse(applyParams(tc.destination, req, tc.tmpl, vars))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got.Query())
+		})
+	}
+}
+
+func TestResolver_ResolveWithParams(t *testing.T) {
+	urlModel := models.URLModel{
+		ID:     "0dd11111",
+		URL:    "https://example.com/",
+		Params: &models.ParamTemplate{Params: map[string]string{"click": "{click_id}"}},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/0dd11111", nil)
+	destination := NewResolver(nil).Resolve(nil, req, urlModel)
+
+	require.NotEmpty(t, destination.ClickID)
+	assert.Equal(t, "https://example.com/?click="+destination.ClickID, destination.URL)
+}
+
+func TestValidateParams(t *testing.T) {
+	assert.NoError(t, ValidateParams(nil))
+	assert.NoError(t, ValidateParams(&models.ParamTemplate{Params: map[string]string{"utm_source": "{referrer_host}"}, Mode: "merge"}))
+	assert.Error(t, ValidateParams(&models.ParamTemplate{Mode: "replace"}))
+	assert.Error(t, ValidateParams(&models.ParamTemplate{Params: map[string]string{"": "x"}}))
+	assert.Error(t, ValidateParams(&models.ParamTemplate{Params: map[string]string{"utm_source": "{unknown}"}}))
+}
@@ -0,0 +1,284 @@
+package redirect
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/geoip"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+)
+
+// Классы устройств, определяемые по заголовку User-Agent.
+const (
+	DeviceIOS     = "ios"
+	DeviceAndroid = "android"
+	DeviceDesktop = "desktop"
+)
+
+// Destination описывает выбранный для посетителя адрес назначения.
+type Destination struct {
+	URL     string
+	Variant string // Имя варианта A/B-теста, если адрес выбран из вариантов
+	ClickID string // Идентификатор перехода, доступный в шаблоне параметров как {click_id}
+}
+
+// Resolver выбирает адрес назначения короткой ссылки для конкретного запроса.
+type Resolver struct {
+	geo *geoip.DB
+	now func() time.Time
+}
+
+// NewResolver создаёт Resolver. База geo может быть nil, тогда правила по странам не срабатывают.
+func NewResolver(geo *geoip.DB) *Resolver {
+	return &Resolver{geo: geo, now: time.Now}
+}
+
+// Resolve возвращает URL первого подходящего правила. Если ни одно правило не подошло,
+// выбирается вариант A/B-теста, а при их отсутствии — основной URL ссылки.
+// Выбор варианта закрепляется cookie, которая записывается в w.
+// К выбранному адресу применяется шаблон параметров ссылки.
+func (res *Resolver) Resolve(w http.ResponseWriter, r *http.Request, urlModel models.URLModel) Destination {
+	destination := Destination{URL: urlModel.URL, ClickID: NewClickID()}
+
+	if ruleURL, ok := res.matchRule(r, urlModel.Rules); ok {
+		destination.URL = ruleURL
+	} else if len(urlModel.Variants) > 0 {
+		variant := chooseVariant(w, r, urlModel)
+		destination.URL, destination.Variant = variant.URL, variant.Name
+	}
+
+	destination.URL = applyParams(destination.URL, r, urlModel.Params, map[string]string{
+		"id":            urlModel.ID,
+		"click_id":      destination.ClickID,
+		"referrer_host": referrerHost(r),
+		"variant":       destination.Variant,
+	})
+	return destination
+}
+
+// matchRule возвращает URL первого правила, которому соответствует запрос.
+func (res *Resolver) matchRule(r *http.Request, rules []models.Rule) (string, bool) {
+	if res == nil || len(rules) == 0 {
+		return "", false
+	}
+
+	visitor := visitor{
+		device:   DeviceClass(r.UserAgent()),
+		language: preferredLanguage(r.Header.Get("Accept-Language")),
+		country:  res.geo.Country(visitorIP(r)),
+		now:      res.now(),
+	}
+
+	for _, rule := range rules {
+		if visitor.matches(rule) {
+			return rule.URL, true
+		}
+	}
+	return "", false
+}
+
+// DeviceClass определяет класс устройства по User-Agent.
+func DeviceClass(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iPod"):
+		return DeviceIOS
+	case strings.Contains(userAgent, "Android"):
+		return DeviceAndroid
+	default:
+		return DeviceDesktop
+	}
+}
+
+// ValidateRules проверяет корректность правил перед сохранением.
+func ValidateRules(rules []models.Rule) error {
+	for i, rule := range rules {
+		if err := validateRule(rule); err != nil {
+			return fmt.Errorf("rules[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateRule(rule models.Rule) error {
+	if err := ValidateURL(rule.URL); err != nil {
+		return err
+	}
+
+	for _, device := range rule.Devices {
+		if device != DeviceIOS && device != DeviceAndroid && device != DeviceDesktop {
+			return fmt.Errorf("unknown device %q, expected ios, android or desktop", device)
+		}
+	}
+	for _, language := range rule.Languages {
+		if language == "" || strings.ContainsAny(language, " ,;") {
+			return fmt.Errorf("invalid language tag %q", language)
+		}
+	}
+	for _, country := range rule.Countries {
+		if len(country) != 2 {
+			return fmt.Errorf("invalid country code %q, expected ISO 3166-1 alpha-2", country)
+		}
+	}
+
+	if tw := rule.Time; tw != nil {
+		if (tw.From == "") != (tw.To == "") {
+			return fmt.Errorf("time window requires both from and to")
+		}
+		if tw.From != "" {
+			if _, err := parseClock(tw.From); err != nil {
+				return err
+			}
+			if _, err := parseClock(tw.To); err != nil {
+				return err
+			}
+		}
+		if tw.Start != nil && tw.End != nil && !tw.Start.Before(*tw.End) {
+			return fmt.Errorf("time window start must be before end")
+		}
+		if _, err := time.LoadLocation(tw.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", tw.Timezone)
+		}
+	}
+	return nil
+}
+
+// ValidateURL проверяет, что адрес назначения является абсолютным HTTP(S) URL.
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid destination URL %q", rawURL)
+	}
+	return nil
+}
+
+// visitor содержит характеристики посетителя, по которым проверяются правила.
+type visitor struct {
+	device   string
+	language string
+	country  string
+	now      time.Time
+}
+
+func (v visitor) matches(rule models.Rule) bool {
+	if len(rule.Devices) > 0 && !contains(rule.Devices, v.device) {
+		return false
+	}
+	if len(rule.Languages) > 0 && !v.matchesLanguage(rule.Languages) {
+		return false
+	}
+	if len(rule.Countries) > 0 && (v.country == "" || !contains(rule.Countries, v.country)) {
+		return false
+	}
+	if rule.Time != nil && !inTimeWindow(*rule.Time, v.now) {
+		return false
+	}
+	return true
+}
+
+// matchesLanguage сравнивает язык посетителя с тегами правила.
+// Тег "en" подходит для en-US, а тег "en-US" — только для en-US.
+func (v visitor) matchesLanguage(languages []string) bool {
+	if v.language == "" {
+		return false
+	}
+	primary, _, _ := strings.Cut(v.language, "-")
+	for _, language := range languages {
+		language = strings.ToLower(language)
+		if language == v.language || language == primary {
+			return true
+		}
+	}
+	return false
+}
+
+func inTimeWindow(tw models.TimeWindow, now time.Time) bool {
+	if tw.Start != nil && now.Before(*tw.Start) {
+		return false
+	}
+	if tw.End != nil && !now.Before(*tw.End) {
+		return false
+	}
+	if tw.From == "" {
+		return true
+	}
+
+	loc, err := time.LoadLocation(tw.Timezone)
+	if err != nil {
+		return false
+	}
+	from, errFrom := parseClock(tw.From)
+	to, errTo := parseClock(tw.To)
+	if errFrom != nil || errTo != nil {
+		return false
+	}
+
+	local := now.In(loc)
+	minutes := local.Hour()*60 + local.Minute()
+	if from <= to {
+		return minutes >= from && minutes < to
+	}
+	// Интервал переходит через полночь, например 22:00–06:00.
+	return minutes >= from || minutes < to
+}
+
+// parseClock переводит время HH:MM в минуты от начала суток.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// preferredLanguage возвращает язык с наибольшим весом из Accept-Language.
+func preferredLanguage(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ {
+			best, bestQ = strings.ToLower(tag), q
+		}
+	}
+	return best
+}
+
+// visitorIP возвращает IP-адрес посетителя с учётом заголовков прокси.
+func visitorIP(r *http.Request) string {
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return strings.TrimSpace(ip)
+	}
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(first)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package redirect
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/geoip"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	iPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36"
+	desktopUA = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	geo, err := geoip.Load(strings.NewReader("203.0.113.0/24,DE\n"))
+	require.NoError(t, err)
+
+	resolver := NewResolver(geo)
+	resolver.now = func() time.Time { return time.Date(2024, 6, 1, 23, 30, 0, 0, time.UTC) }
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	urlModel := models.URLModel{
+		URL: "https://example.com/default",
+		Rules: []models.Rule{
+			{Devices: []string{DeviceIOS}, URL: "https://apps.apple.com/app"},
+			{Devices: []string{DeviceAndroid}, URL: "https://play.google.com/store/apps"},
+			{Time: &models.TimeWindow{Start: &start, End: &end}, URL: "https://example.com/winter"},
+			{Countries: []string{"de"}, URL: "https://example.de/"},
+			{Languages: []string{"ru"}, URL: "https://example.com/ru"},
+			{Time: &models.TimeWindow{From: "22:00", To: "06:00"}, URL: "https://example.com/night"},
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		userAgent      string
+		acceptLanguage string
+		realIP         string
+		want           string
+	}{
+		{name: "iOS", userAgent: iPhoneUA, want: "https://apps.apple.com/app"},
+		{name: "Android", userAgent: androidUA, want: "https://play.google.com/store/apps"},
+		{name: "Country", userAgent: desktopUA, realIP: "203.0.113.10", want: "https://example.de/"},
+		{name: "Language", userAgent: desktopUA, acceptLanguage: "en;q=0.5, ru-RU", want: "https://example.com/ru"},
+		{name: "Night window", userAgent: desktopUA, acceptLanguage: "en-US", want: "https://example.com/night"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/id", nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+			req.Header.Set("X-Real-IP", tc.realIP)
+
+			assert.Equal(t, tc.want, resolver.Resolve(nil, req, urlModel).URL)
+		})
+	}
+
+	// Вне всех окон времени используется основной URL.
+	resolver.now = func() time.Time { return time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC) }
+	req := httptest.NewRequest(http.MethodGet, "/id", nil)
+	req.Header.Set("User-Agent", desktopUA)
+	assert.Equal(t, "https://example.com/default", resolver.Resolve(nil, req, urlModel).URL)
+
+	// Внутри диапазона дат срабатывает сезонное правило.
+	resolver.now = func() time.Time { return time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC) }
+	assert.Equal(t, "https://example.com/winter", resolver.Resolve(nil, req, urlModel).URL)
+}
+
+func TestResolver_Nil(t *testing.T) {
+	var resolver *Resolver
+	req := httptest.NewRequest(http.MethodGet, "/id", nil)
+	urlModel := models.URLModel{URL: "https://example.com", Rules: []models.Rule{{URL: "https://other.com"}}}
+
+	assert.Equal(t, "https://example.com", resolver.Resolve(nil, req, urlModel).URL)
+}
+
+func TestValidateRules(t *testing.T) {
+	testCases := []struct {
+		name    string
+		rule    models.Rule
+		wantErr bool
+	}{
+		{name: "Valid", rule: models.Rule{Devices: []string{"ios"}, Languages: []string{"pt-BR"}, Countries: []string{"BR"}, URL: "https://example.com"}},
+		{name: "Missing URL", rule: models.Rule{Devices: []string{"ios"}}, wantErr: true},
+		{name: "Relative URL", rule: models.Rule{URL: "/path"}, wantErr: true},
+		{name: "Unknown device", rule: models.Rule{Devices: []string{"tv"}, URL: "https://example.com"}, wantErr: true},
+		{name: "Invalid country", rule: models.Rule{Countries: []string{"DEU"}, URL: "https://example.com"}, wantErr: true},
+		{name: "Half time window", rule: models.Rule{Time: &models.TimeWindow{From: "10:00"}, URL: "https://example.com"}, wantErr: true},
+		{name: "Invalid clock", rule: models.Rule{Time: &models.TimeWindow{From: "25:00", To: "10:00"}, URL: "https://example.com"}, wantErr: true},
+		{name: "Unknown timezone", rule: models.Rule{Time: &models.TimeWindow{Timezone: "Mars/Olympus"}, URL: "https://example.com"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateRules([]models.Rule{tc.rule})
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
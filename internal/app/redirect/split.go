@@ -0,0 +1,101 @@
+package redirect
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+)
+
+// variantCookieTTL определяет, как долго посетитель закреплён за выбранным вариантом.
+const variantCookieTTL = 30 * 24 * 60 * 60
+
+// NormalizeVariants присваивает вариантам без имени имена A, B, C и т.д.
+func NormalizeVariants(variants []models.Variant) []models.Variant {
+	normalized := make([]models.Variant, len(variants))
+	for i, variant := range variants {
+		if variant.Name == "" {
+			variant.Name = variantName(i)
+		}
+		normalized[i] = variant
+	}
+	return normalized
+}
+
+// ValidateVariants проверяет, что веса вариантов положительны и в сумме дают 100.
+func ValidateVariants(variants []models.Variant) error {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	total := 0
+	names := make(map[string]struct{}, len(variants))
+	for i, variant := range variants {
+		if err := ValidateURL(variant.URL); err != nil {
+			return fmt.Errorf("variants[%d]: %w", i, err)
+		}
+		if variant.Weight <= 0 {
+			return fmt.Errorf("variants[%d]: weight must be positive", i)
+		}
+		if _, ok := names[variant.Name]; ok {
+			return fmt.Errorf("variants[%d]: duplicate name %q", i, variant.Name)
+		}
+		names[variant.Name] = struct{}{}
+		total += variant.Weight
+	}
+
+	if total != 100 {
+		return fmt.Errorf("variant weights must add up to 100, got %d", total)
+	}
+	return nil
+}
+
+// chooseVariant выбирает вариант A/B-теста для посетителя.
+// Выбор закрепляется cookie, а при её отсутствии определяется хешем отпечатка клиента,
+// поэтому повторные переходы без cookie тоже попадают в тот же вариант.
+func chooseVariant(w http.ResponseWriter, r *http.Request, urlModel models.URLModel) models.Variant {
+	cookieName := "ab_" + urlModel.ID
+	if cookie, err := r.Cookie(cookieName); err == nil {
+		for _, variant := range urlModel.Variants {
+			if variant.Name == cookie.Value {
+				return variant
+			}
+		}
+	}
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s|%s|%s", urlModel.ID, visitorIP(r), r.UserAgent())
+	bucket := int(h.Sum64() % 100)
+
+	chosen := urlModel.Variants[len(urlModel.Variants)-1]
+	for _, variant := range urlModel.Variants {
+		if bucket < variant.Weight {
+			chosen = variant
+			break
+		}
+		bucket -= variant.Weight
+	}
+
+	if w != nil {
+		http.SetCookie(w, &http.Cookie{
+			Name:     cookieName,
+			Value:    chosen.Name,
+			Path:     "/" + urlModel.ID,
+			MaxAge:   variantCookieTTL,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	return chosen
+}
+
+// variantName возвращает буквенное имя варианта по индексу: A, B, ..., Z, AA, AB, ...
+func variantName(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
@@ -0,0 +1,101 @@
+package redirect
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver_Variants(t *testing.T) {
+	urlModel := models.URLModel{
+		ID:  "0dd11111",
+		URL: "https://example.com/",
+		Variants: NormalizeVariants([]models.Variant{
+			{URL: "https://example.com/a", Weight: 70},
+			{URL: "https://example.com/b", Weight: 30},
+		}),
+	}
+	resolver := NewResolver(nil)
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/0dd11111", nil)
+		req.Header.Set("User-Agent", fmt.Sprintf("client-%d", i))
+		counts[resolver.Resolve(nil, req, urlModel).Variant]++
+	}
+
+	// Распределение примерно соответствует весам.
+	assert.InDelta(t, 700, counts["A"], 100)
+	assert.InDelta(t, 300, counts["B"], 100)
+}
+
+func TestResolver_VariantStickiness(t *testing.T) {
+	urlModel := models.URLModel{
+		ID: "0dd11111",
+		Variants: []models.Variant{
+			{Name: "control", URL: "https://example.com/a", Weight: 50},
+			{Name: "test", URL: "https://example.com/b", Weight: 50},
+		},
+	}
+	resolver := NewResolver(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/0dd11111", nil)
+	req.Header.Set("User-Agent", "client")
+	rec := httptest.NewRecorder()
+	first := resolver.Resolve(rec, req, urlModel)
+
+	// Без cookie тот же клиент получает тот же вариант.
+	assert.Equal(t, first.Variant, resolver.Resolve(nil, req, urlModel).Variant)
+
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "ab_0dd11111", cookies[0].Name)
+	assert.Equal(t, first.Variant, cookies[0].Value)
+
+	// Cookie имеет приоритет над отпечатком клиента.
+	other := "control"
+	if first.Variant == "control" {
+		other = "test"
+	}
+	req = httptest.NewRequest(http.MethodGet, "/0dd11111", nil)
+	req.Header.Set("User-Agent", "client")
+	req.AddCookie(&http.Cookie{Name: "ab_0dd11111", Value: other})
+	assert.Equal(t, other, resolver.Resolve(nil, req, urlModel).Variant)
+}
+
+func TestValidateVariants(t *testing.T) {
+	testCases := []struct {
+		name     string
+		variants []models.Variant
+		wantErr  bool
+	}{
+		{name: "Empty", variants: nil},
+		{name: "Valid", variants: []models.Variant{{Name: "A", URL: "https://a.com", Weight: 60}, {Name: "B", URL: "https://b.com", Weight: 40}}},
+		{name: "Wrong total", variants: []models.Variant{{Name: "A", URL: "https://a.com", Weight: 60}, {Name: "B", URL: "https://b.com", Weight: 30}}, wantErr: true},
+		{name: "Zero weight", variants: []models.Variant{{Name: "A", URL: "https://a.com", Weight: 100}, {Name: "B", URL: "https://b.com"}}, wantErr: true},
+		{name: "Duplicate name", variants: []models.Variant{{Name: "A", URL: "https://a.com", Weight: 50}, {Name: "A", URL: "https://b.com", Weight: 50}}, wantErr: true},
+		{name: "Invalid URL", variants: []models.Variant{{Name: "A", URL: "a.com", Weight: 100}}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateVariants(tc.variants)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVariantName(t *testing.T) {
+	assert.Equal(t, "A", variantName(0))
+	assert.Equal(t, "Z", variantName(25))
+	assert.Equal(t, "AA", variantName(26))
+}
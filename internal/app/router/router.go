@@ -2,19 +2,52 @@ package router
 
 import (
 	"log"
+	"net"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/config"
+	"github.com/alexuryumtsev/go-shortener/internal/app/access"
+	"github.com/alexuryumtsev/go-shortener/internal/app/audit"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
 	"github.com/alexuryumtsev/go-shortener/internal/app/compress"
+	"github.com/alexuryumtsev/go-shortener/internal/app/enrich"
+	"github.com/alexuryumtsev/go-shortener/internal/app/geoip"
 	"github.com/alexuryumtsev/go-shortener/internal/app/handlers"
+	"github.com/alexuryumtsev/go-shortener/internal/app/idempotency"
+	"github.com/alexuryumtsev/go-shortener/internal/app/jwt"
 	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
 	"github.com/alexuryumtsev/go-shortener/internal/app/middleware"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/oidc"
+	"github.com/alexuryumtsev/go-shortener/internal/app/openapi"
+	"github.com/alexuryumtsev/go-shortener/internal/app/ratelimit"
+	"github.com/alexuryumtsev/go-shortener/internal/app/redirect"
+	"github.com/alexuryumtsev/go-shortener/internal/app/safety"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/signer"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage/file"
 	"github.com/go-chi/chi/v5"
 )
 
+// Ограничение неудачных попыток ввода пароля к защищённой ссылке.
+const (
+	passwordAttempts       = 5
+	passwordAttemptsWindow = 15 * time.Minute
+)
+
+// Срок действия JWT, выдаваемого в обмен на API-ключ.
+const accessTokenTTL = 15 * time.Minute
+
+// Дата выпуска API v2, с которой API v1 считается устаревшим, если в конфигурации
+// не задана другая дата. Клиенты сравнивают заголовок Deprecation между ответами,
+// поэтому значение по умолчанию не меняется в следующих выпусках.
+var defaultAPIV1Deprecation = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
 // ShortenerRouter создает маршруты для приложения.
-func ShortenerRouter(cfg *config.Config, repo storage.URLStorage) chi.Router {
+// Созданные и изменённые ссылки ставятся в очередь enricher на получение метаданных страницы;
+// обработчик запускается вызывающей стороной. Если enricher равен nil, метаданные не загружаются.
+func ShortenerRouter(cfg *config.Config, repo storage.URLStorage, enricher *enrich.Worker) chi.Router {
 	// Загрузка данных из файла, если используется файловое хранилище.
 	if fileRepo, ok := repo.(*file.FileStorage); ok {
 		if err := fileRepo.LoadFromFile(); err != nil {
@@ -22,17 +55,198 @@ func ShortenerRouter(cfg *config.Config, repo storage.URLStorage) chi.Router {
 		}
 	}
 
+	// Изменения ссылок, рабочих пространств и API-ключей записываются в журнал аудита хранилища.
+	backend := repo
+	recorder := audit.NewRecorder(backend)
+	repo = audit.Wrap(backend)
+
+	// Загрузка базы GeoIP для правил маршрутизации по странам.
+	var geo *geoip.DB
+	if cfg.GeoIPDBPath != "" {
+		var err error
+		if geo, err = geoip.Open(cfg.GeoIPDBPath); err != nil {
+			log.Printf("Error loading GeoIP database: %v", err)
+		}
+	}
+
+	cookieSigner := signer.NewSigner([]byte(cfg.SecretKey))
+
+	// Ключи JWT. Без файла ключей токены подписываются ключом HS256, производным от ключа подписи cookie.
+	jwtKeys, err := jwt.ConfiguredKeySet(cfg.JWTKeysFile, cfg.SecretKey)
+	if err != nil {
+		log.Printf("Error loading JWT keys: %v", err)
+	}
+	tokenAuthority := jwt.NewAuthority(jwtKeys, cfg.JWTIssuer, cfg.JWTAudience)
+	passwordLimiter := ratelimit.NewLimiter(passwordAttempts, passwordAttemptsWindow)
+
+	links := repo
+	if enricher != nil {
+		links = enricher.Wrap(repo)
+	}
+
+	// Права на действия со ссылками и рабочими пространствами проверяются политикой доступа,
+	// запросы с API-ключом дополнительно ограничены областями действия ключа.
+	policy := access.NewPolicy(repo)
+	linksRead := auth.RequireScope(models.ScopeLinksRead)
+	linksWrite := auth.RequireScope(models.ScopeLinksWrite)
+	statsRead := auth.RequireScope(models.ScopeStatsRead)
+
+	// Ответы на создание и удаление ссылок с заголовком Idempotency-Key повторяются из хранилища.
+	idempotent := idempotency.New(backend, cfg.IdempotencyTTL)
+
+	// Внутренние эндпоинты доступны только из доверенной подсети.
+	var trustedSubnet *net.IPNet
+	if cfg.TrustedSubnet != "" {
+		var err error
+		if _, trustedSubnet, err = net.ParseCIDR(cfg.TrustedSubnet); err != nil {
+			log.Printf("Error parsing trusted subnet: %v", err)
+		}
+	}
+
+	// Тела JSON-запросов проверяются по схемам спецификации OpenAPI.
+	spec := openapi.MustLoad()
+
 	// Регистрация маршрутов.
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
 	r.Use(logger.Middleware)
 	r.Use(compress.GzipMiddleware)
 	r.Use(middleware.ErrorMiddleware)
+	r.Use(auth.APIKeyMiddleware(service.NewAPIKeyResolver(repo)))
+	r.Use(auth.JWTMiddleware(tokenAuthority))
+	r.Use(auth.Middleware(cookieSigner))
+	r.Use(recorder.Middleware)
+	r.Use(openapi.Validator(spec))
+	// API v1 устарел: ответы помечаются заголовками Deprecation и Sunset.
+	deprecatedAt := cfg.APIV1Deprecation
+	if deprecatedAt.IsZero() {
+		deprecatedAt = defaultAPIV1Deprecation
+	}
+	sunset := cfg.APIV1Sunset
+	if sunset.IsZero() {
+		sunset = deprecatedAt.AddDate(1, 0, 0)
+	}
+	deprecated := middleware.Deprecation(deprecatedAt, sunset, "/api/docs#versioning")
+
+	// apiV1 регистрирует маршруты API v1 относительно префикса /api.
+	apiV1 := func(r chi.Router) {
+		r.Use(deprecated)
+		r.With(linksWrite, policy.APIKeyWorkspace(access.ActionCreate), idempotent.Middleware).Post("/shorten", handlers.PostJSONHandler(links, cfg.BaseURL))
+		r.With(linksWrite, policy.APIKeyWorkspace(access.ActionCreate), idempotent.Middleware).Post("/shorten/batch", handlers.PostBatchHandler(links, cfg.BaseURL))
+		r.With(linksWrite, policy.APIKeyWorkspace(access.ActionCreate)).Post("/shorten/stream", handlers.PostStreamHandler(links, cfg.BaseURL))
+		r.Get("/urls/{id}/qr", handlers.QRHandler(repo, cfg.BaseURL))
+		r.With(statsRead, policy.Link(access.ActionStats)).Get("/urls/{id}/stats", handlers.StatsHandler(repo))
+		r.With(linksWrite, policy.Link(access.ActionEdit)).Patch("/urls/{id}", handlers.UpdateHandler(links, cfg.BaseURL))
+		r.With(linksWrite, policy.Link(access.ActionDelete), idempotent.Middleware).Delete("/urls/{id}", handlers.DeleteURLHandler(repo, cfg.BaseURL))
+		r.With(linksRead, policy.Link(access.ActionStats)).Get("/urls/{id}/history", handlers.HistoryHandler(repo, cfg.BaseURL))
+		r.With(linksWrite, policy.Link(access.ActionEdit)).Post("/urls/{id}/rollback", handlers.RollbackHandler(links, cfg.BaseURL))
+		r.With(linksRead, policy.APIKeyWorkspace(access.ActionList)).Get("/user/urls", handlers.UserURLsHandler(repo, cfg.BaseURL))
+		r.With(linksWrite, idempotent.Middleware).Delete("/user/urls", handlers.DeleteUserURLsHandler(repo, policy, cfg.BaseURL))
+
+		// Рабочие пространства, их ссылки, участники и приглашения.
+		r.With(auth.RequireSession).Post("/workspaces", handlers.CreateWorkspaceHandler(repo))
+		r.With(auth.RequireSession).Get("/workspaces", handlers.WorkspacesHandler(repo))
+		r.With(auth.RequireSession).Post("/invitations/{token}/accept", handlers.AcceptInvitationHandler(repo))
+		r.Route("/workspaces/{workspace}", func(r chi.Router) {
+			r.With(linksRead, policy.Workspace(access.ActionList)).Get("/urls", handlers.UserURLsHandler(repo, cfg.BaseURL))
+			r.With(linksWrite, policy.Workspace(access.ActionCreate), idempotent.Middleware).Post("/urls", handlers.PostJSONHandler(links, cfg.BaseURL))
+			r.With(linksRead, policy.Workspace(access.ActionList)).Get("/members", handlers.MembersHandler(repo))
+			r.Group(func(r chi.Router) {
+				r.Use(auth.RequireSession, policy.Workspace(access.ActionManage))
+				r.Put("/members/{user}", handlers.SetMemberRoleHandler(repo))
+				r.Delete("/members/{user}", handlers.RemoveMemberHandler(repo))
+				r.Post("/invitations", handlers.InviteHandler(repo))
+				r.Get("/invitations", handlers.InvitationsHandler(repo))
+				r.Delete("/invitations/{invitation}", handlers.RevokeInvitationHandler(repo))
+				r.Get("/audit", handlers.AuditHandler(repo))
+				r.Get("/audit/export", handlers.AuditExportHandler(repo))
+			})
+		})
+
+		// Обмен API-ключа на короткоживущий JWT.
+		r.Post("/auth/token", handlers.TokenHandler(tokenAuthority, accessTokenTTL))
+
+		// Внутренняя статистика и API администратора.
+		r.With(middleware.TrustedSubnet(trustedSubnet)).Get("/internal/stats", handlers.InternalStatsHandler(repo))
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(access.Admin(cfg.AdminUsers))
+			r.Get("/urls", handlers.AdminURLsHandler(repo, cfg.BaseURL))
+			r.Get("/urls/{id}", handlers.AdminURLHandler(repo, cfg.BaseURL))
+			r.Post("/urls/{id}/disable", handlers.SetURLDisabledHandler(repo, cfg.BaseURL, true))
+			r.Post("/urls/{id}/enable", handlers.SetURLDisabledHandler(repo, cfg.BaseURL, false))
+			r.Put("/urls/{id}/owner", handlers.SetURLOwnerHandler(repo, cfg.BaseURL))
+			r.Delete("/urls/{id}", handlers.PurgeURLHandler(repo))
+		})
+
+		// Журнал аудита доступен администраторам, журнал рабочего пространства — также его владельцам.
+		r.Route("/audit", func(r chi.Router) {
+			r.Use(access.Admin(cfg.AdminUsers))
+			r.Get("/", handlers.AuditHandler(repo))
+			r.Get("/export", handlers.AuditExportHandler(repo))
+		})
+
+		// API-ключи машинных клиентов выпускаются и отзываются только пользователем.
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireSession)
+			r.Post("/keys", handlers.CreateAPIKeyHandler(repo, policy))
+			r.Get("/keys", handlers.APIKeysHandler(repo))
+			r.Delete("/keys/{id}", handlers.RevokeAPIKeyHandler(repo))
+		})
+	}
+
+	// apiV2 регистрирует маршруты API v2: ссылки как ресурсы, списки с постраничной навигацией.
+	apiV2 := func(r chi.Router) {
+		r.Route("/links", func(r chi.Router) {
+			r.With(linksRead, policy.APIKeyWorkspace(access.ActionList)).Get("/", handlers.LinksV2Handler(repo, cfg.BaseURL))
+			r.With(linksWrite, policy.APIKeyWorkspace(access.ActionCreate), idempotent.Middleware).Post("/", handlers.CreateLinkV2Handler(links, cfg.BaseURL))
+			r.With(linksWrite, policy.APIKeyWorkspace(access.ActionCreate)).Post("/stream", handlers.PostStreamHandler(links, cfg.BaseURL))
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(linksRead, policy.Link(access.ActionStats)).Get("/", handlers.LinkV2Handler(repo, cfg.BaseURL))
+				r.With(linksWrite, policy.Link(access.ActionEdit)).Patch("/", handlers.UpdateLinkV2Handler(links, cfg.BaseURL))
+				r.With(linksWrite, policy.Link(access.ActionDelete), idempotent.Middleware).Delete("/", handlers.DeleteURLHandler(repo, cfg.BaseURL))
+				r.With(statsRead, policy.Link(access.ActionStats)).Get("/stats", handlers.StatsHandler(repo))
+				r.With(linksRead, policy.Link(access.ActionStats)).Get("/history", handlers.LinkHistoryV2Handler(repo, cfg.BaseURL))
+				r.Get("/qr", handlers.QRHandler(repo, cfg.BaseURL))
+			})
+		})
+		r.Route("/workspaces/{workspace}/links", func(r chi.Router) {
+			r.With(linksRead, policy.Workspace(access.ActionList)).Get("/", handlers.LinksV2Handler(repo, cfg.BaseURL))
+			r.With(linksWrite, policy.Workspace(access.ActionCreate), idempotent.Middleware).Post("/", handlers.CreateLinkV2Handler(links, cfg.BaseURL))
+		})
+	}
+
 	r.Route("/", func(r chi.Router) {
-		r.Post("/", handlers.PostHandler(repo, cfg.BaseURL))
-		r.Get("/{id}", handlers.GetHandler(repo))
-		r.Get("/ping", handlers.PingHandler(repo))
-		r.Post("/api/shorten", handlers.PostJSONHandler(repo, cfg.BaseURL))
-		r.Post("/api/shorten/batch", handlers.PostBatchHandler(repo, cfg.BaseURL))
+		r.With(linksWrite, policy.APIKeyWorkspace(access.ActionCreate), idempotent.TextMiddleware).Post("/", handlers.PostHandler(links, cfg.BaseURL))
+		r.Get("/{id}", handlers.GetHandler(repo, safety.NewDomainList(cfg.FlaggedDomains), cookieSigner, redirect.NewResolver(geo)))
+		r.Post("/{id}", handlers.PasswordHandler(repo, cookieSigner, passwordLimiter))
+		r.Get("/ping", handlers.PingHandler(backend))
+
+		// API v1 доступен по префиксу /api/v1 и, для совместимости, по путям без версии.
+		r.Route("/api", func(r chi.Router) {
+			r.Get("/openapi.json", openapi.SpecHandler(spec))
+			r.Get("/docs", openapi.DocsHandler(spec))
+			r.Route("/v1", apiV1)
+			r.Route("/v2", apiV2)
+			r.Group(apiV1)
+		})
+
+		// Вход через провайдера OpenID Connect.
+		if cfg.OIDCIssuerURL != "" {
+			rules, err := service.ParseWorkspaceRules(cfg.OIDCWorkspaces)
+			if err != nil {
+				log.Printf("Error parsing OIDC workspace rules: %v", err)
+			}
+			provider := oidc.NewProvider(oidc.Config{
+				IssuerURL:    cfg.OIDCIssuerURL,
+				ClientID:     cfg.OIDCClientID,
+				ClientSecret: cfg.OIDCClientSecret,
+				RedirectURL:  cfg.OIDCRedirectURL,
+			}, nil)
+			sso := handlers.SSOOptions{WorkspaceClaim: cfg.OIDCWorkspaceClaim, WorkspaceRules: rules}
+			r.Get("/auth/login", handlers.LoginHandler(provider, cookieSigner))
+			r.Get("/auth/callback", handlers.CallbackHandler(provider, cookieSigner, repo, sso))
+			r.Post("/auth/logout", handlers.LogoutHandler(provider, cfg.BaseURL))
+		}
 	})
 
 	return r
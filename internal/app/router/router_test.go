@@ -0,0 +1,181 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/config"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/idempotency"
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/openapi"
+	"github.com/alexuryumtsev/go-shortener/internal/app/signer"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRoutesDocumented проверяет, что каждый маршрут описан в спецификации OpenAPI,
+// а спецификация не описывает несуществующих маршрутов.
+func TestRoutesDocumented(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	// Маршруты входа через OpenID Connect регистрируются, только если задан провайдер.
+	r := ShortenerRouter(&config.Config{
+		BaseURL:       "http://localhost:8080",
+		SecretKey:     "secret",
+		OIDCIssuerURL: "https://idp.example.com",
+	}, memory.NewInMemoryStorage(), nil)
+
+	routes := make(map[string]bool)
+	err = chi.Walk(r, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		route = strings.ReplaceAll(route, "/*/", "/")
+		route = strings.ReplaceAll(route, "//", "/")
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		routes[method+" "+route] = true
+		assert.Contains(t, spec.Methods(route), method, "route %s %s is not documented in openapi.json", method, route)
+		return nil
+	})
+	require.NoError(t, err)
+
+	for template := range spec.Paths {
+		for _, method := range spec.Methods(template) {
+			assert.True(t, routes[method+" "+template], "openapi.json documents %s %s, but the router has no such route", method, template)
+		}
+	}
+}
+
+// TestAPIVersions проверяет, что API v1 доступен по префиксу /api/v1 и по путям без версии
+// и помечен устаревшим, а API v2 — нет.
+func TestAPIVersions(t *testing.T) {
+	logger.InitLogger()
+	deprecation := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, time.March, 1, 0, 0, 0, 0, time.UTC)
+	r := ShortenerRouter(&config.Config{
+		BaseURL:          "http://localhost:8080",
+		SecretKey:        "secret",
+		APIV1Deprecation: deprecation,
+		APIV1Sunset:      sunset,
+	}, memory.NewInMemoryStorage(), nil)
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		wantCode       int
+		wantDeprecated bool
+	}{
+		{name: "v1", path: "/api/v1/shorten", body: `{"url": "https://example.com/v1"}`, wantCode: http.StatusCreated, wantDeprecated: true},
+		{name: "unversioned alias", path: "/api/shorten", body: `{"url": "https://example.com/alias"}`, wantCode: http.StatusCreated, wantDeprecated: true},
+		{name: "v2", path: "/api/v2/links", body: `{"url": "https://example.com/v2"}`, wantCode: http.StatusCreated},
+		{name: "docs", path: "/api/openapi.json", wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := http.MethodPost
+			if tt.body == "" {
+				method = http.MethodGet
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(method, tt.path, strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.wantCode, rec.Code, rec.Body.String())
+			if !tt.wantDeprecated {
+				assert.Empty(t, rec.Header().Get("Deprecation"))
+				assert.Empty(t, rec.Header().Get("Sunset"))
+				return
+			}
+			assert.Equal(t, fmt.Sprintf("@%d", deprecation.Unix()), rec.Header().Get("Deprecation"))
+			assert.Equal(t, "Mon, 01 Mar 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+
+			// Клиенты v1 по-прежнему получают короткую ссылку в поле result.
+			var resp map[string]string
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.True(t, strings.HasPrefix(resp["result"], "http://localhost:8080/"))
+		})
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	logger.InitLogger()
+	r := ShortenerRouter(&config.Config{
+		BaseURL:   "http://localhost:8080",
+		SecretKey: "secret",
+	}, memory.NewInMemoryStorage(), nil)
+
+	var cookies []*http.Cookie
+	do := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body))
+		req.Header.Set(idempotency.Header, key)
+		req.Header.Set("Accept-Encoding", "gzip")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Анонимный клиент получил бы новый идентификатор при повторе, поэтому ключ без cookie отклоняется.
+	body := `[{"correlation_id": "1", "original_url": "https://example.com/idempotent"}]`
+	anonymous := do("k1", body)
+	require.Equal(t, http.StatusUnauthorized, anonymous.Code)
+	cookies = anonymous.Result().Cookies()
+	require.NotEmpty(t, cookies)
+
+	first := do("k1", body)
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+
+	// Повтор возвращает тот же ответ, хотя ссылка уже существует.
+	replayed := do("k1", body)
+	require.Equal(t, http.StatusCreated, replayed.Code)
+	assert.Equal(t, "true", replayed.Header().Get(idempotency.ReplayedHeader))
+	assert.Equal(t, first.Header().Get("Content-Encoding"), replayed.Header().Get("Content-Encoding"))
+	assert.Equal(t, first.Body.Bytes(), replayed.Body.Bytes())
+
+	rec := do("k1", `[{"correlation_id": "1", "original_url": "https://example.com/other"}]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestIdempotencyKey_Workspace(t *testing.T) {
+	logger.InitLogger()
+	cfg := &config.Config{BaseURL: "http://localhost:8080", SecretKey: "secret"}
+	repo := memory.NewInMemoryStorage()
+	require.NoError(t, repo.CreateWorkspace(context.Background(), models.Workspace{ID: "team", Name: "Team"},
+		models.Member{WorkspaceID: "team", UserID: "alice", Role: models.RoleOwner}))
+	r := ShortenerRouter(cfg, repo, nil)
+
+	cookie := httptest.NewRecorder()
+	auth.SetCookie(cookie, signer.NewSigner([]byte(cfg.SecretKey)), "alice")
+
+	// Ссылки пространства всегда создаются заново, поэтому повтор без ключа создал бы вторую ссылку.
+	for _, target := range []string{"/api/workspaces/team/urls", "/api/v2/workspaces/team/links"} {
+		do := func() *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"url": "https://example.com/team"}`))
+			req.Header.Set(idempotency.Header, "key "+target)
+			req.AddCookie(cookie.Result().Cookies()[0])
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			return rec
+		}
+
+		first := do()
+		require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+		replayed := do()
+		require.Equal(t, http.StatusCreated, replayed.Code, target)
+		assert.Equal(t, "true", replayed.Header().Get(idempotency.ReplayedHeader), target)
+		assert.Equal(t, first.Body.String(), replayed.Body.String(), target)
+	}
+}
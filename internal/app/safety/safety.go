@@ -0,0 +1,48 @@
+package safety
+
+import (
+	"net/url"
+	"strings"
+)
+
+// DomainList хранит список доменов, помеченных как небезопасные.
+type DomainList struct {
+	domains map[string]struct{}
+}
+
+// NewDomainList создаёт список небезопасных доменов.
+func NewDomainList(domains []string) *DomainList {
+	list := &DomainList{domains: make(map[string]struct{}, len(domains))}
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" {
+			list.domains[domain] = struct{}{}
+		}
+	}
+	return list
+}
+
+// IsFlagged проверяет, относится ли URL к помеченному домену или его поддомену.
+func (l *DomainList) IsFlagged(rawURL string) bool {
+	if l == nil || len(l.domains) == 0 {
+		return false
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for host != "" {
+		if _, ok := l.domains[host]; ok {
+			return true
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			break
+		}
+		host = parent
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package safety
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainList_IsFlagged(t *testing.T) {
+	list := NewDomainList([]string{"evil.com", " Phishing.Example "})
+
+	testCases := []struct {
+		name string
+		url  string
+		want bool
+	}{
+		{name: "Exact domain", url: "https://evil.com/login", want: true},
+		{name: "Subdomain", url: "http://login.evil.com", want: true},
+		{name: "Case insensitive", url: "https://PHISHING.example/", want: true},
+		{name: "Similar domain", url: "https://notevil.com", want: false},
+		{name: "Safe domain", url: "https://practicum.yandex.ru/", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, list.IsFlagged(tc.url))
+		})
+	}
+}
+
+func TestDomainList_Nil(t *testing.T) {
+	var list *DomainList
+	assert.False(t, list.IsFlagged("https://evil.com"))
+}
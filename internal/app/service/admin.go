@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// AdminService выполняет действия администратора над ссылками любых владельцев.
+// Удалённые ссылки администратору доступны, пока они не стёрты безвозвратно.
+type AdminService struct {
+	ctx     context.Context
+	storage storage.URLStorage
+	links   *LinkService
+}
+
+func NewAdminService(ctx context.Context, storage storage.URLStorage, baseURL string) *AdminService {
+	return &AdminService{
+		ctx:     ctx,
+		storage: storage,
+		links:   NewLinkService(ctx, storage, baseURL),
+	}
+}
+
+// Stats возвращает общее количество ссылок и пользователей.
+func (s *AdminService) Stats() (models.ServiceStats, error) {
+	return s.storage.Stats(s.ctx)
+}
+
+// Search возвращает страницу ссылок всех владельцев, подходящих под фильтр. Если в фильтре задан
+// пользователь или рабочее пространство, выборка ограничивается их ссылками.
+func (s *AdminService) Search(filter models.URLFilter, cursor string) (models.AdminLinkPage, error) {
+	filter.All = true
+	urlModels, nextCursor, err := s.links.search(filter, cursor)
+	if err != nil {
+		return models.AdminLinkPage{}, err
+	}
+
+	page := models.AdminLinkPage{Items: make([]models.AdminLink, 0, len(urlModels)), NextCursor: nextCursor}
+	for _, urlModel := range urlModels {
+		page.Items = append(page.Items, s.Link(urlModel))
+	}
+	return page, nil
+}
+
+// Link преобразует ссылку в представление для ответа API администратора.
+func (s *AdminService) Link(urlModel models.URLModel) models.AdminLink {
+	return models.AdminLink{
+		LinkResponse: s.links.Link(urlModel),
+		UserID:       urlModel.UserID,
+		Disabled:     urlModel.Disabled,
+		Deleted:      urlModel.Deleted,
+	}
+}
+
+// Get возвращает ссылку по идентификатору, включая удалённые.
+func (s *AdminService) Get(id string) (models.AdminLink, error) {
+	urlModel, exists := s.storage.Get(s.ctx, id)
+	if !exists {
+		return models.AdminLink{}, storage.ErrNotFound
+	}
+	return s.Link(urlModel), nil
+}
+
+// SetDisabled отключает ссылку или снова включает её. Отключённая ссылка не открывается,
+// но остаётся у владельца.
+func (s *AdminService) SetDisabled(id string, disabled bool) (models.AdminLink, error) {
+	if err := s.storage.SetDisabled(s.ctx, id, disabled); err != nil {
+		return models.AdminLink{}, err
+	}
+	return s.Get(id)
+}
+
+// SetOwner передаёт ссылку пользователю userID, а если задан workspaceID — рабочему пространству.
+func (s *AdminService) SetOwner(id, userID, workspaceID string) (models.AdminLink, error) {
+	if userID == "" {
+		return models.AdminLink{}, fmt.Errorf("%w: user_id is required", ErrInvalidInput)
+	}
+	if workspaceID != "" {
+		if _, err := s.storage.Workspace(s.ctx, workspaceID); errors.Is(err, storage.ErrNotFound) {
+			return models.AdminLink{}, fmt.Errorf("%w: unknown workspace %q", ErrInvalidInput, workspaceID)
+		} else if err != nil {
+			return models.AdminLink{}, err
+		}
+	}
+	if err := s.storage.SetOwner(s.ctx, id, userID, workspaceID); err != nil {
+		return models.AdminLink{}, err
+	}
+	return s.Get(id)
+}
+
+// Purge безвозвратно удаляет ссылку вместе с историей изменений и статистикой переходов.
+func (s *AdminService) Purge(id string) error {
+	return s.storage.Purge(s.ctx, id)
+}
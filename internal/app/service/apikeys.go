@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// Ограничения API-ключей.
+const (
+	maxAPIKeyNameLength = 128
+	// lastUsedInterval ограничивает частоту записи времени последнего использования ключа.
+	lastUsedInterval = time.Minute
+)
+
+// apiKeyScopes перечисляет допустимые области действия API-ключей.
+var apiKeyScopes = []string{models.ScopeLinksRead, models.ScopeLinksWrite, models.ScopeStatsRead}
+
+// APIKeyService управляет API-ключами пользователя.
+type APIKeyService struct {
+	ctx     context.Context
+	storage storage.APIKeyStorage
+}
+
+func NewAPIKeyService(ctx context.Context, storage storage.APIKeyStorage) *APIKeyService {
+	return &APIKeyService{ctx: ctx, storage: storage}
+}
+
+// Create выпускает API-ключ пользователя. Если workspaceID не пуст, ключ действует только
+// в этом рабочем пространстве. Значение ключа возвращается только в ответе на этот вызов.
+func (s *APIKeyService) Create(userID, name, workspaceID string, scopes []string) (models.APIKey, error) {
+	if userID == "" {
+		return models.APIKey{}, ErrInvalidInput
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		return models.APIKey{}, fmt.Errorf("%w: name must be 1 to %d bytes long", ErrInvalidInput, maxAPIKeyNameLength)
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	prefix := auth.APIKeyPrefix + randomHex(4)
+	key := models.APIKey{
+		ID:          randomHex(8),
+		Prefix:      prefix,
+		Name:        name,
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		Scopes:      scopes,
+		CreatedAt:   time.Now().UTC(),
+	}
+	secret := prefix + "_" + randomToken()
+	if err := s.storage.SaveAPIKey(s.ctx, key, hashToken(secret)); err != nil {
+		return models.APIKey{}, err
+	}
+	key.Key = secret
+	return key, nil
+}
+
+// List возвращает API-ключи пользователя.
+func (s *APIKeyService) List(userID string) ([]models.APIKey, error) {
+	return s.storage.APIKeys(s.ctx, userID)
+}
+
+// Revoke отзывает API-ключ пользователя.
+func (s *APIKeyService) Revoke(userID, id string) error {
+	return s.storage.DeleteAPIKey(s.ctx, userID, id)
+}
+
+// normalizeScopes проверяет области действия и удаляет повторы.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidInput)
+	}
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(apiKeyScopes, scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidInput, scope)
+		}
+		if !slices.Contains(result, scope) {
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+// APIKeyResolver находит API-ключи по значению для auth.APIKeyMiddleware
+// и запоминает время их последнего использования.
+type APIKeyResolver struct {
+	storage storage.APIKeyStorage
+}
+
+func NewAPIKeyResolver(storage storage.APIKeyStorage) *APIKeyResolver {
+	return &APIKeyResolver{storage: storage}
+}
+
+// Resolve возвращает API-ключ по его значению или storage.ErrNotFound.
+func (r *APIKeyResolver) Resolve(ctx context.Context, secret string) (models.APIKey, error) {
+	key, err := r.storage.APIKeyByHash(ctx, hashToken(secret))
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	now := time.Now().UTC()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedInterval {
+		if err := r.storage.TouchAPIKey(ctx, key.ID, now); err != nil {
+			log.Printf("Error updating API key %s usage: %v", key.ID, err)
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// AuditService выдаёт события журнала аудита постранично и целиком для выгрузки.
+type AuditService struct {
+	ctx     context.Context
+	storage storage.AuditStorage
+}
+
+func NewAuditService(ctx context.Context, storage storage.AuditStorage) *AuditService {
+	return &AuditService{ctx: ctx, storage: storage}
+}
+
+// List возвращает страницу событий, подходящих под фильтр, от новых к старым.
+// Курсор cursor берётся из поля next_cursor предыдущей страницы.
+func (s *AuditService) List(filter models.AuditFilter, cursor string) (models.AuditPage, error) {
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = defaultPageSize
+	case filter.Limit < 0 || filter.Limit > maxPageSize:
+		return models.AuditPage{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidInput, maxPageSize)
+	}
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return models.AuditPage{}, fmt.Errorf("%w: invalid cursor", ErrInvalidInput)
+		}
+		filter.After = &after
+	}
+	if err := validateAuditFilter(filter); err != nil {
+		return models.AuditPage{}, err
+	}
+
+	// Запрашиваем на одно событие больше, чтобы узнать, есть ли следующая страница.
+	limit := filter.Limit
+	filter.Limit++
+	events, err := s.storage.AuditEvents(s.ctx, filter)
+	if err != nil {
+		return models.AuditPage{}, err
+	}
+
+	page := models.AuditPage{Items: events}
+	if len(events) > limit {
+		page.Items = events[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeCursor(models.Cursor{CreatedAt: last.Time, ID: last.ID})
+	}
+	return page, nil
+}
+
+// Export возвращает все события, подходящие под фильтр, от новых к старым.
+func (s *AuditService) Export(filter models.AuditFilter) ([]models.AuditEvent, error) {
+	filter.After = nil
+	filter.Limit = 0
+	if err := validateAuditFilter(filter); err != nil {
+		return nil, err
+	}
+	return s.storage.AuditEvents(s.ctx, filter)
+}
+
+func validateAuditFilter(filter models.AuditFilter) error {
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return fmt.Errorf("%w: since must be before until", ErrInvalidInput)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+)
+
+// ShortenBulk сохраняет пачку строк потока одним обращением к хранилищу и возвращает
+// результаты в порядке строк. Строки с ошибкой разбора или некорректным адресом не сохраняются,
+// повтор адреса внутри пачки считается конфликтом. Ошибка возвращается, только если
+// хранилище не смогло сохранить пачку.
+func (s *URLService) ShortenBulk(items []models.BulkItem) ([]models.BulkResult, error) {
+	results := make([]models.BulkResult, len(items))
+	urlModels := make([]models.URLModel, 0, len(items))
+	saved := make(map[string]int, len(items)) // Индекс ссылки в urlModels по адресу
+	pending := make([]int, len(items))        // Индекс ссылки в urlModels для каждой строки или -1
+	repeated := make([]bool, len(items))      // Адрес строки уже встречался в пачке
+	now := time.Now().UTC()
+
+	for i, item := range items {
+		pending[i] = -1
+		results[i] = models.BulkResult{Line: item.Line, CorrelationID: item.CorrelationID}
+		err := item.Err
+		if err == nil {
+			err = validateURL(item.URL)
+		}
+		if err != nil {
+			results[i].Status = models.BulkError
+			results[i].Error = apperr.Message(err)
+			continue
+		}
+
+		if j, ok := saved[item.URL]; ok {
+			pending[i] = j
+			repeated[i] = true
+			continue
+		}
+		saved[item.URL] = len(urlModels)
+		pending[i] = len(urlModels)
+		urlModel := models.URLModel{
+			URL:         item.URL,
+			CreatedAt:   now,
+			UserID:      auth.UserID(s.ctx),
+			WorkspaceID: auth.WorkspaceID(s.ctx),
+		}
+		urlModel.ID = newID(urlModel)
+		urlModels = append(urlModels, urlModel)
+	}
+
+	if len(urlModels) == 0 {
+		return results, nil
+	}
+	existed, err := s.storage.SaveBatch(s.ctx, urlModels)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.relocateConflicts(urlModels, existed); err != nil {
+		return nil, err
+	}
+	for i, j := range pending {
+		if j < 0 {
+			continue
+		}
+		results[i].ShortURL = s.baseURL + "/" + urlModels[j].ID
+		switch {
+		case repeated[i], existed[j]:
+			results[i].Status = models.BulkConflict
+		default:
+			results[i].Status = models.BulkCreated
+		}
+	}
+	return results, nil
+}
+
+// validateURL проверяет, что адрес назначения — абсолютный URL с хостом.
+func validateURL(rawURL string) error {
+	if rawURL == "" {
+		return apperr.New(apperr.InvalidInput, "empty URL")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return apperr.New(apperr.InvalidInput, "URL must be absolute")
+	}
+	return nil
+}
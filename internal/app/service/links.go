@@ -0,0 +1,530 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/access"
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/redirect"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// ErrInvalidInput возвращается при некорректных входных данных.
+var ErrInvalidInput = apperr.New(apperr.InvalidInput, "invalid input")
+
+// ErrSharedLink возвращается при попытке изменить переход по ссылке, идентификатор которой
+// выведен из адреса: такая ссылка выдаётся каждому, кто сокращает этот адрес.
+var ErrSharedLink = apperr.New(apperr.Conflict, "link is shared by everyone who shortened its URL")
+
+// Ограничения метаданных ссылки и размера страницы списка ссылок.
+const (
+	maxTags         = 20
+	maxTagLength    = 64
+	maxFolderLength = 128
+	maxNotesLength  = 4096
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// redirectCodes — допустимые HTTP-коды редиректа.
+var redirectCodes = map[int]struct{}{
+	http.StatusMovedPermanently:  {},
+	http.StatusFound:             {},
+	http.StatusSeeOther:          {},
+	http.StatusTemporaryRedirect: {},
+	http.StatusPermanentRedirect: {},
+}
+
+// LinkService управляет существующими ссылками: изменением, историей версий, откатом и удалением.
+// Права пользователя проверяются до вызова сервиса политикой доступа access.Policy.
+type LinkService struct {
+	ctx     context.Context
+	storage storage.URLStorage
+	baseURL string
+}
+
+func NewLinkService(ctx context.Context, storage storage.URLStorage, baseURL string) *LinkService {
+	return &LinkService{
+		ctx:     ctx,
+		storage: storage,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Link преобразует ссылку в представление для ответа API.
+func (s *LinkService) Link(urlModel models.URLModel) models.LinkResponse {
+	return models.LinkResponse{
+		ID:          urlModel.ID,
+		ShortURL:    s.baseURL + "/" + urlModel.ID,
+		CreatedAt:   urlModel.CreatedAt,
+		Clicks:      urlModel.Clicks,
+		Meta:        urlModel.Meta,
+		Workspace:   urlModel.WorkspaceID,
+		URLSettings: urlModel.Settings(),
+	}
+}
+
+// List возвращает страницу личных ссылок пользователя или ссылок рабочего пространства, подходящих под фильтр.
+// Курсор cursor берётся из поля next_cursor предыдущей страницы.
+func (s *LinkService) List(filter models.URLFilter, cursor string) (models.LinkPage, error) {
+	if filter.UserID == "" {
+		return models.LinkPage{}, access.ErrForbidden
+	}
+	filter.All = false
+	urlModels, nextCursor, err := s.search(filter, cursor)
+	if err != nil {
+		return models.LinkPage{}, err
+	}
+
+	page := models.LinkPage{Items: make([]models.LinkResponse, 0, len(urlModels)), NextCursor: nextCursor}
+	for _, urlModel := range urlModels {
+		page.Items = append(page.Items, s.Link(urlModel))
+	}
+	return page, nil
+}
+
+// Resource преобразует ссылку в ресурс API v2.
+func (s *LinkService) Resource(urlModel models.URLModel) models.Link {
+	self := s.baseURL + "/api/v2/links/" + urlModel.ID
+	tags := urlModel.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return models.Link{
+		ID:        urlModel.ID,
+		ShortURL:  s.baseURL + "/" + urlModel.ID,
+		URL:       urlModel.URL,
+		Title:     urlModel.Title,
+		Notes:     urlModel.Notes,
+		Tags:      tags,
+		Folder:    urlModel.Folder,
+		Workspace: urlModel.WorkspaceID,
+		Redirect: models.LinkRedirect{
+			Code:              urlModel.StatusCode(),
+			ExpiresAt:         urlModel.ExpiresAt,
+			MaxClicks:         urlModel.MaxClicks,
+			PasswordProtected: urlModel.PasswordHash != "",
+			Interstitial:      urlModel.Interstitial,
+			Rules:             urlModel.Rules,
+			Variants:          urlModel.Variants,
+			Params:            urlModel.Params,
+		},
+		Metadata: models.LinkMetadata{
+			CreatedAt: urlModel.CreatedAt,
+			Clicks:    urlModel.Clicks,
+			Page:      urlModel.Meta,
+		},
+		Links: models.LinkURLs{
+			Self:    self,
+			Stats:   self + "/stats",
+			History: self + "/history",
+			QR:      self + "/qr",
+		},
+	}
+}
+
+// Get возвращает ссылку как ресурс API v2.
+func (s *LinkService) Get(id string) (models.Link, error) {
+	urlModel, err := s.find(id)
+	if err != nil {
+		return models.Link{}, err
+	}
+	return s.Resource(urlModel), nil
+}
+
+// Resources возвращает страницу ссылок, как List, в представлении API v2.
+func (s *LinkService) Resources(filter models.URLFilter, cursor string) (models.LinkList, error) {
+	if filter.UserID == "" {
+		return models.LinkList{}, access.ErrForbidden
+	}
+	filter.All = false
+	urlModels, nextCursor, err := s.search(filter, cursor)
+	if err != nil {
+		return models.LinkList{}, err
+	}
+
+	list := models.LinkList{
+		Data:       make([]models.Link, 0, len(urlModels)),
+		Pagination: models.Pagination{Limit: filter.Limit, NextCursor: nextCursor},
+	}
+	if list.Pagination.Limit == 0 {
+		list.Pagination.Limit = defaultPageSize
+	}
+	for _, urlModel := range urlModels {
+		list.Data = append(list.Data, s.Resource(urlModel))
+	}
+	return list, nil
+}
+
+// search возвращает страницу ссылок, подходящих под фильтр, и курсор следующей страницы.
+func (s *LinkService) search(filter models.URLFilter, cursor string) ([]models.URLModel, string, error) {
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = defaultPageSize
+	case filter.Limit < 0 || filter.Limit > maxPageSize:
+		return nil, "", fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidInput, maxPageSize)
+	}
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: invalid cursor", ErrInvalidInput)
+		}
+		filter.After = &after
+	}
+	filter.Tag = strings.ToLower(strings.TrimSpace(filter.Tag))
+	filter.Folder = strings.TrimSpace(filter.Folder)
+
+	// Запрашиваем на одну ссылку больше, чтобы узнать, есть ли следующая страница.
+	limit := filter.Limit
+	filter.Limit++
+	urlModels, err := s.storage.Search(s.ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(urlModels) > limit {
+		urlModels = urlModels[:limit]
+		last := urlModels[limit-1]
+		nextCursor = encodeCursor(models.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	return urlModels, nextCursor, nil
+}
+
+// Update применяет к ссылке частичное изменение patch, заданное JSON-полями URLSettings.
+// Поле со значением null сбрасывается.
+func (s *LinkService) Update(id, userID string, patch map[string]json.RawMessage) (models.LinkResponse, error) {
+	urlModel, err := s.find(id)
+	if err != nil {
+		return models.LinkResponse{}, err
+	}
+
+	settings := urlModel.Settings()
+	if err := applyPatch(&settings, patch); err != nil {
+		return models.LinkResponse{}, err
+	}
+	return s.save(urlModel, userID, settings)
+}
+
+// History возвращает историю изменений ссылки.
+func (s *LinkService) History(id string) ([]models.URLVersion, error) {
+	if _, err := s.find(id); err != nil {
+		return nil, err
+	}
+	history, err := s.storage.History(s.ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []models.URLVersion{}
+	}
+	return history, nil
+}
+
+// Rollback возвращает ссылку к состоянию после указанной версии.
+// Версия 0 соответствует состоянию ссылки до первого изменения.
+// Откат сохраняется в истории как новая версия.
+func (s *LinkService) Rollback(id, userID string, version int) (models.LinkResponse, error) {
+	urlModel, err := s.find(id)
+	if err != nil {
+		return models.LinkResponse{}, err
+	}
+
+	history, err := s.storage.History(s.ctx, id)
+	if err != nil {
+		return models.LinkResponse{}, err
+	}
+
+	var settings models.URLSettings
+	switch {
+	case version == 0 && len(history) > 0:
+		settings = history[0].Before
+	case version > 0 && version <= len(history):
+		settings = history[version-1].After
+	default:
+		return models.LinkResponse{}, fmt.Errorf("%w: unknown version %d", ErrInvalidInput, version)
+	}
+	return s.save(urlModel, userID, settings)
+}
+
+// save проверяет и сохраняет новые настройки ссылки.
+func (s *LinkService) save(urlModel models.URLModel, userID string, settings models.URLSettings) (models.LinkResponse, error) {
+	settings = normalizeSettings(settings)
+	if err := validateSettings(settings); err != nil {
+		return models.LinkResponse{}, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	// Изменение без отличий не создаёт новую версию.
+	fields := urlModel.Settings().Diff(settings)
+	if len(fields) == 0 {
+		return s.Link(urlModel), nil
+	}
+	if changesRedirect(fields) {
+		shared, err := s.shared(urlModel)
+		if err != nil {
+			return models.LinkResponse{}, err
+		}
+		if shared {
+			return models.LinkResponse{}, fmt.Errorf("%w: create a link with its own settings instead", ErrSharedLink)
+		}
+	}
+
+	_, err := s.storage.Update(s.ctx, urlModel.ID, models.URLVersion{
+		ChangedBy: userID,
+		ChangedAt: time.Now().UTC(),
+		After:     settings,
+	})
+	if err != nil {
+		return models.LinkResponse{}, err
+	}
+	return s.Link(urlModel.WithSettings(settings)), nil
+}
+
+// Delete удаляет ссылки.
+func (s *LinkService) Delete(ids ...string) error {
+	return s.storage.Delete(s.ctx, ids...)
+}
+
+// DeleteAllowed удаляет ссылки, на удаление которых у пользователя есть права, и возвращает
+// их идентификаторы. Несуществующие, уже удалённые и недоступные ссылки пропускаются.
+func (s *LinkService) DeleteAllowed(policy *access.Policy, userID string, ids []string) ([]string, error) {
+	allowed := make([]string, 0, len(ids))
+	for _, id := range ids {
+		urlModel, exists := s.storage.Get(s.ctx, id)
+		if !exists || urlModel.Deleted {
+			continue
+		}
+		if err := policy.AuthorizeLink(s.ctx, userID, urlModel, access.ActionDelete); err != nil {
+			continue
+		}
+		allowed = append(allowed, id)
+	}
+
+	if len(allowed) == 0 {
+		return allowed, nil
+	}
+	if err := s.Delete(allowed...); err != nil {
+		return nil, err
+	}
+	return allowed, nil
+}
+
+// Stats возвращает статистику переходов по ссылке с разбивкой по вариантам A/B-теста.
+func (s *LinkService) Stats(id string) (models.URLStats, error) {
+	urlModel, exists := s.storage.Get(s.ctx, id)
+	if !exists {
+		return models.URLStats{}, storage.ErrNotFound
+	}
+
+	stats := models.URLStats{
+		ID:        urlModel.ID,
+		URL:       urlModel.URL,
+		CreatedAt: urlModel.CreatedAt,
+		Clicks:    urlModel.Clicks,
+		MaxClicks: urlModel.MaxClicks,
+	}
+	if len(urlModel.Variants) == 0 {
+		return stats, nil
+	}
+
+	variantClicks, err := s.storage.VariantClicks(s.ctx, id)
+	if err != nil {
+		return models.URLStats{}, fmt.Errorf("load variant clicks for %s: %w", id, err)
+	}
+	for _, variant := range urlModel.Variants {
+		stats.Variants = append(stats.Variants, models.VariantStats{
+			Variant: variant,
+			Clicks:  variantClicks[variant.Name],
+		})
+	}
+	return stats, nil
+}
+
+// redirectFields — поля настроек, определяющие, куда и как долго ведёт ссылка.
+var redirectFields = map[string]struct{}{
+	"url":           {},
+	"expires_at":    {},
+	"redirect_code": {},
+	"rules":         {},
+	"variants":      {},
+	"params":        {},
+}
+
+// changesRedirect проверяет, затрагивают ли изменённые поля переход по ссылке.
+func changesRedirect(fields []string) bool {
+	for _, field := range fields {
+		if _, ok := redirectFields[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// shared проверяет, выведен ли идентификатор ссылки из её исходного адреса. Такой идентификатор
+// возвращается всем пользователям, сокращающим тот же адрес, поэтому переход по ссылке
+// не должен зависеть от её владельца. Исходный адрес изменённой ссылки берётся из истории.
+func (s *LinkService) shared(urlModel models.URLModel) (bool, error) {
+	history, err := s.storage.History(s.ctx, urlModel.ID)
+	if err != nil {
+		return false, err
+	}
+	originalURL := urlModel.URL
+	if len(history) > 0 {
+		originalURL = history[0].Before.URL
+	}
+	return urlModel.ID == GenerateID(originalURL), nil
+}
+
+// find возвращает ссылку, удалённые ссылки считаются отсутствующими.
+func (s *LinkService) find(id string) (models.URLModel, error) {
+	urlModel, exists := s.storage.Get(s.ctx, id)
+	if !exists || urlModel.Deleted {
+		return models.URLModel{}, storage.ErrNotFound
+	}
+	return urlModel, nil
+}
+
+// applyPatch применяет JSON-поля patch к настройкам ссылки.
+func applyPatch(settings *models.URLSettings, patch map[string]json.RawMessage) error {
+	fields := map[string]any{
+		"url":           &settings.URL,
+		"expires_at":    &settings.ExpiresAt,
+		"redirect_code": &settings.RedirectCode,
+		"title":         &settings.Title,
+		"rules":         &settings.Rules,
+		"variants":      &settings.Variants,
+		"params":        &settings.Params,
+		"notes":         &settings.Notes,
+		"tags":          &settings.Tags,
+		"folder":        &settings.Folder,
+	}
+
+	for name, raw := range patch {
+		target, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidInput, name)
+		}
+		if string(raw) == "null" {
+			// Сбрасываем поле в нулевое значение.
+			v := reflect.ValueOf(target).Elem()
+			v.Set(reflect.Zero(v.Type()))
+			continue
+		}
+		if err := json.Unmarshal(raw, target); err != nil {
+			return fmt.Errorf("%w: field %q: %v", ErrInvalidInput, name, err)
+		}
+	}
+	return nil
+}
+
+// normalizeSettings приводит настройки к каноническому виду для сравнения версий.
+func normalizeSettings(settings models.URLSettings) models.URLSettings {
+	if len(settings.Rules) == 0 {
+		settings.Rules = nil
+	}
+	if len(settings.Variants) == 0 {
+		settings.Variants = nil
+	} else {
+		settings.Variants = redirect.NormalizeVariants(settings.Variants)
+	}
+	if settings.RedirectCode == http.StatusTemporaryRedirect {
+		settings.RedirectCode = 0
+	}
+	if settings.ExpiresAt != nil {
+		expiresAt := settings.ExpiresAt.UTC()
+		settings.ExpiresAt = &expiresAt
+	}
+	settings.Title = strings.TrimSpace(settings.Title)
+	settings.Folder = strings.TrimSpace(settings.Folder)
+	settings.Tags = NormalizeTags(settings.Tags)
+	return settings
+}
+
+// NormalizeTags приводит теги к нижнему регистру, удаляет пустые и повторяющиеся
+// и сортирует их по алфавиту.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]struct{}, len(tags))
+	var result []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if _, ok := seen[tag]; ok || tag == "" {
+			continue
+		}
+		seen[tag] = struct{}{}
+		result = append(result, tag)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ValidateMetadata проверяет заметки, теги и папку ссылки.
+func ValidateMetadata(settings models.URLSettings) error {
+	if len(settings.Notes) > maxNotesLength {
+		return fmt.Errorf("notes must not exceed %d bytes", maxNotesLength)
+	}
+	if len(settings.Folder) > maxFolderLength {
+		return fmt.Errorf("folder must not exceed %d bytes", maxFolderLength)
+	}
+	if len(settings.Tags) > maxTags {
+		return fmt.Errorf("at most %d tags are allowed", maxTags)
+	}
+	for _, tag := range settings.Tags {
+		if len(tag) > maxTagLength {
+			return fmt.Errorf("tag %q must not exceed %d bytes", tag, maxTagLength)
+		}
+	}
+	return nil
+}
+
+// encodeCursor кодирует позицию в списке ссылок в непрозрачную строку.
+func encodeCursor(cursor models.Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor восстанавливает позицию в списке ссылок из строки курсора.
+func decodeCursor(s string) (models.Cursor, error) {
+	var cursor models.Cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, err
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, err
+	}
+	if cursor.ID == "" {
+		return cursor, errors.New("empty cursor")
+	}
+	return cursor, nil
+}
+
+func validateSettings(settings models.URLSettings) error {
+	if err := redirect.ValidateURL(settings.URL); err != nil {
+		return err
+	}
+	if settings.RedirectCode != 0 {
+		if _, ok := redirectCodes[settings.RedirectCode]; !ok {
+			return fmt.Errorf("unsupported redirect_code %d", settings.RedirectCode)
+		}
+	}
+	if err := redirect.ValidateRules(settings.Rules); err != nil {
+		return err
+	}
+	if err := redirect.ValidateVariants(settings.Variants); err != nil {
+		return err
+	}
+	if err := ValidateMetadata(settings); err != nil {
+		return err
+	}
+	return redirect.ValidateParams(settings.Params)
+}
@@ -6,20 +6,22 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
 	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
-	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type URLService struct {
 	ctx     context.Context
-	storage storage.URLWriter
+	storage storage.URLStorage
 	baseURL string
 }
 
-func NewURLService(ctx context.Context, storage storage.URLWriter, baseURL string) *URLService {
+func NewURLService(ctx context.Context, storage storage.URLStorage, baseURL string) *URLService {
 	return &URLService{
 		ctx:     ctx,
 		storage: storage,
@@ -28,52 +30,195 @@ func NewURLService(ctx context.Context, storage storage.URLWriter, baseURL strin
 }
 
 func (s *URLService) ShortenerURL(originalURL string) (string, error) {
-	if originalURL == "" {
-		return "", fmt.Errorf("empty URL")
+	resp, err := s.ShortenerURLModel(models.URLModel{URL: originalURL})
+	return resp.ShortURL, err
+}
+
+// idAttempts — количество попыток подобрать свободный случайный идентификатор ссылки.
+const idAttempts = 5
+
+// ShortenerURLModel сохраняет ссылку с дополнительными параметрами и возвращает её короткий URL и идентификатор.
+// Идентификатор ссылки без собственных параметров выводится из адреса: если адрес уже сокращён,
+// возвращается существующая ссылка и ошибка storage.ErrConflict. Ссылка с паролем, лимитом переходов,
+// правилами и другими параметрами всегда создаётся заново со случайным идентификатором,
+// чтобы не совпасть со ссылкой другого пользователя на тот же адрес. Так же создаются ссылки
+// рабочих пространств, чтобы не выдавать их пользователям вне пространства. Случайный идентификатор
+// получает и ссылка, выведенный идентификатор которой занят ссылкой на другой адрес
+// либо удалённой или отключённой ссылкой.
+func (s *URLService) ShortenerURLModel(urlModel models.URLModel) (models.ResponseBody, error) {
+	if urlModel.URL == "" {
+		return models.ResponseBody{}, apperr.New(apperr.InvalidInput, "empty URL")
 	}
 
-	id := GenerateID(originalURL)
-	urlModel := models.URLModel{ID: id, URL: originalURL}
-	shortenedURL := s.baseURL + "/" + id
+	urlModel.CreatedAt = time.Now().UTC()
+	urlModel.Tags = NormalizeTags(urlModel.Tags)
+	if urlModel.UserID == "" {
+		urlModel.UserID = auth.UserID(s.ctx)
+	}
+	if urlModel.WorkspaceID == "" {
+		urlModel.WorkspaceID = auth.WorkspaceID(s.ctx)
+	}
+
+	if !sharedID(urlModel) {
+		return s.saveWithRandomID(urlModel)
+	}
 
+	urlModel.ID = GenerateID(urlModel.URL)
 	err := s.storage.Save(s.ctx, urlModel)
-	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-			return shortenedURL, err
+	if errors.Is(err, storage.ErrConflict) {
+		if existing, exists := s.storage.Get(s.ctx, urlModel.ID); !reusable(urlModel, existing, exists) {
+			return s.saveWithRandomID(urlModel)
 		}
+		return s.response(urlModel.ID), err
+	}
+	if err != nil {
+		return models.ResponseBody{}, err
 	}
+	return s.response(urlModel.ID), nil
+}
 
-	return shortenedURL, nil
+// sharedID проверяет, получает ли ссылка идентификатор, выведенный из адреса, общий для всех
+// пользователей, сокративших этот адрес без собственных параметров.
+func sharedID(urlModel models.URLModel) bool {
+	return urlModel.WorkspaceID == "" && !urlModel.Custom()
 }
 
-func (s *URLService) SaveBatchShortenerURL(batchModels []models.URLBatchModel) ([]string, error) {
-	var urlModels []models.URLModel
-	for _, req := range batchModels {
-		urlModels = append(urlModels, models.URLModel{
-			ID:  GenerateID(req.OriginalURL), // Функция для генерации короткого ID
-			URL: req.OriginalURL,
-		})
+// newID возвращает идентификатор новой ссылки: выведенный из адреса или случайный.
+func newID(urlModel models.URLModel) string {
+	if sharedID(urlModel) {
+		return GenerateID(urlModel.URL)
 	}
+	return randomHex(4)
+}
 
-	var shortenedURLs []string
-	for _, urlModel := range urlModels {
-		shortenedURLs = append(shortenedURLs, s.baseURL+"/"+urlModel.ID)
+// reusable проверяет, можно ли вернуть вместо urlModel сохранённую ссылку existing с тем же идентификатором.
+// Изменённая ссылка сохраняет идентификатор, выведенный из прежнего адреса, поэтому
+// совпадение идентификаторов не означает совпадения адресов. Ссылки рабочих пространств
+// не выдаются другим пользователям, а удалённые и отключённые ссылки не открываются,
+// поэтому вместо них создаётся новая ссылка.
+func reusable(urlModel, existing models.URLModel, exists bool) bool {
+	if !exists {
+		return true
 	}
+	return existing.URL == urlModel.URL && existing.WorkspaceID == "" && sharedID(urlModel) &&
+		!existing.Deleted && !existing.Disabled
+}
 
-	err := s.storage.SaveBatch(s.ctx, urlModels)
-
+// relocateConflicts сохраняет под случайными идентификаторами ссылки пачки, идентификатор
+// которых занят ссылкой, которую нельзя вернуть вместо них, и отмечает их как новые.
+// Занявшие идентификаторы ссылки читаются одним обращением к хранилищу. Повторы адреса
+// внутри пачки получают идентификатор первой перенесённой ссылки и остаются конфликтами.
+func (s *URLService) relocateConflicts(urlModels []models.URLModel, existed []bool) error {
+	var ids []string
+	for i, urlModel := range urlModels {
+		if existed[i] {
+			ids = append(ids, urlModel.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	stored, err := s.storage.GetMany(s.ctx, ids)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-			return shortenedURLs, err
+		return err
+	}
+
+	relocated := make(map[string]string) // Новый идентификатор по адресу
+	for i, urlModel := range urlModels {
+		if !existed[i] {
+			continue
+		}
+		if existing, exists := stored[urlModel.ID]; reusable(urlModel, existing, exists) {
+			continue
+		}
+		if id, ok := relocated[urlModel.URL]; ok {
+			urlModels[i].ID = id
+			continue
 		}
+		resp, err := s.saveWithRandomID(urlModel)
+		if err != nil {
+			return err
+		}
+		relocated[urlModel.URL] = resp.ID
+		urlModels[i].ID = resp.ID
+		existed[i] = false
+	}
+	return nil
+}
+
+// saveWithRandomID сохраняет ссылку под случайным идентификатором, выбирая новый при совпадении с существующим.
+func (s *URLService) saveWithRandomID(urlModel models.URLModel) (models.ResponseBody, error) {
+	for range idAttempts {
+		urlModel.ID = randomHex(4)
+		err := s.storage.Save(s.ctx, urlModel)
+		if errors.Is(err, storage.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return models.ResponseBody{}, err
+		}
+		return s.response(urlModel.ID), nil
+	}
+	return models.ResponseBody{}, errors.New("failed to allocate short URL")
+}
+
+// response возвращает короткий URL и идентификатор ссылки.
+func (s *URLService) response(id string) models.ResponseBody {
+	return models.ResponseBody{ShortURL: s.baseURL + "/" + id, ID: id}
+}
+
+// SaveBatchShortenerURL сохраняет пакет ссылок и возвращает результаты в порядке пакета.
+// Уже сокращённые адреса, в том числе повторы внутри пакета, не сохраняются повторно
+// и возвращаются со статусом models.BulkConflict. Ссылки рабочего пространства всегда создаются заново.
+func (s *URLService) SaveBatchShortenerURL(batchModels []models.URLBatchModel) ([]models.BatchResponseModel, error) {
+	var urlModels []models.URLModel
+	for _, req := range batchModels {
+		urlModel := models.URLModel{
+			URL:         req.OriginalURL,
+			CreatedAt:   time.Now().UTC(),
+			UserID:      auth.UserID(s.ctx),
+			WorkspaceID: auth.WorkspaceID(s.ctx),
+		}
+		urlModel.ID = newID(urlModel)
+		urlModels = append(urlModels, urlModel)
+	}
+
+	existed, err := s.storage.SaveBatch(s.ctx, urlModels)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.relocateConflicts(urlModels, existed); err != nil {
 		return nil, err
 	}
 
-	return shortenedURLs, nil
+	results := make([]models.BatchResponseModel, len(urlModels))
+	for i, urlModel := range urlModels {
+		results[i] = models.BatchResponseModel{
+			CorrelationID: batchModels[i].CorrelationID,
+			ShortURL:      s.baseURL + "/" + urlModel.ID,
+			Status:        models.BulkCreated,
+		}
+		if existed[i] {
+			results[i].Status = models.BulkConflict
+		}
+	}
+	return results, nil
 }
 
 func GenerateID(url string) string {
 	return fmt.Sprintf("%x", md5.Sum([]byte(url)))[:8] // Используем MD5 и берём первые 8 символов.
 }
+
+// HashPassword возвращает bcrypt-хеш пароля для защищённой ссылки.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("invalid password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword проверяет пароль по сохранённому хешу.
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/access"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/oidc"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// roleRank упорядочивает роли по возрастанию прав.
+var roleRank = map[string]int{models.RoleViewer: 1, models.RoleEditor: 2, models.RoleOwner: 3}
+
+// WorkspaceRule сопоставляет значение утверждения ID-токена (например, группу) с ролью
+// в рабочем пространстве.
+type WorkspaceRule struct {
+	Value       string
+	WorkspaceID string
+	Role        string
+}
+
+// ParseWorkspaceRules разбирает правила вида "group=workspace:role" через запятую.
+func ParseWorkspaceRules(s string) ([]WorkspaceRule, error) {
+	var rules []WorkspaceRule
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		value, target, ok := strings.Cut(item, "=")
+		workspaceID, role, ok2 := strings.Cut(target, ":")
+		if !ok || !ok2 || value == "" || workspaceID == "" {
+			return nil, fmt.Errorf("invalid workspace rule %q, expected value=workspace:role", item)
+		}
+		if !access.ValidRole(role) {
+			return nil, fmt.Errorf("invalid workspace rule %q: unknown role %q", item, role)
+		}
+		rules = append(rules, WorkspaceRule{Value: value, WorkspaceID: workspaceID, Role: role})
+	}
+	return rules, nil
+}
+
+// SSOService сопоставляет пользователей провайдера OpenID Connect с внутренними пользователями
+// и рабочими пространствами.
+type SSOService struct {
+	ctx     context.Context
+	storage storage.WorkspaceStorage
+}
+
+func NewSSOService(ctx context.Context, storage storage.WorkspaceStorage) *SSOService {
+	return &SSOService{ctx: ctx, storage: storage}
+}
+
+// SignIn возвращает внутренний идентификатор пользователя и синхронизирует его членство
+// в рабочих пространствах, упомянутых в правилах rules, со значениями утверждения claim.
+// Если пользователю подходят несколько правил одного пространства, выбирается роль с наибольшими
+// правами; если не подходит ни одно, пользователь удаляется из пространства. Роль владельца
+// не изменяется, членство в пространствах вне правил не затрагивается.
+func (s *SSOService) SignIn(identity oidc.Identity, claim string, rules []WorkspaceRule) (string, error) {
+	userID := SSOUserID(identity.Issuer, identity.Subject)
+
+	roles := make(map[string]string)
+	values := identity.Values(claim)
+	for _, rule := range rules {
+		if _, ok := roles[rule.WorkspaceID]; !ok {
+			roles[rule.WorkspaceID] = ""
+		}
+		if slices.Contains(values, rule.Value) && roleRank[rule.Role] > roleRank[roles[rule.WorkspaceID]] {
+			roles[rule.WorkspaceID] = rule.Role
+		}
+	}
+
+	for workspaceID, role := range roles {
+		if err := s.syncMember(workspaceID, userID, role); err != nil {
+			return "", err
+		}
+	}
+	return userID, nil
+}
+
+// syncMember приводит роль пользователя в рабочем пространстве к role. Пустая роль означает,
+// что пользователь не должен состоять в пространстве.
+func (s *SSOService) syncMember(workspaceID, userID, role string) error {
+	if _, err := s.storage.Workspace(s.ctx, workspaceID); errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Workspace %s from SSO mapping does not exist", workspaceID)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	member, err := s.storage.Member(s.ctx, workspaceID, userID)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		if role == "" {
+			return nil
+		}
+		member = models.Member{WorkspaceID: workspaceID, UserID: userID, JoinedAt: time.Now().UTC()}
+	case err != nil:
+		return err
+	case member.Role == role || member.Role == models.RoleOwner:
+		return nil
+	case role == "":
+		err := s.storage.DeleteMember(s.ctx, workspaceID, userID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	member.Role = role
+	return s.storage.SaveMember(s.ctx, member)
+}
+
+// SSOUserID возвращает внутренний идентификатор пользователя провайдера. Идентификатор
+// не меняется между входами и не пересекается у пользователей разных провайдеров.
+func SSOUserID(issuer, subject string) string {
+	sum := sha256.Sum256([]byte(issuer + "\x00" + subject))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/access"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// Ограничения рабочих пространств.
+const (
+	maxWorkspaceNameLength = 128
+	invitationTTL          = 7 * 24 * time.Hour
+)
+
+// WorkspaceService управляет рабочими пространствами, их участниками и приглашениями.
+// Права пользователя проверяются до вызова сервиса политикой доступа access.Policy.
+type WorkspaceService struct {
+	ctx     context.Context
+	storage storage.WorkspaceStorage
+}
+
+func NewWorkspaceService(ctx context.Context, storage storage.WorkspaceStorage) *WorkspaceService {
+	return &WorkspaceService{ctx: ctx, storage: storage}
+}
+
+// Create создаёт рабочее пространство, владельцем которого становится пользователь.
+func (s *WorkspaceService) Create(userID, name string) (models.Workspace, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxWorkspaceNameLength {
+		return models.Workspace{}, fmt.Errorf("%w: name must be 1 to %d bytes long", ErrInvalidInput, maxWorkspaceNameLength)
+	}
+
+	now := time.Now().UTC()
+	workspace := models.Workspace{ID: randomHex(8), Name: name, CreatedBy: userID, CreatedAt: now}
+	owner := models.Member{WorkspaceID: workspace.ID, UserID: userID, Role: models.RoleOwner, JoinedAt: now}
+	if err := s.storage.CreateWorkspace(s.ctx, workspace, owner); err != nil {
+		return models.Workspace{}, err
+	}
+	return workspace, nil
+}
+
+// List возвращает рабочие пространства пользователя.
+func (s *WorkspaceService) List(userID string) ([]models.Membership, error) {
+	return s.storage.UserWorkspaces(s.ctx, userID)
+}
+
+// Members возвращает участников рабочего пространства.
+func (s *WorkspaceService) Members(workspaceID string) ([]models.Member, error) {
+	return s.storage.Members(s.ctx, workspaceID)
+}
+
+// SetRole меняет роль участника. Пространство не может остаться без владельца.
+func (s *WorkspaceService) SetRole(workspaceID, userID, role string) (models.Member, error) {
+	if !access.ValidRole(role) {
+		return models.Member{}, fmt.Errorf("%w: unknown role %q", ErrInvalidInput, role)
+	}
+	member, err := s.storage.Member(s.ctx, workspaceID, userID)
+	if err != nil {
+		return models.Member{}, err
+	}
+	if member.Role == role {
+		return member, nil
+	}
+	if err := s.keepOwner(member); err != nil {
+		return models.Member{}, err
+	}
+
+	member.Role = role
+	if err := s.storage.SaveMember(s.ctx, member); err != nil {
+		return models.Member{}, err
+	}
+	return member, nil
+}
+
+// RemoveMember исключает участника. Пространство не может остаться без владельца.
+func (s *WorkspaceService) RemoveMember(workspaceID, userID string) error {
+	member, err := s.storage.Member(s.ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.keepOwner(member); err != nil {
+		return err
+	}
+	return s.storage.DeleteMember(s.ctx, workspaceID, userID)
+}
+
+// keepOwner проверяет, что у пространства останется владелец, если member перестанет им быть.
+func (s *WorkspaceService) keepOwner(member models.Member) error {
+	if member.Role != models.RoleOwner {
+		return nil
+	}
+	members, err := s.storage.Members(s.ctx, member.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		if m.Role == models.RoleOwner && m.UserID != member.UserID {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: workspace must keep at least one owner", ErrInvalidInput)
+}
+
+// Invite создаёт приглашение с ролью role. Токен приглашения возвращается только в ответе
+// на этот вызов, хранилище получает его хеш.
+func (s *WorkspaceService) Invite(workspaceID, createdBy, role string) (models.Invitation, error) {
+	if !access.ValidRole(role) {
+		return models.Invitation{}, fmt.Errorf("%w: unknown role %q", ErrInvalidInput, role)
+	}
+
+	now := time.Now().UTC()
+	invitation := models.Invitation{
+		ID:          randomHex(8),
+		WorkspaceID: workspaceID,
+		Role:        role,
+		CreatedBy:   createdBy,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(invitationTTL),
+	}
+	token := randomToken()
+	if err := s.storage.SaveInvitation(s.ctx, invitation, hashToken(token)); err != nil {
+		return models.Invitation{}, err
+	}
+	invitation.Token = token
+	return invitation, nil
+}
+
+// Invitations возвращает действующие и просроченные приглашения в рабочее пространство.
+func (s *WorkspaceService) Invitations(workspaceID string) ([]models.Invitation, error) {
+	return s.storage.Invitations(s.ctx, workspaceID)
+}
+
+// RevokeInvitation отзывает приглашение.
+func (s *WorkspaceService) RevokeInvitation(workspaceID, id string) error {
+	return s.storage.DeleteInvitation(s.ctx, workspaceID, id)
+}
+
+// Accept принимает приглашение по токену и добавляет пользователя в рабочее пространство.
+func (s *WorkspaceService) Accept(token, userID string) (models.Member, error) {
+	if userID == "" {
+		return models.Member{}, access.ErrForbidden
+	}
+	member, err := s.storage.AcceptInvitation(s.ctx, hashToken(token), userID, time.Now().UTC())
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.Member{}, fmt.Errorf("%w: invitation is invalid or expired", storage.ErrNotFound)
+	}
+	return member, err
+}
+
+// randomHex возвращает случайную строку из n байт в шестнадцатеричной записи.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// randomToken возвращает случайный токен приглашения.
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashToken возвращает хеш токена приглашения для хранения.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,68 @@
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Назначения подписанных значений. Назначение входит в подпись, поэтому значение,
+// подписанное для одного назначения, не проходит проверку для другого.
+const (
+	PurposeUser       = "user"        // Cookie с идентификатором пользователя
+	PurposeLinkUnlock = "link-unlock" // Cookie доступа к защищённой паролем ссылке
+	PurposeOIDCLogin  = "oidc-login"  // Cookie с состоянием входа через OpenID Connect
+)
+
+// Signer подписывает значения HMAC-SHA256 для хранения в cookie.
+type Signer struct {
+	secret []byte
+	now    func() time.Time
+}
+
+// NewSigner создаёт подписчик с указанным секретным ключом.
+func NewSigner(secret []byte) *Signer {
+	return &Signer{secret: secret, now: time.Now}
+}
+
+// Sign возвращает подписанный для назначения purpose токен вида value.expires.signature.
+func (s *Signer) Sign(purpose, value string, expires time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value)) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + s.signature(purpose, payload)
+}
+
+// Verify проверяет подпись токена для назначения purpose и срок его действия и возвращает исходное значение.
+func (s *Signer) Verify(purpose, token string) (string, bool) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return "", false
+	}
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(s.signature(purpose, payload))) {
+		return "", false
+	}
+
+	encoded, expiresStr, found := strings.Cut(payload, ".")
+	if !found {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil || s.now().Unix() >= expires {
+		return "", false
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+	return string(value), true
+}
+
+func (s *Signer) signature(purpose, payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(purpose + ":" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,40 @@
+package signer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigner_SignAndVerify(t *testing.T) {
+	s := NewSigner([]byte("secret"))
+	token := s.Sign(PurposeUser, "0dd11111", time.Now().Add(time.Hour))
+
+	value, ok := s.Verify(PurposeUser, token)
+	assert.True(t, ok)
+	assert.Equal(t, "0dd11111", value)
+}
+
+func TestSigner_Invalid(t *testing.T) {
+	s := NewSigner([]byte("secret"))
+	token := s.Sign(PurposeUser, "0dd11111", time.Now().Add(time.Hour))
+
+	testCases := []struct {
+		name  string
+		token string
+	}{
+		{name: "Empty", token: ""},
+		{name: "Tampered signature", token: token + "x"},
+		{name: "Other key", token: NewSigner([]byte("other")).Sign(PurposeUser, "0dd11111", time.Now().Add(time.Hour))},
+		{name: "Other purpose", token: s.Sign(PurposeLinkUnlock, "0dd11111", time.Now().Add(time.Hour))},
+		{name: "Expired", token: s.Sign(PurposeUser, "0dd11111", time.Now().Add(-time.Second))},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, ok := s.Verify(PurposeUser, tc.token)
+			assert.False(t, ok)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package apikeys
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// Типы событий изменения API-ключей.
+const (
+	KeyCreated = "key_created"
+	KeyDeleted = "key_deleted"
+	KeyUsed    = "key_used"
+)
+
+// Event описывает изменение API-ключей. Файловое хранилище сохраняет события
+// в журнал и восстанавливает по нему состояние.
+type Event struct {
+	Type    string         `json:"type"`
+	Key     *models.APIKey `json:"key,omitempty"`
+	KeyHash string         `json:"key_hash,omitempty"`
+	UserID  string         `json:"user_id,omitempty"`
+	ID      string         `json:"id,omitempty"`
+	Time    time.Time      `json:"time,omitempty"`
+}
+
+// State хранит API-ключи в памяти.
+// State не потокобезопасен, синхронизация остаётся на стороне хранилища.
+type State struct {
+	journal func(Event) error        // Вызывается перед применением каждого изменения, может быть nil
+	keys    map[string]models.APIKey // Хеш ключа → ключ
+	hashes  map[string]string        // Идентификатор ключа → хеш
+}
+
+// NewState создаёт пустое состояние. Если journal не nil, каждое изменение
+// применяется только после его успешной записи в журнал.
+func NewState(journal func(Event) error) *State {
+	return &State{
+		journal: journal,
+		keys:    make(map[string]models.APIKey),
+		hashes:  make(map[string]string),
+	}
+}
+
+// Keys возвращает ключи пользователя в порядке создания.
+func (s *State) Keys(userID string) []models.APIKey {
+	keys := []models.APIKey{}
+	for _, key := range s.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// ByHash возвращает ключ по хешу.
+func (s *State) ByHash(keyHash string) (models.APIKey, error) {
+	key, ok := s.keys[keyHash]
+	if !ok {
+		return models.APIKey{}, storage.ErrNotFound
+	}
+	return key, nil
+}
+
+// check проверяет, что событие может быть применено к текущему состоянию.
+func (s *State) check(e Event) error {
+	switch e.Type {
+	case KeyCreated:
+		if e.Key == nil || e.KeyHash == "" {
+			return fmt.Errorf("invalid %s event", e.Type)
+		}
+		if _, ok := s.hashes[e.Key.ID]; ok {
+			return fmt.Errorf("api key %s already exists", e.Key.ID)
+		}
+		return nil
+	case KeyDeleted:
+		hash, ok := s.hashes[e.ID]
+		if !ok || s.keys[hash].UserID != e.UserID {
+			return storage.ErrNotFound
+		}
+		return nil
+	case KeyUsed:
+		if _, ok := s.hashes[e.ID]; !ok {
+			return storage.ErrNotFound
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown event type %q", e.Type)
+}
+
+// Save сохраняет ключ с хешем keyHash.
+func (s *State) Save(key models.APIKey, keyHash string) error {
+	key.Key = ""
+	return s.apply(Event{Type: KeyCreated, Key: &key, KeyHash: keyHash})
+}
+
+// Delete отзывает ключ пользователя.
+func (s *State) Delete(userID, id string) error {
+	return s.apply(Event{Type: KeyDeleted, UserID: userID, ID: id})
+}
+
+// Touch запоминает время последнего использования ключа.
+func (s *State) Touch(id string, usedAt time.Time) error {
+	return s.apply(Event{Type: KeyUsed, ID: id, Time: usedAt})
+}
+
+// Replay применяет событие из журнала без повторной записи в журнал.
+func (s *State) Replay(e Event) error {
+	if err := s.check(e); err != nil {
+		return err
+	}
+	s.mutate(e)
+	return nil
+}
+
+// apply проверяет событие, записывает его в журнал и применяет.
+func (s *State) apply(e Event) error {
+	if err := s.check(e); err != nil {
+		return err
+	}
+	if s.journal != nil {
+		if err := s.journal(e); err != nil {
+			return err
+		}
+	}
+	s.mutate(e)
+	return nil
+}
+
+func (s *State) mutate(e Event) {
+	switch e.Type {
+	case KeyCreated:
+		s.keys[e.KeyHash] = *e.Key
+		s.hashes[e.Key.ID] = e.KeyHash
+	case KeyDeleted:
+		delete(s.keys, s.hashes[e.ID])
+		delete(s.hashes, e.ID)
+	case KeyUsed:
+		hash := s.hashes[e.ID]
+		key := s.keys[hash]
+		usedAt := e.Time
+		key.LastUsedAt = &usedAt
+		s.keys[hash] = key
+	}
+}
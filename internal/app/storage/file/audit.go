@@ -0,0 +1,111 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/fileutils"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// Размер журнала аудита, после которого он переименовывается в архивный файл .audit.N
+// и запись продолжается в новый файл. Архивные файлы не удаляются.
+const maxAuditFileSize = 16 << 20
+
+// AppendAudit дописывает событие в журнал аудита, при необходимости начиная новый файл журнала.
+func (s *FileStorage) AppendAudit(ctx context.Context, event models.AuditEvent) error {
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+
+	if info, err := os.Stat(s.auditPath()); err == nil && info.Size() >= s.auditMaxSize {
+		if err := s.rotateAudit(); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+	if err := fileutils.AppendJSONLine(s.auditPath(), event); err != nil {
+		return fmt.Errorf("failed to append audit event: %w", err)
+	}
+	return nil
+}
+
+// AuditEvents читает архивные и текущий файлы журнала аудита и возвращает события, подходящие под фильтр.
+func (s *FileStorage) AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+
+	archives, err := s.auditArchives()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []models.AuditEvent
+	for _, path := range append(archives, s.auditPath()) {
+		err := fileutils.ReadJSONLines(path, func(line []byte) error {
+			var event models.AuditEvent
+			if err := json.Unmarshal(line, &event); err != nil {
+				return err
+			}
+			if filter.Match(event) {
+				events = append(events, event)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log %s: %w", path, err)
+		}
+	}
+	return storage.SelectAuditEvents(events, filter), nil
+}
+
+// auditPath возвращает путь к текущему файлу журнала аудита.
+func (s *FileStorage) auditPath() string {
+	return s.filePath + ".audit"
+}
+
+// rotateAudit переименовывает текущий файл журнала аудита в архивный со следующим номером.
+// Вызывается под блокировкой auditMu.
+func (s *FileStorage) rotateAudit() error {
+	archives, err := s.auditArchives()
+	if err != nil {
+		return err
+	}
+	next := 1
+	if len(archives) > 0 {
+		next = auditArchiveNumber(s.auditPath(), archives[len(archives)-1]) + 1
+	}
+	return os.Rename(s.auditPath(), s.auditPath()+"."+strconv.Itoa(next))
+}
+
+// auditArchives возвращает архивные файлы журнала аудита от старых к новым.
+func (s *FileStorage) auditArchives() ([]string, error) {
+	matches, err := filepath.Glob(s.auditPath() + ".*")
+	if err != nil {
+		return nil, err
+	}
+	archives := make([]string, 0, len(matches))
+	for _, path := range matches {
+		if auditArchiveNumber(s.auditPath(), path) > 0 {
+			archives = append(archives, path)
+		}
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		return auditArchiveNumber(s.auditPath(), archives[i]) < auditArchiveNumber(s.auditPath(), archives[j])
+	})
+	return archives, nil
+}
+
+// auditArchiveNumber возвращает номер архивного файла журнала аудита или 0, если path не является архивом.
+func auditArchiveNumber(auditPath, path string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(path, auditPath+"."))
+	if err != nil || n < 1 {
+		return 0
+	}
+	return n
+}
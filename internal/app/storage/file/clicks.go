@@ -0,0 +1,116 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/fileutils"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// Минимальное число записей в журнале переходов, после которого журнал сворачивается в файл ссылок.
+// Журнал сворачивается, когда записей в нём больше, чем ссылок, но не меньше этого числа,
+// поэтому перезапись файла ссылок приходится в среднем на постоянное число переходов.
+const minClicksCompaction = 10000
+
+// clickRecord описывает строку журнала переходов.
+type clickRecord struct {
+	ID      string `json:"short_url"`
+	Variant string `json:"variant,omitempty"`
+}
+
+// RegisterClick увеличивает счётчик переходов, если лимит не исчерпан, и дописывает переход
+// в журнал переходов. Переходы записываются под отдельной блокировкой, поэтому запись на диск
+// не блокирует чтение ссылок.
+func (s *FileStorage) RegisterClick(ctx context.Context, click models.Click) error {
+	s.clicksMu.Lock()
+	defer s.clicksMu.Unlock()
+
+	s.mu.Lock()
+	urlModel, exists := s.data[click.ID]
+	if !exists || urlModel.Deleted {
+		s.mu.Unlock()
+		return storage.ErrNotFound
+	}
+	if urlModel.ClicksExhausted() {
+		s.mu.Unlock()
+		return storage.ErrClickLimitExceeded
+	}
+	s.data[click.ID] = urlModel.WithClick(click.Variant)
+	s.mu.Unlock()
+
+	if err := fileutils.AppendJSONLine(s.clicksPath(), clickRecord{ID: click.ID, Variant: click.Variant}); err != nil {
+		// Переход не записан: счётчик возвращается к прежнему значению.
+		s.mu.Lock()
+		if current, ok := s.data[click.ID]; ok {
+			current.Clicks = urlModel.Clicks
+			current.VariantClicks = urlModel.VariantClicks
+			s.data[click.ID] = current
+		}
+		s.mu.Unlock()
+		return fmt.Errorf("failed to append click: %w", err)
+	}
+
+	s.clickEntries++
+	if s.clickEntries >= s.clicksCompactMin && s.clickEntries > len(s.data) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err := s.rewrite(); err != nil {
+			return fmt.Errorf("failed to compact clicks: %w", err)
+		}
+	}
+	return nil
+}
+
+// clicksPath возвращает путь к журналу переходов.
+func (s *FileStorage) clicksPath() string {
+	return s.filePath + ".clicks"
+}
+
+// loadClicks применяет журнал переходов к загруженным ссылкам. Переходы по отсутствующим ссылкам
+// пропускаются. Вызывается под обеими блокировками.
+func (s *FileStorage) loadClicks() error {
+	entries := 0
+	err := fileutils.ReadJSONLines(s.clicksPath(), func(line []byte) error {
+		var rec clickRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return err
+		}
+		entries++
+		if urlModel, exists := s.data[rec.ID]; exists {
+			s.data[rec.ID] = urlModel.WithClick(rec.Variant)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load clicks: %w", err)
+	}
+	s.clickEntries = entries
+	return nil
+}
+
+// rewrite перезаписывает файл ссылок текущим состоянием и очищает журнал переходов,
+// счётчики которого вошли в записи ссылок. Вызывается под обеими блокировками.
+func (s *FileStorage) rewrite() error {
+	urlModels := make([]models.URLModel, 0, len(s.data))
+	for _, urlModel := range s.data {
+		urlModels = append(urlModels, urlModel)
+	}
+	sort.Slice(urlModels, func(i, j int) bool {
+		return models.Cursor{CreatedAt: urlModels[j].CreatedAt, ID: urlModels[j].ID}.Before(urlModels[i])
+	})
+	if err := s.fileStorage.Rewrite(urlModels); err != nil {
+		return fmt.Errorf("failed to rewrite storage: %w", err)
+	}
+	s.counter = len(urlModels)
+
+	if err := os.Remove(s.clicksPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to truncate clicks: %w", err)
+	}
+	s.clickEntries = 0
+	return nil
+}
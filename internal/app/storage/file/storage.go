@@ -2,111 +2,602 @@ package file
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/fileutils"
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/apikeys"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/idempotency"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/index"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/workspaces"
 )
 
 // FileStorage управляет сохранением и получением данных в файле.
 type FileStorage struct {
 	mu          sync.RWMutex
-	data        map[string]string
+	data        map[string]models.URLModel
+	history     map[string][]models.URLVersion
+	index       *index.Index
+	workspaces  *workspaces.State  // Рабочие пространства, восстанавливаемые из журнала событий
+	apiKeys     *apikeys.State     // API-ключи, восстанавливаемые из журнала событий
+	idempotency *idempotency.State // Ответы на идемпотентные запросы, восстанавливаемые из журнала событий
 	filePath    string
 	counter     int
 	fileStorage *fileutils.FileStorage
+	// Журнал аудита пишется в отдельные файлы под собственной блокировкой.
+	auditMu      sync.Mutex
+	auditMaxSize int64
+	// Переходы дописываются в отдельный журнал под собственной блокировкой, которая берётся до mu.
+	clicksMu         sync.Mutex
+	clickEntries     int // Число записей в журнале переходов
+	clicksCompactMin int
 }
 
 // NewFileStorage создаёт новое файловое хранилище.
 func NewFileStorage(filePath string) *FileStorage {
-	return &FileStorage{
-		data:        make(map[string]string),
-		filePath:    filePath,
-		counter:     0,
-		fileStorage: fileutils.NewFileStorage(filePath),
+	s := &FileStorage{
+		data:         make(map[string]models.URLModel),
+		history:      make(map[string][]models.URLVersion),
+		index:        index.New(),
+		filePath:     filePath,
+		counter:      0,
+		fileStorage:  fileutils.NewFileStorage(filePath),
+		auditMaxSize: maxAuditFileSize,
 	}
+	s.workspaces = workspaces.NewState(s.appendWorkspaceEvent)
+	s.apiKeys = apikeys.NewState(s.appendAPIKeyEvent)
+	s.idempotency = idempotency.NewState(s.appendIdempotencyEvent)
+	s.clicksCompactMin = minClicksCompaction
+	return s
 }
 
-// Save сохраняет URL и записывает данные в файл.
+// Save сохраняет URL и записывает данные в файл, если ссылки с таким идентификатором ещё нет.
 func (s *FileStorage) Save(ctx context.Context, urlModel models.URLModel) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Проверяем, существует ли уже оригинальный URL
-	for _, existingURL := range s.data {
-		if existingURL == urlModel.URL {
-			// Если URL уже существует, ничего не делаем
-			return nil
+	if _, exists := s.data[urlModel.ID]; exists {
+		return storage.ErrConflict
+	}
+	if err := s.appendRecord(urlModel); err != nil {
+		return err
+	}
+	s.data[urlModel.ID] = urlModel
+	s.index.Put(urlModel)
+	return nil
+}
+
+// SaveBatch дописывает в файл ссылки, которых ещё нет в хранилище, открывая файл один раз на пакет.
+func (s *FileStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) ([]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	existed := make([]bool, len(urlModels))
+	for i, urlModel := range urlModels {
+		if _, existed[i] = s.data[urlModel.ID]; existed[i] {
+			continue
 		}
+		s.counter++
+		if err := s.fileStorage.SaveRecord(file, s.counter, urlModel); err != nil {
+			return nil, err
+		}
+		s.data[urlModel.ID] = urlModel
+		s.index.Put(urlModel)
 	}
+	return existed, nil
+}
 
-	s.data[urlModel.ID] = urlModel.URL
-	s.counter++
+// Get возвращает оригинальный URL по идентификатору.
+func (s *FileStorage) Get(ctx context.Context, id string) (models.URLModel, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	urlModel, exists := s.data[id]
+	return urlModel, exists
+}
 
+// GetMany возвращает ссылки по идентификаторам.
+func (s *FileStorage) GetMany(ctx context.Context, ids []string) (map[string]models.URLModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	urlModels := make(map[string]models.URLModel, len(ids))
+	for _, id := range ids {
+		if urlModel, exists := s.data[id]; exists {
+			urlModels[id] = urlModel
+		}
+	}
+	return urlModels, nil
+}
+
+// appendRecord дописывает запись в конец файла. Вызывается под блокировкой.
+func (s *FileStorage) appendRecord(urlModel models.URLModel) error {
 	file, err := os.OpenFile(s.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
+	s.counter++
 	return s.fileStorage.SaveRecord(file, s.counter, urlModel)
 }
 
-// SaveBatch сохраняет множество URL в файл.
-func (s *FileStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) error {
+// VariantClicks возвращает количество переходов по вариантам A/B-теста.
+func (s *FileStorage) VariantClicks(ctx context.Context, id string) (map[string]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	variantClicks := make(map[string]int64, len(s.data[id].VariantClicks))
+	for name, clicks := range s.data[id].VariantClicks {
+		variantClicks[name] = clicks
+	}
+	return variantClicks, nil
+}
+
+// Update изменяет ссылку, дописывая обновлённую запись и версию в файлы хранилища.
+func (s *FileStorage) Update(ctx context.Context, id string, version models.URLVersion) (models.URLVersion, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	file, err := os.OpenFile(s.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
+	urlModel, exists := s.data[id]
+	if !exists {
+		return models.URLVersion{}, storage.ErrNotFound
+	}
+
+	version.Version = len(s.history[id]) + 1
+	version.Before = urlModel.Settings()
+	version.Fields = version.Before.Diff(version.After)
+	urlModel = urlModel.WithSettings(version.After)
+
+	if err := s.appendRecord(urlModel); err != nil {
+		return models.URLVersion{}, err
+	}
+	if err := fileutils.AppendJSONLine(s.historyPath(), historyRecord{ID: id, URLVersion: version}); err != nil {
+		return models.URLVersion{}, err
+	}
+
+	s.data[id] = urlModel
+	s.index.Put(urlModel)
+	s.history[id] = append(s.history[id], version)
+	return version, nil
+}
+
+// History возвращает историю изменений ссылки.
+func (s *FileStorage) History(ctx context.Context, id string) ([]models.URLVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]models.URLVersion(nil), s.history[id]...), nil
+}
+
+// Delete помечает ссылки удалёнными, дописывая обновлённые записи в файл.
+func (s *FileStorage) Delete(ctx context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		urlModel, exists := s.data[id]
+		if !exists || urlModel.Deleted {
+			continue
+		}
+		urlModel.Deleted = true
+		if err := s.appendRecord(urlModel); err != nil {
+			return err
+		}
+		s.data[id] = urlModel
+		s.index.Put(urlModel)
+	}
+	return nil
+}
+
+// SetMeta сохраняет метаданные страницы назначения, дописывая обновлённую запись в файл.
+func (s *FileStorage) SetMeta(ctx context.Context, id string, meta models.PageMeta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	urlModel, exists := s.data[id]
+	if !exists {
+		return storage.ErrNotFound
+	}
+	urlModel.Meta = &meta
+
+	if err := s.appendRecord(urlModel); err != nil {
 		return err
 	}
-	defer file.Close()
+	s.data[id] = urlModel
+	return nil
+}
+
+// Search ищет ссылки пользователя по инвертированному индексу.
+func (s *FileStorage) Search(ctx context.Context, filter models.URLFilter) ([]models.URLModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index.Search(s.data, filter), nil
+}
+
+// Stats возвращает количество неудалённых ссылок и их владельцев.
+func (s *FileStorage) Stats(ctx context.Context) (models.ServiceStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return storage.CountStats(s.data), nil
+}
+
+// SetDisabled отключает ссылку или снова включает её, дописывая обновлённую запись в файл.
+func (s *FileStorage) SetDisabled(ctx context.Context, id string, disabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	for _, urlModel := range urlModels {
-		// Проверяем, существует ли уже оригинальный URL
-		for _, existingURL := range s.data {
-			if existingURL == urlModel.URL {
-				// Если URL уже существует, ничего не делаем
+	urlModel, exists := s.data[id]
+	if !exists {
+		return storage.ErrNotFound
+	}
+	urlModel.Disabled = disabled
+
+	if err := s.appendRecord(urlModel); err != nil {
+		return err
+	}
+	s.data[id] = urlModel
+	return nil
+}
+
+// SetOwner передаёт ссылку другому пользователю или рабочему пространству, дописывая обновлённую запись в файл.
+func (s *FileStorage) SetOwner(ctx context.Context, id, userID, workspaceID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	urlModel, exists := s.data[id]
+	if !exists {
+		return storage.ErrNotFound
+	}
+	urlModel.UserID, urlModel.WorkspaceID = userID, workspaceID
+
+	if err := s.appendRecord(urlModel); err != nil {
+		return err
+	}
+	s.data[id] = urlModel
+	s.index.Put(urlModel)
+	return nil
+}
+
+// Purge безвозвратно удаляет ссылку и её историю. Так как файлы хранилища дописываются,
+// они перезаписываются целиком без записей удаляемой ссылки.
+func (s *FileStorage) Purge(ctx context.Context, id string) error {
+	s.clicksMu.Lock()
+	defer s.clicksMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	urlModel, exists := s.data[id]
+	if !exists {
+		return storage.ErrNotFound
+	}
+
+	delete(s.data, id)
+	if err := s.rewrite(); err != nil {
+		s.data[id] = urlModel
+		return err
+	}
+
+	if _, ok := s.history[id]; ok {
+		var records []any
+		for linkID, versions := range s.history {
+			if linkID == id {
 				continue
 			}
+			for _, version := range versions {
+				records = append(records, historyRecord{ID: linkID, URLVersion: version})
+			}
+		}
+		if err := fileutils.WriteJSONLines(s.historyPath(), records); err != nil {
+			return fmt.Errorf("failed to rewrite history: %w", err)
 		}
+	}
 
-		s.data[urlModel.ID] = urlModel.URL
-		s.counter++
+	delete(s.history, id)
+	s.index.Delete(id)
+	return nil
+}
 
-		if err := s.fileStorage.SaveRecord(file, s.counter, urlModel); err != nil {
+// historyRecord описывает строку в файле истории изменений.
+type historyRecord struct {
+	ID string `json:"short_url"`
+	models.URLVersion
+}
+
+// historyPath возвращает путь к файлу истории изменений.
+func (s *FileStorage) historyPath() string {
+	return s.filePath + ".history"
+}
+
+// loadHistory загружает историю изменений. Вызывается под блокировкой.
+func (s *FileStorage) loadHistory() error {
+	history := make(map[string][]models.URLVersion)
+	err := fileutils.ReadJSONLines(s.historyPath(), func(line []byte) error {
+		var rec historyRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
 			return err
 		}
+		history[rec.ID] = append(history[rec.ID], rec.URLVersion)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load history: %w", err)
 	}
-
+	s.history = history
 	return nil
 }
 
-// Get возвращает оригинальный URL по идентификатору.
-func (s *FileStorage) Get(ctx context.Context, id string) (models.URLModel, bool) {
+// CreateWorkspace создаёт рабочее пространство с владельцем owner.
+func (s *FileStorage) CreateWorkspace(ctx context.Context, workspace models.Workspace, owner models.Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workspaces.CreateWorkspace(workspace, owner)
+}
+
+// Workspace возвращает рабочее пространство по идентификатору.
+func (s *FileStorage) Workspace(ctx context.Context, id string) (models.Workspace, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	url, exists := s.data[id]
-	return models.URLModel{ID: id, URL: url}, exists
+	return s.workspaces.Workspace(id)
+}
+
+// UserWorkspaces возвращает рабочие пространства пользователя с его ролями.
+func (s *FileStorage) UserWorkspaces(ctx context.Context, userID string) ([]models.Membership, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.workspaces.UserWorkspaces(userID), nil
+}
+
+// Member возвращает участника рабочего пространства.
+func (s *FileStorage) Member(ctx context.Context, workspaceID, userID string) (models.Member, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.workspaces.Member(workspaceID, userID)
+}
+
+// Members возвращает участников рабочего пространства.
+func (s *FileStorage) Members(ctx context.Context, workspaceID string) ([]models.Member, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.workspaces.Members(workspaceID), nil
+}
+
+// SaveMember добавляет участника или меняет его роль.
+func (s *FileStorage) SaveMember(ctx context.Context, member models.Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workspaces.SaveMember(member)
+}
+
+// DeleteMember исключает участника из рабочего пространства.
+func (s *FileStorage) DeleteMember(ctx context.Context, workspaceID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workspaces.DeleteMember(workspaceID, userID)
+}
+
+// SaveInvitation сохраняет приглашение в рабочее пространство.
+func (s *FileStorage) SaveInvitation(ctx context.Context, invitation models.Invitation, tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workspaces.SaveInvitation(invitation, tokenHash)
+}
+
+// Invitations возвращает приглашения в рабочее пространство.
+func (s *FileStorage) Invitations(ctx context.Context, workspaceID string) ([]models.Invitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.workspaces.Invitations(workspaceID), nil
+}
+
+// DeleteInvitation отзывает приглашение.
+func (s *FileStorage) DeleteInvitation(ctx context.Context, workspaceID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workspaces.DeleteInvitation(workspaceID, id)
+}
+
+// AcceptInvitation принимает приглашение и добавляет пользователя в рабочее пространство.
+func (s *FileStorage) AcceptInvitation(ctx context.Context, tokenHash, userID string, now time.Time) (models.Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workspaces.AcceptInvitation(tokenHash, userID, now)
+}
+
+// SaveAPIKey сохраняет API-ключ с хешем keyHash.
+func (s *FileStorage) SaveAPIKey(ctx context.Context, key models.APIKey, keyHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apiKeys.Save(key, keyHash)
+}
+
+// APIKeys возвращает API-ключи пользователя.
+func (s *FileStorage) APIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.apiKeys.Keys(userID), nil
+}
+
+// APIKeyByHash возвращает API-ключ по хешу.
+func (s *FileStorage) APIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.apiKeys.ByHash(keyHash)
+}
+
+// DeleteAPIKey отзывает API-ключ пользователя.
+func (s *FileStorage) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apiKeys.Delete(userID, id)
+}
+
+// TouchAPIKey запоминает время последнего использования API-ключа.
+func (s *FileStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apiKeys.Touch(id, usedAt)
+}
+
+// ReserveIdempotencyKey закрепляет ключ идемпотентности за запросом.
+func (s *FileStorage) ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.idempotency.Reserve(record)
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с ключом идемпотентности.
+func (s *FileStorage) CompleteIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.idempotency.Complete(record)
+}
+
+// ReleaseIdempotencyKey освобождает ключ идемпотентности незавершённого запроса.
+func (s *FileStorage) ReleaseIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.idempotency.Release(record)
+}
+
+// PurgeIdempotencyKeys удаляет записи ключей идемпотентности с истёкшим сроком хранения
+// и сжимает журнал: он перезаписывается событиями, восстанавливающими оставшиеся записи.
+func (s *FileStorage) PurgeIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purged := s.idempotency.Purge(now)
+	var events []any
+	for _, event := range s.idempotency.Events() {
+		events = append(events, event)
+	}
+	if err := fileutils.WriteJSONLines(s.idempotencyPath(), events); err != nil {
+		return purged, fmt.Errorf("failed to compact idempotency keys: %w", err)
+	}
+	return purged, nil
+}
+
+// workspacesPath возвращает путь к журналу событий рабочих пространств.
+func (s *FileStorage) workspacesPath() string {
+	return s.filePath + ".workspaces"
+}
+
+// appendWorkspaceEvent дописывает событие в журнал рабочих пространств. Вызывается под блокировкой.
+func (s *FileStorage) appendWorkspaceEvent(event workspaces.Event) error {
+	return fileutils.AppendJSONLine(s.workspacesPath(), event)
+}
+
+// loadWorkspaces восстанавливает рабочие пространства из журнала. Вызывается под блокировкой.
+func (s *FileStorage) loadWorkspaces() error {
+	state := workspaces.NewState(s.appendWorkspaceEvent)
+	err := fileutils.ReadJSONLines(s.workspacesPath(), func(line []byte) error {
+		var event workspaces.Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			return err
+		}
+		return state.Replay(event)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load workspaces: %w", err)
+	}
+	s.workspaces = state
+	return nil
+}
+
+// apiKeysPath возвращает путь к журналу событий API-ключей.
+func (s *FileStorage) apiKeysPath() string {
+	return s.filePath + ".apikeys"
+}
+
+// appendAPIKeyEvent дописывает событие в журнал API-ключей. Вызывается под блокировкой.
+func (s *FileStorage) appendAPIKeyEvent(event apikeys.Event) error {
+	return fileutils.AppendJSONLine(s.apiKeysPath(), event)
+}
+
+// loadAPIKeys восстанавливает API-ключи из журнала. Вызывается под блокировкой.
+func (s *FileStorage) loadAPIKeys() error {
+	state := apikeys.NewState(s.appendAPIKeyEvent)
+	err := fileutils.ReadJSONLines(s.apiKeysPath(), func(line []byte) error {
+		var event apikeys.Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			return err
+		}
+		return state.Replay(event)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load api keys: %w", err)
+	}
+	s.apiKeys = state
+	return nil
+}
+
+// idempotencyPath возвращает путь к журналу событий ключей идемпотентности.
+func (s *FileStorage) idempotencyPath() string {
+	return s.filePath + ".idempotency"
+}
+
+// appendIdempotencyEvent дописывает событие в журнал ключей идемпотентности. Вызывается под блокировкой.
+func (s *FileStorage) appendIdempotencyEvent(event idempotency.Event) error {
+	return fileutils.AppendJSONLine(s.idempotencyPath(), event)
+}
+
+// loadIdempotency восстанавливает ключи идемпотентности из журнала. Вызывается под блокировкой.
+func (s *FileStorage) loadIdempotency() error {
+	state := idempotency.NewState(s.appendIdempotencyEvent)
+	err := fileutils.ReadJSONLines(s.idempotencyPath(), func(line []byte) error {
+		var event idempotency.Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			return err
+		}
+		return state.Replay(event)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load idempotency keys: %w", err)
+	}
+	s.idempotency = state
+	return nil
+}
+
+// loadJournals восстанавливает данные из журналов рабочих пространств, API-ключей,
+// ключей идемпотентности и истории. Вызывается под блокировкой.
+func (s *FileStorage) loadJournals() error {
+	if err := s.loadWorkspaces(); err != nil {
+		return err
+	}
+	if err := s.loadAPIKeys(); err != nil {
+		return err
+	}
+	if err := s.loadIdempotency(); err != nil {
+		return err
+	}
+	return s.loadHistory()
 }
 
 // LoadFromFile загружает данные из файла.
 func (s *FileStorage) LoadFromFile() error {
+	s.clicksMu.Lock()
+	defer s.clicksMu.Unlock()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	file, err := os.Open(s.filePath)
 	if os.IsNotExist(err) {
-		// Если файл не существует, создаем его.
+		// Если файл не существует, создаем его. Журналы ведутся в отдельных файлах
+		// и могут существовать без ссылок.
 		file, err = os.Create(s.filePath)
 		if err != nil {
 			return err
 		}
-		defer file.Close()
-		return nil
+		file.Close()
+		if err := s.loadClicks(); err != nil {
+			return err
+		}
+		return s.loadJournals()
 	} else if err != nil {
 		return err
 	}
@@ -118,14 +609,21 @@ func (s *FileStorage) LoadFromFile() error {
 	}
 
 	// Валидация формата данных
-	for shortURL, originalURL := range data {
-		if shortURL == "" || originalURL == "" {
+	for shortURL, urlModel := range data {
+		if shortURL == "" || urlModel.URL == "" {
 			return fmt.Errorf("invalid data format: short_url or original_url is empty")
 		}
 	}
 
 	s.data = data
-	return nil
+	if err := s.loadClicks(); err != nil {
+		return err
+	}
+	s.index = index.New()
+	for _, urlModel := range s.data {
+		s.index.Put(urlModel)
+	}
+	return s.loadJournals()
 }
 
 // Ping проверяет соединение с базой данных (для файлового хранилища всегда возвращает nil).
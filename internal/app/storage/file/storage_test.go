@@ -3,11 +3,18 @@ package file
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	appstorage "github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/storagetest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStorage_SaveAndLoad(t *testing.T) {
@@ -61,6 +68,43 @@ func TestStorage_SaveAndLoad(t *testing.T) {
 	assert.Equal(t, urlModel3, result)
 }
 
+func TestStorage_LinkIsolation(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	storagetest.LinkIsolation(t, NewFileStorage(filePath), "https://docs.example.com/secret")
+
+	// После перезапуска ссылки восстанавливаются с прежними владельцами.
+	restored := NewFileStorage(filePath)
+	require.NoError(t, restored.LoadFromFile())
+	stats, err := restored.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, models.ServiceStats{URLs: 2, Users: 2}, stats)
+}
+
+func TestStorage_ClickLimitIsolation(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	storagetest.ClickLimitIsolation(t, NewFileStorage(filePath), "https://example.com/invite")
+}
+
+func TestStorage_EditedLinkIsolation(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	storagetest.EditedLinkIsolation(t, NewFileStorage(filePath), "https://example.com/")
+}
+
+func TestStorage_WorkspaceLinkIsolation(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	storagetest.WorkspaceLinkIsolation(t, NewFileStorage(filePath), "https://example.com/team")
+}
+
+func TestStorage_RemovedLinkIsolation(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	storagetest.RemovedLinkIsolation(t, NewFileStorage(filePath), "https://example.com/")
+}
+
+func TestStorage_BatchConflicts(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	storagetest.BatchConflicts(t, NewFileStorage(filePath), "https://example.com/")
+}
+
 func TestStorage_SaveToFileFormat(t *testing.T) {
 	filePath := "test_storage_format.json"
 	defer os.Remove(filePath)
@@ -84,3 +128,372 @@ func TestStorage_SaveToFileFormat(t *testing.T) {
 	assert.Equal(t, "4rSPg8ap", record["short_url"])
 	assert.Equal(t, "http://yandex.ru", record["original_url"])
 }
+
+func TestStorage_RegisterClick(t *testing.T) {
+	filePath := "test_storage_clicks.json"
+	defer os.Remove(filePath)
+	defer os.Remove(filePath + ".clicks")
+
+	storage := NewFileStorage(filePath)
+	ctx := context.Background()
+	err := storage.Save(ctx, models.URLModel{ID: "4rSPg8ap", URL: "http://yandex.ru", MaxClicks: 2})
+	assert.NoError(t, err)
+
+	assert.NoError(t, storage.RegisterClick(ctx, models.Click{ID: "4rSPg8ap", Variant: "A"}))
+	assert.NoError(t, storage.RegisterClick(ctx, models.Click{ID: "4rSPg8ap"}))
+	assert.ErrorIs(t, storage.RegisterClick(ctx, models.Click{ID: "4rSPg8ap"}), appstorage.ErrClickLimitExceeded)
+
+	// Счётчик и лимит переходов восстанавливаются из файла.
+	newStorage := NewFileStorage(filePath)
+	err = newStorage.LoadFromFile()
+	assert.NoError(t, err)
+
+	result, exists := newStorage.Get(ctx, "4rSPg8ap")
+	assert.True(t, exists)
+	assert.Equal(t, int64(2), result.Clicks)
+	assert.True(t, result.ClicksExhausted())
+
+	variantClicks, err := newStorage.VariantClicks(ctx, "4rSPg8ap")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"A": 1}, variantClicks)
+}
+
+func TestStorage_ClicksCompaction(t *testing.T) {
+	filePath := "test_storage_clicks_compaction.json"
+	defer os.Remove(filePath)
+	defer os.Remove(filePath + ".clicks")
+
+	storage := NewFileStorage(filePath)
+	storage.clicksCompactMin = 3
+	ctx := context.Background()
+	require.NoError(t, storage.Save(ctx, models.URLModel{ID: "abc", URL: "https://example.com"}))
+
+	lines := func(path string) int {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			return 0
+		}
+		require.NoError(t, err)
+		return strings.Count(string(data), "\n")
+	}
+
+	// Переход дописывает в журнал короткую запись, а не копию ссылки.
+	require.NoError(t, storage.RegisterClick(ctx, models.Click{ID: "abc"}))
+	require.NoError(t, storage.RegisterClick(ctx, models.Click{ID: "abc", Variant: "B"}))
+	assert.Equal(t, 1, lines(filePath))
+	assert.Equal(t, 2, lines(filePath+".clicks"))
+
+	// После clicksCompactMin записей журнал сворачивается в файл ссылок.
+	require.NoError(t, storage.RegisterClick(ctx, models.Click{ID: "abc"}))
+	assert.Equal(t, 1, lines(filePath))
+	assert.Zero(t, lines(filePath+".clicks"))
+	require.NoError(t, storage.RegisterClick(ctx, models.Click{ID: "abc"}))
+
+	restored := NewFileStorage(filePath)
+	require.NoError(t, restored.LoadFromFile())
+	result, exists := restored.Get(ctx, "abc")
+	require.True(t, exists)
+	assert.Equal(t, int64(4), result.Clicks)
+	assert.Equal(t, map[string]int64{"B": 1}, result.VariantClicks)
+
+	// Удаление ссылки перезаписывает файл и очищает журнал переходов.
+	require.NoError(t, restored.Purge(ctx, "abc"))
+	assert.Zero(t, lines(filePath))
+	assert.Zero(t, lines(filePath+".clicks"))
+}
+
+func TestStorage_UpdateHistory(t *testing.T) {
+	filePath := "test_storage_history.json"
+	defer os.Remove(filePath)
+	defer os.Remove(filePath + ".history")
+
+	storage := NewFileStorage(filePath)
+	ctx := context.Background()
+	err := storage.Save(ctx, models.URLModel{ID: "4rSPg8ap", URL: "http://yandex.ru"})
+	assert.NoError(t, err)
+
+	version, err := storage.Update(ctx, "4rSPg8ap", models.URLVersion{
+		ChangedBy: "owner",
+		After:     models.URLSettings{URL: "http://ya.ru", Title: "Ya"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version.Version)
+	assert.Equal(t, "http://yandex.ru", version.Before.URL)
+
+	// Изменения и история восстанавливаются из файлов.
+	newStorage := NewFileStorage(filePath)
+	err = newStorage.LoadFromFile()
+	assert.NoError(t, err)
+
+	result, exists := newStorage.Get(ctx, "4rSPg8ap")
+	assert.True(t, exists)
+	assert.Equal(t, "http://ya.ru", result.URL)
+	assert.Equal(t, "Ya", result.Title)
+
+	history, err := newStorage.History(ctx, "4rSPg8ap")
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, "owner", history[0].ChangedBy)
+
+	_, err = newStorage.Update(ctx, "unknown", models.URLVersion{})
+	assert.ErrorIs(t, err, appstorage.ErrNotFound)
+}
+
+func TestStorage_WorkspacesReplay(t *testing.T) {
+	filePath := "test_storage_workspaces.json"
+	defer os.Remove(filePath)
+	defer os.Remove(filePath + ".workspaces")
+
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	storage := NewFileStorage(filePath)
+
+	workspace := models.Workspace{ID: "ws1", Name: "Marketing", CreatedBy: "alice", CreatedAt: now}
+	owner := models.Member{WorkspaceID: "ws1", UserID: "alice", Role: models.RoleOwner, JoinedAt: now}
+	assert.NoError(t, storage.CreateWorkspace(ctx, workspace, owner))
+	assert.Error(t, storage.CreateWorkspace(ctx, workspace, owner))
+
+	invitation := models.Invitation{ID: "inv1", WorkspaceID: "ws1", Role: models.RoleEditor, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, storage.SaveInvitation(ctx, invitation, "hash1"))
+	expired := models.Invitation{ID: "inv2", WorkspaceID: "ws1", Role: models.RoleViewer, CreatedAt: now, ExpiresAt: now}
+	assert.NoError(t, storage.SaveInvitation(ctx, expired, "hash2"))
+
+	member, err := storage.AcceptInvitation(ctx, "hash1", "bob", now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleEditor, member.Role)
+	_, err = storage.AcceptInvitation(ctx, "hash1", "carol", now.Add(time.Minute))
+	assert.ErrorIs(t, err, appstorage.ErrNotFound)
+	_, err = storage.AcceptInvitation(ctx, "hash2", "carol", now.Add(time.Minute))
+	assert.ErrorIs(t, err, appstorage.ErrNotFound)
+
+	member.Role = models.RoleViewer
+	assert.NoError(t, storage.SaveMember(ctx, member))
+
+	// Состояние восстанавливается из журнала.
+	restored := NewFileStorage(filePath)
+	assert.NoError(t, restored.LoadFromFile())
+
+	members, err := restored.Members(ctx, "ws1")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Member{owner, member}, members)
+
+	invitations, err := restored.Invitations(ctx, "ws1")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Invitation{expired}, invitations)
+
+	memberships, err := restored.UserWorkspaces(ctx, "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Membership{{Workspace: workspace, Role: models.RoleViewer}}, memberships)
+}
+
+func TestStorage_APIKeysReplay(t *testing.T) {
+	filePath := "test_storage_apikeys.json"
+	defer os.Remove(filePath)
+	defer os.Remove(filePath + ".apikeys")
+
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	storage := NewFileStorage(filePath)
+
+	key := models.APIKey{ID: "k1", Prefix: "sk_0001", Name: "ci", UserID: "alice", Scopes: []string{models.ScopeLinksWrite}, CreatedAt: now}
+	assert.NoError(t, storage.SaveAPIKey(ctx, key, "hash1"))
+	assert.NoError(t, storage.SaveAPIKey(ctx, models.APIKey{ID: "k2", UserID: "alice", CreatedAt: now.Add(time.Minute)}, "hash2"))
+	assert.NoError(t, storage.TouchAPIKey(ctx, "k1", now.Add(time.Hour)))
+	assert.ErrorIs(t, storage.DeleteAPIKey(ctx, "bob", "k2"), appstorage.ErrNotFound)
+	assert.NoError(t, storage.DeleteAPIKey(ctx, "alice", "k2"))
+
+	restored := NewFileStorage(filePath)
+	assert.NoError(t, restored.LoadFromFile())
+
+	usedAt := now.Add(time.Hour)
+	key.LastUsedAt = &usedAt
+	restoredKey, err := restored.APIKeyByHash(ctx, "hash1")
+	assert.NoError(t, err)
+	assert.Equal(t, key, restoredKey)
+	_, err = restored.APIKeyByHash(ctx, "hash2")
+	assert.ErrorIs(t, err, appstorage.ErrNotFound)
+
+	keys, err := restored.APIKeys(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
+
+func TestStorage_IdempotencyReplay(t *testing.T) {
+	filePath := "test_storage_idempotency.json"
+	defer os.Remove(filePath)
+	defer os.Remove(filePath + ".idempotency")
+
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	storage := NewFileStorage(filePath)
+
+	record := models.IdempotencyRecord{UserID: "alice", Key: "k1", RequestHash: "h1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	_, reserved, err := storage.ReserveIdempotencyKey(ctx, record)
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+	record.Status = 201
+	record.Header = map[string][]string{"Content-Type": {"application/json"}}
+	record.Body = []byte(`{"result":"http://localhost/abc"}`)
+	assert.NoError(t, storage.CompleteIdempotencyKey(ctx, record))
+
+	// Незавершённый запрос освобождает ключ.
+	pending := models.IdempotencyRecord{UserID: "alice", Key: "k2", CreatedAt: now, ExpiresAt: now.Add(time.Hour), LockedUntil: now.Add(time.Hour)}
+	_, reserved, err = storage.ReserveIdempotencyKey(ctx, pending)
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+	assert.NoError(t, storage.ReleaseIdempotencyKey(ctx, pending))
+
+	restored := NewFileStorage(filePath)
+	assert.NoError(t, restored.LoadFromFile())
+
+	existing, reserved, err := restored.ReserveIdempotencyKey(ctx, models.IdempotencyRecord{UserID: "alice", Key: "k1", CreatedAt: now.Add(time.Minute)})
+	assert.NoError(t, err)
+	assert.False(t, reserved)
+	record.Completed = true
+	assert.Equal(t, record, existing)
+
+	_, reserved, err = restored.ReserveIdempotencyKey(ctx, models.IdempotencyRecord{UserID: "alice", Key: "k2", CreatedAt: now.Add(time.Minute)})
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+
+	// Истёкший ключ закрепляется заново.
+	_, reserved, err = restored.ReserveIdempotencyKey(ctx, models.IdempotencyRecord{UserID: "alice", Key: "k1", CreatedAt: now.Add(2 * time.Hour)})
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+}
+
+func TestStorage_IdempotencyPurge(t *testing.T) {
+	filePath := "test_storage_idempotency_purge.json"
+	defer os.Remove(filePath)
+	defer os.Remove(filePath + ".idempotency")
+
+	storage := NewFileStorage(filePath)
+	storagetest.IdempotencyPurge(t, storage, "alice")
+
+	// Журнал сжат до событий оставшихся записей: закрепление и ответ завершённого запроса
+	// и закрепление выполняющегося.
+	data, err := os.ReadFile(filePath + ".idempotency")
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(data), "\n"))
+
+	restored := NewFileStorage(filePath)
+	require.NoError(t, restored.LoadFromFile())
+	purged, err := restored.PurgeIdempotencyKeys(context.Background(), time.Date(2000, time.January, 1, 14, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+}
+
+func TestStorage_IdempotencyLease(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "storage.json")
+	storagetest.IdempotencyLease(t, NewFileStorage(filePath), "alice")
+
+	// Перехват ключа восстанавливается из журнала.
+	restored := NewFileStorage(filePath)
+	require.NoError(t, restored.LoadFromFile())
+	now := time.Date(2000, time.January, 1, 12, 10, 0, 0, time.UTC)
+	existing, reserved, err := restored.ReserveIdempotencyKey(context.Background(),
+		models.IdempotencyRecord{UserID: "alice", Key: "lease", CreatedAt: now})
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	assert.True(t, existing.Completed)
+	assert.Equal(t, []byte("created"), existing.Body)
+}
+
+func TestStorage_AdminActions(t *testing.T) {
+	filePath := "test_storage_admin.json"
+	defer os.Remove(filePath)
+	defer os.Remove(filePath + ".history")
+
+	storage := NewFileStorage(filePath)
+	ctx := context.Background()
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, storage.Save(ctx, models.URLModel{ID: "keep", URL: "http://ya.ru", UserID: "alice", CreatedAt: created}))
+	assert.NoError(t, storage.Save(ctx, models.URLModel{ID: "purge", URL: "http://spam.example", UserID: "bob", CreatedAt: created}))
+	_, err := storage.Update(ctx, "purge", models.URLVersion{ChangedBy: "bob", After: models.URLSettings{URL: "http://spam.example/2"}})
+	assert.NoError(t, err)
+	_, err = storage.Update(ctx, "keep", models.URLVersion{ChangedBy: "alice", After: models.URLSettings{URL: "http://ya.ru/2"}})
+	assert.NoError(t, err)
+
+	assert.NoError(t, storage.SetDisabled(ctx, "keep", true))
+	assert.NoError(t, storage.SetOwner(ctx, "keep", "carol", ""))
+	assert.NoError(t, storage.Purge(ctx, "purge"))
+	assert.ErrorIs(t, storage.Purge(ctx, "purge"), appstorage.ErrNotFound)
+	assert.ErrorIs(t, storage.SetDisabled(ctx, "missing", true), appstorage.ErrNotFound)
+
+	stats, err := storage.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ServiceStats{URLs: 1, Users: 1}, stats)
+
+	// Стёртая ссылка не восстанавливается из файлов, остальные изменения сохраняются.
+	loaded := NewFileStorage(filePath)
+	assert.NoError(t, loaded.LoadFromFile())
+	_, exists := loaded.Get(ctx, "purge")
+	assert.False(t, exists)
+	history, err := loaded.History(ctx, "purge")
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+
+	keep, exists := loaded.Get(ctx, "keep")
+	assert.True(t, exists)
+	assert.True(t, keep.Disabled)
+	assert.Equal(t, "carol", keep.UserID)
+	assert.Equal(t, "http://ya.ru/2", keep.URL)
+	history, err = loaded.History(ctx, "keep")
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	links, err := loaded.Search(ctx, models.URLFilter{UserID: "carol"})
+	assert.NoError(t, err)
+	assert.Len(t, links, 1)
+	links, err = loaded.Search(ctx, models.URLFilter{All: true})
+	assert.NoError(t, err)
+	assert.Len(t, links, 1)
+}
+
+func TestStorage_AuditLogRotation(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "storage.json")
+
+	storage := NewFileStorage(filePath)
+	storage.auditMaxSize = 200
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		event := models.AuditEvent{
+			ID:     fmt.Sprintf("event%02d", i),
+			Time:   start.Add(time.Duration(i) * time.Minute),
+			Action: "link.update",
+			Actor:  "alice",
+			Target: "abc",
+			After:  json.RawMessage(`{"url":"https://example.com"}`),
+		}
+		if i%2 == 1 {
+			event.Actor = "bob"
+		}
+		assert.NoError(t, storage.AppendAudit(ctx, event))
+	}
+
+	archives, err := filepath.Glob(filePath + ".audit.*")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, archives)
+
+	// События читаются из архивных и текущего файлов журнала, в том числе новым экземпляром хранилища.
+	loaded := NewFileStorage(filePath)
+	events, err := loaded.AuditEvents(ctx, models.AuditFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, events, 10)
+	assert.Equal(t, "event09", events[0].ID)
+	assert.Equal(t, "event00", events[9].ID)
+
+	events, err = loaded.AuditEvents(ctx, models.AuditFilter{
+		Actor: "bob",
+		Since: start.Add(2 * time.Minute),
+		After: &models.Cursor{CreatedAt: start.Add(9 * time.Minute), ID: "event09"},
+		Limit: 2,
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "event07", events[0].ID)
+		assert.Equal(t, "event05", events[1].ID)
+	}
+}
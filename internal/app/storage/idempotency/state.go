@@ -0,0 +1,170 @@
+package idempotency
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// Типы событий изменения ключей идемпотентности.
+const (
+	KeyReserved  = "key_reserved"
+	KeyCompleted = "key_completed"
+	KeyReleased  = "key_released"
+)
+
+// Event описывает изменение записи ключа идемпотентности. Файловое хранилище сохраняет события
+// в журнал и восстанавливает по нему состояние.
+type Event struct {
+	Type   string                   `json:"type"`
+	Record models.IdempotencyRecord `json:"record"`
+}
+
+// recordKey — ключ записи: ключи идемпотентности разных пользователей не пересекаются.
+type recordKey struct {
+	userID string
+	key    string
+}
+
+// State хранит записи ключей идемпотентности в памяти.
+// State не потокобезопасен, синхронизация остаётся на стороне хранилища.
+type State struct {
+	journal func(Event) error // Вызывается перед применением каждого изменения, может быть nil
+	records map[recordKey]models.IdempotencyRecord
+}
+
+// NewState создаёт пустое состояние. Если journal не nil, каждое изменение
+// применяется только после его успешной записи в журнал.
+func NewState(journal func(Event) error) *State {
+	return &State{
+		journal: journal,
+		records: make(map[recordKey]models.IdempotencyRecord),
+	}
+}
+
+// Reserve закрепляет ключ за запросом, если ключ свободен, срок его записи истёк или
+// незавершённый запрос перестал удерживать ключ; иначе возвращает существующую запись.
+func (s *State) Reserve(record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	existing, ok := s.records[recordKey{record.UserID, record.Key}]
+	if ok && existing.ExpiresAt.After(record.CreatedAt) &&
+		(existing.Completed || existing.LockedUntil.After(record.CreatedAt)) {
+		return existing, false, nil
+	}
+	record.Completed = false
+	record.Status, record.Header, record.Body = 0, nil, nil
+	if err := s.apply(Event{Type: KeyReserved, Record: record}); err != nil {
+		return models.IdempotencyRecord{}, false, err
+	}
+	return record, true, nil
+}
+
+// Complete сохраняет ответ в закреплённой записи.
+func (s *State) Complete(record models.IdempotencyRecord) error {
+	record.Completed = true
+	return s.apply(Event{Type: KeyCompleted, Record: record})
+}
+
+// Release удаляет незавершённую запись, закреплённую запросом record. Завершённые, отсутствующие
+// и закреплённые другим запросом записи не изменяются.
+func (s *State) Release(record models.IdempotencyRecord) error {
+	existing, ok := s.records[recordKey{record.UserID, record.Key}]
+	if !ok || existing.Completed || !sameRequest(existing, record) {
+		return nil
+	}
+	return s.apply(Event{Type: KeyReleased, Record: models.IdempotencyRecord{UserID: record.UserID, Key: record.Key}})
+}
+
+// sameRequest проверяет, закреплена ли запись existing тем же запросом, что и record.
+func sameRequest(existing, record models.IdempotencyRecord) bool {
+	return existing.RequestHash == record.RequestHash && existing.CreatedAt.Equal(record.CreatedAt)
+}
+
+// Purge удаляет записи, срок которых истёк к now, без записи в журнал: истёкшие записи
+// не влияют на состояние, поэтому журнал можно сжать позже. Возвращает количество удалённых записей.
+func (s *State) Purge(now time.Time) int {
+	purged := 0
+	for key, record := range s.records {
+		if !record.ExpiresAt.After(now) {
+			delete(s.records, key)
+			purged++
+		}
+	}
+	return purged
+}
+
+// Events возвращает события, воспроизводящие текущее состояние, для сжатия журнала.
+// Записи упорядочены по пользователю и ключу.
+func (s *State) Events() []Event {
+	keys := make([]recordKey, 0, len(s.records))
+	for key := range s.records {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b recordKey) int {
+		return cmp.Or(cmp.Compare(a.userID, b.userID), cmp.Compare(a.key, b.key))
+	})
+
+	events := make([]Event, 0, len(keys))
+	for _, key := range keys {
+		record := s.records[key]
+		reserved := record
+		reserved.Completed = false
+		reserved.Status, reserved.Header, reserved.Body = 0, nil, nil
+		events = append(events, Event{Type: KeyReserved, Record: reserved})
+		if record.Completed {
+			events = append(events, Event{Type: KeyCompleted, Record: record})
+		}
+	}
+	return events
+}
+
+// Replay применяет событие из журнала без повторной записи в журнал.
+func (s *State) Replay(e Event) error {
+	if err := s.check(e); err != nil {
+		return err
+	}
+	s.mutate(e)
+	return nil
+}
+
+// check проверяет, что событие может быть применено к текущему состоянию.
+func (s *State) check(e Event) error {
+	switch e.Type {
+	case KeyReserved, KeyReleased:
+		return nil
+	case KeyCompleted:
+		existing, ok := s.records[recordKey{e.Record.UserID, e.Record.Key}]
+		if !ok || existing.Completed || !sameRequest(existing, e.Record) {
+			return storage.ErrNotFound
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown event type %q", e.Type)
+}
+
+// apply проверяет событие, записывает его в журнал и применяет.
+func (s *State) apply(e Event) error {
+	if err := s.check(e); err != nil {
+		return err
+	}
+	if s.journal != nil {
+		if err := s.journal(e); err != nil {
+			return err
+		}
+	}
+	s.mutate(e)
+	return nil
+}
+
+func (s *State) mutate(e Event) {
+	key := recordKey{e.Record.UserID, e.Record.Key}
+	switch e.Type {
+	case KeyReserved, KeyCompleted:
+		s.records[key] = e.Record
+	case KeyReleased:
+		delete(s.records, key)
+	}
+}
@@ -0,0 +1,174 @@
+package index
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+)
+
+// Префиксы ключей инвертированного индекса.
+const (
+	userKey      = "u:"
+	workspaceKey = "g:"
+	tagKey       = "t:"
+	folderKey    = "f:"
+	termKey      = "w:"
+)
+
+// Index — инвертированный индекс ссылок по владельцу (пользователю или рабочему пространству), тегам, папке и словам
+// из названия, заметок, адреса и тегов. Индекс не потокобезопасен,
+// синхронизация остаётся на стороне хранилища.
+type Index struct {
+	postings map[string]map[string]struct{} // Ключ → идентификаторы ссылок
+	keys     map[string][]string            // Идентификатор ссылки → её ключи
+}
+
+// New создаёт пустой индекс.
+func New() *Index {
+	return &Index{
+		postings: make(map[string]map[string]struct{}),
+		keys:     make(map[string][]string),
+	}
+}
+
+// Put добавляет ссылку в индекс, заменяя её предыдущее состояние.
+// Удалённые ссылки исключаются из индекса.
+func (idx *Index) Put(urlModel models.URLModel) {
+	idx.Delete(urlModel.ID)
+	if urlModel.Deleted {
+		return
+	}
+
+	keys := []string{scopeKey(urlModel.WorkspaceID, urlModel.UserID)}
+	if urlModel.Folder != "" {
+		keys = append(keys, folderKey+urlModel.Folder)
+	}
+	for _, tag := range urlModel.Tags {
+		keys = append(keys, tagKey+tag)
+	}
+	for _, term := range Terms(urlModel) {
+		keys = append(keys, termKey+term)
+	}
+
+	for _, key := range keys {
+		ids, ok := idx.postings[key]
+		if !ok {
+			ids = make(map[string]struct{})
+			idx.postings[key] = ids
+		}
+		ids[urlModel.ID] = struct{}{}
+	}
+	idx.keys[urlModel.ID] = keys
+}
+
+// Delete удаляет ссылку из индекса.
+func (idx *Index) Delete(id string) {
+	for _, key := range idx.keys[id] {
+		delete(idx.postings[key], id)
+		if len(idx.postings[key]) == 0 {
+			delete(idx.postings, key)
+		}
+	}
+	delete(idx.keys, id)
+}
+
+// Search возвращает ссылки из data, подходящие под фильтр, от новых к старым.
+func (idx *Index) Search(data map[string]models.URLModel, filter models.URLFilter) []models.URLModel {
+	var ids map[string]struct{}
+	if filter.All && filter.WorkspaceID == "" && filter.UserID == "" {
+		ids = make(map[string]struct{}, len(idx.keys))
+		for id := range idx.keys {
+			ids[id] = struct{}{}
+		}
+	} else {
+		ids = copySet(idx.postings[scopeKey(filter.WorkspaceID, filter.UserID)])
+	}
+	if filter.Tag != "" {
+		ids = intersect(ids, idx.postings[tagKey+filter.Tag])
+	}
+	if filter.Folder != "" {
+		ids = intersect(ids, idx.postings[folderKey+filter.Folder])
+	}
+	for _, word := range Tokenize(filter.Query) {
+		ids = intersect(ids, idx.matchTerm(word))
+	}
+
+	result := make([]models.URLModel, 0, len(ids))
+	for id := range ids {
+		urlModel, ok := data[id]
+		if !ok || (filter.After != nil && !filter.After.Before(urlModel)) {
+			continue
+		}
+		result = append(result, urlModel)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return models.Cursor{CreatedAt: result[i].CreatedAt, ID: result[i].ID}.Before(result[j])
+	})
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result
+}
+
+// scopeKey возвращает ключ владельца ссылки: рабочего пространства или пользователя для личных ссылок.
+func scopeKey(workspaceID, userID string) string {
+	if workspaceID != "" {
+		return workspaceKey + workspaceID
+	}
+	return userKey + userID
+}
+
+// matchTerm возвращает ссылки, содержащие слово, включающее подстроку word.
+func (idx *Index) matchTerm(word string) map[string]struct{} {
+	ids := make(map[string]struct{})
+	for key, postings := range idx.postings {
+		if strings.HasPrefix(key, termKey) && strings.Contains(key[len(termKey):], word) {
+			for id := range postings {
+				ids[id] = struct{}{}
+			}
+		}
+	}
+	return ids
+}
+
+// Terms возвращает слова ссылки, по которым выполняется полнотекстовый поиск.
+func Terms(urlModel models.URLModel) []string {
+	text := strings.Join(append([]string{urlModel.Title, urlModel.Notes, urlModel.URL}, urlModel.Tags...), " ")
+	return Tokenize(text)
+}
+
+// Tokenize разбивает текст на уникальные слова в нижнем регистре.
+// Разделителями считаются все символы, кроме букв и цифр.
+func Tokenize(text string) []string {
+	seen := make(map[string]struct{})
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if _, ok := seen[word]; !ok {
+			seen[word] = struct{}{}
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+func copySet(set map[string]struct{}) map[string]struct{} {
+	result := make(map[string]struct{}, len(set))
+	for id := range set {
+		result[id] = struct{}{}
+	}
+	return result
+}
+
+func intersect(set, other map[string]struct{}) map[string]struct{} {
+	for id := range set {
+		if _, ok := other[id]; !ok {
+			delete(set, id)
+		}
+	}
+	return set
+}
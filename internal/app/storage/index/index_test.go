@@ -0,0 +1,70 @@
+package index
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndex_Search(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	data := map[string]models.URLModel{
+		"a": {ID: "a", UserID: "u1", URL: "https://practicum.yandex.ru/go", Title: "Курс Go", Tags: []string{"go", "study"}, Folder: "work", CreatedAt: now},
+		"b": {ID: "b", UserID: "u1", URL: "https://example.com/", Notes: "черновик лендинга", Tags: []string{"marketing"}, CreatedAt: now.Add(time.Minute)},
+		"c": {ID: "c", UserID: "u1", URL: "https://go.dev/doc", Folder: "work", CreatedAt: now.Add(2 * time.Minute)},
+		"d": {ID: "d", UserID: "u2", URL: "https://go.dev/", Tags: []string{"go"}, CreatedAt: now},
+	}
+	idx := New()
+	for _, urlModel := range data {
+		idx.Put(urlModel)
+	}
+
+	tests := []struct {
+		name     string
+		filter   models.URLFilter
+		expected []string
+	}{
+		{name: "all user links newest first", filter: models.URLFilter{UserID: "u1"}, expected: []string{"c", "b", "a"}},
+		{name: "by tag", filter: models.URLFilter{UserID: "u1", Tag: "go"}, expected: []string{"a"}},
+		{name: "by folder", filter: models.URLFilter{UserID: "u1", Folder: "work"}, expected: []string{"c", "a"}},
+		{name: "query by url substring", filter: models.URLFilter{UserID: "u1", Query: "go.DEV"}, expected: []string{"c"}},
+		{name: "query by notes", filter: models.URLFilter{UserID: "u1", Query: "лендинг"}, expected: []string{"b"}},
+		{name: "query words are combined", filter: models.URLFilter{UserID: "u1", Query: "курс study"}, expected: []string{"a"}},
+		{name: "limit", filter: models.URLFilter{UserID: "u1", Limit: 2}, expected: []string{"c", "b"}},
+		{
+			name:     "after cursor",
+			filter:   models.URLFilter{UserID: "u1", After: &models.Cursor{CreatedAt: now.Add(time.Minute), ID: "b"}},
+			expected: []string{"a"},
+		},
+		{name: "unknown user", filter: models.URLFilter{UserID: "u3"}, expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := []string{}
+			for _, urlModel := range idx.Search(data, tt.filter) {
+				ids = append(ids, urlModel.ID)
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
+
+func TestIndex_PutReplacesPreviousState(t *testing.T) {
+	idx := New()
+	urlModel := models.URLModel{ID: "a", UserID: "u1", URL: "https://example.com/", Tags: []string{"old"}}
+	idx.Put(urlModel)
+
+	urlModel.Tags = []string{"new"}
+	idx.Put(urlModel)
+	data := map[string]models.URLModel{"a": urlModel}
+
+	assert.Empty(t, idx.Search(data, models.URLFilter{UserID: "u1", Tag: "old"}))
+	assert.Len(t, idx.Search(data, models.URLFilter{UserID: "u1", Tag: "new"}), 1)
+
+	idx.Delete("a")
+	assert.Empty(t, idx.Search(data, models.URLFilter{UserID: "u1"}))
+	assert.Empty(t, idx.postings)
+}
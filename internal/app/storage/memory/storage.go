@@ -3,47 +3,381 @@ package memory
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/apikeys"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/idempotency"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/index"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/workspaces"
 )
 
 // InMemoryStorage управляет сохранением и получением данных в памяти.
 type InMemoryStorage struct {
-	mu   sync.RWMutex
-	data map[string]string
+	mu      sync.RWMutex
+	data    map[string]models.URLModel
+	history map[string][]models.URLVersion
+	index   *index.Index
+	// Рабочие пространства, участники и приглашения.
+	workspaces *workspaces.State
+	apiKeys    *apikeys.State
+	// Ответы на запросы с ключом идемпотентности.
+	idempotency *idempotency.State
+	audit       []models.AuditEvent // Журнал аудита в порядке записи
 }
 
 // NewInMemoryStorage создаёт новое хранилище в памяти.
 func NewInMemoryStorage() *InMemoryStorage {
 	return &InMemoryStorage{
-		data: make(map[string]string),
+		data:        make(map[string]models.URLModel),
+		history:     make(map[string][]models.URLVersion),
+		index:       index.New(),
+		workspaces:  workspaces.NewState(nil),
+		apiKeys:     apikeys.NewState(nil),
+		idempotency: idempotency.NewState(nil),
 	}
 }
 
-// Save сохраняет URL в памяти.
+// Save сохраняет URL в памяти, если ссылки с таким идентификатором ещё нет.
 func (s *InMemoryStorage) Save(ctx context.Context, urlModel models.URLModel) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.data[urlModel.ID] = urlModel.URL
+	if _, exists := s.data[urlModel.ID]; exists {
+		return storage.ErrConflict
+	}
+	s.data[urlModel.ID] = urlModel
+	s.index.Put(urlModel)
 	return nil
 }
 
-// SaveBatch сохраняет множество URL в памяти.
-func (s *InMemoryStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) error {
+// SaveBatch сохраняет в памяти ссылки, которых ещё нет в хранилище.
+func (s *InMemoryStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) ([]bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	for _, urlModel := range urlModels {
-		s.data[urlModel.ID] = urlModel.URL
+	existed := make([]bool, len(urlModels))
+	for i, urlModel := range urlModels {
+		if _, existed[i] = s.data[urlModel.ID]; existed[i] {
+			continue
+		}
+		s.data[urlModel.ID] = urlModel
+		s.index.Put(urlModel)
 	}
-	return nil
+	return existed, nil
 }
 
 // Get возвращает оригинальный URL по идентификатору из памяти.
 func (s *InMemoryStorage) Get(ctx context.Context, id string) (models.URLModel, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	url, exists := s.data[id]
-	return models.URLModel{ID: id, URL: url}, exists
+	urlModel, exists := s.data[id]
+	return urlModel, exists
+}
+
+// GetMany возвращает ссылки по идентификаторам из памяти.
+func (s *InMemoryStorage) GetMany(ctx context.Context, ids []string) (map[string]models.URLModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	urlModels := make(map[string]models.URLModel, len(ids))
+	for _, id := range ids {
+		if urlModel, exists := s.data[id]; exists {
+			urlModels[id] = urlModel
+		}
+	}
+	return urlModels, nil
+}
+
+// RegisterClick увеличивает счётчик переходов по ссылке, если лимит переходов не исчерпан.
+func (s *InMemoryStorage) RegisterClick(ctx context.Context, click models.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	urlModel, exists := s.data[click.ID]
+	if !exists || urlModel.Deleted {
+		return storage.ErrNotFound
+	}
+	if urlModel.ClicksExhausted() {
+		return storage.ErrClickLimitExceeded
+	}
+	urlModel = urlModel.WithClick(click.Variant)
+	s.data[click.ID] = urlModel
+	return nil
+}
+
+// VariantClicks возвращает количество переходов по вариантам A/B-теста.
+func (s *InMemoryStorage) VariantClicks(ctx context.Context, id string) (map[string]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	variantClicks := make(map[string]int64, len(s.data[id].VariantClicks))
+	for name, clicks := range s.data[id].VariantClicks {
+		variantClicks[name] = clicks
+	}
+	return variantClicks, nil
+}
+
+// Update изменяет ссылку и добавляет версию в историю.
+func (s *InMemoryStorage) Update(ctx context.Context, id string, version models.URLVersion) (models.URLVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	urlModel, exists := s.data[id]
+	if !exists {
+		return models.URLVersion{}, storage.ErrNotFound
+	}
+
+	version.Version = len(s.history[id]) + 1
+	version.Before = urlModel.Settings()
+	version.Fields = version.Before.Diff(version.After)
+
+	urlModel = urlModel.WithSettings(version.After)
+	s.data[id] = urlModel
+	s.index.Put(urlModel)
+	s.history[id] = append(s.history[id], version)
+	return version, nil
+}
+
+// History возвращает историю изменений ссылки.
+func (s *InMemoryStorage) History(ctx context.Context, id string) ([]models.URLVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]models.URLVersion(nil), s.history[id]...), nil
+}
+
+// Delete помечает ссылки удалёнными.
+func (s *InMemoryStorage) Delete(ctx context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		urlModel, exists := s.data[id]
+		if !exists || urlModel.Deleted {
+			continue
+		}
+		urlModel.Deleted = true
+		s.data[id] = urlModel
+		s.index.Put(urlModel)
+	}
+	return nil
+}
+
+// SetMeta сохраняет метаданные страницы назначения.
+func (s *InMemoryStorage) SetMeta(ctx context.Context, id string, meta models.PageMeta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	urlModel, exists := s.data[id]
+	if !exists {
+		return storage.ErrNotFound
+	}
+	urlModel.Meta = &meta
+	s.data[id] = urlModel
+	return nil
+}
+
+// Search ищет ссылки пользователя по инвертированному индексу.
+func (s *InMemoryStorage) Search(ctx context.Context, filter models.URLFilter) ([]models.URLModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index.Search(s.data, filter), nil
+}
+
+// Stats возвращает количество неудалённых ссылок и их владельцев.
+func (s *InMemoryStorage) Stats(ctx context.Context) (models.ServiceStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return storage.CountStats(s.data), nil
+}
+
+// SetDisabled отключает ссылку или снова включает её.
+func (s *InMemoryStorage) SetDisabled(ctx context.Context, id string, disabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	urlModel, exists := s.data[id]
+	if !exists {
+		return storage.ErrNotFound
+	}
+	urlModel.Disabled = disabled
+	s.data[id] = urlModel
+	return nil
+}
+
+// SetOwner передаёт ссылку другому пользователю или рабочему пространству.
+func (s *InMemoryStorage) SetOwner(ctx context.Context, id, userID, workspaceID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	urlModel, exists := s.data[id]
+	if !exists {
+		return storage.ErrNotFound
+	}
+	urlModel.UserID, urlModel.WorkspaceID = userID, workspaceID
+	s.data[id] = urlModel
+	s.index.Put(urlModel)
+	return nil
+}
+
+// Purge безвозвратно удаляет ссылку и её историю.
+func (s *InMemoryStorage) Purge(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.data[id]; !exists {
+		return storage.ErrNotFound
+	}
+	delete(s.data, id)
+	delete(s.history, id)
+	s.index.Delete(id)
+	return nil
+}
+
+// CreateWorkspace создаёт рабочее пространство с владельцем owner.
+func (s *InMemoryStorage) CreateWorkspace(ctx context.Context, workspace models.Workspace, owner models.Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workspaces.CreateWorkspace(workspace, owner)
+}
+
+// Workspace возвращает рабочее пространство по идентификатору.
+func (s *InMemoryStorage) Workspace(ctx context.Context, id string) (models.Workspace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.workspaces.Workspace(id)
+}
+
+// UserWorkspaces возвращает рабочие пространства пользователя с его ролями.
+func (s *InMemoryStorage) UserWorkspaces(ctx context.Context, userID string) ([]models.Membership, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.workspaces.UserWorkspaces(userID), nil
+}
+
+// Member возвращает участника рабочего пространства.
+func (s *InMemoryStorage) Member(ctx context.Context, workspaceID, userID string) (models.Member, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.workspaces.Member(workspaceID, userID)
+}
+
+// Members возвращает участников рабочего пространства.
+func (s *InMemoryStorage) Members(ctx context.Context, workspaceID string) ([]models.Member, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.workspaces.Members(workspaceID), nil
+}
+
+// SaveMember добавляет участника или меняет его роль.
+func (s *InMemoryStorage) SaveMember(ctx context.Context, member models.Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workspaces.SaveMember(member)
+}
+
+// DeleteMember исключает участника из рабочего пространства.
+func (s *InMemoryStorage) DeleteMember(ctx context.Context, workspaceID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workspaces.DeleteMember(workspaceID, userID)
+}
+
+// SaveInvitation сохраняет приглашение в рабочее пространство.
+func (s *InMemoryStorage) SaveInvitation(ctx context.Context, invitation models.Invitation, tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workspaces.SaveInvitation(invitation, tokenHash)
+}
+
+// Invitations возвращает приглашения в рабочее пространство.
+func (s *InMemoryStorage) Invitations(ctx context.Context, workspaceID string) ([]models.Invitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.workspaces.Invitations(workspaceID), nil
+}
+
+// DeleteInvitation отзывает приглашение.
+func (s *InMemoryStorage) DeleteInvitation(ctx context.Context, workspaceID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workspaces.DeleteInvitation(workspaceID, id)
+}
+
+// AcceptInvitation принимает приглашение и добавляет пользователя в рабочее пространство.
+func (s *InMemoryStorage) AcceptInvitation(ctx context.Context, tokenHash, userID string, now time.Time) (models.Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workspaces.AcceptInvitation(tokenHash, userID, now)
+}
+
+// SaveAPIKey сохраняет API-ключ с хешем keyHash.
+func (s *InMemoryStorage) SaveAPIKey(ctx context.Context, key models.APIKey, keyHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apiKeys.Save(key, keyHash)
+}
+
+// APIKeys возвращает API-ключи пользователя.
+func (s *InMemoryStorage) APIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.apiKeys.Keys(userID), nil
+}
+
+// APIKeyByHash возвращает API-ключ по хешу.
+func (s *InMemoryStorage) APIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.apiKeys.ByHash(keyHash)
+}
+
+// DeleteAPIKey отзывает API-ключ пользователя.
+func (s *InMemoryStorage) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apiKeys.Delete(userID, id)
+}
+
+// TouchAPIKey запоминает время последнего использования API-ключа.
+func (s *InMemoryStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apiKeys.Touch(id, usedAt)
+}
+
+// ReserveIdempotencyKey закрепляет ключ идемпотентности за запросом.
+func (s *InMemoryStorage) ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.idempotency.Reserve(record)
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с ключом идемпотентности.
+func (s *InMemoryStorage) CompleteIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.idempotency.Complete(record)
+}
+
+// ReleaseIdempotencyKey освобождает ключ идемпотентности незавершённого запроса.
+func (s *InMemoryStorage) ReleaseIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.idempotency.Release(record)
+}
+
+// PurgeIdempotencyKeys удаляет записи ключей идемпотентности с истёкшим сроком хранения.
+func (s *InMemoryStorage) PurgeIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.idempotency.Purge(now), nil
+}
+
+// AppendAudit добавляет событие в журнал аудита.
+func (s *InMemoryStorage) AppendAudit(ctx context.Context, event models.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audit = append(s.audit, event)
+	return nil
+}
+
+// AuditEvents возвращает события журнала аудита, подходящие под фильтр.
+func (s *InMemoryStorage) AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return storage.SelectAuditEvents(s.audit, filter), nil
 }
 
 // LoadFromFile загружает данные из памяти (не требуется для памяти).
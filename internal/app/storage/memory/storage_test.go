@@ -2,9 +2,13 @@ package memory
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	appstorage "github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/storagetest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,6 +40,38 @@ func TestInMemoryStorage_GetNonExistent(t *testing.T) {
 	assert.False(t, exists, "URL should not exist in storage")
 }
 
+func TestInMemoryStorage_LinkIsolation(t *testing.T) {
+	storagetest.LinkIsolation(t, NewInMemoryStorage(), "https://docs.example.com/secret")
+}
+
+func TestInMemoryStorage_ClickLimitIsolation(t *testing.T) {
+	storagetest.ClickLimitIsolation(t, NewInMemoryStorage(), "https://example.com/invite")
+}
+
+func TestInMemoryStorage_EditedLinkIsolation(t *testing.T) {
+	storagetest.EditedLinkIsolation(t, NewInMemoryStorage(), "https://example.com/")
+}
+
+func TestInMemoryStorage_WorkspaceLinkIsolation(t *testing.T) {
+	storagetest.WorkspaceLinkIsolation(t, NewInMemoryStorage(), "https://example.com/team")
+}
+
+func TestInMemoryStorage_RemovedLinkIsolation(t *testing.T) {
+	storagetest.RemovedLinkIsolation(t, NewInMemoryStorage(), "https://example.com/")
+}
+
+func TestInMemoryStorage_BatchConflicts(t *testing.T) {
+	storagetest.BatchConflicts(t, NewInMemoryStorage(), "https://example.com/")
+}
+
+func TestInMemoryStorage_IdempotencyPurge(t *testing.T) {
+	storagetest.IdempotencyPurge(t, NewInMemoryStorage(), "alice")
+}
+
+func TestInMemoryStorage_IdempotencyLease(t *testing.T) {
+	storagetest.IdempotencyLease(t, NewInMemoryStorage(), "alice")
+}
+
 func TestInMemoryStorage_LoadFromFile(t *testing.T) {
 	storage := NewInMemoryStorage()
 
@@ -43,3 +79,51 @@ func TestInMemoryStorage_LoadFromFile(t *testing.T) {
 	err := storage.LoadFromFile()
 	assert.NoError(t, err, "LoadFromFile should not return an error")
 }
+
+func TestInMemoryStorage_RegisterClickLimit(t *testing.T) {
+	storage := NewInMemoryStorage()
+	ctx := context.Background()
+	err := storage.Save(ctx, models.URLModel{ID: "testID", URL: "https://example.com", MaxClicks: 5})
+	assert.NoError(t, err)
+
+	// Параллельные переходы не должны превысить лимит.
+	var wg sync.WaitGroup
+	var succeeded atomic.Int64
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if storage.RegisterClick(ctx, models.Click{ID: "testID"}) == nil {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(5), succeeded.Load())
+	assert.ErrorIs(t, storage.RegisterClick(ctx, models.Click{ID: "testID"}), appstorage.ErrClickLimitExceeded)
+
+	urlModel, _ := storage.Get(ctx, "testID")
+	assert.Equal(t, int64(5), urlModel.Clicks)
+}
+
+func TestInMemoryStorage_SaveBatch(t *testing.T) {
+	storage := NewInMemoryStorage()
+	ctx := context.Background()
+
+	existing := models.URLModel{ID: "a", URL: "https://example.com/a", Title: "old"}
+	assert.NoError(t, storage.Save(ctx, existing))
+
+	existed, err := storage.SaveBatch(ctx, []models.URLModel{
+		{ID: "a", URL: "https://example.com/a", Title: "new"},
+		{ID: "b", URL: "https://example.com/b"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, existed)
+
+	// Существующая ссылка не перезаписывается.
+	urlModel, _ := storage.Get(ctx, "a")
+	assert.Equal(t, "old", urlModel.Title)
+	_, exists := storage.Get(ctx, "b")
+	assert.True(t, exists)
+}
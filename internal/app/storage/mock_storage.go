@@ -4,26 +4,43 @@ import (
 	"context"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/index"
 )
 
 type MockStorage struct {
-	data map[string]models.URLModel
+	// Методы рабочих пространств, API-ключей, журнала аудита и ключей идемпотентности
+	// не реализованы, тесты с ними используют хранилище в памяти.
+	WorkspaceStorage
+	APIKeyStorage
+	AuditStorage
+	IdempotencyStorage
+	data    map[string]models.URLModel
+	history map[string][]models.URLVersion
 }
 
 func NewMockStorage() *MockStorage {
-	return &MockStorage{data: make(map[string]models.URLModel)}
+	return &MockStorage{
+		data:    make(map[string]models.URLModel),
+		history: make(map[string][]models.URLVersion),
+	}
 }
 
 func (m *MockStorage) Save(ctx context.Context, urlModel models.URLModel) error {
+	if _, exists := m.data[urlModel.ID]; exists {
+		return ErrConflict
+	}
 	m.data[urlModel.ID] = urlModel
 	return nil
 }
 
-func (m *MockStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) error {
-	for _, urlModel := range urlModels {
-		m.data[urlModel.ID] = urlModel
+func (m *MockStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) ([]bool, error) {
+	existed := make([]bool, len(urlModels))
+	for i, urlModel := range urlModels {
+		if _, existed[i] = m.data[urlModel.ID]; !existed[i] {
+			m.data[urlModel.ID] = urlModel
+		}
 	}
-	return nil
+	return existed, nil
 }
 
 func (m *MockStorage) Get(ctx context.Context, id string) (models.URLModel, bool) {
@@ -31,6 +48,110 @@ func (m *MockStorage) Get(ctx context.Context, id string) (models.URLModel, bool
 	return urlModel, exists
 }
 
+func (m *MockStorage) GetMany(ctx context.Context, ids []string) (map[string]models.URLModel, error) {
+	urlModels := make(map[string]models.URLModel, len(ids))
+	for _, id := range ids {
+		if urlModel, exists := m.data[id]; exists {
+			urlModels[id] = urlModel
+		}
+	}
+	return urlModels, nil
+}
+
+func (m *MockStorage) RegisterClick(ctx context.Context, click models.Click) error {
+	urlModel, exists := m.data[click.ID]
+	if !exists || urlModel.Deleted {
+		return ErrNotFound
+	}
+	if urlModel.ClicksExhausted() {
+		return ErrClickLimitExceeded
+	}
+	m.data[click.ID] = urlModel.WithClick(click.Variant)
+	return nil
+}
+
+func (m *MockStorage) VariantClicks(ctx context.Context, id string) (map[string]int64, error) {
+	return m.data[id].VariantClicks, nil
+}
+
+func (m *MockStorage) Update(ctx context.Context, id string, version models.URLVersion) (models.URLVersion, error) {
+	urlModel, exists := m.data[id]
+	if !exists {
+		return models.URLVersion{}, ErrNotFound
+	}
+	version.Version = len(m.history[id]) + 1
+	version.Before = urlModel.Settings()
+	version.Fields = version.Before.Diff(version.After)
+	m.data[id] = urlModel.WithSettings(version.After)
+	m.history[id] = append(m.history[id], version)
+	return version, nil
+}
+
+func (m *MockStorage) History(ctx context.Context, id string) ([]models.URLVersion, error) {
+	return m.history[id], nil
+}
+
+func (m *MockStorage) Delete(ctx context.Context, ids ...string) error {
+	for _, id := range ids {
+		if urlModel, exists := m.data[id]; exists {
+			urlModel.Deleted = true
+			m.data[id] = urlModel
+		}
+	}
+	return nil
+}
+
+func (m *MockStorage) SetMeta(ctx context.Context, id string, meta models.PageMeta) error {
+	urlModel, exists := m.data[id]
+	if !exists {
+		return ErrNotFound
+	}
+	urlModel.Meta = &meta
+	m.data[id] = urlModel
+	return nil
+}
+
+func (m *MockStorage) Search(ctx context.Context, filter models.URLFilter) ([]models.URLModel, error) {
+	idx := index.New()
+	for _, urlModel := range m.data {
+		idx.Put(urlModel)
+	}
+	return idx.Search(m.data, filter), nil
+}
+
+func (m *MockStorage) Stats(ctx context.Context) (models.ServiceStats, error) {
+	return CountStats(m.data), nil
+}
+
+func (m *MockStorage) SetDisabled(ctx context.Context, id string, disabled bool) error {
+	urlModel, exists := m.data[id]
+	if !exists {
+		return ErrNotFound
+	}
+	urlModel.Disabled = disabled
+	m.data[id] = urlModel
+	return nil
+}
+
+func (m *MockStorage) SetOwner(ctx context.Context, id, userID, workspaceID string) error {
+	urlModel, exists := m.data[id]
+	if !exists {
+		return ErrNotFound
+	}
+	urlModel.UserID, urlModel.WorkspaceID = userID, workspaceID
+	m.data[id] = urlModel
+	return nil
+}
+
+func (m *MockStorage) Purge(ctx context.Context, id string) error {
+	if _, exists := m.data[id]; !exists {
+		return ErrNotFound
+	}
+	delete(m.data, id)
+	delete(m.history, id)
+	return nil
+}
+
 // LoadFromFile имитирует загрузку данных из файла.
 func (m *MockStorage) LoadFromFile() error {
 	// Можно имитировать ошибку или инициализировать данными для тестов.
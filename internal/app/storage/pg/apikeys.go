@@ -0,0 +1,95 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+// apiKeyColumns перечисляет столбцы API-ключа в порядке сканирования scanAPIKey.
+const apiKeyColumns = `id, prefix, name, user_id, workspace_id, scopes, created_at, last_used_at`
+
+// SaveAPIKey сохраняет API-ключ с хешем keyHash.
+func (s *DatabaseStorage) SaveAPIKey(ctx context.Context, key models.APIKey, keyHash string) error {
+	query := `
+		INSERT INTO api_keys (key_hash, id, prefix, name, user_id, workspace_id, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := s.db.Pool.Exec(ctx, query, keyHash, key.ID, key.Prefix, key.Name, key.UserID, key.WorkspaceID,
+		textArrayArg(key.Scopes), key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save api key: %w", err)
+	}
+	return nil
+}
+
+// APIKeys возвращает API-ключи пользователя в порядке создания.
+func (s *DatabaseStorage) APIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	rows, err := s.db.Pool.Query(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// APIKeyByHash возвращает API-ключ по хешу.
+func (s *DatabaseStorage) APIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	row := s.db.Pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, keyHash)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.APIKey{}, storage.ErrNotFound
+	}
+	return key, err
+}
+
+// DeleteAPIKey отзывает API-ключ пользователя.
+func (s *DatabaseStorage) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	tag, err := s.db.Pool.Exec(ctx, `DELETE FROM api_keys WHERE user_id = $1 AND id = $2`, userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey запоминает время последнего использования API-ключа.
+func (s *DatabaseStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	tag, err := s.db.Pool.Exec(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, usedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func scanAPIKey(row pgx.Row) (models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(&key.ID, &key.Prefix, &key.Name, &key.UserID, &key.WorkspaceID, &key.Scopes,
+		&key.CreatedAt, &key.LastUsedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.APIKey{}, err
+		}
+		return models.APIKey{}, fmt.Errorf("failed to scan api key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,83 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+)
+
+// auditColumns перечисляет столбцы события аудита в порядке сканирования в AuditEvents.
+const auditColumns = `id, time, action, actor, api_key_id, workspace_id, target, request_id, client_ip, before, after`
+
+// AppendAudit добавляет событие в журнал аудита.
+func (s *DatabaseStorage) AppendAudit(ctx context.Context, event models.AuditEvent) error {
+	query := `INSERT INTO audit_log (` + auditColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	_, err := s.db.Pool.Exec(ctx, query, event.ID, event.Time, event.Action, event.Actor, event.APIKeyID,
+		event.WorkspaceID, event.Target, event.RequestID, event.ClientIP, []byte(event.Before), []byte(event.After))
+	if err != nil {
+		return fmt.Errorf("failed to append audit event: %w", err)
+	}
+	return nil
+}
+
+// AuditEvents возвращает события журнала аудита, подходящие под фильтр.
+func (s *DatabaseStorage) AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{"true"}
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = "+arg(filter.Actor))
+	}
+	if filter.Action != "" {
+		action := arg(filter.Action)
+		conditions = append(conditions, fmt.Sprintf("(action = %[1]s OR left(action, length(%[1]s) + 1) = %[1]s || '.')", action))
+	}
+	if filter.Target != "" {
+		conditions = append(conditions, "target = "+arg(filter.Target))
+	}
+	if filter.WorkspaceID != "" {
+		conditions = append(conditions, "workspace_id = "+arg(filter.WorkspaceID))
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "time >= "+arg(filter.Since))
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "time < "+arg(filter.Until))
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(time, id) < (%s, %s)", arg(filter.After.CreatedAt), arg(filter.After.ID)))
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log WHERE ` + strings.Join(conditions, " AND ") +
+		` ORDER BY time DESC, id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ` + arg(filter.Limit)
+	}
+
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var event models.AuditEvent
+		var before, after []byte
+		err := rows.Scan(&event.ID, &event.Time, &event.Action, &event.Actor, &event.APIKeyID, &event.WorkspaceID,
+			&event.Target, &event.RequestID, &event.ClientIP, &before, &after)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		event.Before, event.After = before, after
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,114 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+// reserveAttempts ограничивает число попыток закрепить ключ, запись которого
+// удаляется одновременно с проверкой.
+const reserveAttempts = 3
+
+// ReserveIdempotencyKey закрепляет ключ идемпотентности за запросом. Вставка перезаписывает
+// только запись с истёкшим сроком или незавершённую запись, удержание которой истекло,
+// поэтому одновременные запросы с одним ключом не закрепят его дважды.
+func (s *DatabaseStorage) ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (user_id, key, request_hash, created_at, expires_at, locked_until)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at,
+			locked_until = EXCLUDED.locked_until, completed = false, status = 0, header = NULL, body = NULL
+		WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+			OR (NOT idempotency_keys.completed AND idempotency_keys.locked_until <= EXCLUDED.created_at)
+		RETURNING user_id`
+	for range reserveAttempts {
+		var userID string
+		err := s.db.Pool.QueryRow(ctx, query, record.UserID, record.Key, record.RequestHash,
+			record.CreatedAt, record.ExpiresAt, record.LockedUntil).Scan(&userID)
+		if err == nil {
+			record.Completed, record.Status, record.Header, record.Body = false, 0, nil, nil
+			return record, true, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return models.IdempotencyRecord{}, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+
+		existing, err := s.idempotencyRecord(ctx, record.UserID, record.Key)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue // Запись удалена после вставки: пробуем закрепить ключ снова
+		}
+		return existing, false, err
+	}
+	return models.IdempotencyRecord{}, false, fmt.Errorf("failed to reserve idempotency key %q", record.Key)
+}
+
+func (s *DatabaseStorage) idempotencyRecord(ctx context.Context, userID, key string) (models.IdempotencyRecord, error) {
+	query := `
+		SELECT request_hash, created_at, expires_at, locked_until, completed, status, header, body
+		FROM idempotency_keys WHERE user_id = $1 AND key = $2`
+	record := models.IdempotencyRecord{UserID: userID, Key: key}
+	var header []byte
+	err := s.db.Pool.QueryRow(ctx, query, userID, key).Scan(&record.RequestHash, &record.CreatedAt,
+		&record.ExpiresAt, &record.LockedUntil, &record.Completed, &record.Status, &header, &record.Body)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return record, err
+		}
+		return record, fmt.Errorf("failed to query idempotency key: %w", err)
+	}
+	if header != nil {
+		if err := json.Unmarshal(header, &record.Header); err != nil {
+			return record, fmt.Errorf("invalid idempotency response header: %w", err)
+		}
+	}
+	return record, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с ключом идемпотентности.
+func (s *DatabaseStorage) CompleteIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE idempotency_keys SET completed = true, status = $4, header = $5, body = $6
+		WHERE user_id = $1 AND key = $2 AND request_hash = $3 AND created_at = $7 AND NOT completed`
+	tag, err := s.db.Pool.Exec(ctx, query, record.UserID, record.Key, record.RequestHash, record.Status, header, record.Body,
+		record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ идемпотентности незавершённого запроса.
+func (s *DatabaseStorage) ReleaseIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2 AND request_hash = $3 AND created_at = $4 AND NOT completed`
+	_, err := s.db.Pool.Exec(ctx, query, record.UserID, record.Key, record.RequestHash, record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys удаляет записи ключей идемпотентности с истёкшим сроком хранения.
+func (s *DatabaseStorage) PurgeIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	tag, err := s.db.Pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -2,11 +2,19 @@ package pg
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/alexuryumtsev/go-shortener/internal/app/db"
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/index"
 	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
@@ -20,34 +28,383 @@ func NewDatabaseStorage(db *db.Database) *DatabaseStorage {
 	return &DatabaseStorage{db: db}
 }
 
-// Save сохраняет URL в базе данных.
+// insertURLQuery добавляет ссылку со всеми полями, задаваемыми при создании.
+const insertURLQuery = `
+	INSERT INTO urls (short_url, original_url, created_at, interstitial, password_hash, max_clicks, rules, variants, params,
+		user_id, title, redirect_code, expires_at, notes, tags, folder, meta, workspace_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`
+
+// urlColumns перечисляет поля ссылки в порядке, ожидаемом scanURL.
+const urlColumns = `short_url, original_url, created_at, clicks, interstitial, password_hash, max_clicks, rules, variants, params,
+	user_id, title, redirect_code, expires_at, notes, tags, folder, meta, workspace_id, is_deleted, is_disabled`
+
+// searchExpr — текстовое выражение для поиска по ссылке, совпадает с выражением индекса urls_search_idx.
+const searchExpr = `(title || ' ' || notes || ' ' || original_url)`
+
+// Save сохраняет URL в базе данных. Существующая ссылка с тем же идентификатором не изменяется.
 func (s *DatabaseStorage) Save(ctx context.Context, urlModel models.URLModel) error {
-	query := `INSERT INTO urls (short_url, original_url) VALUES ($1, $2)`
-	_, err := s.db.Pool.Exec(ctx, query, urlModel.ID, urlModel.URL)
+	args, err := insertArgs(urlModel)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Pool.Exec(ctx, insertURLQuery, args...)
+
+	if err != nil {
+		return saveError(err)
+	}
+	return nil
+}
+
+// saveError описывает ошибку сохранения ссылки. Нарушение уникальности short_url
+// означает, что ссылка с таким идентификатором уже существует, и сопоставляется с storage.ErrConflict.
+func saveError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		return fmt.Errorf("%w: %w", storage.ErrConflict, err)
+	}
+	return fmt.Errorf("failed to save URL: %w", err)
+}
+
+// SaveBatch отправляет вставки пачки одним pgx.Batch за один обмен с сервером; пачка выполняется
+// в неявной транзакции, поэтому при ошибке не сохраняется ни одна ссылка. Строка, возвращённая
+// RETURNING, означает, что ссылка вставлена; отсутствие строки — что она уже существовала.
+func (s *DatabaseStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) ([]bool, error) {
+	batch := &pgx.Batch{}
+	for _, urlModel := range urlModels {
+		args, err := insertArgs(urlModel)
+		if err != nil {
+			return nil, err
+		}
+		batch.Queue(insertURLQuery+` ON CONFLICT DO NOTHING RETURNING short_url`, args...)
+	}
+
+	results := s.db.Pool.SendBatch(ctx, batch)
+	defer results.Close()
+
+	existed := make([]bool, len(urlModels))
+	for i := range urlModels {
+		var id string
+		err := results.QueryRow().Scan(&id)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			existed[i] = true
+		case err != nil:
+			return nil, saveError(err)
+		}
+	}
+	if err := results.Close(); err != nil {
+		return nil, saveError(err)
+	}
+	return existed, nil
+}
+
+// Get возвращает оригинальный URL по идентификатору из базы данных.
+func (s *DatabaseStorage) Get(ctx context.Context, id string) (models.URLModel, bool) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE short_url = $1`
+	urlModel, err := scanURL(s.db.Pool.QueryRow(ctx, query, id))
+	if err != nil {
+		return models.URLModel{}, false
+	}
+	return urlModel, true
+}
 
+// GetMany возвращает ссылки по идентификаторам одним запросом.
+func (s *DatabaseStorage) GetMany(ctx context.Context, ids []string) (map[string]models.URLModel, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE short_url = ANY($1)`
+	rows, err := s.db.Pool.Query(ctx, query, ids)
 	if err != nil {
-		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == pgerrcode.UniqueViolation {
-			return fmt.Errorf("conflict: %w", err)
+		return nil, fmt.Errorf("failed to query URLs: %w", err)
+	}
+	defer rows.Close()
+
+	urlModels := make(map[string]models.URLModel, len(ids))
+	for rows.Next() {
+		urlModel, err := scanURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan URL: %w", err)
 		}
-		return fmt.Errorf("failed to save URL: %w", err)
+		urlModels[urlModel.ID] = urlModel
+	}
+	return urlModels, rows.Err()
+}
+
+// RegisterClick атомарно увеличивает счётчик переходов, если лимит переходов не исчерпан,
+// и записывает событие перехода в журнал click_events. Запрос также сообщает, существует ли
+// неудалённая ссылка, чтобы отличить отсутствующую ссылку от исчерпанного лимита.
+func (s *DatabaseStorage) RegisterClick(ctx context.Context, click models.Click) error {
+	query := `
+		WITH link AS (
+			SELECT short_url FROM urls WHERE short_url = $1 AND NOT is_deleted
+		), updated AS (
+			UPDATE urls SET clicks = clicks + 1
+			WHERE short_url = $1 AND NOT is_deleted AND (max_clicks = 0 OR clicks < max_clicks)
+			RETURNING short_url
+		), inserted AS (
+			INSERT INTO click_events (short_url, variant, click_id, clicked_at)
+			SELECT short_url, $2, $3, $4 FROM updated
+			RETURNING short_url
+		)
+		SELECT EXISTS (SELECT 1 FROM link), EXISTS (SELECT 1 FROM inserted)`
+
+	var exists, registered bool
+	err := s.db.Pool.QueryRow(ctx, query, click.ID, click.Variant, click.ClickID, click.Time).Scan(&exists, &registered)
+	switch {
+	case err != nil:
+		return fmt.Errorf("failed to register click: %w", err)
+	case !exists:
+		return storage.ErrNotFound
+	case !registered:
+		return storage.ErrClickLimitExceeded
 	}
 	return nil
 }
 
-// SaveBatch сохраняет множество URL в базе данных.
-func (s *DatabaseStorage) SaveBatch(ctx context.Context, urlModels []models.URLModel) error {
+// VariantClicks возвращает количество переходов по вариантам A/B-теста из журнала переходов.
+func (s *DatabaseStorage) VariantClicks(ctx context.Context, id string) (map[string]int64, error) {
+	query := `SELECT variant, count(*) FROM click_events WHERE short_url = $1 AND variant <> '' GROUP BY variant`
+	rows, err := s.db.Pool.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query variant clicks: %w", err)
+	}
+	defer rows.Close()
+
+	variantClicks := make(map[string]int64)
+	for rows.Next() {
+		var variant string
+		var clicks int64
+		if err := rows.Scan(&variant, &clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan variant clicks: %w", err)
+		}
+		variantClicks[variant] = clicks
+	}
+	return variantClicks, rows.Err()
+}
+
+// Update изменяет ссылку и добавляет версию в историю в одной транзакции.
+func (s *DatabaseStorage) Update(ctx context.Context, id string, version models.URLVersion) (models.URLVersion, error) {
 	tx, err := s.db.Pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return models.URLVersion{}, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	for _, urlModel := range urlModels {
-		query := `INSERT INTO urls (short_url, original_url) VALUES ($1, $2) ON CONFLICT (short_url) DO NOTHING`
-		_, err := tx.Exec(ctx, query, urlModel.ID, urlModel.URL)
+	// Блокируем строку, чтобы параллельные изменения получали последовательные номера версий.
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE short_url = $1 FOR UPDATE`
+	urlModel, err := scanURL(tx.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.URLVersion{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return models.URLVersion{}, fmt.Errorf("failed to load URL: %w", err)
+	}
+
+	version.Before = urlModel.Settings()
+	version.Fields = version.Before.Diff(version.After)
+	urlModel = urlModel.WithSettings(version.After)
+
+	rules, variants, params, err := encodeSettings(version.After)
+	if err != nil {
+		return models.URLVersion{}, err
+	}
+	update := `
+		UPDATE urls SET original_url = $2, expires_at = $3, redirect_code = $4, title = $5,
+			rules = $6, variants = $7, params = $8, notes = $9, tags = $10, folder = $11
+		WHERE short_url = $1`
+	_, err = tx.Exec(ctx, update, id, urlModel.URL, urlModel.ExpiresAt, urlModel.RedirectCode, urlModel.Title,
+		rules, variants, params, urlModel.Notes, textArrayArg(urlModel.Tags), urlModel.Folder)
+	if err != nil {
+		return models.URLVersion{}, fmt.Errorf("failed to update URL: %w", err)
+	}
+
+	before, err := json.Marshal(version.Before)
+	if err != nil {
+		return models.URLVersion{}, fmt.Errorf("failed to encode version: %w", err)
+	}
+	after, err := json.Marshal(version.After)
+	if err != nil {
+		return models.URLVersion{}, fmt.Errorf("failed to encode version: %w", err)
+	}
+	insert := `
+		INSERT INTO url_history (short_url, version, changed_by, changed_at, fields, before, after)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5, $6 FROM url_history WHERE short_url = $1
+		RETURNING version`
+	err = tx.QueryRow(ctx, insert, id, version.ChangedBy, version.ChangedAt, version.Fields, before, after).Scan(&version.Version)
+	if err != nil {
+		return models.URLVersion{}, fmt.Errorf("failed to save version: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.URLVersion{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return version, nil
+}
+
+// History возвращает историю изменений ссылки.
+func (s *DatabaseStorage) History(ctx context.Context, id string) ([]models.URLVersion, error) {
+	query := `
+		SELECT version, changed_by, changed_at, fields, before, after
+		FROM url_history WHERE short_url = $1 ORDER BY version`
+	rows, err := s.db.Pool.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []models.URLVersion
+	for rows.Next() {
+		var version models.URLVersion
+		var before, after []byte
+		if err := rows.Scan(&version.Version, &version.ChangedBy, &version.ChangedAt, &version.Fields, &before, &after); err != nil {
+			return nil, fmt.Errorf("failed to scan version: %w", err)
+		}
+		if err := json.Unmarshal(before, &version.Before); err != nil {
+			return nil, fmt.Errorf("failed to decode version: %w", err)
+		}
+		if err := json.Unmarshal(after, &version.After); err != nil {
+			return nil, fmt.Errorf("failed to decode version: %w", err)
+		}
+		history = append(history, version)
+	}
+	return history, rows.Err()
+}
+
+// Delete помечает ссылки удалёнными.
+func (s *DatabaseStorage) Delete(ctx context.Context, ids ...string) error {
+	_, err := s.db.Pool.Exec(ctx, `UPDATE urls SET is_deleted = true WHERE short_url = ANY($1) AND NOT is_deleted`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to delete URLs: %w", err)
+	}
+	return nil
+}
+
+// SetMeta сохраняет метаданные страницы назначения.
+func (s *DatabaseStorage) SetMeta(ctx context.Context, id string, meta models.PageMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode meta: %w", err)
+	}
+	tag, err := s.db.Pool.Exec(ctx, `UPDATE urls SET meta = $2 WHERE short_url = $1`, id, data)
+	if err != nil {
+		return fmt.Errorf("failed to save meta: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// Search ищет ссылки пользователя. Фильтр по тегу использует GIN-индекс urls_tags_idx,
+// поиск по словам — триграммный индекс urls_search_idx.
+func (s *DatabaseStorage) Search(ctx context.Context, filter models.URLFilter) ([]models.URLModel, error) {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{"NOT is_deleted"}
+	switch {
+	case filter.WorkspaceID != "":
+		conditions = append(conditions, "workspace_id = "+arg(filter.WorkspaceID))
+	case filter.All && filter.UserID == "":
+		// Поиск администратора по ссылкам всех владельцев.
+	default:
+		conditions = append(conditions, "user_id = "+arg(filter.UserID), "workspace_id = ''")
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, "tags @> ARRAY["+arg(filter.Tag)+"::text]")
+	}
+	if filter.Folder != "" {
+		conditions = append(conditions, "folder = "+arg(filter.Folder))
+	}
+	// Слова состоят только из букв и цифр, поэтому не содержат спецсимволов LIKE.
+	for _, word := range index.Tokenize(filter.Query) {
+		pattern := arg("%" + word + "%")
+		conditions = append(conditions, fmt.Sprintf(
+			"(%s ILIKE %s OR EXISTS (SELECT 1 FROM unnest(tags) AS tag WHERE tag LIKE %s))", searchExpr, pattern, pattern))
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(created_at, short_url) < (%s, %s)",
+			arg(filter.After.CreatedAt), arg(filter.After.ID)))
+	}
+
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE ` + strings.Join(conditions, " AND ") +
+		` ORDER BY created_at DESC, short_url DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ` + arg(filter.Limit)
+	}
+
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search URLs: %w", err)
+	}
+	defer rows.Close()
+
+	var urlModels []models.URLModel
+	for rows.Next() {
+		urlModel, err := scanURL(rows)
 		if err != nil {
-			return fmt.Errorf("failed to save URL: %w", err)
+			return nil, fmt.Errorf("failed to scan URL: %w", err)
 		}
+		urlModels = append(urlModels, urlModel)
+	}
+	return urlModels, rows.Err()
+}
+
+// Stats возвращает количество неудалённых ссылок и их владельцев.
+func (s *DatabaseStorage) Stats(ctx context.Context) (models.ServiceStats, error) {
+	var stats models.ServiceStats
+	query := `SELECT count(*), count(DISTINCT NULLIF(user_id, '')) FROM urls WHERE NOT is_deleted`
+	if err := s.db.Pool.QueryRow(ctx, query).Scan(&stats.URLs, &stats.Users); err != nil {
+		return models.ServiceStats{}, fmt.Errorf("failed to count stats: %w", err)
+	}
+	return stats, nil
+}
+
+// SetDisabled отключает ссылку или снова включает её.
+func (s *DatabaseStorage) SetDisabled(ctx context.Context, id string, disabled bool) error {
+	tag, err := s.db.Pool.Exec(ctx, `UPDATE urls SET is_disabled = $2 WHERE short_url = $1`, id, disabled)
+	if err != nil {
+		return fmt.Errorf("failed to update URL: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// SetOwner передаёт ссылку другому пользователю или рабочему пространству.
+func (s *DatabaseStorage) SetOwner(ctx context.Context, id, userID, workspaceID string) error {
+	tag, err := s.db.Pool.Exec(ctx, `UPDATE urls SET user_id = $2, workspace_id = $3 WHERE short_url = $1`, id, userID, workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to update URL: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// Purge безвозвратно удаляет ссылку, её историю и журнал переходов в одной транзакции.
+func (s *DatabaseStorage) Purge(ctx context.Context, id string) error {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM urls WHERE short_url = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to purge URL: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM url_history WHERE short_url = $1`, id); err != nil {
+		return fmt.Errorf("failed to purge history: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM click_events WHERE short_url = $1`, id); err != nil {
+		return fmt.Errorf("failed to purge clicks: %w", err)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
@@ -56,18 +413,101 @@ func (s *DatabaseStorage) SaveBatch(ctx context.Context, urlModels []models.URLM
 	return nil
 }
 
-// Get возвращает оригинальный URL по идентификатору из базы данных.
-func (s *DatabaseStorage) Get(ctx context.Context, id string) (models.URLModel, bool) {
-	query := `SELECT original_url FROM urls WHERE short_url = $1`
-	row := s.db.Pool.QueryRow(ctx, query, id)
+// insertArgs возвращает параметры для insertURLQuery.
+func insertArgs(urlModel models.URLModel) ([]any, error) {
+	rules, variants, params, err := encodeSettings(urlModel.Settings())
+	if err != nil {
+		return nil, err
+	}
+	var meta []byte
+	if urlModel.Meta != nil {
+		if meta, err = json.Marshal(urlModel.Meta); err != nil {
+			return nil, fmt.Errorf("failed to encode meta: %w", err)
+		}
+	}
+	return []any{
+		urlModel.ID, urlModel.URL, createdAt(urlModel), urlModel.Interstitial,
+		urlModel.PasswordHash, urlModel.MaxClicks, rules, variants, params,
+		urlModel.UserID, urlModel.Title, urlModel.RedirectCode, urlModel.ExpiresAt,
+		urlModel.Notes, textArrayArg(urlModel.Tags), urlModel.Folder, meta, urlModel.WorkspaceID,
+	}, nil
+}
+
+// encodeSettings кодирует JSONB-поля настроек ссылки.
+func encodeSettings(settings models.URLSettings) (rules, variants, params []byte, err error) {
+	if rules, err = encodeJSON(settings.Rules, len(settings.Rules)); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to encode rules: %w", err)
+	}
+	if variants, err = encodeJSON(settings.Variants, len(settings.Variants)); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to encode variants: %w", err)
+	}
+	if settings.Params != nil {
+		if params, err = json.Marshal(settings.Params); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to encode params: %w", err)
+		}
+	}
+	return rules, variants, params, nil
+}
 
+// textArrayArg возвращает значение для колонки TEXT[], которая не допускает NULL.
+func textArrayArg(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// encodeJSON кодирует значение для колонки JSONB, возвращая NULL для пустых коллекций.
+func encodeJSON(v any, length int) ([]byte, error) {
+	if length == 0 {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// scanURL читает ссылку из строки, выбранной с колонками urlColumns.
+func scanURL(row pgx.Row) (models.URLModel, error) {
 	var urlModel models.URLModel
-	urlModel.ID = id
-	err := row.Scan(&urlModel.URL)
+	var rules, variants, params, meta []byte
+	err := row.Scan(&urlModel.ID, &urlModel.URL, &urlModel.CreatedAt, &urlModel.Clicks,
+		&urlModel.Interstitial, &urlModel.PasswordHash, &urlModel.MaxClicks, &rules, &variants, &params,
+		&urlModel.UserID, &urlModel.Title, &urlModel.RedirectCode, &urlModel.ExpiresAt,
+		&urlModel.Notes, &urlModel.Tags, &urlModel.Folder, &meta, &urlModel.WorkspaceID, &urlModel.Deleted, &urlModel.Disabled)
 	if err != nil {
-		return models.URLModel{}, false
+		return models.URLModel{}, err
 	}
-	return urlModel, true
+	if len(urlModel.Tags) == 0 {
+		urlModel.Tags = nil
+	}
+	if len(rules) > 0 {
+		if err := json.Unmarshal(rules, &urlModel.Rules); err != nil {
+			return models.URLModel{}, fmt.Errorf("failed to decode rules: %w", err)
+		}
+	}
+	if len(variants) > 0 {
+		if err := json.Unmarshal(variants, &urlModel.Variants); err != nil {
+			return models.URLModel{}, fmt.Errorf("failed to decode variants: %w", err)
+		}
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &urlModel.Params); err != nil {
+			return models.URLModel{}, fmt.Errorf("failed to decode params: %w", err)
+		}
+	}
+	if len(meta) > 0 {
+		if err := json.Unmarshal(meta, &urlModel.Meta); err != nil {
+			return models.URLModel{}, fmt.Errorf("failed to decode meta: %w", err)
+		}
+	}
+	return urlModel, nil
+}
+
+// createdAt возвращает дату создания ссылки, подставляя текущее время, если она не задана.
+func createdAt(urlModel models.URLModel) time.Time {
+	if urlModel.CreatedAt.IsZero() {
+		return time.Now().UTC()
+	}
+	return urlModel.CreatedAt
 }
 
 // LoadFromFile загружает данные из базы данных (не требуется для базы данных).
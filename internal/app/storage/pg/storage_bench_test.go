@@ -0,0 +1,70 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/stretchr/testify/require"
+)
+
+// saveBatchPerRow — прежняя реализация SaveBatch: отдельный INSERT на каждую ссылку внутри транзакции.
+func saveBatchPerRow(ctx context.Context, s *DatabaseStorage, urlModels []models.URLModel) error {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, urlModel := range urlModels {
+		args, err := insertArgs(urlModel)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, insertURLQuery+` ON CONFLICT (short_url) DO NOTHING`, args...); err != nil {
+			return saveError(err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func BenchmarkSaveBatch(b *testing.B) {
+	ctx := context.Background()
+	prefix := fmt.Sprintf("https://bench.example/%d/", time.Now().UnixNano())
+	s := testStorage(b, prefix)
+
+	methods := []struct {
+		name string
+		save func(urlModels []models.URLModel) error
+	}{
+		{"per_row", func(urlModels []models.URLModel) error {
+			return saveBatchPerRow(ctx, s, urlModels)
+		}},
+		{"batch", func(urlModels []models.URLModel) error {
+			_, err := s.SaveBatch(ctx, urlModels)
+			return err
+		}},
+	}
+
+	for _, size := range []int{10, 100, 1000} {
+		for _, method := range methods {
+			name := fmt.Sprintf("%s/%d", method.name, size)
+			b.Run(name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					urlModels := make([]models.URLModel, size)
+					for j := range urlModels {
+						u := fmt.Sprintf("%s%s/%d/%d", prefix, name, i, j)
+						urlModels[j] = models.URLModel{ID: service.GenerateID(u), URL: u, CreatedAt: time.Now().UTC()}
+					}
+					b.StartTimer()
+
+					require.NoError(b, method.save(urlModels))
+				}
+			})
+		}
+	}
+}
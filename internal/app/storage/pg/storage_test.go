@@ -0,0 +1,75 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/db"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// testStorage подключается к базе из DATABASE_DSN и удаляет созданные тестом ссылки
+// с адресами, начинающимися с prefix, вместе с их переходами и историей, а также ключи идемпотентности
+// пользователей с идентификаторами, начинающимися с prefix. Без DATABASE_DSN тест пропускается.
+func testStorage(tb testing.TB, prefix string) *DatabaseStorage {
+	dsn := os.Getenv("DATABASE_DSN")
+	if dsn == "" {
+		tb.Skip("DATABASE_DSN is not set")
+	}
+	ctx := context.Background()
+	database, err := db.NewDatabaseConnection(ctx, dsn)
+	require.NoError(tb, err)
+	tb.Cleanup(func() {
+		for _, table := range []string{"click_events", "url_history"} {
+			database.Pool.Exec(ctx, `DELETE FROM `+table+` WHERE short_url IN (SELECT short_url FROM urls WHERE original_url LIKE $1)`, prefix+"%")
+		}
+		database.Pool.Exec(ctx, `DELETE FROM urls WHERE original_url LIKE $1`, prefix+"%")
+		database.Pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE user_id LIKE $1`, prefix+"%")
+		database.Close()
+	})
+	return NewDatabaseStorage(database)
+}
+
+func TestDatabaseStorage_LinkIsolation(t *testing.T) {
+	prefix := fmt.Sprintf("https://test.example/%d/", time.Now().UnixNano())
+	storagetest.LinkIsolation(t, testStorage(t, prefix), prefix+"secret")
+}
+
+func TestDatabaseStorage_EditedLinkIsolation(t *testing.T) {
+	prefix := fmt.Sprintf("https://test.example/%d/", time.Now().UnixNano())
+	storagetest.EditedLinkIsolation(t, testStorage(t, prefix), prefix)
+}
+
+func TestDatabaseStorage_WorkspaceLinkIsolation(t *testing.T) {
+	prefix := fmt.Sprintf("https://test.example/%d/", time.Now().UnixNano())
+	storagetest.WorkspaceLinkIsolation(t, testStorage(t, prefix), prefix+"team")
+}
+
+func TestDatabaseStorage_RemovedLinkIsolation(t *testing.T) {
+	prefix := fmt.Sprintf("https://test.example/%d/", time.Now().UnixNano())
+	storagetest.RemovedLinkIsolation(t, testStorage(t, prefix), prefix)
+}
+
+func TestDatabaseStorage_BatchConflicts(t *testing.T) {
+	prefix := fmt.Sprintf("https://test.example/%d/", time.Now().UnixNano())
+	storagetest.BatchConflicts(t, testStorage(t, prefix), prefix)
+}
+
+func TestDatabaseStorage_IdempotencyPurge(t *testing.T) {
+	prefix := fmt.Sprintf("https://test.example/%d/", time.Now().UnixNano())
+	storagetest.IdempotencyPurge(t, testStorage(t, prefix), prefix+"alice")
+}
+
+func TestDatabaseStorage_IdempotencyLease(t *testing.T) {
+	prefix := fmt.Sprintf("https://test.example/%d/", time.Now().UnixNano())
+	storagetest.IdempotencyLease(t, testStorage(t, prefix), prefix+"alice")
+}
+
+func TestDatabaseStorage_ClickLimitIsolation(t *testing.T) {
+	prefix := fmt.Sprintf("https://test.example/%d/", time.Now().UnixNano())
+	storagetest.ClickLimitIsolation(t, testStorage(t, prefix), prefix+"invite")
+}
@@ -0,0 +1,219 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateWorkspace создаёт рабочее пространство и добавляет владельца в одной транзакции.
+func (s *DatabaseStorage) CreateWorkspace(ctx context.Context, workspace models.Workspace, owner models.Member) error {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO workspaces (id, name, created_by, created_at) VALUES ($1, $2, $3, $4)`,
+		workspace.ID, workspace.Name, workspace.CreatedBy, workspace.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO workspace_members (workspace_id, user_id, role, joined_at) VALUES ($1, $2, $3, $4)`,
+		owner.WorkspaceID, owner.UserID, owner.Role, owner.JoinedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save member: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Workspace возвращает рабочее пространство по идентификатору.
+func (s *DatabaseStorage) Workspace(ctx context.Context, id string) (models.Workspace, error) {
+	var workspace models.Workspace
+	err := s.db.Pool.QueryRow(ctx, `SELECT id, name, created_by, created_at FROM workspaces WHERE id = $1`, id).
+		Scan(&workspace.ID, &workspace.Name, &workspace.CreatedBy, &workspace.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Workspace{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return models.Workspace{}, fmt.Errorf("failed to load workspace: %w", err)
+	}
+	return workspace, nil
+}
+
+// UserWorkspaces возвращает рабочие пространства пользователя с его ролями.
+func (s *DatabaseStorage) UserWorkspaces(ctx context.Context, userID string) ([]models.Membership, error) {
+	query := `
+		SELECT w.id, w.name, w.created_by, w.created_at, m.role
+		FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE m.user_id = $1 ORDER BY w.created_at`
+	rows, err := s.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workspaces: %w", err)
+	}
+	defer rows.Close()
+
+	memberships := []models.Membership{}
+	for rows.Next() {
+		var m models.Membership
+		if err := rows.Scan(&m.ID, &m.Name, &m.CreatedBy, &m.CreatedAt, &m.Role); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace: %w", err)
+		}
+		memberships = append(memberships, m)
+	}
+	return memberships, rows.Err()
+}
+
+// Member возвращает участника рабочего пространства.
+func (s *DatabaseStorage) Member(ctx context.Context, workspaceID, userID string) (models.Member, error) {
+	member := models.Member{WorkspaceID: workspaceID, UserID: userID}
+	err := s.db.Pool.QueryRow(ctx,
+		`SELECT role, joined_at FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`, workspaceID, userID).
+		Scan(&member.Role, &member.JoinedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Member{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return models.Member{}, fmt.Errorf("failed to load member: %w", err)
+	}
+	return member, nil
+}
+
+// Members возвращает участников рабочего пространства в порядке вступления.
+func (s *DatabaseStorage) Members(ctx context.Context, workspaceID string) ([]models.Member, error) {
+	query := `
+		SELECT workspace_id, user_id, role, joined_at FROM workspace_members
+		WHERE workspace_id = $1 ORDER BY joined_at, user_id`
+	rows, err := s.db.Pool.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.Member{}
+	for rows.Next() {
+		var member models.Member
+		if err := rows.Scan(&member.WorkspaceID, &member.UserID, &member.Role, &member.JoinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan member: %w", err)
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// SaveMember добавляет участника или меняет его роль.
+func (s *DatabaseStorage) SaveMember(ctx context.Context, member models.Member) error {
+	query := `
+		INSERT INTO workspace_members (workspace_id, user_id, role, joined_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+	_, err := s.db.Pool.Exec(ctx, query, member.WorkspaceID, member.UserID, member.Role, member.JoinedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save member: %w", err)
+	}
+	return nil
+}
+
+// DeleteMember исключает участника из рабочего пространства.
+func (s *DatabaseStorage) DeleteMember(ctx context.Context, workspaceID, userID string) error {
+	tag, err := s.db.Pool.Exec(ctx,
+		`DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`, workspaceID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete member: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// SaveInvitation сохраняет приглашение в рабочее пространство.
+func (s *DatabaseStorage) SaveInvitation(ctx context.Context, invitation models.Invitation, tokenHash string) error {
+	query := `
+		INSERT INTO workspace_invitations (token_hash, id, workspace_id, role, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := s.db.Pool.Exec(ctx, query, tokenHash, invitation.ID, invitation.WorkspaceID, invitation.Role,
+		invitation.CreatedBy, invitation.CreatedAt, invitation.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save invitation: %w", err)
+	}
+	return nil
+}
+
+// Invitations возвращает приглашения в рабочее пространство в порядке создания.
+func (s *DatabaseStorage) Invitations(ctx context.Context, workspaceID string) ([]models.Invitation, error) {
+	query := `
+		SELECT id, workspace_id, role, created_by, created_at, expires_at FROM workspace_invitations
+		WHERE workspace_id = $1 ORDER BY created_at`
+	rows, err := s.db.Pool.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := []models.Invitation{}
+	for rows.Next() {
+		var inv models.Invitation
+		if err := rows.Scan(&inv.ID, &inv.WorkspaceID, &inv.Role, &inv.CreatedBy, &inv.CreatedAt, &inv.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan invitation: %w", err)
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+// DeleteInvitation отзывает приглашение.
+func (s *DatabaseStorage) DeleteInvitation(ctx context.Context, workspaceID, id string) error {
+	tag, err := s.db.Pool.Exec(ctx,
+		`DELETE FROM workspace_invitations WHERE workspace_id = $1 AND id = $2`, workspaceID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete invitation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation удаляет действующее приглашение и добавляет пользователя в рабочее пространство
+// в одной транзакции. Роль существующего участника не меняется.
+func (s *DatabaseStorage) AcceptInvitation(ctx context.Context, tokenHash, userID string, now time.Time) (models.Member, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return models.Member{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var workspaceID, role string
+	err = tx.QueryRow(ctx, `
+		DELETE FROM workspace_invitations WHERE token_hash = $1 AND expires_at > $2
+		RETURNING workspace_id, role`, tokenHash, now).Scan(&workspaceID, &role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Member{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return models.Member{}, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	member := models.Member{WorkspaceID: workspaceID, UserID: userID}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role, joined_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = workspace_members.role
+		RETURNING role, joined_at`, workspaceID, userID, role, now).Scan(&member.Role, &member.JoinedAt)
+	if err != nil {
+		return models.Member{}, fmt.Errorf("failed to save member: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.Member{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return member, nil
+}
@@ -2,24 +2,182 @@ package storage
 
 import (
 	"context"
+	"sort"
+	"time"
 
+	"github.com/alexuryumtsev/go-shortener/internal/app/apperr"
 	"github.com/alexuryumtsev/go-shortener/internal/app/models"
 )
 
+// ErrClickLimitExceeded возвращается, если лимит переходов по ссылке исчерпан.
+var ErrClickLimitExceeded = apperr.New(apperr.Gone, "click limit exceeded")
+
+// ErrNotFound возвращается, если ссылка не найдена.
+var ErrNotFound = apperr.New(apperr.NotFound, "URL not found")
+
+// ErrConflict возвращается, если ссылка с таким идентификатором уже сохранена.
+var ErrConflict = apperr.New(apperr.Conflict, "URL already shortened")
+
 // URLReader определяет методы для чтения URL.
+// GetMany возвращает найденные ссылки по идентификаторам; неизвестные идентификаторы пропускаются.
 type URLReader interface {
 	Get(ctx context.Context, id string) (models.URLModel, bool)
+	GetMany(ctx context.Context, ids []string) (map[string]models.URLModel, error)
 	LoadFromFile() error
 }
 
 // URLWriter определяет методы для записи URL.
+// Save сохраняет новую ссылку и возвращает ErrConflict, если ссылка с таким идентификатором
+// уже существует; существующая ссылка не изменяется.
+// SaveBatch сохраняет ссылки, которых ещё нет в хранилище, и сообщает для каждой ссылки,
+// существовала ли она раньше; существующие ссылки не изменяются.
 type URLWriter interface {
 	Save(ctx context.Context, urlModel models.URLModel) error
-	SaveBatch(ctx context.Context, urlModels []models.URLModel) error
+	SaveBatch(ctx context.Context, urlModels []models.URLModel) (existed []bool, err error)
+}
+
+// URLClickCounter определяет методы для учёта переходов по коротким ссылкам.
+// RegisterClick атомарно проверяет лимит переходов и возвращает ErrClickLimitExceeded, если он исчерпан,
+// и ErrNotFound, если ссылки нет или она удалена.
+type URLClickCounter interface {
+	RegisterClick(ctx context.Context, click models.Click) error
+	VariantClicks(ctx context.Context, id string) (map[string]int64, error)
+}
+
+// URLEditor определяет методы для изменения ссылок с сохранением истории версий.
+// Update применяет version.After к ссылке и сохраняет версию, заполняя в ней
+// номер, предыдущее состояние и список изменённых полей.
+// Delete помечает ссылки удалёнными, неизвестные идентификаторы пропускаются.
+type URLEditor interface {
+	Update(ctx context.Context, id string, version models.URLVersion) (models.URLVersion, error)
+	History(ctx context.Context, id string) ([]models.URLVersion, error)
+	Delete(ctx context.Context, ids ...string) error
+}
+
+// URLSearcher определяет методы поиска ссылок пользователя.
+// Search возвращает не более filter.Limit ссылок, отсортированных от новых к старым
+// и следующих за позицией filter.After.
+type URLSearcher interface {
+	Search(ctx context.Context, filter models.URLFilter) ([]models.URLModel, error)
+}
+
+// URLEnricher определяет методы сохранения метаданных страницы назначения.
+type URLEnricher interface {
+	SetMeta(ctx context.Context, id string, meta models.PageMeta) error
+}
+
+// URLAdmin определяет методы администрирования ссылок.
+// SetDisabled, SetOwner и Purge возвращают ErrNotFound для неизвестной ссылки.
+// Purge безвозвратно удаляет ссылку вместе с историей изменений и журналом переходов.
+type URLAdmin interface {
+	Stats(ctx context.Context) (models.ServiceStats, error)
+	SetDisabled(ctx context.Context, id string, disabled bool) error
+	SetOwner(ctx context.Context, id, userID, workspaceID string) error
+	Purge(ctx context.Context, id string) error
 }
 
-// URLStorage объединяет интерфейсы URLReader и URLWriter.
+// WorkspaceStorage определяет методы хранения рабочих пространств, их участников и приглашений.
+// Приглашения хранятся и ищутся по хешу токена. AcceptInvitation атомарно удаляет
+// приглашение, действующее на момент now, и добавляет пользователя в пространство;
+// роль существующего участника не меняется.
+type WorkspaceStorage interface {
+	CreateWorkspace(ctx context.Context, workspace models.Workspace, owner models.Member) error
+	Workspace(ctx context.Context, id string) (models.Workspace, error)
+	UserWorkspaces(ctx context.Context, userID string) ([]models.Membership, error)
+	Member(ctx context.Context, workspaceID, userID string) (models.Member, error)
+	Members(ctx context.Context, workspaceID string) ([]models.Member, error)
+	SaveMember(ctx context.Context, member models.Member) error
+	DeleteMember(ctx context.Context, workspaceID, userID string) error
+	SaveInvitation(ctx context.Context, invitation models.Invitation, tokenHash string) error
+	Invitations(ctx context.Context, workspaceID string) ([]models.Invitation, error)
+	DeleteInvitation(ctx context.Context, workspaceID, id string) error
+	AcceptInvitation(ctx context.Context, tokenHash, userID string, now time.Time) (models.Member, error)
+}
+
+// APIKeyStorage определяет методы хранения API-ключей. Ключи хранятся и ищутся по хешу.
+// DeleteAPIKey и отсутствующий ключ в APIKeyByHash дают ErrNotFound.
+type APIKeyStorage interface {
+	SaveAPIKey(ctx context.Context, key models.APIKey, keyHash string) error
+	APIKeys(ctx context.Context, userID string) ([]models.APIKey, error)
+	APIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID, id string) error
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+}
+
+// AuditStorage определяет методы журнала аудита. Журнал только пополняется: записанные события
+// не изменяются и не удаляются. AuditEvents возвращает события, отсортированные от новых к старым.
+type AuditStorage interface {
+	AppendAudit(ctx context.Context, event models.AuditEvent) error
+	AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+}
+
+// IdempotencyStorage определяет методы хранения ответов на запросы с заголовком Idempotency-Key.
+// ReserveIdempotencyKey атомарно закрепляет ключ пользователя за запросом и возвращает reserved = true,
+// если ключ свободен, срок его записи истёк к record.CreatedAt или незавершённый запрос не продлил
+// удержание ключа (LockedUntil) до этого момента; иначе возвращает существующую запись.
+// CompleteIdempotencyKey сохраняет ответ в закреплённой записи, ReleaseIdempotencyKey удаляет
+// незавершённую запись, чтобы запрос можно было повторить. Оба метода изменяют запись, только
+// если она закреплена тем же запросом: RequestHash и CreatedAt совпадают, иначе Complete
+// возвращает ErrNotFound, а Release ничего не делает. PurgeIdempotencyKeys удаляет записи,
+// срок которых истёк к now, и возвращает их количество.
+type IdempotencyStorage interface {
+	ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (existing models.IdempotencyRecord, reserved bool, err error)
+	CompleteIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error
+	ReleaseIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error
+	PurgeIdempotencyKeys(ctx context.Context, now time.Time) (int, error)
+}
+
+// URLStorage объединяет интерфейсы чтения, записи, учёта переходов, изменения, поиска, обогащения
+// и администрирования ссылок, а также хранения рабочих пространств, API-ключей, журнала аудита
+// и ответов на идемпотентные запросы.
 type URLStorage interface {
 	URLReader
 	URLWriter
+	URLClickCounter
+	URLEditor
+	URLSearcher
+	URLEnricher
+	URLAdmin
+	WorkspaceStorage
+	APIKeyStorage
+	AuditStorage
+	IdempotencyStorage
+}
+
+// CountStats подсчитывает общую статистику по ссылкам хранилищ, держащих данные в памяти.
+func CountStats(data map[string]models.URLModel) models.ServiceStats {
+	var stats models.ServiceStats
+	users := make(map[string]struct{})
+	for _, urlModel := range data {
+		if urlModel.Deleted {
+			continue
+		}
+		stats.URLs++
+		if urlModel.UserID != "" {
+			users[urlModel.UserID] = struct{}{}
+		}
+	}
+	stats.Users = len(users)
+	return stats
+}
+
+// SelectAuditEvents отбирает события, подходящие под фильтр, для хранилищ, держащих журнал аудита
+// в памяти или читающих его целиком, и возвращает их от новых к старым.
+func SelectAuditEvents(events []models.AuditEvent, filter models.AuditFilter) []models.AuditEvent {
+	selected := make([]models.AuditEvent, 0)
+	for _, event := range events {
+		if filter.Match(event) && (filter.After == nil || filter.After.BeforeEvent(event)) {
+			selected = append(selected, event)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		if selected[i].Time.Equal(selected[j].Time) {
+			return selected[i].ID > selected[j].ID
+		}
+		return selected[i].Time.After(selected[j].Time)
+	})
+	if filter.Limit > 0 && len(selected) > filter.Limit {
+		selected = selected[:filter.Limit]
+	}
+	return selected
 }
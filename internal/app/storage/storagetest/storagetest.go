@@ -0,0 +1,384 @@
+// Package storagetest содержит общие проверки, которые выполняются для каждой реализации хранилища.
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/auth"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const baseURL = "http://localhost:8080"
+
+// LinkIsolation проверяет, что ссылки разных пользователей на адрес url не перезаписывают друг друга:
+// Save не изменяет существующую ссылку, а защищённая паролем ссылка получает собственный идентификатор.
+// Адрес url не должен встречаться в хранилище.
+func LinkIsolation(t *testing.T, repo storage.URLStorage, url string) {
+	t.Helper()
+	// Пользователи уникальны для адреса, чтобы проверка не зависела от данных общей базы.
+	aliceID, bobID := "alice "+url, "bob "+url
+	alice := auth.WithUserID(context.Background(), aliceID)
+	bob := auth.WithUserID(context.Background(), bobID)
+
+	hash, err := service.HashPassword("secret")
+	require.NoError(t, err)
+	protected, err := service.NewURLService(alice, repo, baseURL).ShortenerURLModel(models.URLModel{URL: url, PasswordHash: hash})
+	require.NoError(t, err)
+
+	// Ссылка без пароля на тот же адрес создаётся отдельно и не затрагивает защищённую.
+	plain, err := service.NewURLService(bob, repo, baseURL).ShortenerURLModel(models.URLModel{URL: url})
+	require.NoError(t, err)
+	assert.NotEqual(t, protected.ID, plain.ID)
+
+	// Повторное сокращение адреса без параметров возвращает существующую ссылку.
+	again, err := service.NewURLService(alice, repo, baseURL).ShortenerURLModel(models.URLModel{URL: url})
+	assert.ErrorIs(t, err, storage.ErrConflict)
+	assert.Equal(t, plain, again)
+
+	// Сохранение ссылки с занятым идентификатором не меняет владельца и пароль.
+	err = repo.Save(bob, models.URLModel{ID: protected.ID, URL: url, UserID: bobID, CreatedAt: time.Now().UTC()})
+	assert.ErrorIs(t, err, storage.ErrConflict)
+
+	link, exists := repo.Get(alice, protected.ID)
+	require.True(t, exists)
+	assert.Equal(t, aliceID, link.UserID)
+	assert.Equal(t, hash, link.PasswordHash)
+
+	links, err := repo.Search(alice, models.URLFilter{UserID: aliceID})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, protected.ID, links[0].ID)
+
+	link, exists = repo.Get(bob, plain.ID)
+	require.True(t, exists)
+	assert.Equal(t, bobID, link.UserID)
+	assert.Empty(t, link.PasswordHash)
+}
+
+// WorkspaceLinkIsolation проверяет, что ссылки рабочего пространства на адрес url всегда создаются
+// заново и не выдаются пользователям вне пространства, а личная ссылка на тот же адрес создаётся
+// отдельно. Адрес url не должен встречаться в хранилище.
+func WorkspaceLinkIsolation(t *testing.T, repo storage.URLStorage, url string) {
+	t.Helper()
+	aliceID, bobID := "alice "+url, "bob "+url
+	team := auth.WithAPIKey(context.Background(), models.APIKey{UserID: aliceID, WorkspaceID: "team " + url})
+	bob := auth.WithUserID(context.Background(), bobID)
+	teamService := service.NewURLService(team, repo, baseURL)
+
+	created, err := teamService.ShortenerURLModel(models.URLModel{URL: url})
+	require.NoError(t, err)
+	batch, err := teamService.SaveBatchShortenerURL([]models.URLBatchModel{{CorrelationID: "1", OriginalURL: url}})
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	assert.Equal(t, models.BulkCreated, batch[0].Status)
+	bulk, err := teamService.ShortenBulk([]models.BulkItem{{Line: 1, URL: url}})
+	require.NoError(t, err)
+	require.Len(t, bulk, 1)
+	assert.Equal(t, models.BulkCreated, bulk[0].Status)
+	teamURLs := []string{created.ShortURL, batch[0].ShortURL, bulk[0].ShortURL}
+	assert.Len(t, uniq(teamURLs), 3)
+
+	personal, err := service.NewURLService(bob, repo, baseURL).ShortenerURLModel(models.URLModel{URL: url})
+	require.NoError(t, err)
+	assert.NotContains(t, teamURLs, personal.ShortURL)
+
+	again, err := teamService.ShortenerURLModel(models.URLModel{URL: url})
+	require.NoError(t, err)
+	assert.NotEqual(t, personal.ID, again.ID)
+
+	link, exists := repo.Get(bob, personal.ID)
+	require.True(t, exists)
+	assert.Equal(t, bobID, link.UserID)
+	assert.Empty(t, link.WorkspaceID)
+	link, exists = repo.Get(team, created.ID)
+	require.True(t, exists)
+	assert.Equal(t, "team "+url, link.WorkspaceID)
+}
+
+// RemovedLinkIsolation проверяет, что повторное сокращение адресов prefix+"deleted" и prefix+"disabled",
+// ссылки на которые удалены или отключены администратором, создаёт новые ссылки, а не возвращает
+// неоткрывающиеся. Адреса с префиксом prefix не должны встречаться в хранилище.
+func RemovedLinkIsolation(t *testing.T, repo storage.URLStorage, prefix string) {
+	t.Helper()
+	ctx := auth.WithUserID(context.Background(), "owner "+prefix)
+	urlService := service.NewURLService(ctx, repo, baseURL)
+	deleted, disabled := prefix+"deleted", prefix+"disabled"
+
+	removed := make(map[string]string, 2)
+	for _, url := range []string{deleted, disabled} {
+		link, err := urlService.ShortenerURLModel(models.URLModel{URL: url})
+		require.NoError(t, err)
+		removed[url] = link.ID
+	}
+	require.NoError(t, repo.Delete(ctx, removed[deleted]))
+	require.NoError(t, repo.SetDisabled(ctx, removed[disabled], true))
+
+	for _, url := range []string{deleted, disabled} {
+		again, err := urlService.ShortenerURLModel(models.URLModel{URL: url})
+		require.NoError(t, err, url)
+		assert.NotEqual(t, removed[url], again.ID, url)
+
+		link, exists := repo.Get(ctx, again.ID)
+		require.True(t, exists, url)
+		assert.False(t, link.Deleted || link.Disabled, url)
+	}
+
+	batch, err := urlService.SaveBatchShortenerURL([]models.URLBatchModel{
+		{CorrelationID: "1", OriginalURL: deleted},
+		{CorrelationID: "2", OriginalURL: disabled},
+	})
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+	for i, url := range []string{deleted, disabled} {
+		assert.Equal(t, models.BulkCreated, batch[i].Status, url)
+		assert.NotEqual(t, baseURL+"/"+removed[url], batch[i].ShortURL, url)
+	}
+}
+
+// BatchConflicts проверяет, что GetMany возвращает только известные ссылки, а занятые идентификаторы
+// пачки читаются через GetMany без отдельного Get на каждую ссылку. Адреса с префиксом prefix
+// не должны встречаться в хранилище.
+func BatchConflicts(t *testing.T, repo storage.URLStorage, prefix string) {
+	t.Helper()
+	ctx := auth.WithUserID(context.Background(), "owner "+prefix)
+	urls := []string{prefix + "a", prefix + "b", prefix + "c"}
+
+	var batch []models.URLBatchModel
+	for i, url := range urls {
+		batch = append(batch, models.URLBatchModel{CorrelationID: strconv.Itoa(i), OriginalURL: url})
+	}
+	created, err := service.NewURLService(ctx, repo, baseURL).SaveBatchShortenerURL(batch)
+	require.NoError(t, err)
+	require.Len(t, created, len(urls))
+
+	ids := []string{service.GenerateID(urls[0]), service.GenerateID(urls[1]), service.GenerateID(prefix + "unknown")}
+	found, err := repo.GetMany(ctx, ids)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, urls[0], found[ids[0]].URL)
+	assert.Equal(t, urls[1], found[ids[1]].URL)
+
+	counting := &countingStorage{URLStorage: repo}
+	again, err := service.NewURLService(ctx, counting, baseURL).SaveBatchShortenerURL(batch)
+	require.NoError(t, err)
+	for i := range again {
+		assert.Equal(t, models.BulkConflict, again[i].Status)
+		assert.Equal(t, created[i].ShortURL, again[i].ShortURL)
+	}
+	assert.Zero(t, counting.gets)
+	assert.Equal(t, 1, counting.getManys)
+}
+
+// countingStorage считает обращения к хранилищу за ссылками.
+type countingStorage struct {
+	storage.URLStorage
+	gets, getManys int
+}
+
+func (s *countingStorage) Get(ctx context.Context, id string) (models.URLModel, bool) {
+	s.gets++
+	return s.URLStorage.Get(ctx, id)
+}
+
+func (s *countingStorage) GetMany(ctx context.Context, ids []string) (map[string]models.URLModel, error) {
+	s.getManys++
+	return s.URLStorage.GetMany(ctx, ids)
+}
+
+// uniq возвращает значения values без повторов.
+func uniq(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+// ClickLimitIsolation проверяет, что ссылка с лимитом переходов на уже сокращённый адрес url
+// создаётся отдельно и соблюдает свой лимит, не затрагивая существующую ссылку без лимита,
+// а переход по отсутствующей или удалённой ссылке возвращает storage.ErrNotFound. Адрес url не должен встречаться в хранилище.
+func ClickLimitIsolation(t *testing.T, repo storage.URLStorage, url string) {
+	t.Helper()
+	ctx := auth.WithUserID(context.Background(), "owner "+url)
+	urlService := service.NewURLService(ctx, repo, baseURL)
+
+	unlimited, err := urlService.ShortenerURLModel(models.URLModel{URL: url})
+	require.NoError(t, err)
+	oneTime, err := urlService.ShortenerURLModel(models.URLModel{URL: url, MaxClicks: 1})
+	require.NoError(t, err)
+	assert.NotEqual(t, unlimited.ID, oneTime.ID)
+
+	now := time.Now().UTC()
+	require.NoError(t, repo.RegisterClick(ctx, models.Click{ID: oneTime.ID, ClickID: "1", Time: now}))
+	assert.ErrorIs(t, repo.RegisterClick(ctx, models.Click{ID: oneTime.ID, ClickID: "2", Time: now}), storage.ErrClickLimitExceeded)
+
+	for _, clickID := range []string{"3", "4"} {
+		require.NoError(t, repo.RegisterClick(ctx, models.Click{ID: unlimited.ID, ClickID: clickID, Time: now}))
+	}
+
+	// Переход по отсутствующей или удалённой ссылке не путается с исчерпанным лимитом.
+	require.NoError(t, repo.Delete(ctx, oneTime.ID))
+	assert.ErrorIs(t, repo.RegisterClick(ctx, models.Click{ID: oneTime.ID, ClickID: "5", Time: now}), storage.ErrNotFound)
+	require.NoError(t, repo.Purge(ctx, oneTime.ID))
+	assert.ErrorIs(t, repo.RegisterClick(ctx, models.Click{ID: oneTime.ID, ClickID: "6", Time: now}), storage.ErrNotFound)
+}
+
+// EditedLinkIsolation проверяет, что владелец не может изменить переход по ссылке, идентификатор
+// которой выведен из адреса и выдаётся всем, кто сокращает этот адрес, а после изменения адреса
+// такой ссылки в обход сервиса повторное сокращение прежнего адреса prefix+"typo" создаёт новую
+// ссылку и не затрагивает изменённую. Адреса с префиксом prefix не должны встречаться в хранилище.
+func EditedLinkIsolation(t *testing.T, repo storage.URLStorage, prefix string) {
+	t.Helper()
+	ownerID, strangerID := "owner "+prefix, "stranger "+prefix
+	owner := auth.WithUserID(context.Background(), ownerID)
+	stranger := auth.WithUserID(context.Background(), strangerID)
+	typo, fixed := prefix+"typo", prefix+"fixed"
+
+	link, err := service.NewURLService(owner, repo, baseURL).ShortenerURLModel(models.URLModel{URL: typo})
+	require.NoError(t, err)
+	links := service.NewLinkService(owner, repo, baseURL)
+	for field, value := range map[string]string{
+		"url":   strconv.Quote(fixed),
+		"rules": `[{"url":"` + fixed + `","countries":["DE"]}]`,
+	} {
+		_, err = links.Update(link.ID, ownerID, map[string]json.RawMessage{field: json.RawMessage(value)})
+		assert.ErrorIs(t, err, service.ErrSharedLink, field)
+	}
+	_, err = links.Update(link.ID, ownerID, map[string]json.RawMessage{"title": json.RawMessage(`"Typo"`)})
+	require.NoError(t, err)
+
+	shared, err := service.NewURLService(stranger, repo, baseURL).ShortenerURLModel(models.URLModel{URL: typo})
+	require.ErrorIs(t, err, storage.ErrConflict)
+	assert.Equal(t, link.ID, shared.ID)
+
+	// Адрес ссылки, изменённой до появления запрета, больше не совпадает с выведенным идентификатором.
+	_, err = repo.Update(owner, link.ID, models.URLVersion{
+		ChangedBy: ownerID,
+		ChangedAt: time.Now().UTC(),
+		After:     models.URLSettings{URL: fixed, Title: "Typo"},
+	})
+	require.NoError(t, err)
+
+	again, err := service.NewURLService(stranger, repo, baseURL).ShortenerURLModel(models.URLModel{URL: typo})
+	require.NoError(t, err)
+	assert.NotEqual(t, link.ID, again.ID)
+
+	batch, err := service.NewURLService(stranger, repo, baseURL).SaveBatchShortenerURL([]models.URLBatchModel{
+		{CorrelationID: "1", OriginalURL: typo},
+	})
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	assert.NotEqual(t, baseURL+"/"+link.ID, batch[0].ShortURL)
+
+	edited, exists := repo.Get(owner, link.ID)
+	require.True(t, exists)
+	assert.Equal(t, ownerID, edited.UserID)
+	assert.Equal(t, fixed, edited.URL)
+	history, err := repo.History(owner, link.ID)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	created, exists := repo.Get(stranger, again.ID)
+	require.True(t, exists)
+	assert.Equal(t, strangerID, created.UserID)
+	assert.Equal(t, typo, created.URL)
+}
+
+// IdempotencyPurge проверяет, что PurgeIdempotencyKeys удаляет только записи с истёкшим сроком:
+// завершённые и выполняющиеся запросы в пределах срока хранения остаются. Записи создаются
+// для пользователя userID, который не должен встречаться в хранилище.
+func IdempotencyPurge(t *testing.T, repo storage.IdempotencyStorage, userID string) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Date(2000, time.January, 1, 12, 0, 0, 0, time.UTC)
+	reserve := func(key string, createdAt time.Time) models.IdempotencyRecord {
+		record := models.IdempotencyRecord{UserID: userID, Key: key, RequestHash: "h-" + key,
+			CreatedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour), LockedUntil: createdAt.Add(time.Hour)}
+		_, reserved, err := repo.ReserveIdempotencyKey(ctx, record)
+		require.NoError(t, err)
+		require.True(t, reserved)
+		return record
+	}
+
+	completed := reserve("completed", now.Add(-time.Minute))
+	completed.Status = 201
+	completed.Header = map[string][]string{"Content-Type": {"application/json"}}
+	completed.Body = []byte(`{"result":"http://localhost/abc"}`)
+	require.NoError(t, repo.CompleteIdempotencyKey(ctx, completed))
+	pending := reserve("pending", now)
+	expired := reserve("expired", now.Add(-2*time.Hour))
+	require.NoError(t, repo.CompleteIdempotencyKey(ctx, expired))
+
+	purged, err := repo.PurgeIdempotencyKeys(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	purged, err = repo.PurgeIdempotencyKeys(ctx, now)
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	existing, reserved, err := repo.ReserveIdempotencyKey(ctx, models.IdempotencyRecord{UserID: userID, Key: "completed", CreatedAt: now})
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, completed.Body, existing.Body)
+	assert.Equal(t, completed.Header, existing.Header)
+	assert.True(t, existing.Completed)
+
+	existing, reserved, err = repo.ReserveIdempotencyKey(ctx, models.IdempotencyRecord{UserID: userID, Key: "pending", CreatedAt: now})
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, pending.RequestHash, existing.RequestHash)
+}
+
+// IdempotencyLease проверяет, что незавершённая запись, удержание которой истекло, закрепляется
+// повтором запроса, а закрепивший её раньше запрос больше не может ни сохранить ответ, ни
+// освободить ключ. Завершённая запись хранится до конца срока. Записи создаются для пользователя
+// userID, который не должен встречаться в хранилище.
+func IdempotencyLease(t *testing.T, repo storage.IdempotencyStorage, userID string) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Date(2000, time.January, 1, 12, 0, 0, 0, time.UTC)
+	record := func(createdAt time.Time) models.IdempotencyRecord {
+		return models.IdempotencyRecord{UserID: userID, Key: "lease", RequestHash: "h", CreatedAt: createdAt,
+			ExpiresAt: createdAt.Add(time.Hour), LockedUntil: createdAt.Add(time.Minute)}
+	}
+
+	crashed := record(now)
+	_, reserved, err := repo.ReserveIdempotencyKey(ctx, crashed)
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	existing, reserved, err := repo.ReserveIdempotencyKey(ctx, record(now.Add(30*time.Second)))
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	assert.True(t, existing.CreatedAt.Equal(now))
+
+	retry := record(now.Add(2 * time.Minute))
+	_, reserved, err = repo.ReserveIdempotencyKey(ctx, retry)
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	crashed.Status = 201
+	assert.ErrorIs(t, repo.CompleteIdempotencyKey(ctx, crashed), storage.ErrNotFound)
+	require.NoError(t, repo.ReleaseIdempotencyKey(ctx, crashed))
+	existing, reserved, err = repo.ReserveIdempotencyKey(ctx, record(now.Add(150*time.Second)))
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	assert.True(t, existing.CreatedAt.Equal(retry.CreatedAt))
+
+	retry.Status = 201
+	retry.Body = []byte("created")
+	require.NoError(t, repo.CompleteIdempotencyKey(ctx, retry))
+	existing, reserved, err = repo.ReserveIdempotencyKey(ctx, record(now.Add(10*time.Minute)))
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	assert.True(t, existing.Completed)
+	assert.Equal(t, retry.Body, existing.Body)
+}
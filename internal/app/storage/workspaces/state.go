@@ -0,0 +1,261 @@
+package workspaces
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage"
+)
+
+// Типы событий изменения рабочих пространств.
+const (
+	WorkspaceCreated   = "workspace_created"
+	MemberSaved        = "member_saved"
+	MemberDeleted      = "member_deleted"
+	InvitationCreated  = "invitation_created"
+	InvitationDeleted  = "invitation_deleted"
+	InvitationAccepted = "invitation_accepted"
+)
+
+// Event описывает изменение рабочих пространств. Файловое хранилище сохраняет события
+// в журнал и восстанавливает по нему состояние.
+type Event struct {
+	Type        string             `json:"type"`
+	Workspace   *models.Workspace  `json:"workspace,omitempty"`
+	Member      *models.Member     `json:"member,omitempty"`
+	Invitation  *models.Invitation `json:"invitation,omitempty"`
+	WorkspaceID string             `json:"workspace_id,omitempty"`
+	UserID      string             `json:"user_id,omitempty"`
+	ID          string             `json:"id,omitempty"`
+	TokenHash   string             `json:"token_hash,omitempty"`
+	Time        time.Time          `json:"time,omitempty"`
+}
+
+// invitation хранит приглашение вместе с хешем его токена.
+type invitation struct {
+	models.Invitation
+	tokenHash string
+}
+
+// State хранит рабочие пространства, участников и приглашения в памяти.
+// State не потокобезопасен, синхронизация остаётся на стороне хранилища.
+type State struct {
+	journal     func(Event) error // Вызывается перед применением каждого изменения, может быть nil
+	workspaces  map[string]models.Workspace
+	members     map[string]map[string]models.Member // Пространство → пользователь → участник
+	invitations map[string]invitation               // Хеш токена → приглашение
+}
+
+// NewState создаёт пустое состояние. Если journal не nil, каждое изменение
+// применяется только после его успешной записи в журнал.
+func NewState(journal func(Event) error) *State {
+	return &State{
+		journal:     journal,
+		workspaces:  make(map[string]models.Workspace),
+		members:     make(map[string]map[string]models.Member),
+		invitations: make(map[string]invitation),
+	}
+}
+
+// Workspace возвращает рабочее пространство по идентификатору.
+func (s *State) Workspace(id string) (models.Workspace, error) {
+	workspace, ok := s.workspaces[id]
+	if !ok {
+		return models.Workspace{}, storage.ErrNotFound
+	}
+	return workspace, nil
+}
+
+// UserWorkspaces возвращает пространства пользователя, отсортированные по дате создания.
+func (s *State) UserWorkspaces(userID string) []models.Membership {
+	memberships := []models.Membership{}
+	for id, members := range s.members {
+		if member, ok := members[userID]; ok {
+			memberships = append(memberships, models.Membership{Workspace: s.workspaces[id], Role: member.Role})
+		}
+	}
+	sort.Slice(memberships, func(i, j int) bool {
+		return memberships[i].CreatedAt.Before(memberships[j].CreatedAt)
+	})
+	return memberships
+}
+
+// Member возвращает участника пространства.
+func (s *State) Member(workspaceID, userID string) (models.Member, error) {
+	member, ok := s.members[workspaceID][userID]
+	if !ok {
+		return models.Member{}, storage.ErrNotFound
+	}
+	return member, nil
+}
+
+// Members возвращает участников пространства в порядке вступления.
+func (s *State) Members(workspaceID string) []models.Member {
+	members := []models.Member{}
+	for _, member := range s.members[workspaceID] {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].JoinedAt.Equal(members[j].JoinedAt) {
+			return members[i].UserID < members[j].UserID
+		}
+		return members[i].JoinedAt.Before(members[j].JoinedAt)
+	})
+	return members
+}
+
+// Invitations возвращает приглашения в пространство в порядке создания.
+func (s *State) Invitations(workspaceID string) []models.Invitation {
+	invitations := []models.Invitation{}
+	for _, inv := range s.invitations {
+		if inv.WorkspaceID == workspaceID {
+			invitations = append(invitations, inv.Invitation)
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool {
+		return invitations[i].CreatedAt.Before(invitations[j].CreatedAt)
+	})
+	return invitations
+}
+
+// check проверяет, что событие может быть применено к текущему состоянию.
+func (s *State) check(e Event) error {
+	switch e.Type {
+	case WorkspaceCreated:
+		if e.Workspace == nil || e.Member == nil {
+			return fmt.Errorf("invalid %s event", e.Type)
+		}
+		if _, ok := s.workspaces[e.Workspace.ID]; ok {
+			return fmt.Errorf("workspace %s already exists", e.Workspace.ID)
+		}
+		return nil
+	case MemberSaved:
+		if e.Member == nil {
+			return fmt.Errorf("invalid %s event", e.Type)
+		}
+		_, err := s.Workspace(e.Member.WorkspaceID)
+		return err
+	case MemberDeleted:
+		_, err := s.Member(e.WorkspaceID, e.UserID)
+		return err
+	case InvitationCreated:
+		if e.Invitation == nil || e.TokenHash == "" {
+			return fmt.Errorf("invalid %s event", e.Type)
+		}
+		_, err := s.Workspace(e.Invitation.WorkspaceID)
+		return err
+	case InvitationDeleted:
+		if _, ok := s.invitationByID(e.WorkspaceID, e.ID); !ok {
+			return storage.ErrNotFound
+		}
+		return nil
+	case InvitationAccepted:
+		inv, ok := s.invitations[e.TokenHash]
+		if !ok || !e.Time.Before(inv.ExpiresAt) {
+			return storage.ErrNotFound
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown event type %q", e.Type)
+}
+
+// CreateWorkspace создаёт рабочее пространство с владельцем owner.
+func (s *State) CreateWorkspace(workspace models.Workspace, owner models.Member) error {
+	return s.apply(Event{Type: WorkspaceCreated, Workspace: &workspace, Member: &owner})
+}
+
+// SaveMember добавляет участника или меняет его роль.
+func (s *State) SaveMember(member models.Member) error {
+	return s.apply(Event{Type: MemberSaved, Member: &member})
+}
+
+// DeleteMember исключает участника из пространства.
+func (s *State) DeleteMember(workspaceID, userID string) error {
+	return s.apply(Event{Type: MemberDeleted, WorkspaceID: workspaceID, UserID: userID})
+}
+
+// SaveInvitation сохраняет приглашение с хешем токена tokenHash.
+func (s *State) SaveInvitation(inv models.Invitation, tokenHash string) error {
+	inv.Token = ""
+	return s.apply(Event{Type: InvitationCreated, Invitation: &inv, TokenHash: tokenHash})
+}
+
+// DeleteInvitation отзывает приглашение.
+func (s *State) DeleteInvitation(workspaceID, id string) error {
+	return s.apply(Event{Type: InvitationDeleted, WorkspaceID: workspaceID, ID: id})
+}
+
+// AcceptInvitation принимает приглашение и возвращает участника пространства.
+func (s *State) AcceptInvitation(tokenHash, userID string, now time.Time) (models.Member, error) {
+	workspaceID := s.invitations[tokenHash].WorkspaceID
+	if err := s.apply(Event{Type: InvitationAccepted, TokenHash: tokenHash, UserID: userID, Time: now}); err != nil {
+		return models.Member{}, err
+	}
+	return s.Member(workspaceID, userID)
+}
+
+// Replay применяет событие из журнала без повторной записи в журнал.
+func (s *State) Replay(e Event) error {
+	if err := s.check(e); err != nil {
+		return err
+	}
+	s.mutate(e)
+	return nil
+}
+
+// apply проверяет событие, записывает его в журнал и применяет.
+func (s *State) apply(e Event) error {
+	if err := s.check(e); err != nil {
+		return err
+	}
+	if s.journal != nil {
+		if err := s.journal(e); err != nil {
+			return err
+		}
+	}
+	s.mutate(e)
+	return nil
+}
+
+func (s *State) mutate(e Event) {
+	switch e.Type {
+	case WorkspaceCreated:
+		s.workspaces[e.Workspace.ID] = *e.Workspace
+		s.saveMember(*e.Member)
+	case MemberSaved:
+		s.saveMember(*e.Member)
+	case MemberDeleted:
+		delete(s.members[e.WorkspaceID], e.UserID)
+	case InvitationCreated:
+		s.invitations[e.TokenHash] = invitation{Invitation: *e.Invitation, tokenHash: e.TokenHash}
+	case InvitationDeleted:
+		inv, _ := s.invitationByID(e.WorkspaceID, e.ID)
+		delete(s.invitations, inv.tokenHash)
+	case InvitationAccepted:
+		inv := s.invitations[e.TokenHash]
+		delete(s.invitations, e.TokenHash)
+		if _, err := s.Member(inv.WorkspaceID, e.UserID); err != nil {
+			s.saveMember(models.Member{WorkspaceID: inv.WorkspaceID, UserID: e.UserID, Role: inv.Role, JoinedAt: e.Time})
+		}
+	}
+}
+
+func (s *State) saveMember(member models.Member) {
+	members, ok := s.members[member.WorkspaceID]
+	if !ok {
+		members = make(map[string]models.Member)
+		s.members[member.WorkspaceID] = members
+	}
+	members[member.UserID] = member
+}
+
+func (s *State) invitationByID(workspaceID, id string) (invitation, bool) {
+	for _, inv := range s.invitations {
+		if inv.WorkspaceID == workspaceID && inv.ID == id {
+			return inv, true
+		}
+	}
+	return invitation{}, false
+}
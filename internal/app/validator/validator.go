@@ -2,6 +2,7 @@ package validator
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"regexp"
 )
@@ -24,3 +25,11 @@ func ValidateBaseURL(baseURL string) error {
 	}
 	return nil
 }
+
+// ValidateCIDR проверяет корректность подсети в нотации CIDR.
+func ValidateCIDR(cidr string) error {
+	if _, _, err := net.ParseCIDR(cidr); err != nil {
+		return fmt.Errorf("invalid subnet: %v", err)
+	}
+	return nil
+}
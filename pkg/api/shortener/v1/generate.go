@@ -0,0 +1,4 @@
+// Package shortenerv1 содержит сгенерированные по shortener.proto типы и клиент gRPC API сервиса сокращения ссылок.
+package shortenerv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative shortener.proto
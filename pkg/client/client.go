@@ -0,0 +1,372 @@
+// Package client содержит клиент HTTP API сервиса сокращения ссылок.
+//
+// Клиент сжимает тела запросов и принимает сжатые ответы, повторяет запросы, отклонённые
+// ограничением частоты (429) или завершившиеся ошибкой сервера (5xx), и аутентифицируется
+// по cookie, API-ключу или JWT:
+//
+//	c, err := client.New("https://short.example.com", client.WithAPIKey(os.Getenv("SHORTENER_API_KEY")))
+//	result, err := c.Shorten(ctx, "https://example.com/very/long/url")
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Параметры повтора запросов по умолчанию.
+const (
+	DefaultMaxAttempts = 3
+	DefaultMinBackoff  = 100 * time.Millisecond
+	DefaultMaxBackoff  = 2 * time.Second
+)
+
+// maxErrorBodySize ограничивает текст ошибки, читаемый из ответа.
+const maxErrorBodySize = 4 << 10
+
+// Ошибки, с которыми сравниваются ошибки API через errors.Is.
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrGone         = errors.New("gone")
+)
+
+// APIError описывает ответ сервиса с кодом ошибки. Поля Type, RequestID и Fields заполняются
+// из описания проблемы (application/problem+json), если сервис его вернул.
+type APIError struct {
+	StatusCode int
+	Message    string       // Текст ответа сервиса или описание проблемы
+	Type       string       // Тип проблемы
+	RequestID  string       // Идентификатор запроса на сервере
+	Fields     []FieldError // Ошибки в полях тела запроса
+}
+
+// FieldError описывает ошибку в поле тела запроса.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	message := e.Message
+	for _, field := range e.Fields {
+		if field.Field == "" {
+			message += "; " + field.Message
+		} else {
+			message += "; " + field.Field + ": " + field.Message
+		}
+	}
+	if message == "" {
+		return fmt.Sprintf("shortener: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("shortener: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), strings.TrimPrefix(message, "; "))
+}
+
+// Is сопоставляет ошибку с ErrUnauthorized, ErrForbidden, ErrNotFound, ErrConflict и ErrGone по коду ответа.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrGone:
+		return e.StatusCode == http.StatusGone
+	}
+	return false
+}
+
+// Client выполняет запросы к API сервиса сокращения ссылок. Клиент безопасен
+// для одновременного использования из нескольких горутин.
+type Client struct {
+	baseURL     *url.URL
+	httpClient  *http.Client
+	bearer      string
+	cookies     []*http.Cookie
+	gzip        bool
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+}
+
+// Option настраивает клиент.
+type Option func(*Client)
+
+// WithHTTPClient задаёт HTTP-клиент для запросов. Клиент без хранилища cookie получает собственное,
+// чтобы сохранять идентификатор пользователя между запросами.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey аутентифицирует запросы API-ключом.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.bearer = key
+	}
+}
+
+// WithToken аутентифицирует запросы JWT, например полученным в обмен на API-ключ.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.bearer = token
+	}
+}
+
+// WithCookie аутентифицирует запросы cookie пользователя, выданной сервисом.
+func WithCookie(cookie *http.Cookie) Option {
+	return func(c *Client) {
+		c.cookies = append(c.cookies, cookie)
+	}
+}
+
+// WithGzip включает или отключает сжатие тел запросов и ответов. По умолчанию сжатие включено.
+func WithGzip(enabled bool) Option {
+	return func(c *Client) {
+		c.gzip = enabled
+	}
+}
+
+// WithRetry задаёт количество попыток выполнения запроса и границы экспоненциальной задержки между ними.
+// Значение attempts = 1 отключает повторы.
+func WithRetry(attempts int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = max(attempts, 1)
+		c.minBackoff = minBackoff
+		c.maxBackoff = max(maxBackoff, minBackoff)
+	}
+}
+
+// New создаёт клиент сервиса с адресом baseURL.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/") + "/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: expected http or https scheme", baseURL)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	c := &Client{
+		baseURL:     u,
+		httpClient:  &http.Client{Jar: jar},
+		gzip:        true,
+		maxAttempts: DefaultMaxAttempts,
+		minBackoff:  DefaultMinBackoff,
+		maxBackoff:  DefaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.httpClient.Jar == nil {
+		httpClient := *c.httpClient
+		httpClient.Jar = jar
+		c.httpClient = &httpClient
+	}
+	c.httpClient.Jar.SetCookies(c.baseURL, c.cookies)
+	return c, nil
+}
+
+// Cookies возвращает cookie, выданные клиенту сервисом, например для сохранения идентификатора пользователя.
+func (c *Client) Cookies() []*http.Cookie {
+	return c.httpClient.Jar.Cookies(c.baseURL)
+}
+
+// request описывает запрос к API.
+type request struct {
+	method      string
+	path        string // Путь относительно адреса сервиса
+	query       url.Values
+	body        []byte
+	contentType string
+	noRedirect  bool // Возвращать ответы с редиректом, не переходя по ним
+}
+
+// response содержит ответ сервиса с распакованным телом.
+type response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// jsonRequest кодирует тело запроса в JSON.
+func jsonRequest(method, path string, v any) (request, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return request{}, fmt.Errorf("encode request: %w", err)
+	}
+	return request{method: method, path: path, body: body, contentType: "application/json"}, nil
+}
+
+// do выполняет запрос, повторяя его при сетевых ошибках, ответах 429 и 5xx с экспоненциальной задержкой.
+// Заголовок Retry-After ответа имеет приоритет над вычисленной задержкой.
+func (c *Client) do(ctx context.Context, req request) (*response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, req)
+		if attempt >= c.maxAttempts || !retryable(ctx, resp, err) {
+			return resp, err
+		}
+
+		timer := time.NewTimer(c.backoff(attempt, resp))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, req request) (*response, error) {
+	target := c.baseURL.ResolveReference(&url.URL{Path: req.path, RawQuery: req.query.Encode()})
+
+	var body io.Reader
+	encoding := ""
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+		if c.gzip {
+			compressed, err := compress(req.body)
+			if err != nil {
+				return nil, err
+			}
+			body, encoding = bytes.NewReader(compressed), "gzip"
+		}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	if encoding != "" {
+		httpReq.Header.Set("Content-Encoding", encoding)
+	}
+	if c.gzip {
+		httpReq.Header.Set("Accept-Encoding", "gzip")
+	}
+	if c.bearer != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.bearer)
+	}
+
+	httpClient := c.httpClient
+	if req.noRedirect {
+		noRedirect := *c.httpClient
+		noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		httpClient = &noRedirect
+	}
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	reader := io.Reader(httpResp.Body)
+	if httpResp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(httpResp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("decompress response: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+	respBody, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	return &response{StatusCode: httpResp.StatusCode, Header: httpResp.Header, Body: respBody}, nil
+}
+
+// retryable сообщает, стоит ли повторить запрос.
+func retryable(ctx context.Context, resp *response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// backoff возвращает задержку перед повтором после попытки attempt: значение заголовка Retry-After
+// или экспоненциально растущую задержку со случайным разбросом.
+func (c *Client) backoff(attempt int, resp *response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	delay := c.maxBackoff
+	if shift := attempt - 1; shift < 32 && c.minBackoff<<shift < c.maxBackoff {
+		delay = c.minBackoff << shift
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// problem — описание ошибки в формате RFC 7807.
+type problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Detail    string       `json:"detail"`
+	RequestID string       `json:"request_id"`
+	Errors    []FieldError `json:"errors"`
+}
+
+// apiError возвращает ошибку для ответа с неожиданным кодом.
+func apiError(resp *response) error {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "application/problem+json" {
+		var p problem
+		if err := json.Unmarshal(resp.Body, &p); err == nil {
+			message := p.Detail
+			if message == "" {
+				message = p.Title
+			}
+			return &APIError{
+				StatusCode: resp.StatusCode,
+				Message:    message,
+				Type:       p.Type,
+				RequestID:  p.RequestID,
+				Fields:     p.Errors,
+			}
+		}
+	}
+
+	message := resp.Body
+	if len(message) > maxErrorBodySize {
+		message = message[:maxErrorBodySize]
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
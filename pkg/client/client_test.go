@@ -0,0 +1,250 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexuryumtsev/go-shortener/config"
+	"github.com/alexuryumtsev/go-shortener/internal/app/jwt"
+	"github.com/alexuryumtsev/go-shortener/internal/app/logger"
+	"github.com/alexuryumtsev/go-shortener/internal/app/models"
+	"github.com/alexuryumtsev/go-shortener/internal/app/router"
+	"github.com/alexuryumtsev/go-shortener/internal/app/service"
+	"github.com/alexuryumtsev/go-shortener/internal/app/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	logger.InitLogger()
+	repo := memory.NewInMemoryStorage()
+	srv := httptest.NewServer(nil)
+	t.Cleanup(srv.Close)
+	cfg := &config.Config{BaseURL: srv.URL, SecretKey: "secret"}
+	shortener := router.ShortenerRouter(cfg, repo, nil)
+
+	// Запоминаем кодировку тел запросов, чтобы проверить сжатие.
+	var mu sync.Mutex
+	var encodings []string
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength != 0 {
+			mu.Lock()
+			encodings = append(encodings, r.Header.Get("Content-Encoding"))
+			mu.Unlock()
+		}
+		shortener.ServeHTTP(w, r)
+	})
+
+	ctx := context.Background()
+	c, err := New(srv.URL)
+	require.NoError(t, err)
+
+	plain, err := c.Shorten(ctx, "https://example.com/plain")
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/"+service.GenerateID("https://example.com/plain"), plain.ShortURL)
+	assert.False(t, plain.Conflict)
+
+	tagged, err := c.ShortenJSON(ctx, ShortenRequest{URL: "https://example.com/json", Title: "Docs", Tags: []string{"go"}})
+	require.NoError(t, err)
+	// Ссылка с названием и тегами получает случайный идентификатор.
+	require.True(t, strings.HasPrefix(tagged.ShortURL, srv.URL+"/"))
+	id := strings.TrimPrefix(tagged.ShortURL, srv.URL+"/")
+	assert.NotEqual(t, service.GenerateID("https://example.com/json"), id)
+
+	batch, err := c.ShortenBatch(ctx, []BatchItem{
+		{CorrelationID: "a", OriginalURL: "https://example.com/a"},
+		{CorrelationID: "b", OriginalURL: "https://example.com/b"},
+	})
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+	assert.Equal(t, "b", batch[1].CorrelationID)
+
+	mu.Lock()
+	assert.Equal(t, []string{"gzip", "gzip", "gzip"}, encodings)
+	mu.Unlock()
+
+	t.Run("resolve", func(t *testing.T) {
+		resolution, err := c.Resolve(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, Resolution{Location: "https://example.com/json", StatusCode: http.StatusTemporaryRedirect}, resolution)
+
+		_, err = c.Resolve(ctx, "missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("list and stats", func(t *testing.T) {
+		page, err := c.UserURLs(ctx, ListOptions{})
+		require.NoError(t, err)
+		assert.Len(t, page.Items, 4)
+
+		page, err = c.UserURLs(ctx, ListOptions{Tag: "go"})
+		require.NoError(t, err)
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, "Docs", page.Items[0].Title)
+		assert.Equal(t, tagged.ShortURL, page.Items[0].ShortURL)
+
+		page, err = c.UserURLs(ctx, ListOptions{Limit: 3})
+		require.NoError(t, err)
+		require.NotEmpty(t, page.NextCursor)
+		next, err := c.UserURLs(ctx, ListOptions{Limit: 3, Cursor: page.NextCursor})
+		require.NoError(t, err)
+		assert.Len(t, next.Items, 1)
+
+		stats, err := c.Stats(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), stats.Clicks)
+	})
+
+	t.Run("cookie", func(t *testing.T) {
+		cookies := c.Cookies()
+		require.NotEmpty(t, cookies)
+		same, err := New(srv.URL, WithCookie(cookies[0]))
+		require.NoError(t, err)
+		page, err := same.UserURLs(ctx, ListOptions{})
+		require.NoError(t, err)
+		assert.Len(t, page.Items, 4)
+
+		other, err := New(srv.URL)
+		require.NoError(t, err)
+		page, err = other.UserURLs(ctx, ListOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, page.Items)
+		_, err = other.Stats(ctx, id)
+		assert.ErrorIs(t, err, ErrForbidden)
+	})
+
+	t.Run("api key and jwt", func(t *testing.T) {
+		key, err := service.NewAPIKeyService(ctx, repo).Create("alice", "ci", "", []string{models.ScopeLinksRead, models.ScopeLinksWrite})
+		require.NoError(t, err)
+		keys, err := jwt.ConfiguredKeySet("", cfg.SecretKey)
+		require.NoError(t, err)
+		token, err := jwt.NewAuthority(keys, "", "").Issue(jwt.Claims{Subject: "alice"}, time.Minute, time.Now())
+		require.NoError(t, err)
+
+		withKey, err := New(srv.URL, WithAPIKey(key.Key))
+		require.NoError(t, err)
+		_, err = withKey.Shorten(ctx, "https://example.com/alice")
+		require.NoError(t, err)
+
+		withToken, err := New(srv.URL, WithToken(token), WithGzip(false))
+		require.NoError(t, err)
+		page, err := withToken.UserURLs(ctx, ListOptions{})
+		require.NoError(t, err)
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, "https://example.com/alice", page.Items[0].URL)
+
+		invalid, err := New(srv.URL, WithAPIKey("sk_invalid"))
+		require.NoError(t, err)
+		_, err = invalid.UserURLs(ctx, ListOptions{})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("problem details", func(t *testing.T) {
+		_, err := c.ShortenJSON(ctx, ShortenRequest{URL: "example.com"})
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		assert.Equal(t, "/api/docs#problem-invalid-input", apiErr.Type)
+		assert.Equal(t, []FieldError{{Field: "url", Message: `must be an absolute URL, got "example.com"`}}, apiErr.Fields)
+		assert.NotEmpty(t, apiErr.RequestID)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, c.DeleteURLs(ctx, id, "missing"))
+		_, err := c.Resolve(ctx, id)
+		assert.ErrorIs(t, err, ErrGone)
+	})
+}
+
+func TestClient_Conflict(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte("http://localhost/abc"))
+		case "/api/shorten":
+			json.NewEncoder(w).Encode(map[string]string{"result": "http://localhost/abc"})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	c, err := New(srv.URL)
+	require.NoError(t, err)
+
+	result, err := c.Shorten(context.Background(), "https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, ShortenResult{ShortURL: "http://localhost/abc", Conflict: true}, result)
+
+	result, err = c.ShortenJSON(context.Background(), ShortenRequest{URL: "https://example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, ShortenResult{ShortURL: "http://localhost/abc", Conflict: true}, result)
+
+	_, err = c.ShortenBatch(context.Background(), []BatchItem{{CorrelationID: "1", OriginalURL: "https://example.com"}})
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestClient_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     []int
+		attempts     int
+		wantAttempts int32
+		wantErr      bool
+	}{
+		{name: "server errors", failures: []int{http.StatusServiceUnavailable, http.StatusBadGateway}, attempts: 3, wantAttempts: 3},
+		{name: "rate limited", failures: []int{http.StatusTooManyRequests}, attempts: 3, wantAttempts: 2},
+		{name: "attempts exhausted", failures: []int{500, 500, 500}, attempts: 2, wantAttempts: 2, wantErr: true},
+		{name: "client error is not retried", failures: []int{http.StatusBadRequest}, attempts: 3, wantAttempts: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1))
+				if n <= len(tt.failures) {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(tt.failures[n-1])
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("http://localhost/abc"))
+			}))
+			defer srv.Close()
+
+			c, err := New(srv.URL, WithRetry(tt.attempts, time.Millisecond, time.Millisecond))
+			require.NoError(t, err)
+			result, err := c.Shorten(context.Background(), "https://example.com")
+			assert.Equal(t, tt.wantAttempts, calls.Load())
+			if tt.wantErr {
+				var apiErr *APIError
+				require.True(t, errors.As(err, &apiErr))
+				assert.Equal(t, tt.failures[tt.wantAttempts-1], apiErr.StatusCode)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "http://localhost/abc", result.ShortURL)
+		})
+	}
+}
+
+func TestClient_Backoff(t *testing.T) {
+	c, err := New("http://localhost", WithRetry(5, 100*time.Millisecond, time.Second))
+	require.NoError(t, err)
+
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		delay := c.backoff(attempt, nil)
+		assert.GreaterOrEqual(t, delay, want/2)
+		assert.LessOrEqual(t, delay, want)
+	}
+	assert.Equal(t, 2*time.Second, c.backoff(1, &response{Header: http.Header{"Retry-After": {"2"}}}))
+
+	_, err = New("localhost:8080")
+	assert.Error(t, err)
+}
@@ -0,0 +1,268 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoRedirect возвращается Resolve для ссылок, которые вместо редиректа показывают
+// страницу предпросмотра или форму ввода пароля.
+var ErrNoRedirect = errors.New("link does not redirect")
+
+// ShortenRequest описывает сокращаемую ссылку.
+type ShortenRequest struct {
+	URL          string   `json:"url"`
+	Title        string   `json:"title,omitempty"`
+	Notes        string   `json:"notes,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Folder       string   `json:"folder,omitempty"`
+	MaxClicks    int64    `json:"max_clicks,omitempty"`   // Допустимое количество переходов, 0 — без ограничений
+	Interstitial bool     `json:"interstitial,omitempty"` // Всегда показывать страницу предпросмотра
+	Password     string   `json:"password,omitempty"`     // Пароль защищённой ссылки
+	Workspace    string   `json:"-"`                      // Рабочее пространство, в котором создаётся ссылка
+}
+
+// ShortenResult содержит короткую ссылку. Conflict означает, что адрес уже был сокращён
+// и возвращена существующая ссылка.
+type ShortenResult struct {
+	ShortURL string
+	Conflict bool
+}
+
+// BatchItem описывает ссылку пакетного сокращения.
+type BatchItem struct {
+	CorrelationID string `json:"correlation_id"`
+	OriginalURL   string `json:"original_url"`
+}
+
+// BatchResult содержит короткую ссылку для элемента пакета с тем же CorrelationID.
+// Status — created для созданной ссылки или conflict, если адрес уже был сокращён.
+type BatchResult struct {
+	CorrelationID string `json:"correlation_id"`
+	ShortURL      string `json:"short_url"`
+	Status        string `json:"status"`
+}
+
+// Resolution описывает редирект короткой ссылки.
+type Resolution struct {
+	Location   string // Адрес назначения
+	StatusCode int    // HTTP-код редиректа
+}
+
+// ListOptions задаёт фильтры и страницу списка ссылок.
+type ListOptions struct {
+	Workspace string // Рабочее пространство; пустое — личные ссылки или пространство API-ключа
+	Tag       string
+	Folder    string
+	Query     string // Слова, которые должны встречаться в названии, заметках, адресе или тегах
+	Limit     int
+	Cursor    string // NextCursor предыдущей страницы
+}
+
+// Link описывает ссылку пользователя.
+type Link struct {
+	ID           string     `json:"id"`
+	ShortURL     string     `json:"short_url"`
+	URL          string     `json:"url"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Clicks       int64      `json:"clicks"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RedirectCode int        `json:"redirect_code,omitempty"`
+	Title        string     `json:"title,omitempty"`
+	Notes        string     `json:"notes,omitempty"`
+	Tags         []string   `json:"tags,omitempty"`
+	Folder       string     `json:"folder,omitempty"`
+	Workspace    string     `json:"workspace_id,omitempty"`
+}
+
+// LinkPage содержит страницу списка ссылок.
+type LinkPage struct {
+	Items      []Link `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"` // Пустой, если страница последняя
+}
+
+// Stats содержит статистику переходов по ссылке.
+type Stats struct {
+	ID        string         `json:"id"`
+	URL       string         `json:"url"`
+	CreatedAt time.Time      `json:"created_at"`
+	Clicks    int64          `json:"clicks"`
+	MaxClicks int64          `json:"max_clicks,omitempty"`
+	Variants  []VariantStats `json:"variants,omitempty"`
+}
+
+// VariantStats содержит статистику переходов по варианту A/B-теста.
+type VariantStats struct {
+	Name   string `json:"name,omitempty"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	Clicks int64  `json:"clicks"`
+}
+
+// QROptions задаёт формат и размер QR-кода.
+type QROptions struct {
+	Format string // png или svg, по умолчанию png
+	Size   int    // Размер изображения в пикселях, 0 — по умолчанию сервиса
+}
+
+// Shorten сокращает ссылку через POST / с адресом в теле запроса.
+func (c *Client) Shorten(ctx context.Context, originalURL string) (ShortenResult, error) {
+	resp, err := c.do(ctx, request{method: http.MethodPost, body: []byte(originalURL), contentType: "text/plain"})
+	if err != nil {
+		return ShortenResult{}, err
+	}
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusConflict:
+		return ShortenResult{
+			ShortURL: strings.TrimSpace(string(resp.Body)),
+			Conflict: resp.StatusCode == http.StatusConflict,
+		}, nil
+	default:
+		return ShortenResult{}, apiError(resp)
+	}
+}
+
+// ShortenJSON сокращает ссылку с дополнительными параметрами через POST /api/shorten
+// или POST /api/workspaces/{workspace}/urls.
+func (c *Client) ShortenJSON(ctx context.Context, shorten ShortenRequest) (ShortenResult, error) {
+	path := "api/shorten"
+	if shorten.Workspace != "" {
+		path = "api/workspaces/" + url.PathEscape(shorten.Workspace) + "/urls"
+	}
+	req, err := jsonRequest(http.MethodPost, path, shorten)
+	if err != nil {
+		return ShortenResult{}, err
+	}
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return ShortenResult{}, err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusConflict {
+		return ShortenResult{}, apiError(resp)
+	}
+
+	var body struct {
+		Result string `json:"result"`
+	}
+	if err := json.Unmarshal(resp.Body, &body); err != nil {
+		return ShortenResult{}, fmt.Errorf("decode response: %w", err)
+	}
+	return ShortenResult{ShortURL: body.Result, Conflict: resp.StatusCode == http.StatusConflict}, nil
+}
+
+// ShortenBatch сокращает несколько ссылок одним запросом POST /api/shorten/batch. Уже сокращённые
+// адреса возвращаются со статусом conflict; серверы прежних версий вместо этого не сохраняют пакет
+// и отвечают ошибкой, совпадающей с ErrConflict.
+func (c *Client) ShortenBatch(ctx context.Context, items []BatchItem) ([]BatchResult, error) {
+	req, err := jsonRequest(http.MethodPost, "api/shorten/batch", items)
+	if err != nil {
+		return nil, err
+	}
+	var results []BatchResult
+	if err := c.doJSON(ctx, req, http.StatusCreated, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Resolve возвращает адрес назначения короткой ссылки, не переходя по нему. Запрос учитывается
+// в статистике как переход. Для ссылок со страницей предпросмотра или паролем возвращается ErrNoRedirect.
+func (c *Client) Resolve(ctx context.Context, id string) (Resolution, error) {
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: url.PathEscape(id), noRedirect: true})
+	if err != nil {
+		return Resolution{}, err
+	}
+	switch {
+	case resp.StatusCode >= http.StatusMultipleChoices && resp.StatusCode < http.StatusBadRequest:
+		return Resolution{Location: resp.Header.Get("Location"), StatusCode: resp.StatusCode}, nil
+	case resp.StatusCode == http.StatusOK:
+		return Resolution{}, ErrNoRedirect
+	default:
+		return Resolution{}, apiError(resp)
+	}
+}
+
+// UserURLs возвращает страницу ссылок пользователя или рабочего пространства.
+func (c *Client) UserURLs(ctx context.Context, opts ListOptions) (LinkPage, error) {
+	path := "api/user/urls"
+	if opts.Workspace != "" {
+		path = "api/workspaces/" + url.PathEscape(opts.Workspace) + "/urls"
+	}
+	query := url.Values{}
+	for name, value := range map[string]string{"tag": opts.Tag, "folder": opts.Folder, "q": opts.Query, "cursor": opts.Cursor} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+
+	var page LinkPage
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: path, query: query}, http.StatusOK, &page)
+	return page, err
+}
+
+// DeleteURLs удаляет ссылки. Ссылки, которые пользователь не вправе удалять, пропускаются сервисом.
+func (c *Client) DeleteURLs(ctx context.Context, ids ...string) error {
+	req, err := jsonRequest(http.MethodDelete, "api/user/urls", ids)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return apiError(resp)
+	}
+	return nil
+}
+
+// Stats возвращает статистику переходов по ссылке.
+func (c *Client) Stats(ctx context.Context, id string) (Stats, error) {
+	var stats Stats
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: "api/urls/" + url.PathEscape(id) + "/stats"}, http.StatusOK, &stats)
+	return stats, err
+}
+
+// QR возвращает изображение QR-кода короткой ссылки.
+func (c *Client) QR(ctx context.Context, id string, opts QROptions) ([]byte, error) {
+	query := url.Values{}
+	if opts.Format != "" {
+		query.Set("format", opts.Format)
+	}
+	if opts.Size > 0 {
+		query.Set("size", strconv.Itoa(opts.Size))
+	}
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: "api/urls/" + url.PathEscape(id) + "/qr", query: query})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError(resp)
+	}
+	return resp.Body, nil
+}
+
+// doJSON выполняет запрос и декодирует JSON-ответ с кодом status в v.
+func (c *Client) doJSON(ctx context.Context, req request, status int, v any) error {
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != status {
+		return apiError(resp)
+	}
+	if err := json.Unmarshal(resp.Body, v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}